@@ -0,0 +1,156 @@
+package object
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/chanyoung/nil/pkg/client"
+	cr "github.com/chanyoung/nil/pkg/client/request"
+	"github.com/chanyoung/nil/pkg/cmap"
+	"github.com/pkg/errors"
+)
+
+// maxCopySourcesSize is the maximum size of the copy sources in the body of
+// the copy request, which is enough for the sources of 10000 parts.
+const maxCopySourcesSize = 8 * 1024 * 1024
+
+// copySource is the copy source with the url of its data in the source ds.
+type copySource struct {
+	client.CopySource
+	url string
+}
+
+// copyToPrimary writes the object whose data is copied from the sources in
+// the request body. The data is read from the ds of each source directly,
+// so it does not pass through the gateway. The source ds must be an alive
+// ds in the cluster map, and the url of its data is built here.
+func (h *handlers) copyToPrimary(w http.ResponseWriter, r *http.Request) {
+	reqSources := make([]client.CopySource, 0)
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxCopySourcesSize)).Decode(&reqSources); err != nil {
+		http.Error(w, "invalid copy sources", http.StatusBadRequest)
+		return
+	}
+
+	var size int64
+	sources := make([]copySource, len(reqSources))
+	for i, s := range reqSources {
+		if s.Offset < 0 || s.Length <= 0 {
+			http.Error(w, "invalid copy source range", http.StatusBadRequest)
+			return
+		}
+		if s.Bucket == "" || s.ObjectID == "" {
+			http.Error(w, "invalid copy source object", http.StatusBadRequest)
+			return
+		}
+
+		id, err := strconv.ParseInt(s.DsID, 10, 64)
+		if err != nil {
+			http.Error(w, "invalid copy source ds", http.StatusBadRequest)
+			return
+		}
+		ds, err := h.cmapAPI.SearchCall().Node().ID(cmap.ID(id)).Type(cmap.DS).Status(cmap.NodeAlive).Do()
+		if err != nil {
+			http.Error(w, "no alive copy source ds", http.StatusBadRequest)
+			return
+		}
+
+		sources[i] = copySource{
+			CopySource: s,
+			url:        "https://" + ds.Addr.String() + "/" + url.PathEscape(s.Bucket) + "/" + url.PathEscape(s.ObjectID),
+		}
+		size += s.Length
+	}
+
+	in := &sourcesReader{sources: sources}
+	defer in.Close()
+
+	h.write(w, r, in, size)
+}
+
+// sourcesReader reads the copy sources in order.
+type sourcesReader struct {
+	sources []copySource
+	cur     io.ReadCloser
+	// remain is the length of the current source which is not read yet.
+	remain int64
+}
+
+// next opens the next source.
+func (r *sourcesReader) next() error {
+	if len(r.sources) == 0 {
+		return io.EOF
+	}
+
+	body, err := openSource(r.sources[0])
+	if err != nil {
+		return err
+	}
+
+	r.cur, r.remain, r.sources = body, r.sources[0].Length, r.sources[1:]
+	return nil
+}
+
+func (r *sourcesReader) Read(p []byte) (int, error) {
+	for {
+		if r.cur == nil {
+			if err := r.next(); err != nil {
+				return 0, err
+			}
+		}
+
+		if int64(len(p)) > r.remain {
+			p = p[:r.remain]
+		}
+		n, err := r.cur.Read(p)
+		r.remain -= int64(n)
+
+		if r.remain == 0 {
+			r.cur.Close()
+			r.cur = nil
+			if n == 0 {
+				continue
+			}
+			return n, nil
+		}
+		if err == io.EOF {
+			// The source ds sent less data than requested.
+			return n, io.ErrUnexpectedEOF
+		}
+		return n, err
+	}
+}
+
+func (r *sourcesReader) Close() error {
+	if r.cur == nil {
+		return nil
+	}
+	return r.cur.Close()
+}
+
+// openSource opens a stream which reads the copy source from its ds.
+func openSource(s copySource) (io.ReadCloser, error) {
+	headers := client.NewHeaders()
+	headers.SetLocalChainID(s.ChainID)
+	headers.SetVolumeID(s.VolumeID)
+	headers.SetRange(s.Offset, s.Length)
+
+	req, err := cr.NewRequest(client.ReadFromPrimary, http.MethodGet, s.url, nil, headers, 0)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create source ds request")
+	}
+
+	resp, err := req.Send()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to send source ds request")
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("source ds returns http status code: %d", resp.StatusCode)
+	}
+
+	return resp.Body, nil
+}
@@ -0,0 +1,101 @@
+package object
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/chanyoung/nil/app/ds/infrastructure/repository"
+	"github.com/chanyoung/nil/pkg/client"
+	"github.com/chanyoung/nil/pkg/s3"
+	"github.com/chanyoung/nil/pkg/util/mlog"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+)
+
+// maxDeleteObjectsSize is the maximum size of the object ids in the body of
+// the DeleteObjectsFromPrimary request.
+const maxDeleteObjectsSize = 8 * 1024 * 1024
+
+// DeleteObjectsHandler handles the request for deleting the objects in a
+// volume. The result lists the objects which are failed to be deleted.
+func (h *handlers) DeleteObjectsHandler(w http.ResponseWriter, r *http.Request) {
+	ctxLogger := mlog.GetMethodLogger(logger, "handlers.DeleteObjectsHandler")
+
+	if client.RequestType(r.Header.Get("Request-Type")) != client.DeleteObjectsFromPrimary {
+		http.Error(w, "not supported request type", http.StatusBadRequest)
+		return
+	}
+
+	oids := make([]string, 0)
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxDeleteObjectsSize)).Decode(&oids); err != nil {
+		http.Error(w, "invalid object ids", http.StatusBadRequest)
+		return
+	}
+
+	bucket, vol := mux.Vars(r)["bucket"], r.Header.Get("Volume-Id")
+	result := client.DeleteObjectsResult{Errors: make([]client.DeleteObjectError, 0)}
+
+	// Look up the locks of all the objects at once. Every object is failed
+	// to be deleted if the locks are unknown.
+	locks, err := h.dataLocks(bucket, oids)
+	if err != nil {
+		ctxLogger.Error(errors.Wrap(err, "failed to get the object locks"))
+	}
+
+	for _, oid := range oids {
+		if locks == nil {
+			result.Errors = append(result.Errors, client.DeleteObjectError{
+				ObjectID:    oid,
+				Message:     "failed to get the object lock",
+				LockUnknown: true,
+			})
+			continue
+		}
+
+		code, err := h.deleteObject(vol, oid, locks[oid])
+		if err == nil {
+			continue
+		}
+		if code == http.StatusInternalServerError {
+			ctxLogger.Error(err)
+		}
+		result.Errors = append(result.Errors, client.DeleteObjectError{
+			ObjectID: oid,
+			Message:  err.Error(),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		ctxLogger.Error(errors.Wrap(err, "failed to write the result"))
+	}
+}
+
+// deleteObject deletes the object in the volume, and returns the http
+// status of the failure. The data protected by the given object lock of its
+// version is not deleted. The governance mode is never bypassed here, since
+// the version bypassing the governance is removed in the mds first, which
+// releases its data.
+func (h *handlers) deleteObject(vol, oid string, lock s3.ObjectLock) (int, error) {
+	if lock.Protected(false, time.Now()) {
+		return http.StatusForbidden, errors.New("object is locked")
+	}
+
+	storeReq := &repository.Request{
+		Op:  repository.Delete,
+		Vol: vol,
+		Oid: oid,
+	}
+
+	if err := h.store.Push(storeReq); err != nil {
+		return http.StatusInternalServerError, errors.Wrap(err, "failed to push deleting request into the backend store")
+	}
+
+	if err := storeReq.Wait(); err != nil {
+		return http.StatusInternalServerError, errors.Wrap(err, "failed to delete object")
+	}
+
+	return http.StatusNoContent, nil
+}
@@ -1,14 +1,25 @@
 package object
 
 import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
 	"net/http"
+	"net/rpc"
+	"strconv"
+	"time"
 
+	"github.com/chanyoung/nil/app/ds/infrastructure/repository"
 	"github.com/chanyoung/nil/pkg/client"
 	cr "github.com/chanyoung/nil/pkg/client/request"
 	"github.com/chanyoung/nil/pkg/cmap"
 	"github.com/chanyoung/nil/pkg/nilrpc"
+	"github.com/chanyoung/nil/pkg/s3"
 	"github.com/chanyoung/nil/pkg/util/config"
 	"github.com/chanyoung/nil/pkg/util/mlog"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 )
 
@@ -20,6 +31,9 @@ type handlers struct {
 	store Repository
 	// endec               *endec
 	cmapAPI cmap.SlaveAPI
+	// dataLocks returns the object locks of the versions which own the
+	// data of the objects, keyed by the object id.
+	dataLocks func(bucket string, oids []string) (map[string]s3.ObjectLock, error)
 }
 
 // NewHandlers creates a client handlers with necessary dependencies.
@@ -43,36 +57,28 @@ func NewHandlers(cfg *config.Ds, cmapAPI cmap.SlaveAPI, f *cr.RequestEventFactor
 	// }
 	// go ed.Run()
 
-	return &handlers{
+	h := &handlers{
 		requestEventFactory: f,
 		// chunkPool:           pool,
 		// endec:               ed,
 		store:   s,
 		cmapAPI: cmapAPI,
-	}, nil
+	}
+	h.dataLocks = h.getDataLocks
+
+	return h, nil
 }
 
 // PutObjectHandler handles the client request for creating an object.
 func (h *handlers) PutObjectHandler(w http.ResponseWriter, r *http.Request) {
-	http.Error(w, "not implemented", http.StatusNotImplemented)
-	// ctxLogger := mlog.GetMethodLogger(logger, "handlers.PutObjectHandler")
-
-	// req, err := h.requestEventFactory.CreateRequestEvent(w, r)
-	// if err == client.ErrInvalidProtocol {
-	// 	ctxLogger.Error(errors.Wrap(err, "failed to create request event"))
-	// 	http.Error(w, err.Error(), http.StatusBadRequest)
-	// 	return
-	// }
-
-	// switch req.Type() {
-	// case client.WriteToPrimary:
-	// 	h.writeToPrimary(req)
-	// case client.WriteToFollower:
-	// 	h.writeCopy(req)
-	// default:
-	// 	http.Error(w, err.Error(), http.StatusBadRequest)
-	// 	return
-	// }
+	switch client.RequestType(r.Header.Get("Request-Type")) {
+	case client.WriteToPrimary:
+		h.writeToPrimary(w, r)
+	case client.CopyToPrimary:
+		h.copyToPrimary(w, r)
+	default:
+		http.Error(w, "not supported request type", http.StatusBadRequest)
+	}
 }
 
 // GetChunkHandler handles the client request for downloading a chunk.
@@ -169,89 +175,55 @@ func (h *handlers) PutChunkHandler(w http.ResponseWriter, r *http.Request) {
 	// w.WriteHeader(http.StatusOK)
 }
 
-func (h *handlers) writeToPrimary(req client.RequestEvent) {
-	// ctxLogger := mlog.GetMethodLogger(logger, "handlers.writeToPrimary")
-
-	// contentLength, err := strconv.ParseInt(req.Request().Header.Get("Content-Length"), 10, 64)
-	// if err != nil {
-	// 	ctxLogger.Error(errors.Wrap(err, "invalid content length"))
-	// 	req.SendInternalError()
-	// 	return
-	// }
-
-	// // Write into the available chunk.
-	// cid, err := h.chunkPool.FindAvailableChunk(
-	// 	egID(req.Request().Header.Get("Local-Chain-Id")),
-	// 	vID(req.Request().Header.Get("Volume-Id")), contentLength,
-	// )
-	// if err != nil {
-	// 	ctxLogger.Error(errors.Wrap(err, "failed to find available chunk"))
-	// 	req.SendInternalError()
-	// 	return
-	// }
-
-	// if len(req.MD5()) < 32 {
-	// 	req.SendInternalError()
-	// 	return
-	// }
-
-	// storeReq := &repository.Request{
-	// 	Op:     repository.Write,
-	// 	Vol:    req.Request().Header.Get("Volume-Id"),
-	// 	LocGid: req.Request().Header.Get("Local-Chain-Id"),
-	// 	Oid:    strings.Replace(strings.Trim(req.Request().RequestURI, "/"), "/", ".", -1),
-	// 	Cid:    string(cid),
-	// 	Osize:  contentLength,
-	// 	Md5:    req.MD5(),
-
-	// 	In: req.Request().Body,
-	// }
-
-	// err = h.store.Push(storeReq)
-	// if err != nil {
-	// 	ctxLogger.Error(errors.Wrap(err, "failed to push writing request into the backend store"))
-	// 	h.chunkPool.FinishWriting(cid, 0)
-	// 	req.SendInternalError()
-	// 	return
-	// }
-
-	// err = storeReq.Wait()
-	// if err != nil {
-	// 	// TODO: handling error in writing process.
-
-	// 	// Rollback writed data.
-	// 	storeReq.Cid = ""
-	// 	storeReq.Op = repository.DeleteReal
-	// 	h.store.Push(storeReq)
-	// 	storeReq.Wait()
-	// 	h.chunkPool.FinishWriting(cid, 0)
+// writeToPrimary writes the object which is sent from the gateway into the
+// store.
+func (h *handlers) writeToPrimary(w http.ResponseWriter, r *http.Request) {
+	if r.ContentLength < 0 {
+		http.Error(w, "missing content length", http.StatusLengthRequired)
+		return
+	}
 
-	// 	ctxLogger.Error(errors.Wrap(err, "failed to write into the backend store"))
-	// 	req.SendInternalError()
-	// 	return
-	// }
-
-	// // Copy to the remote follower node
-	// err = h.writeToRemoteFollower(req, contentLength, cid)
-	// if err != nil {
-	// 	// TODO: handling error in writing.
-
-	// 	// Rollback writed data.
-	// 	storeReq.Cid = ""
-	// 	storeReq.Op = repository.DeleteReal
-	// 	h.store.Push(storeReq)
-	// 	storeReq.Wait()
-	// 	h.chunkPool.FinishWriting(cid, 0)
-
-	// 	ctxLogger.Error(errors.Wrap(err, "failed to write to remote follower"))
-	// 	req.SendInternalError()
-	// 	return
-	// }
-
-	// // Commit writed data.
-	// h.chunkPool.FinishWriting(cid, contentLength)
+	h.write(w, r, r.Body, r.ContentLength)
+}
 
-	// req.SendSuccess()
+// write writes the object data of the given size into the store and
+// replies the md5 of the written data as an ETag. The store selects the
+// volume if the request does not give it, so the volume is replied too.
+func (h *handlers) write(w http.ResponseWriter, r *http.Request, in io.Reader, size int64) {
+	ctxLogger := mlog.GetMethodLogger(logger, "handlers.write")
+
+	hash := md5.New()
+	storeReq := &repository.Request{
+		Op:     repository.Write,
+		Vol:    r.Header.Get("Volume-Id"),
+		LocGid: r.Header.Get("Local-Chain-Id"),
+		Oid:    mux.Vars(r)["object"],
+		Osize:  size,
+		Md5:    r.Header.Get("Md5"),
+		In:     io.TeeReader(in, hash),
+	}
+
+	if err := h.store.Push(storeReq); err != nil {
+		ctxLogger.Error(errors.Wrap(err, "failed to push writing request into the backend store"))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := storeReq.Wait(); err != nil {
+		// Rollback written data.
+		storeReq.Op = repository.DeleteReal
+		if h.store.Push(storeReq) == nil {
+			storeReq.Wait()
+		}
+
+		ctxLogger.Error(errors.Wrap(err, "failed to write into the backend store"))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Volume-Id", storeReq.Vol)
+	w.Header().Set("ETag", hex.EncodeToString(hash.Sum(nil)))
+	w.WriteHeader(http.StatusOK)
 }
 
 // func (h *handlers) writeToRemoteFollower(req client.RequestEvent, size int64, cid chunkID) error {
@@ -456,61 +428,96 @@ func (h *handlers) writeToPrimary(req client.RequestEvent) {
 // }
 
 // GetObjectHandler handles the client request for getting an object.
+// The gateway always requests an explicit byte range of the object.
 func (h *handlers) GetObjectHandler(w http.ResponseWriter, r *http.Request) {
-	// ctxLogger := mlog.GetMethodLogger(logger, "handlers.GetObjectHandler")
-
-	// _, err := h.requestEventFactory.CreateRequestEvent(w, r)
-	// if err == client.ErrInvalidProtocol {
-	// 	http.Error(w, err.Error(), http.StatusBadRequest)
-	// 	return
-	// }
-
-	// size, ok := h.store.GetObjectSize(r.Header.Get("Volume-Id"), strings.Replace(strings.Trim(r.URL.Path, "/"), "/", ".", -1))
-	// if ok == false {
-	// 	ctxLogger.Error("failed to get object size")
-	// 	w.WriteHeader(http.StatusInternalServerError)
-	// 	return
-	// }
-	// w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
-
-	// md5, ok := h.store.GetObjectMD5(r.Header.Get("Volume-Id"), strings.Replace(strings.Trim(r.URL.Path, "/"), "/", ".", -1))
-	// if ok == false {
-	// 	ctxLogger.Error("failed to get object md5")
-	// 	w.WriteHeader(http.StatusInternalServerError)
-	// 	return
-	// }
-	// w.Header().Set("ETag", md5)
-
-	// storeReq := &repository.Request{
-	// 	Op:     repository.Read,
-	// 	Vol:    r.Header.Get("Volume-Id"),
-	// 	Oid:    strings.Replace(strings.Trim(r.URL.Path, "/"), "/", ".", -1),
-	// 	LocGid: r.Header.Get("Local-Chain-Id"),
-	// 	Osize:  size,
-
-	// 	Out: w,
-	// }
-	// h.store.Push(storeReq)
-
-	// err = storeReq.Wait()
-	// if err != nil {
-	// 	ctxLogger.Error(errors.Wrap(err, "failed to read object"))
-	// 	w.WriteHeader(http.StatusInternalServerError)
-	// 	return
-	// }
-
-	// req.SendSuccess()
-	http.Error(w, "not implemented", http.StatusNotImplemented)
+	ctxLogger := mlog.GetMethodLogger(logger, "handlers.GetObjectHandler")
+
+	var first, last int64
+	if _, err := fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-%d", &first, &last); err != nil || last < first {
+		http.Error(w, "invalid range", http.StatusBadRequest)
+		return
+	}
+
+	storeReq := &repository.Request{
+		Op:     repository.Read,
+		Vol:    r.Header.Get("Volume-Id"),
+		LocGid: r.Header.Get("Local-Chain-Id"),
+		Oid:    mux.Vars(r)["object"],
+		Offset: first,
+		Osize:  last - first + 1,
+		Out:    w,
+	}
+
+	w.Header().Set("Content-Length", strconv.FormatInt(storeReq.Osize, 10))
+	if err := h.store.Push(storeReq); err != nil {
+		ctxLogger.Error(errors.Wrap(err, "failed to push reading request into the backend store"))
+		w.Header().Del("Content-Length")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := storeReq.Wait(); err != nil {
+		ctxLogger.Error(errors.Wrap(err, "failed to read object"))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 }
 
 // DeleteObjectHandler handles the client request for deleting an object.
 func (h *handlers) DeleteObjectHandler(w http.ResponseWriter, r *http.Request) {
-	// _, err := h.requestEventFactory.CreateRequestEvent(w, r)
-	// if err == client.ErrInvalidProtocol {
-	// 	http.Error(w, err.Error(), http.StatusBadRequest)
-	// }
+	ctxLogger := mlog.GetMethodLogger(logger, "handlers.DeleteObjectHandler")
+
+	vars := mux.Vars(r)
+	locks, err := h.dataLocks(vars["bucket"], []string{vars["object"]})
+	if err != nil {
+		ctxLogger.Error(errors.Wrap(err, "failed to get the object lock"))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if code, err := h.deleteObject(r.Header.Get("Volume-Id"), vars["object"], locks[vars["object"]]); err != nil {
+		if code == http.StatusInternalServerError {
+			ctxLogger.Error(err)
+		}
+		http.Error(w, err.Error(), code)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
 
-	http.Error(w, "not implemented", http.StatusNotImplemented)
+// getDataLocks asks the mds the object locks of the versions which own the
+// data of the objects in a single call. The data which no version owns is
+// not in the result, and is not locked.
+func (h *handlers) getDataLocks(bucket string, oids []string) (map[string]s3.ObjectLock, error) {
+	mds, err := h.cmapAPI.SearchCall().Node().Type(cmap.MDS).Status(cmap.NodeAlive).Do()
+	if err != nil {
+		return nil, errors.Wrap(err, "find alive mds failed")
+	}
+
+	conn, err := nilrpc.Dial(mds.Addr.String(), nilrpc.RPCNil, time.Duration(2*time.Second))
+	if err != nil {
+		return nil, errors.Wrap(err, "dial to mds failed")
+	}
+	defer conn.Close()
+
+	req := &nilrpc.MOBGetDataLocksRequest{Bucket: bucket, ObjectIDs: oids}
+	res := &nilrpc.MOBGetDataLocksResponse{}
+
+	cli := rpc.NewClient(conn)
+	if err := cli.Call(nilrpc.MdsObjectGetDataLocks.String(), req, res); err != nil {
+		return nil, errors.Wrap(err, "mds rpc client calling failed")
+	}
+
+	switch res.S3ErrCode {
+	case s3.ErrNone:
+		return res.Locks, nil
+	case s3.ErrNoSuchBucket:
+		// The bucket is removed only when it has no objects.
+		return map[string]s3.ObjectLock{}, nil
+	default:
+		return nil, fmt.Errorf("mds returns s3 error code: %d", res.S3ErrCode)
+	}
 }
 
 func (h *handlers) SetChunkPool(req *nilrpc.DOBSetChunkPoolRequest, res *nilrpc.DOBSetChunkPoolResponse) error {
@@ -528,6 +535,7 @@ type Handlers interface {
 	PutObjectHandler(w http.ResponseWriter, r *http.Request)
 	GetObjectHandler(w http.ResponseWriter, r *http.Request)
 	DeleteObjectHandler(w http.ResponseWriter, r *http.Request)
+	DeleteObjectsHandler(w http.ResponseWriter, r *http.Request)
 	GetChunkHandler(w http.ResponseWriter, r *http.Request)
 	PutChunkHandler(w http.ResponseWriter, r *http.Request)
 	RPCHandler() RPCHandler
@@ -0,0 +1,209 @@
+package object
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chanyoung/nil/app/ds/infrastructure/repository"
+	"github.com/chanyoung/nil/pkg/client"
+	"github.com/chanyoung/nil/pkg/cmap"
+	"github.com/chanyoung/nil/pkg/s3"
+	"github.com/chanyoung/nil/pkg/util/config"
+	"github.com/chanyoung/nil/pkg/util/mlog"
+	"github.com/gorilla/mux"
+)
+
+func TestMain(m *testing.M) {
+	mlog.Init("stderr")
+	code := m.Run()
+	// The cluster map of the tests is saved in the working directory.
+	os.RemoveAll("cmap")
+	os.Exit(code)
+}
+
+// memStore is a Repository which only keeps the ids of the objects.
+type memStore struct {
+	objects map[string]bool
+}
+
+func (m *memStore) Push(r *repository.Request) error {
+	switch r.Op {
+	case repository.Delete:
+		if !m.objects[r.Oid] {
+			r.Err = errors.New("no such object")
+			return nil
+		}
+		delete(m.objects, r.Oid)
+	default:
+		r.Err = errors.New("not supported")
+	}
+	return nil
+}
+
+func (m *memStore) GetObjectSize(lvID, objID string) (int64, bool) { return 0, false }
+func (m *memStore) GetObjectMD5(lvID, objID string) (string, bool) { return "", false }
+func (m *memStore) GetChunkHeaderSize() int64                      { return 0 }
+func (m *memStore) GetObjectHeaderSize() int64                     { return 0 }
+
+func TestDeleteLockedObject(t *testing.T) {
+	now := time.Now()
+	future, past := now.Add(time.Hour), now.Add(-time.Hour)
+
+	testCases := []struct {
+		name    string
+		lock    s3.ObjectLock
+		lockErr error
+		code    int
+	}{
+		{"not locked", s3.ObjectLock{}, nil, http.StatusNoContent},
+		{"compliance", s3.ObjectLock{Mode: s3.RetentionCompliance, RetainUntil: future}, nil, http.StatusForbidden},
+		{"governance", s3.ObjectLock{Mode: s3.RetentionGovernance, RetainUntil: future}, nil, http.StatusForbidden},
+		{"legal hold", s3.ObjectLock{LegalHold: true}, nil, http.StatusForbidden},
+		{"expired compliance", s3.ObjectLock{Mode: s3.RetentionCompliance, RetainUntil: past}, nil, http.StatusNoContent},
+		{"unknown lock", s3.ObjectLock{}, errors.New("mds is not reachable"), http.StatusInternalServerError},
+	}
+
+	for _, c := range testCases {
+		store := &memStore{objects: map[string]bool{"oid": true}}
+		hs, err := NewHandlers(&config.Ds{}, nil, nil, store)
+		if err != nil {
+			t.Fatal(err)
+		}
+		h := hs.(*handlers)
+
+		var lockedBucket, lockedOid string
+		h.dataLocks = func(bucket string, oids []string) (map[string]s3.ObjectLock, error) {
+			lockedBucket, lockedOid = bucket, strings.Join(oids, ",")
+			if c.lockErr != nil {
+				return nil, c.lockErr
+			}
+			return map[string]s3.ObjectLock{"oid": c.lock}, nil
+		}
+
+		router := mux.NewRouter()
+		router.HandleFunc("/{bucket}/{object:.+}", h.DeleteObjectHandler).Methods("DELETE")
+
+		// The ds does not honor the governance bypass of the client.
+		r := httptest.NewRequest("DELETE", "/bucket/oid", nil)
+		r.Header.Set("Volume-Id", "1")
+		r.Header.Set("X-Amz-Bypass-Governance-Retention", "true")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+
+		if w.Code != c.code {
+			t.Errorf("%s: expected status %d, got %d: %s", c.name, c.code, w.Code, w.Body)
+		}
+		if lockedBucket != "bucket" || lockedOid != "oid" {
+			t.Errorf("%s: expected the lock of bucket/oid is checked, got %s/%s", c.name, lockedBucket, lockedOid)
+		}
+		if deleted := !store.objects["oid"]; deleted != (c.code == http.StatusNoContent) {
+			t.Errorf("%s: expected deleted %t, got %t", c.name, c.code == http.StatusNoContent, deleted)
+		}
+	}
+}
+
+func TestDeleteObjects(t *testing.T) {
+	store := &memStore{objects: map[string]bool{"a": true, "b": true, "locked": true}}
+	hs, err := NewHandlers(&config.Ds{}, nil, nil, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := hs.(*handlers)
+	var lookups [][]string
+	h.dataLocks = func(bucket string, oids []string) (map[string]s3.ObjectLock, error) {
+		lookups = append(lookups, oids)
+		return map[string]s3.ObjectLock{"locked": {LegalHold: true}}, nil
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{bucket}", h.DeleteObjectsHandler).Methods("POST")
+
+	r := httptest.NewRequest("POST", "/bucket", strings.NewReader(`["a","locked","missing","b"]`))
+	r.Header.Set("Request-Type", string(client.DeleteObjectsFromPrimary))
+	r.Header.Set("Volume-Id", "1")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body)
+	}
+
+	result := client.DeleteObjectsResult{}
+	if err := json.NewDecoder(w.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+	var failed []string
+	for _, e := range result.Errors {
+		failed = append(failed, e.ObjectID)
+	}
+	if len(failed) != 2 || failed[0] != "locked" || failed[1] != "missing" {
+		t.Errorf("expected locked and missing are failed, got %v", failed)
+	}
+	if len(lookups) != 1 || len(lookups[0]) != 4 {
+		t.Errorf("expected the locks of all the objects are looked up at once, got %v", lookups)
+	}
+
+	if len(store.objects) != 1 || !store.objects["locked"] {
+		t.Errorf("expected only the locked object is left, got %v", store.objects)
+	}
+}
+
+func TestCopyFromUnknownDs(t *testing.T) {
+	cmapService, err := cmap.NewService(mlog.GetPackageLogger("app/ds/application/object"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmapService.UpdateCMap(&cmap.CMap{
+		Version: 1,
+		Nodes: []cmap.Node{
+			{ID: 1, Type: cmap.DS, Stat: cmap.NodeAlive, Addr: "ds1"},
+			{ID: 2, Type: cmap.DS, Stat: cmap.NodeFaulty, Addr: "ds2"},
+			{ID: 3, Type: cmap.MDS, Stat: cmap.NodeAlive, Addr: "mds3"},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	store := &memStore{objects: make(map[string]bool)}
+	hs, err := NewHandlers(&config.Ds{}, cmapService.SlaveAPI(), nil, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := hs.(*handlers)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{bucket}/{object:.+}", h.PutObjectHandler).Methods("PUT")
+
+	testCases := []struct {
+		name   string
+		source client.CopySource
+	}{
+		{"invalid id", client.CopySource{DsID: "ds1", Bucket: "src", ObjectID: "oid", Length: 10}},
+		{"unknown ds", client.CopySource{DsID: "4", Bucket: "src", ObjectID: "oid", Length: 10}},
+		{"faulty ds", client.CopySource{DsID: "2", Bucket: "src", ObjectID: "oid", Length: 10}},
+		{"not ds", client.CopySource{DsID: "3", Bucket: "src", ObjectID: "oid", Length: 10}},
+		{"no object", client.CopySource{DsID: "1", Bucket: "src", Length: 10}},
+	}
+
+	for _, c := range testCases {
+		body, err := json.Marshal([]client.CopySource{c.source})
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := httptest.NewRequest("PUT", "/bucket/copy", strings.NewReader(string(body)))
+		r.Header.Set("Request-Type", string(client.CopyToPrimary))
+		r.Header.Set("Volume-Id", "1")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d: %s", c.name, w.Code, w.Body)
+		}
+	}
+}
@@ -27,6 +27,7 @@ func makeHandler(oh object.Handlers) http.Handler {
 	br.Methods("PUT").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusInternalServerError)
 	})
+	br.Methods("POST").HandlerFunc(oh.DeleteObjectsHandler)
 
 	// Object request handlers
 	or.Methods("PUT").HandlerFunc(oh.PutObjectHandler)
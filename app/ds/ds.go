@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 
 	"github.com/chanyoung/nil/app/ds/application/cluster"
@@ -47,8 +48,12 @@ func Bootstrap(cfg config.Ds) error {
 		deviceRepository device.Repository
 		volumeRepository volume.Repository
 	)
+	chunkSize, err := strconv.ParseInt(cfg.ChunkSize, 10, 64)
+	if err != nil {
+		return errors.Wrap(err, "invalid chunk size")
+	}
 	if cfg.Store == "part" {
-		store = partstore.NewService(cfg.WorkDir)
+		store = partstore.NewService(cfg.WorkDir, chunkSize)
 		objectStore = partstore.NewObjectRepository(store)
 		gencodingStore = partstore.NewGencodingRepository(store)
 		deviceRepository = store.NewDeviceRepository()
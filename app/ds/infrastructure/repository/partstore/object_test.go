@@ -0,0 +1,435 @@
+package partstore
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/chanyoung/nil/app/ds/application/object"
+	"github.com/chanyoung/nil/app/ds/domain/model/volume"
+	"github.com/chanyoung/nil/app/ds/infrastructure/repository"
+	"github.com/chanyoung/nil/pkg/client"
+	"github.com/chanyoung/nil/pkg/util/config"
+	"github.com/chanyoung/nil/pkg/util/mlog"
+	"github.com/gorilla/mux"
+)
+
+func TestMain(m *testing.M) {
+	mlog.Init("stderr")
+	os.Exit(m.Run())
+}
+
+// newTestVol adds the volume with two partitions under the directory.
+func newTestVol(t *testing.T, s *service, dir string) *vol {
+	v := newVol("1", volume.New(volume.Name(volumeName("1")), filepath.Join(dir, volumeName("1")), volume.High, 1<<30))
+	for _, part := range []string{"part1", "part2"} {
+		if err := os.MkdirAll(filepath.Join(v.MntPoint(), part), 0775); err != nil {
+			t.Fatal(err)
+		}
+		if err := v.addPart(part); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s.vols[volumeName(v.id)] = v
+	return v
+}
+
+// newTestHandler returns the object handler of the ds which is backed by
+// the store.
+func newTestHandler(t *testing.T, s *service) http.Handler {
+	h, err := object.NewHandlers(&config.Ds{}, nil, nil, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := mux.NewRouter()
+	r.HandleFunc("/{bucket}/{object:.+}", h.PutObjectHandler).Methods("PUT")
+	r.HandleFunc("/{bucket}/{object:.+}", h.GetObjectHandler).Methods("GET")
+	return r
+}
+
+func putObject(h http.Handler, oid string, data []byte) *httptest.ResponseRecorder {
+	r := httptest.NewRequest("PUT", "/bucket/"+oid, bytes.NewReader(data))
+	r.Header.Set("Request-Type", string(client.WriteToPrimary))
+	r.Header.Set("Local-Chain-Id", "7")
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func getObject(h http.Handler, vol, oid string, first, last int64) *httptest.ResponseRecorder {
+	r := httptest.NewRequest("GET", "/bucket/"+oid, nil)
+	r.Header.Set("Volume-Id", vol)
+	r.Header.Set("Local-Chain-Id", "7")
+	r.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", first, last))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestObjectRoundTrip(t *testing.T) {
+	dir := "testObjectRoundTrip"
+	defer os.RemoveAll(dir)
+
+	s := newService(dir, 1024)
+	newTestVol(t, s, dir)
+	go s.Run()
+	h := newTestHandler(t, s)
+
+	// The object larger than the chunk size has a chunk of its own.
+	objects := map[string][]byte{}
+	for i, size := range []int{1, 100, 500, 3000, 700} {
+		data := make([]byte, size)
+		rand.Read(data)
+		oid := fmt.Sprintf("obj%d", i)
+		objects[oid] = data
+
+		w := putObject(h, oid, data)
+		if w.Code != http.StatusOK {
+			t.Fatalf("put %s: expected status 200, got %d: %s", oid, w.Code, w.Body)
+		}
+
+		sum := md5.Sum(data)
+		if etag := w.Header().Get("ETag"); etag != hex.EncodeToString(sum[:]) {
+			t.Errorf("put %s: expected etag %x, got %s", oid, sum, etag)
+		}
+		if vol := w.Header().Get("Volume-Id"); vol != "1" {
+			t.Errorf("put %s: expected volume 1, got %s", oid, vol)
+		}
+	}
+
+	if w := putObject(h, "obj0", []byte("again")); w.Code == http.StatusOK {
+		t.Error("expected the existing object can not be written again")
+	}
+
+	for oid, data := range objects {
+		w := getObject(h, "1", oid, 0, int64(len(data)-1))
+		if w.Code != http.StatusOK {
+			t.Fatalf("get %s: expected status 200, got %d: %s", oid, w.Code, w.Body)
+		}
+		if !bytes.Equal(w.Body.Bytes(), data) {
+			t.Errorf("get %s: read data is different from the written data", oid)
+		}
+	}
+}
+
+func TestObjectRange(t *testing.T) {
+	dir := "testObjectRange"
+	defer os.RemoveAll(dir)
+
+	s := newService(dir, 1024)
+	newTestVol(t, s, dir)
+	go s.Run()
+	h := newTestHandler(t, s)
+
+	// Surround the object by others in the same chunk, so the range
+	// out of the object would read their data.
+	var data []byte
+	for _, oid := range []string{"before", "object", "after"} {
+		b := make([]byte, 300)
+		rand.Read(b)
+		if w := putObject(h, oid, b); w.Code != http.StatusOK {
+			t.Fatalf("put %s: expected status 200, got %d: %s", oid, w.Code, w.Body)
+		}
+		if oid == "object" {
+			data = b
+		}
+	}
+
+	testCases := []struct {
+		first, last int64
+		ok          bool
+	}{
+		{0, 0, true},
+		{0, 299, true},
+		{1, 1, true},
+		{100, 199, true},
+		{299, 299, true},
+		{250, 300, false},
+		{300, 300, false},
+	}
+
+	for _, c := range testCases {
+		w := getObject(h, "1", "object", c.first, c.last)
+		if !c.ok {
+			if w.Code == http.StatusOK {
+				t.Errorf("bytes=%d-%d: expected error, got status 200", c.first, c.last)
+			}
+			continue
+		}
+
+		if w.Code != http.StatusOK {
+			t.Errorf("bytes=%d-%d: expected status 200, got %d: %s", c.first, c.last, w.Code, w.Body)
+			continue
+		}
+		if !bytes.Equal(w.Body.Bytes(), data[c.first:c.last+1]) {
+			t.Errorf("bytes=%d-%d: read data is different from the range of the object", c.first, c.last)
+		}
+	}
+}
+
+// deadSpace returns the size of the dead space in the chunks of the volume.
+func deadSpace(v *vol) int64 {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	var dead int64
+	for _, c := range v.chkMap {
+		dead += c.dead
+	}
+	return dead
+}
+
+func TestLoadChunks(t *testing.T) {
+	dir := "testLoadChunks"
+	defer os.RemoveAll(dir)
+
+	s := newService(dir, 1024)
+	v := newTestVol(t, s, dir)
+	go s.Run()
+
+	objects := map[string][]byte{}
+	for i, size := range []int{10, 600, 2000, 300} {
+		data := make([]byte, size)
+		rand.Read(data)
+		oid := fmt.Sprintf("obj%d", i)
+		objects[oid] = data
+
+		r := &repository.Request{
+			Op:     repository.Write,
+			LocGid: "7",
+			Oid:    oid,
+			Osize:  int64(size),
+			In:     bytes.NewReader(data),
+		}
+		if err := s.Push(r); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.Wait(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The space of the failed write at the end of the chunk is truncated.
+	r := &repository.Request{
+		Op:     repository.Write,
+		LocGid: "7",
+		Oid:    "short",
+		Osize:  100,
+		In:     bytes.NewReader(make([]byte, 10)),
+	}
+	if err := s.Push(r); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Wait(); err == nil {
+		t.Fatal("expected the short write fails")
+	}
+	if d := deadSpace(v); d != 0 {
+		t.Errorf("expected no dead bytes, got %d", d)
+	}
+
+	// Load the chunks into a new store.
+	s = newService(dir, 1024)
+	v = newTestVol(t, s, dir)
+	go s.Run()
+
+	if len(v.objMap) != len(objects) {
+		t.Errorf("expected %d objects loaded, got %d", len(objects), len(v.objMap))
+	}
+	if d := deadSpace(v); d != 0 {
+		t.Errorf("expected no dead bytes loaded, got %d", d)
+	}
+	for oid, data := range objects {
+		out := new(bytes.Buffer)
+		r := &repository.Request{
+			Op:    repository.Read,
+			Vol:   "1",
+			Oid:   oid,
+			Osize: int64(len(data)),
+			Out:   out,
+		}
+		if err := s.Push(r); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.Wait(); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(out.Bytes(), data) {
+			t.Errorf("read %s: loaded data is different from the written data", oid)
+		}
+	}
+}
+
+func TestLoadPendingObject(t *testing.T) {
+	dir := "testLoadPendingObject"
+	defer os.RemoveAll(dir)
+
+	s := newService(dir, 1024)
+	v := newTestVol(t, s, dir)
+	go s.Run()
+
+	// The space of the object which is not finished by the crash is
+	// followed by the space of the failed write and the object written
+	// after them.
+	if _, _, err := v.reserve("crashed", "7", 100, s.chunkSize); err != nil {
+		t.Fatal(err)
+	}
+	r := &repository.Request{
+		Op:     repository.Write,
+		LocGid: "7",
+		Oid:    "short",
+		Osize:  100,
+		In:     bytes.NewReader(make([]byte, 10)),
+	}
+	if err := s.Push(r); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Wait(); err == nil {
+		t.Fatal("expected the short write fails")
+	}
+	data := make([]byte, 50)
+	rand.Read(data)
+	r = &repository.Request{
+		Op:     repository.Write,
+		LocGid: "7",
+		Oid:    "after",
+		Osize:  int64(len(data)),
+		In:     bytes.NewReader(data),
+	}
+	if err := s.Push(r); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if d := deadSpace(v); d != objHeaderSize+100 {
+		t.Errorf("expected %d dead bytes of the failed write, got %d", objHeaderSize+100, d)
+	}
+
+	// Load the chunks into a new store.
+	s = newService(dir, 1024)
+	v = newTestVol(t, s, dir)
+	go s.Run()
+
+	if _, ok := v.objMap["crashed"]; ok {
+		t.Error("expected the pending object is not loaded")
+	}
+	if d := deadSpace(v); d != 2*(objHeaderSize+100) {
+		t.Errorf("expected %d dead bytes loaded, got %d", 2*(objHeaderSize+100), d)
+	}
+	out := new(bytes.Buffer)
+	r = &repository.Request{
+		Op:    repository.Read,
+		Vol:   "1",
+		Oid:   "after",
+		Osize: int64(len(data)),
+		Out:   out,
+	}
+	if err := s.Push(r); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out.Bytes(), data) {
+		t.Error("expected the object after the pending space is loaded")
+	}
+}
+
+// deleteObject deletes the object in the store. The object lock is checked
+// by the handler, which is tested in the object package.
+func deleteObject(s *service, vol, oid string) error {
+	r := &repository.Request{
+		Op:     repository.Delete,
+		Vol:    vol,
+		LocGid: "7",
+		Oid:    oid,
+	}
+	if err := s.Push(r); err != nil {
+		return err
+	}
+	return r.Wait()
+}
+
+func TestObjectDelete(t *testing.T) {
+	dir := "testObjectDelete"
+	defer os.RemoveAll(dir)
+
+	s := newService(dir, 1024)
+	v := newTestVol(t, s, dir)
+	go s.Run()
+	h := newTestHandler(t, s)
+
+	objects := map[string][]byte{}
+	for _, oid := range []string{"a", "b", "c"} {
+		data := make([]byte, 200)
+		rand.Read(data)
+		objects[oid] = data
+
+		if w := putObject(h, oid, data); w.Code != http.StatusOK {
+			t.Fatalf("put %s: expected status 200, got %d: %s", oid, w.Code, w.Body)
+		}
+	}
+	cid := v.objMap["a"].Cid
+	path := v.chunkPath(cid, v.chkMap[cid])
+
+	if err := deleteObject(s, "1", "b"); err != nil {
+		t.Fatalf("delete b: %v", err)
+	}
+	if err := deleteObject(s, "1", "b"); err == nil {
+		t.Error("expected the deleted object can not be deleted again")
+	}
+	if w := getObject(h, "1", "b", 0, 199); w.Code == http.StatusOK {
+		t.Error("expected the deleted object can not be read")
+	}
+	for _, oid := range []string{"a", "c"} {
+		w := getObject(h, "1", oid, 0, 199)
+		if w.Code != http.StatusOK || !bytes.Equal(w.Body.Bytes(), objects[oid]) {
+			t.Errorf("get %s: expected the object is not affected by the deletion", oid)
+		}
+	}
+
+	// The space of the deleted object is recorded as dead.
+	dead := objHeaderSize + 200
+	if v.chkMap[cid].dead != dead {
+		t.Errorf("expected %d dead bytes, got %d", dead, v.chkMap[cid].dead)
+	}
+
+	// The deletion is kept in the chunk.
+	s = newService(dir, 1024)
+	v = newTestVol(t, s, dir)
+	go s.Run()
+
+	if _, ok := v.objMap["b"]; ok {
+		t.Error("expected the deleted object is not loaded")
+	}
+	if len(v.objMap) != 2 {
+		t.Errorf("expected 2 objects loaded, got %d", len(v.objMap))
+	}
+	if v.chkMap[cid].dead != dead {
+		t.Errorf("expected %d dead bytes loaded, got %d", dead, v.chkMap[cid].dead)
+	}
+
+	// The chunk is removed when all of its objects are deleted.
+	for _, oid := range []string{"a", "c"} {
+		if err := deleteObject(s, "1", oid); err != nil {
+			t.Fatalf("delete %s: %v", oid, err)
+		}
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected the empty chunk is removed, got %v", err)
+	}
+	if _, ok := v.chkMap[cid]; ok {
+		t.Error("expected the empty chunk is removed from the chunk map")
+	}
+}
@@ -1,12 +1,458 @@
 package partstore
 
-import "github.com/chanyoung/nil/app/ds/domain/model/volume"
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/chanyoung/nil/app/ds/domain/model/volume"
+	"github.com/chanyoung/nil/app/ds/infrastructure/repository"
+	"github.com/chanyoung/nil/pkg/util/uuid"
+)
+
+var (
+	chunkHeaderSize = int64(binary.Size(repository.ChunkHeader{}))
+	objHeaderSize   = int64(binary.Size(repository.ObjHeader{}))
+
+	chunkMagic = [4]byte{0x7f, 'c', 'h', 'k'}
+	objMagic   = [4]byte{0x7f, 'o', 'b', 'j'}
+	// pendingMagic marks the space which is reserved for the object being
+	// written. It becomes objMagic after the data is written durably, so
+	// the object which is not written fully is never loaded.
+	pendingMagic = [4]byte{0x7f, 'p', 'n', 'd'}
+	// deadMagic marks the space of the object which is removed or is
+	// failed to be written, so it is skipped when the chunk is loaded.
+	deadMagic = [4]byte{0x7f, 'd', 'e', 'd'}
+)
+
+// volumeName returns the name of the volume which consists of the i-th
+// partitions of the devices. The cluster refers the volume by i.
+func volumeName(id string) string {
+	return "vol-" + id
+}
 
 type vol struct {
 	// Embed volume.
 	*volume.Volume
 
-	// TODO: adds lv specific fields here.
+	// id is the id of the volume in the cluster.
+	id string
+
+	// parts are the partitions which are mounted under the volume.
+	parts []string
+	// sched is the index of the partition which the last chunk is
+	// created in.
+	sched int
+
+	// objMap maps the objects to the chunks which they are written in.
+	objMap map[string]repository.ObjMap
+	// chkMap contains the chunks of the volume.
+	chkMap map[string]*chunkInfo
+	// writing is the chunk of each local group which the objects are
+	// appended to.
+	writing map[string]string
+
+	mu sync.RWMutex
+}
+
+// chunkInfo contains information of the chunk in the volume.
+type chunkInfo struct {
+	partID string
+	locGid string
+	// size is the length of the chunk, including the space which is
+	// reserved by the objects being written.
+	size int64
+	// live is the number of the objects in the chunk.
+	live int
+	// dead is the size of the space of the removed and the failed objects
+	// in the chunk, which is reclaimed by compacting the chunk.
+	dead int64
+	// writers is the number of the objects which are being written.
+	writers int
+}
+
+func newVol(id string, v *volume.Volume) *vol {
+	return &vol{
+		Volume:  v,
+		id:      id,
+		objMap:  make(map[string]repository.ObjMap),
+		chkMap:  make(map[string]*chunkInfo),
+		writing: make(map[string]string),
+	}
+}
+
+// chunkPath returns the path of the chunk.
+func (v *vol) chunkPath(cid string, c *chunkInfo) string {
+	return filepath.Join(v.MntPoint(), c.partID, c.locGid, cid)
+}
+
+// addPart adds the partition which is mounted under the volume and loads
+// the chunks which are already stored in it.
+func (v *vol) addPart(partID string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.parts = append(v.parts, partID)
+
+	locGids, err := ioutil.ReadDir(filepath.Join(v.MntPoint(), partID))
+	if err != nil {
+		return err
+	}
+	for _, lg := range locGids {
+		if !lg.IsDir() {
+			continue
+		}
+
+		chunks, err := ioutil.ReadDir(filepath.Join(v.MntPoint(), partID, lg.Name()))
+		if err != nil {
+			return err
+		}
+		for _, chk := range chunks {
+			if err := v.loadChunk(partID, lg.Name(), chk.Name()); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// loadChunk adds the chunk and the objects in it. The space of the pending
+// and the dead objects is skipped by their recorded size, and the objects
+// are read until the end of the chunk or the space which is not written
+// fully. The caller must hold the lock of the volume.
+func (v *vol) loadChunk(partID, locGid, cid string) error {
+	c := &chunkInfo{
+		partID: partID,
+		locGid: locGid,
+	}
+
+	f, err := os.Open(v.chunkPath(cid, c))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	cHeader := repository.ChunkHeader{}
+	if err := binary.Read(f, binary.LittleEndian, &cHeader); err != nil || cHeader.Magic != chunkMagic {
+		return fmt.Errorf("invalid chunk: %s", cid)
+	}
+
+	c.size = chunkHeaderSize
+load:
+	for {
+		oHeader, err := readObjHeader(f, c.size)
+		if err != nil || oHeader.Offset != c.size+objHeaderSize || oHeader.Offset+oHeader.Size > fi.Size() {
+			break
+		}
+
+		switch oHeader.Magic {
+		case objMagic:
+			v.objMap[string(bytes.TrimRight(oHeader.Name[:], "\x00"))] = repository.ObjMap{
+				Cid:    cid,
+				Offset: c.size,
+				ObjInfo: repository.ObjInfo{
+					Size: oHeader.Size,
+					MD5:  string(bytes.TrimRight(oHeader.MD5[:], "\x00")),
+				},
+			}
+			c.live++
+		case pendingMagic, deadMagic:
+			c.dead += objHeaderSize + oHeader.Size
+		default:
+			break load
+		}
+
+		c.size = oHeader.Offset + oHeader.Size
+	}
+
+	v.chkMap[cid] = c
+	v.collect(cid)
+
+	return nil
+}
+
+// reserve reserves the space for the header and the data of the object of
+// the size in the writing chunk of the local group, and returns the chunk
+// and the offset of the space. The space is marked as pending in the chunk
+// before it is given, so the chunk can be loaded past it even if the object
+// is never finished. A new chunk is created if the writing chunk can not
+// hold the object, so the object larger than the chunk size has a chunk of
+// its own.
+func (v *vol) reserve(oid, locGid string, size, chunkSize int64) (string, int64, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if _, ok := v.objMap[oid]; ok {
+		return "", 0, fmt.Errorf("object is already existed: %s", oid)
+	}
+
+	n := objHeaderSize + size
+	cid, ok := v.writing[locGid]
+	c := v.chkMap[cid]
+	if !ok || (c.size > chunkHeaderSize && c.size+n > chunkSize) {
+		var err error
+		if cid, c, err = v.createChunk(locGid); err != nil {
+			return "", 0, err
+		}
+	}
+
+	off := c.size
+	oHeader := repository.ObjHeader{
+		Magic:  pendingMagic,
+		Size:   size,
+		Offset: off + objHeaderSize,
+	}
+	copy(oHeader.Name[:], oid)
+
+	f, err := os.OpenFile(v.chunkPath(cid, c), os.O_WRONLY, 0775)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+
+	if err := writeObjHeader(f, off, oHeader); err != nil {
+		return "", 0, err
+	}
+
+	c.size += n
+	c.writers++
+
+	return cid, off, nil
+}
+
+// createChunk creates a new writing chunk of the local group in the next
+// partition of the volume. The caller must hold the lock of the volume.
+func (v *vol) createChunk(locGid string) (string, *chunkInfo, error) {
+	if len(v.parts) == 0 {
+		return "", nil, fmt.Errorf("no partition in volume: %s", v.Name())
+	}
+	v.sched = (v.sched + 1) % len(v.parts)
+
+	cid := uuid.Gen()
+	c := &chunkInfo{
+		partID: v.parts[v.sched],
+		locGid: locGid,
+	}
+
+	// Create a directory for a local group if not exist.
+	path := v.chunkPath(cid, c)
+	if err := os.MkdirAll(filepath.Dir(path), 0775); err != nil {
+		return "", nil, err
+	}
+
+	cHeader := repository.ChunkHeader{
+		Magic: chunkMagic,
+		Type:  [1]byte{'D'},
+		State: [1]byte{'P'},
+	}
+
+	b := new(bytes.Buffer)
+	if err := binary.Write(b, binary.LittleEndian, cHeader); err != nil {
+		return "", nil, err
+	}
+	if err := ioutil.WriteFile(path, b.Bytes(), 0775); err != nil {
+		return "", nil, err
+	}
+	c.size = int64(b.Len())
+
+	old, ok := v.writing[locGid]
+	v.chkMap[cid] = c
+	v.writing[locGid] = cid
+	if ok {
+		v.collect(old)
+	}
+
+	return cid, c, nil
+}
+
+// writeObject writes the data of the object of the request into the space
+// which is reserved at the offset of the chunk. The pending header of the
+// space becomes the header of the object after the data is synced, and
+// the space is marked as dead if the data is failed to be written.
+func (v *vol) writeObject(cid string, off int64, r *repository.Request) error {
+	v.mu.RLock()
+	c := v.chkMap[cid]
+	v.mu.RUnlock()
+
+	f, err := os.OpenFile(v.chunkPath(cid, c), os.O_WRONLY, 0775)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	oHeader := repository.ObjHeader{
+		Magic:  objMagic,
+		Size:   r.Osize,
+		Offset: off + objHeaderSize,
+	}
+	copy(oHeader.Name[:], r.Oid)
+	copy(oHeader.MD5[:], r.Md5)
+
+	if _, err = f.Seek(oHeader.Offset, io.SeekStart); err == nil {
+		_, err = io.CopyN(f, r.In, r.Osize)
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if err == nil {
+		if err = writeObjHeader(f, off, oHeader); err == nil {
+			err = f.Sync()
+		}
+	}
+	if err != nil {
+		oHeader.Magic = deadMagic
+		writeObjHeader(f, off, oHeader)
+		return err
+	}
+
+	return nil
+}
+
+// finish finishes writing the object into the reserved space of the chunk.
+// The object is added into the object map if it is written successfully.
+func (v *vol) finish(oid, cid string, off int64, info repository.ObjInfo, written bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	c := v.chkMap[cid]
+	c.writers--
+
+	if !written {
+		// The space of the failed object is truncated if it is at the
+		// end of the chunk, otherwise it is left as dead.
+		if off+objHeaderSize+info.Size == c.size && c.writers == 0 && os.Truncate(v.chunkPath(cid, c), off) == nil {
+			c.size = off
+		} else {
+			c.dead += objHeaderSize + info.Size
+		}
+		v.collect(cid)
+		return
+	}
+
+	v.objMap[oid] = repository.ObjMap{
+		Cid:     cid,
+		Offset:  off,
+		ObjInfo: info,
+	}
+	c.live++
+}
+
+// removeObject removes the object from the volume. The space of the object
+// is truncated if it is at the end of the chunk, otherwise it is marked as
+// dead in the chunk, so the object is not loaded again. The chunk is
+// removed when it has no objects left.
+func (v *vol) removeObject(oid string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	obj, ok := v.objMap[oid]
+	if !ok {
+		return fmt.Errorf("no such object: %s", oid)
+	}
+	c := v.chkMap[obj.Cid]
+
+	f, err := os.OpenFile(v.chunkPath(obj.Cid, c), os.O_RDWR, 0775)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	oHeader, err := readObjHeader(f, obj.Offset)
+	if err != nil {
+		return err
+	}
+
+	if oHeader.Offset+oHeader.Size == c.size && c.writers == 0 {
+		if err = f.Truncate(obj.Offset); err != nil {
+			return err
+		}
+		c.size = obj.Offset
+	} else {
+		oHeader.Magic = deadMagic
+		if err = writeObjHeader(f, obj.Offset, oHeader); err != nil {
+			return err
+		}
+		c.dead += objHeaderSize + oHeader.Size
+	}
+
+	delete(v.objMap, oid)
+	c.live--
+	v.collect(obj.Cid)
+
+	return nil
+}
+
+// collect removes the chunk if it is not the writing chunk and all of its
+// objects are removed. The caller must hold the lock of the volume.
+func (v *vol) collect(cid string) {
+	c, ok := v.chkMap[cid]
+	if !ok || c.live > 0 || c.writers > 0 || v.writing[c.locGid] == cid {
+		return
+	}
+
+	if err := os.Remove(v.chunkPath(cid, c)); err != nil && !os.IsNotExist(err) {
+		return
+	}
+	delete(v.chkMap, cid)
+}
+
+// removeChunk removes the chunk and all objects in it.
+func (v *vol) removeChunk(cid string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	c, ok := v.chkMap[cid]
+	if !ok {
+		return fmt.Errorf("no such chunk: %s", cid)
+	}
+	if c.writers > 0 {
+		return fmt.Errorf("chunk is being written: %s", cid)
+	}
+
+	if err := os.Remove(v.chunkPath(cid, c)); err != nil {
+		return err
+	}
+
+	for oid, obj := range v.objMap {
+		if obj.Cid == cid {
+			delete(v.objMap, oid)
+		}
+	}
+	if v.writing[c.locGid] == cid {
+		delete(v.writing, c.locGid)
+	}
+	delete(v.chkMap, cid)
+
+	return nil
+}
+
+// readObjHeader reads the object header at the offset of the chunk.
+func readObjHeader(f *os.File, off int64) (repository.ObjHeader, error) {
+	oHeader := repository.ObjHeader{}
+	err := binary.Read(io.NewSectionReader(f, off, objHeaderSize), binary.LittleEndian, &oHeader)
+	return oHeader, err
+}
+
+// writeObjHeader writes the object header at the offset of the chunk.
+func writeObjHeader(f *os.File, off int64, oHeader repository.ObjHeader) error {
+	b := new(bytes.Buffer)
+	if err := binary.Write(b, binary.LittleEndian, oHeader); err != nil {
+		return err
+	}
+
+	_, err := f.WriteAt(b.Bytes(), off)
+	return err
 }
 
 // dev contains device information.
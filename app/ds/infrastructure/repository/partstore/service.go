@@ -3,8 +3,10 @@ package partstore
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
@@ -24,29 +26,32 @@ type service struct {
 	vols         map[string]*vol
 	devs         map[string]*dev
 	basePath     string
+	chunkSize    int64
 	requestQueue queue
 	pushCh       chan interface{}
 	devLock      sync.RWMutex
 }
 
 // NewService returns a new backend store service.
-func NewService(basePath string) repository.Service {
+func NewService(basePath string, chunkSize int64) repository.Service {
 	return &service{
-		basePath: basePath,
-		vols:     map[string]*vol{},
-		devs:     map[string]*dev{},
-		pushCh:   make(chan interface{}, 1),
+		basePath:  basePath,
+		chunkSize: chunkSize,
+		vols:      map[string]*vol{},
+		devs:      map[string]*dev{},
+		pushCh:    make(chan interface{}, 1),
 	}
 }
 
 // newService returns a new backend store service.
 // This is only for unit test. Do not use in real service.
-func newService(basePath string) *service {
+func newService(basePath string, chunkSize int64) *service {
 	return &service{
-		basePath: basePath,
-		vols:     map[string]*vol{},
-		devs:     map[string]*dev{},
-		pushCh:   make(chan interface{}, 1),
+		basePath:  basePath,
+		chunkSize: chunkSize,
+		vols:      map[string]*vol{},
+		devs:      map[string]*dev{},
+		pushCh:    make(chan interface{}, 1),
 	}
 }
 
@@ -620,13 +625,42 @@ func (s *service) GetObjectMD5(volID, objID string) (string, bool) {
 }
 
 func (s *service) GetChunkHeaderSize() int64 {
-	// TODO: fill the method
-	return 8
+	return chunkHeaderSize
 }
 
 func (s *service) GetObjectHeaderSize() int64 {
-	// TODO: fill the method
-	return 100
+	return objHeaderSize
+}
+
+// findVol returns the volume with the given id in the cluster.
+func (s *service) findVol(id string) (*vol, error) {
+	v, ok := s.vols[volumeName(id)]
+	if !ok {
+		return nil, fmt.Errorf("no such volume: %s", id)
+	}
+	return v, nil
+}
+
+// writableVol returns the volume with the given id. If the id is not
+// given, it returns the largest active volume.
+func (s *service) writableVol(id string) (*vol, error) {
+	if id != "" {
+		return s.findVol(id)
+	}
+
+	var found *vol
+	for _, v := range s.vols {
+		if v.Status() != volume.Active || len(v.parts) == 0 {
+			continue
+		}
+		if found == nil || v.Size() > found.Size() || (v.Size() == found.Size() && v.id < found.id) {
+			found = v
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("no writable volume")
+	}
+	return found, nil
 }
 
 func (s *service) handleCall(r *repository.Request) {
@@ -650,101 +684,59 @@ func (s *service) handleCall(r *repository.Request) {
 
 func (s *service) read(r *repository.Request) {
 	// Find and get the requested logical volume.
+	v, err := s.findVol(r.Vol)
+	if err != nil {
+		r.Err = err
+		return
+	}
 
-	// refactoring
-
-	/*
-		vol, ok := s.vols[r.Vol]
-		if !ok {
-			r.Err = fmt.Errorf("no such partition group: %s", r.Vol)
-			return
-		}
-
-
-			vol.Lock.Obj.RLock()
-			obj, ok := vol.ObjMap[r.Oid]
-			vol.Lock.Obj.RUnlock()
-			if !ok {
-				r.Err = fmt.Errorf("no such object: %s", r.Oid)
-				return
-			}
-
-			// Find and get the requested object.
-			vol.Lock.Chk.RLock()
-			chk, ok := vol.ChunkMap[obj.Cid]
-			vol.Lock.Chk.RUnlock()
-			if !ok {
-				r.Err = fmt.Errorf("no chunk of such object: %s", r.Oid)
-				return
-			}
-
-			// Open a chunk requested by a client.
-			lgDir := vol.MntPoint + "/" + chk.PartID + "/" + r.LocGid
-			fChunk, err := os.Open(lgDir + "/" + obj.Cid)
-			if err != nil {
-				r.Err = err
-				return
-			}
-			defer fChunk.Close()
-
-			// If the partition is one of the spin-down disks, spin-up the disk immediately.
-			s.devLock.RLock()
-			dev, ok := s.devs[chk.PartID]
-			s.devLock.RUnlock()
-			if !ok {
-				r.Err = fmt.Errorf("no such partition in partgroup : %s", chk.PartID)
-				return
-			}
-
-			t := time.Now()
-			tn := t.Nanosecond()
-			tDiff := uint(tn) - dev.Timestamp
-			dev.Timestamp = uint(tn)
-
-			s.devLock.Lock()
-			if dev.State == "Standby" {
-				dev.State = "Active"
-				dev.StandbyTime = dev.StandbyTime + tDiff
-			} else {
-				dev.ActiveTime = dev.ActiveTime + tDiff
-			}
-			s.devLock.Unlock()
+	// Find and get the requested object.
+	v.mu.RLock()
+	obj, ok := v.objMap[r.Oid]
+	c := v.chkMap[obj.Cid]
+	v.mu.RUnlock()
+	if !ok {
+		r.Err = fmt.Errorf("no such object: %s", r.Oid)
+		return
+	}
+	if r.Offset < 0 || r.Osize < 0 || r.Offset+r.Osize > obj.ObjInfo.Size {
+		r.Err = fmt.Errorf("range %d+%d is out of object: %s", r.Offset, r.Osize, r.Oid)
+		return
+	}
 
-			// Seek offset beginning of the requested object in the chunk.
-			_, err = fChunk.Seek(obj.Offset, os.SEEK_SET)
-			if err != nil {
-				r.Err = err
-				return
-			}
+	// Open a chunk requested by a client.
+	fChunk, err := os.Open(v.chunkPath(obj.Cid, c))
+	if err != nil {
+		r.Err = err
+		return
+	}
+	defer fChunk.Close()
 
-			oHeader := new(repository.ObjHeader)
-			err = binary.Read(fChunk, binary.LittleEndian, oHeader)
-			//fmt.Printf("%s, %d, %d\n", oHeader.Name, oHeader.Size, oHeader.Offset)
-			if err != nil {
-				r.Err = err
-				return
-			}
+	oHeader, err := readObjHeader(fChunk, obj.Offset)
+	if err != nil {
+		r.Err = err
+		return
+	}
+	if oHeader.Magic != objMagic {
+		r.Err = fmt.Errorf("invalid object header: %s", r.Oid)
+		return
+	}
 
-			_, err = fChunk.Seek(oHeader.Offset, os.SEEK_SET)
-			if err != nil {
-				r.Err = err
-				return
-			}
+	// Seek offset beginning of the requested range in the object.
+	_, err = fChunk.Seek(oHeader.Offset+r.Offset, io.SeekStart)
+	if err != nil {
+		r.Err = err
+		return
+	}
 
-			// Read contents of the requested object from the chunk.
-			n, err := io.CopyN(r.Out, fChunk, r.Osize)
-			if err != nil {
-				r.Err = err
-				return
-			}
+	// Read contents of the requested object from the chunk.
+	if _, err = io.CopyN(r.Out, fChunk, r.Osize); err != nil {
+		r.Err = err
+		return
+	}
 
-			if dev.Name != "" {
-				dev.TotalIO = dev.TotalIO + uint(n)
-			}
-			// Complete to read the requested object.
-			r.Err = nil
-	*/
-	return
+	// Complete to read the requested object.
+	r.Err = nil
 }
 
 func (s *service) readAll(r *repository.Request) {
@@ -817,242 +809,29 @@ func (s *service) readAll(r *repository.Request) {
 }
 
 func (s *service) write(r *repository.Request) {
-	//var totalWrite uint
-
 	// Find and get a logical volume.
-	// refactoring
-
-	/*
-
-		vol, ok := s.vols[r.Vol]
-		if !ok {
-			r.Err = fmt.Errorf("no such partition group: %s", r.Vol)
-			return
-		}
-
-
-			vol.Lock.Obj.RLock()
-			_, ok = vol.ObjMap[r.Oid]
-			vol.Lock.Obj.RUnlock()
-			if ok {
-				r.Err = fmt.Errorf("object is already existed: %s", r.Oid)
-				return
-			}
-
-			vol.Lock.Chk.RLock()
-			chk, ok := vol.ChunkMap[r.Cid]
-			vol.Lock.Chk.RUnlock()
-			if !ok {
-				vol.SubPartGroup.Hot.DiskSched = vol.SubPartGroup.Hot.DiskSched%vol.SubPartGroup.Hot.NumOfPart + 1
-				DiskSched := vol.SubPartGroup.Hot.DiskSched
-				PartID := "hot_part" + strconv.Itoa(int(DiskSched))
-
-				vol.Lock.Chk.Lock()
-				vol.ChunkMap[r.Cid] = repository.ChunkMap{
-					PartID: PartID,
-					ChunkInfo: repository.ChunkInfo{
-						Type:   r.Type,
-						LocGid: r.LocGid,
-					},
-				}
-				vol.Lock.Chk.Unlock()
-
-				vol.Lock.Chk.RLock()
-				chk = vol.ChunkMap[r.Cid]
-				vol.Lock.Chk.RUnlock()
-			}
-
-			// Create a directory for a local group if not exist.
-			lgDir := vol.MntPoint + "/" + chk.PartID + "/" + r.LocGid
-			_, err := os.Stat(lgDir)
-			if os.IsNotExist(err) {
-				os.MkdirAll(lgDir, 0775)
-			}
-
-			// Open a chunk that objects will be written to.
-			fChunk, err := os.OpenFile(lgDir+"/"+r.Cid, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0775)
-			if err != nil {
-				r.Err = err
-				return
-			}
-			defer fChunk.Close()
-
-			// Obtain a path of the chunk.
-			fChunkName := fChunk.Name()
-
-			// Get an information of the chunk.
-			fChunkInfo, err := os.Lstat(fChunkName)
-			if err != nil {
-				r.Err = err
-				return
-			}
-
-			// Get current length of the chunk.
-			fChunkLen := fChunkInfo.Size()
-
-			// If the chunk is newly generated, write a chunk header.
-			if fChunkLen == 0 {
-				cHeader := repository.ChunkHeader{
-					Magic:   [4]byte{0x7f, 'c', 'h', 'k'},
-					Type:    [1]byte{},
-					State:   [1]byte{'P'},
-					Encoded: false,
-				}
-
-				cHeader.Type[0] = 'D'
-
-				b := new(bytes.Buffer)
-				bufio.NewWriter(b)
-				err := binary.Write(b, binary.LittleEndian, cHeader)
-				if err != nil {
-					r.Err = err
-					return
-				}
-
-				n, err := fChunk.Write(b.Bytes())
-				//fmt.Printf("chunk written: %d\n", n)
-
-				if n != b.Len() {
-					r.Err = err
-					return
-				}
-
-				totalWrite = totalWrite + uint(n)
-			}
-			// Get an information of the chunk.
-			fChunkInfo, err = os.Lstat(fChunkName)
-			if err != nil {
-				r.Err = err
-				return
-			}
-
-			// Get current length of the chunk.
-			fChunkLen = fChunkInfo.Size()
-
-			// Create an object header for requested object.
-			oHeader := repository.ObjHeader{
-				Magic:  [4]byte{0x7f, 'o', 'b', 'j'},
-				Name:   [48]byte{},
-				MD5:    [32]byte{},
-				Size:   r.Osize,
-				Offset: fChunkLen + s.GetObjectHeaderSize(),
-			}
-
-			for i := 0; i < len(r.Oid); i++ {
-				oHeader.Name[i] = r.Oid[i]
-			}
-			//fmt.Println("len(r.Oid) : ", len(r.Oid))
-
-			for i := len(r.Oid); i < len(oHeader.Name); i++ {
-				oHeader.Name[i] = '\x00'
-			}
-
-			//fmt.Printf("len(r.Md5) : %d, len(oHeader.MD5) : %d\n", len(r.Md5), len(oHeader.MD5))
-			for i := 0; i < len(r.Md5); i++ {
-				oHeader.MD5[i] = r.Md5[i]
-			}
-
-			b := new(bytes.Buffer)
-			bufio.NewWriter(b)
-			err = binary.Write(b, binary.LittleEndian, oHeader)
-			if err != nil {
-				r.Err = err
-				return
-			}
-
-			//fmt.Println(b.Len())
-
-			// If the partition is one of the spin-down disks, spin-up the disk immediately.
-			s.devLock.RLock()
-			dev, ok := s.devs[chk.PartID]
-			s.devLock.RUnlock()
-			if !ok {
-				r.Err = fmt.Errorf("no such partition in partgroup : %s", chk.PartID)
-				return
-			}
-
-			t := time.Now()
-			tn := t.Nanosecond()
-			tDiff := uint(tn) - dev.Timestamp
-			dev.Timestamp = uint(tn)
-
-			s.devLock.Lock()
-			if dev.State == "Standby" {
-				dev.State = "Active"
-				dev.StandbyTime = dev.StandbyTime + tDiff
-			} else {
-				dev.ActiveTime = dev.ActiveTime + tDiff
-			}
-			s.devLock.Unlock()
-
-			// Check whether the chunk is full or has not enough space to write the object.
-			if fChunkLen >= vol.ChunkSize {
-				r.Err = fmt.Errorf("chunk full")
-				return
-			} else if fChunkLen+int64(b.Len())+r.Osize > vol.ChunkSize {
-				err = fChunk.Truncate(vol.ChunkSize)
-				totalWrite = totalWrite + uint(vol.ChunkSize-fChunkLen)
-				if dev.Name != "" {
-					dev.TotalIO = dev.TotalIO + totalWrite
-					dev.Free = dev.Free - totalWrite
-					dev.Used = dev.Used + totalWrite
-				}
-				r.Err = fmt.Errorf("truncated")
-				return
-			}
-
-			// Get an information of the chunk.
-			fChunkInfo, err = os.Lstat(fChunkName)
-			if err != nil {
-				r.Err = err
-				return
-			}
-
-			// Get current length of the chunk.
-			fChunkLen = fChunkInfo.Size()
-
-			// Write the object header into the chunk.
-			n, err := fChunk.Write(b.Bytes())
-			totalWrite = totalWrite + uint(n)
-			//fmt.Printf("buf len: %d, object written: %d\n", b.Len(), n)
-
-			// ToDo: implement more information of cHeader.
-			if n != b.Len() {
-				r.Err = err
-				return
-			}
-
-			// Write the object into the chunk if it will not be full.
-			_, err = io.CopyN(fChunk, r.In, r.Osize)
-			if err != nil {
-				r.Err = err
-				return
-			}
-
-			// Store mapping information between the object and the chunk.
-			vol.Lock.Obj.Lock()
-
-			vol.ObjMap[r.Oid] = repository.ObjMap{
-				Cid: r.Cid,
-				ObjInfo: repository.ObjInfo{
-					Size: r.Osize,
-					MD5:  r.Md5,
-				},
-				Offset: fChunkLen,
-			}
+	v, err := s.writableVol(r.Vol)
+	if err != nil {
+		r.Err = err
+		return
+	}
 
-			vol.Lock.Obj.Unlock()
+	cid, off, err := v.reserve(r.Oid, r.LocGid, r.Osize, s.chunkSize)
+	if err != nil {
+		r.Err = err
+		return
+	}
 
-			if dev.Name != "" {
-				dev.TotalIO = dev.TotalIO + totalWrite + uint(r.Osize)
-				dev.Free = dev.Free - (totalWrite + uint(r.Osize))
-				dev.Used = dev.Used + (totalWrite + uint(r.Osize))
-			}
+	err = v.writeObject(cid, off, r)
+	v.finish(r.Oid, cid, off, repository.ObjInfo{Size: r.Osize, MD5: r.Md5}, err == nil)
+	if err != nil {
+		r.Err = err
+		return
+	}
 
-			// Complete to write the object into the chunk.
-			r.Err = nil
-	*/
-	return
+	// Complete to write the object into the chunk.
+	r.Vol = v.id
+	r.Err = nil
 }
 
 func (s *service) writeAll(r *repository.Request) {
@@ -1149,163 +928,28 @@ func (s *service) writeAll(r *repository.Request) {
 
 func (s *service) delete(r *repository.Request) {
 	// Find and get a logical volume.
-	// refactoring
-
-	/*
-		vol, ok := s.vols[r.Vol]
-		if !ok {
-			r.Err = fmt.Errorf("no such partition group: %s", r.Vol)
-			return
-		}
-
-		// Check if the requested object is in the object map.
-
-
-			vol.Lock.Obj.RLock()
-			_, ok = vol.ObjMap[r.Oid]
-			vol.Lock.Obj.RUnlock()
-			if !ok {
-				r.Err = fmt.Errorf("no such object: %s", r.Oid)
-				return
-			}
-
-			// Delete the object from the map.
-			vol.Lock.Obj.Lock()
-			delete(vol.ObjMap, r.Oid)
-			vol.Lock.Obj.Unlock()
+	v, err := s.findVol(r.Vol)
+	if err != nil {
+		r.Err = err
+		return
+	}
 
-			// Complete to delete the object from the map.
-			r.Err = nil
-	*/
-	return
+	r.Err = v.removeObject(r.Oid)
 }
 
 func (s *service) deleteReal(r *repository.Request) {
 	// Find and get a logical volume.
-	// refactoring
-
-	/*
-		vol, ok := s.vols[r.Vol]
-		if !ok {
-			r.Err = fmt.Errorf("no such partition group: %s", r.Vol)
-			return
-		}
-
-		// Check if the requested object is in the object map.
-
-
-			vol.Lock.Chk.RLock()
-			chk, ok := vol.ChunkMap[r.Cid]
-			vol.Lock.Chk.RUnlock()
-
-			// Remove chunk
-			if ok {
-				lgDir := vol.MntPoint + "/" + chk.PartID + "/" + r.LocGid
-
-				// Remove all metadata of chunk
-				vol.Lock.Obj.Lock()
-				for key, value := range vol.ObjMap {
-					if value.Cid == r.Cid {
-						delete(vol.ObjMap, key)
-					}
-				}
-				vol.Lock.Obj.Unlock()
-
-				fChunkInfo, err := os.Lstat(lgDir + "/" + r.Cid)
-				if err != nil {
-					r.Err = err
-					return
-				}
-
-				fChunkLen := fChunkInfo.Size()
-
-				err = os.Remove(lgDir + "/" + r.Cid)
-				if err != nil {
-					r.Err = fmt.Errorf("no such chunk: %s", r.Cid)
-					return
-				}
-
-				vol.Lock.Chk.Lock()
-				delete(vol.ChunkMap, r.Cid)
-				vol.Lock.Chk.Unlock()
-
-				s.devLock.RLock()
-				dev, ok := s.devs[chk.PartID]
-				if ok {
-					dev.TotalIO = dev.TotalIO + uint(fChunkLen)
-					dev.Used = dev.Used - uint(fChunkLen)
-					dev.Free = dev.Free + uint(fChunkLen)
-					dev.Size = dev.Used + dev.Free
-				}
-				s.devLock.RUnlock()
-
-				r.Err = nil
-				return
-			}
-
-			// Remove object
-			vol.Lock.Obj.RLock()
-			obj, ok := vol.ObjMap[r.Oid]
-			vol.Lock.Obj.RUnlock()
-			if !ok {
-				r.Err = fmt.Errorf("no such object: %s", r.Oid)
-				return
-			}
-
-			vol.Lock.Obj.RLock()
-			chk, ok = vol.ChunkMap[obj.Cid]
-			vol.Lock.Obj.RUnlock()
-			if !ok {
-				r.Err = fmt.Errorf("no chunk including such object: %s", r.Cid)
-				return
-			}
-
-			lgDir := vol.MntPoint + "/" + chk.PartID + "/" + r.LocGid
-			fChunk, err := os.OpenFile(lgDir+"/"+obj.Cid, os.O_RDWR, 0775)
-			if err != nil {
-				r.Err = err
-				return
-			}
-			defer fChunk.Close()
-
-			// Obtain a path of the chunk.
-			fChunkName := fChunk.Name()
-
-			// Get an information of the chunk.
-			fChunkInfo, err := os.Lstat(fChunkName)
-			if err != nil {
-				r.Err = err
-				return
-			}
-
-			// Get current length of the chunk.
-			fChunkLen := fChunkInfo.Size()
-
-			if obj.Offset+obj.ObjInfo.Size+s.GetObjectHeaderSize() != fChunkLen {
-				r.Err = fmt.Errorf("can remove only a last object of a chunk")
-				return
-			}
-			fChunk.Seek(obj.Offset, io.SeekStart)
-			fChunk.Truncate(fChunkLen - (fChunkLen - obj.Offset))
-
-			vol.Lock.Obj.Lock()
-			delete(vol.ObjMap, r.Oid)
-			vol.Lock.Obj.Unlock()
-
-			s.devLock.RLock()
-			dev, ok := s.devs[chk.PartID]
-			s.devLock.RUnlock()
-
-			if ok {
-				dev.TotalIO = dev.TotalIO + uint(fChunkLen-obj.Offset)
-				dev.Free = dev.Free + uint(fChunkLen-obj.Offset)
-				dev.Used = dev.Free - uint(fChunkLen-obj.Offset)
-				dev.Size = dev.Free + dev.Used
-			}
+	v, err := s.findVol(r.Vol)
+	if err != nil {
+		r.Err = err
+		return
+	}
 
-			r.Err = nil
-	*/
-	return
+	if r.Cid != "" {
+		r.Err = v.removeChunk(r.Cid)
+		return
+	}
+	r.Err = v.removeObject(r.Oid)
 }
 
 // RenameChunk renames oldpath to newpath of chunk.
@@ -1627,7 +1271,7 @@ func (r *devRepository) Create(given *device.Device) error {
 		}
 
 		// Set a volume name.
-		vName := "vol-" + strconv.Itoa(i)
+		vName := volumeName(strconv.Itoa(i))
 
 		// Set volume speed.
 		// Assumption: the number of partition for each device is 4.
@@ -1669,9 +1313,7 @@ func (r *devRepository) Create(given *device.Device) error {
 			continue
 		}
 
-		r.vols[vName] = &vol{
-			Volume: volume.New(volume.Name(vName), vName, vSpeed, pSize),
-		}
+		r.vols[vName] = newVol(strconv.Itoa(i), volume.New(volume.Name(vName), vName, vSpeed, pSize))
 
 		// Create a directory for the volume if not exist.
 		_, err = os.Stat(r.vols[vName].MntPoint())
@@ -1700,6 +1342,11 @@ func (r *devRepository) Create(given *device.Device) error {
 			fmt.Printf("mount failed : %s, %s\n", p, partPath)
 			return device.ErrInvalidDevice
 		}
+
+		// Load the chunks which are already stored in the partition.
+		if err = r.vols[vName].addPart(filepath.Base(partPath)); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -13,7 +13,7 @@ func TestDevice(t *testing.T) {
 	defer os.RemoveAll(dir)
 
 	d := device.New("/dev/loop10")
-	s := newService(dir)
+	s := newService(dir, 1024)
 
 	r := s.NewDeviceRepository()
 
@@ -29,7 +29,7 @@ func TestVolume(t *testing.T) {
 	defer os.RemoveAll(dir)
 
 	d := device.New("/dev/loop10")
-	s := newService(dir)
+	s := newService(dir, 1024)
 
 	rd := s.NewDeviceRepository()
 	rv := s.NewVolumeRepository()
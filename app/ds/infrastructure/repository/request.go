@@ -15,6 +15,7 @@ const (
 	// Read requests an object handle that can read the requested object.
 	Read Operation = iota
 	// Write requests an object handle that can write the requested object.
+	// If Vol is not given, the store selects the volume and sets Vol to it.
 	Write
 	// WriteAll requests an object handle that can write the requested object.
 	WriteAll
@@ -36,8 +37,9 @@ type Request struct {
 	Cid    string // Chunk ID
 	Type   string // Chunk type (Data or Parity)
 
-	Osize int64  // Object Size
-	Md5   string // MD5 string
+	Osize  int64  // Object Size
+	Offset int64  // Offset in the object where the read begins
+	Md5    string // MD5 string
 
 	In  io.Reader
 	Out io.Writer
@@ -57,7 +59,7 @@ func (r *Request) Verify() error {
 			return fmt.Errorf("%v: invalid arguments", r)
 		}
 	case Write:
-		if r.Vol == "" || r.Oid == "" || r.In == nil {
+		if r.Oid == "" || r.In == nil {
 			return fmt.Errorf("%v: invalid arguments", r)
 		}
 	case Delete:
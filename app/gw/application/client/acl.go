@@ -0,0 +1,199 @@
+package client
+
+import (
+	"net/http"
+
+	"github.com/chanyoung/nil/pkg/client"
+	"github.com/chanyoung/nil/pkg/nilrpc"
+	"github.com/chanyoung/nil/pkg/s3"
+	"github.com/chanyoung/nil/pkg/util/mlog"
+	"github.com/gorilla/mux"
+)
+
+// maxACLBodySize is the maximum size of the request body of the put acl.
+const maxACLBodySize = 64 * 1024
+
+// PutBucketACLHandler handles the client request for replacing the acl of
+// the bucket.
+func (h *handlers) PutBucketACLHandler(w http.ResponseWriter, r *http.Request) {
+	ctxLogger := mlog.GetMethodLogger(logger, "handlers.PutBucketACLHandler")
+
+	req := h.authenticate(w, r)
+	if req == nil {
+		return
+	}
+
+	bucket := mux.Vars(r)["bucket"]
+	b := h.ownedBucket(req, bucket)
+	if b == nil {
+		return
+	}
+
+	acl, code := readACL(r, b.Owner)
+	if code != s3.ErrNone {
+		req.SendError(code)
+		return
+	}
+
+	res := &nilrpc.MACSetBucketACLResponse{}
+	if err := h.callMds(nilrpc.MdsAccountSetBucketACL, &nilrpc.MACSetBucketACLRequest{
+		BucketName: bucket,
+		ACL:        acl,
+	}, res); err != nil {
+		ctxLogger.Error(err)
+		req.SendInternalError()
+		return
+	}
+	if res.S3ErrCode != s3.ErrNone {
+		req.SendError(res.S3ErrCode)
+		return
+	}
+
+	req.SendSuccess()
+}
+
+// GetBucketACLHandler handles the client request for getting the acl of
+// the bucket.
+func (h *handlers) GetBucketACLHandler(w http.ResponseWriter, r *http.Request) {
+	req := h.authenticate(w, r)
+	if req == nil {
+		return
+	}
+
+	b := h.ownedBucket(req, mux.Vars(r)["bucket"])
+	if b == nil {
+		return
+	}
+
+	s3.SendResponse(w, s3.NewAccessControlPolicy(b.ACL, s3.Owner{ID: b.Owner, DisplayName: b.Owner}))
+}
+
+// PutObjectACLHandler handles the client request for replacing the acl of
+// the version of the object.
+func (h *handlers) PutObjectACLHandler(w http.ResponseWriter, r *http.Request) {
+	ctxLogger := mlog.GetMethodLogger(logger, "handlers.PutObjectACLHandler")
+
+	req := h.authenticate(w, r)
+	if req == nil {
+		return
+	}
+
+	vars := mux.Vars(r)
+	b := h.ownedBucket(req, vars["bucket"])
+	if b == nil {
+		return
+	}
+
+	acl, code := readACL(r, b.Owner)
+	if code != s3.ErrNone {
+		req.SendError(code)
+		return
+	}
+
+	res := &nilrpc.MOBSetACLResponse{}
+	if err := h.callMds(nilrpc.MdsObjectSetACL, &nilrpc.MOBSetACLRequest{
+		Name:      vars["object"],
+		Bucket:    vars["bucket"],
+		VersionID: r.URL.Query().Get("versionId"),
+		ACL:       acl,
+	}, res); err != nil {
+		ctxLogger.Error(err)
+		req.SendInternalError()
+		return
+	}
+	if res.S3ErrCode != s3.ErrNone {
+		req.SendError(res.S3ErrCode)
+		return
+	}
+
+	setVersionHeaders(w, res.VersionID, false)
+	req.SendSuccess()
+}
+
+// GetObjectACLHandler handles the client request for getting the acl of
+// the version of the object.
+func (h *handlers) GetObjectACLHandler(w http.ResponseWriter, r *http.Request) {
+	ctxLogger := mlog.GetMethodLogger(logger, "handlers.GetObjectACLHandler")
+
+	req := h.authenticate(w, r)
+	if req == nil {
+		return
+	}
+
+	vars := mux.Vars(r)
+	b := h.ownedBucket(req, vars["bucket"])
+	if b == nil {
+		return
+	}
+
+	res := &nilrpc.MOBObjectHeadResponse{}
+	if err := h.callMds(nilrpc.MdsObjectHead, &nilrpc.MOBObjectHeadRequest{
+		Name:      vars["object"],
+		Bucket:    vars["bucket"],
+		VersionID: r.URL.Query().Get("versionId"),
+	}, res); err != nil {
+		ctxLogger.Error(err)
+		req.SendInternalError()
+		return
+	}
+	setVersionHeaders(w, res.VersionID, res.DeleteMarker)
+	if res.S3ErrCode != s3.ErrNone {
+		req.SendError(res.S3ErrCode)
+		return
+	}
+
+	s3.SendResponse(w, s3.NewAccessControlPolicy(res.ACL, s3.Owner{ID: b.Owner, DisplayName: b.Owner}))
+}
+
+// readACL returns the canned acl to be put by the request, which is given
+// either in the header or in the body as the grants.
+func readACL(r *http.Request, owner string) (string, s3.ErrorCode) {
+	acl, code := s3.ParseCannedACL(r.Header)
+	if code != s3.ErrNone {
+		return "", code
+	}
+	if acl != "" {
+		if r.ContentLength > 0 {
+			return "", s3.ErrInvalidRequest
+		}
+		return acl, s3.ErrNone
+	}
+
+	policy := s3.AccessControlPolicy{}
+	if code := readXMLBody(r, maxACLBodySize, &policy); code != s3.ErrNone {
+		return "", code
+	}
+	return policy.CannedACL(owner)
+}
+
+// requestACL returns the canned acl of the bucket or the object to be
+// created, which is private if the request does not have it.
+func requestACL(header http.Header) (string, s3.ErrorCode) {
+	acl, code := s3.ParseCannedACL(header)
+	if acl == "" && code == s3.ErrNone {
+		acl = s3.ACLPrivate
+	}
+	return acl, code
+}
+
+// canRead returns true if the requester owns the bucket or the object, or
+// is granted read access by its acl.
+func canRead(req client.RequestEvent, owner, acl string) bool {
+	return req.AccessKey() == owner || s3.ACLGrantsRead(acl, !req.Anonymous())
+}
+
+// objectReadable returns true if the requester can read the object of the
+// key in the bucket, whose lookup results in the code, the acl and the
+// tags. The requester who can not list the bucket is not told whether the
+// object exists.
+func objectReadable(req client.RequestEvent, b *nilrpc.MACGetBucketResponse, key string, code s3.ErrorCode, acl string, tags map[string]string) bool {
+	switch code {
+	case s3.ErrNone:
+		conditions := tagConditions(nil, s3.ConditionExistingObjectTag, tags)
+		return authorized(req, b, "s3:GetObject", key, canRead(req, b.Owner, acl), conditions)
+	case s3.ErrNoSuchKey, s3.ErrNoSuchVersion, s3.ErrMethodNotAllowed:
+		return authorized(req, b, "s3:ListBucket", "", canRead(req, b.Owner, b.ACL), nil)
+	default:
+		return true
+	}
+}
@@ -3,45 +3,54 @@ package client
 import (
 	"net/http"
 	"net/rpc"
+	"strings"
 	"time"
 
-	"github.com/chanyoung/nil/app/gw/application/auth"
-	"github.com/chanyoung/nil/app/gw/domain/model/cred"
 	"github.com/chanyoung/nil/pkg/client"
 	"github.com/chanyoung/nil/pkg/nilrpc"
 	"github.com/chanyoung/nil/pkg/s3"
 	"github.com/chanyoung/nil/pkg/util/mlog"
+	"github.com/gorilla/mux"
 )
 
 // MakeBucketHandler handles the client request for making a new bucket.
 func (h *handlers) MakeBucketHandler(w http.ResponseWriter, r *http.Request) {
-	ctxLogger := mlog.GetMethodLogger(logger, "handlers.MakeBucketHandler")
-
-	req, err := h.requestEventFactory.CreateRequestEvent(w, r)
-	if err == client.ErrInvalidProtocol {
-		ctxLogger.Error(err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	req := h.authenticate(w, r)
+	if req == nil {
 		return
 	}
 
-	sk, err := h.authHandlers.GetSecretKey(cred.Key(req.AccessKey()))
-	if err == auth.ErrInternal {
-		req.SendInternalError()
-		return
-	} else if err == auth.ErrNoSuchKey {
-		req.SendNoSuchKey()
+	if req.Anonymous() {
+		req.SendError(s3.ErrAccessDenied)
 		return
 	}
 
-	if req.Auth(sk.String()) == false {
-		req.SendIncorrectKey()
+	acl, code := requestACL(r.Header)
+	if code != s3.ErrNone {
+		req.SendError(code)
 		return
 	}
 
-	if err = h.makeBucket(
+	// The bucket created with the object lock enabled has no default
+	// retention until it is configured.
+	var objectLock string
+	if strings.EqualFold(r.Header.Get(s3.BucketObjectLockHeader), "true") {
+		var err error
+		objectLock, err = marshalObjectLock(s3.ObjectLockConfiguration{
+			ObjectLockEnabled: s3.ObjectLockEnabled,
+		})
+		if err != nil {
+			req.SendInternalError()
+			return
+		}
+	}
+
+	if err := h.makeBucket(
 		req.AccessKey(),
 		req.Region(),
 		req.Bucket(),
+		acl,
+		objectLock,
 	); err != nil {
 		req.SendInternalError()
 		return
@@ -50,7 +59,7 @@ func (h *handlers) MakeBucketHandler(w http.ResponseWriter, r *http.Request) {
 	req.SendSuccess()
 }
 
-func (h *handlers) makeBucket(accessKey, region, bucket string) error {
+func (h *handlers) makeBucket(accessKey, region, bucket, acl, objectLock string) error {
 	ctxLogger := mlog.GetMethodLogger(logger, "handlers.makeBucket")
 
 	// // 1. Lookup mds from cmap.
@@ -74,6 +83,8 @@ func (h *handlers) makeBucket(accessKey, region, bucket string) error {
 		AccessKey:  accessKey,
 		Region:     region,
 		BucketName: bucket,
+		ACL:        acl,
+		ObjectLock: objectLock,
 	}
 	res := &nilrpc.MACMakeBucketResponse{}
 
@@ -93,12 +104,112 @@ func (h *handlers) makeBucket(accessKey, region, bucket string) error {
 	return nil
 }
 
+// HeadBucketHandler handles the client request for checking the bucket
+// exists and the client has permission to access it.
+func (h *handlers) HeadBucketHandler(w http.ResponseWriter, r *http.Request) {
+	req := h.authenticate(w, r)
+	if req == nil {
+		return
+	}
+
+	b := h.readableBucket(req, mux.Vars(r)["bucket"])
+	if b == nil {
+		return
+	}
+
+	w.Header().Set("X-Amz-Bucket-Region", b.Region)
+	w.WriteHeader(http.StatusOK)
+}
+
+// ownedBucket returns the information of the bucket if it is owned by the
+// requester or the bucket policy allows the request. Otherwise it sends
+// the error response and returns nil.
+func (h *handlers) ownedBucket(req client.RequestEvent, bucket string) *nilrpc.MACGetBucketResponse {
+	b, code := h.requestBucket(req, bucket)
+	if code == s3.ErrNone && !accessible(req, b, b.Owner == req.AccessKey()) {
+		code = s3.ErrAccessDenied
+	}
+	if code != s3.ErrNone {
+		req.SendError(code)
+		return nil
+	}
+
+	return b
+}
+
+// readableBucket returns the information of the bucket if the requester
+// owns it, is granted read access by its acl or is allowed by the bucket
+// policy. Otherwise it sends the error response and returns nil.
+func (h *handlers) readableBucket(req client.RequestEvent, bucket string) *nilrpc.MACGetBucketResponse {
+	b, code := h.requestBucket(req, bucket)
+	if code == s3.ErrNone && !accessible(req, b, canRead(req, b.Owner, b.ACL)) {
+		code = s3.ErrAccessDenied
+	}
+	if code != s3.ErrNone {
+		req.SendError(code)
+		return nil
+	}
+
+	return b
+}
+
+// findBucket returns the information of the bucket, or the error code if
+// it can not be found.
+func (h *handlers) findBucket(bucket string) (*nilrpc.MACGetBucketResponse, s3.ErrorCode) {
+	ctxLogger := mlog.GetMethodLogger(logger, "handlers.findBucket")
+
+	b, err := h.getBucket(bucket)
+	if err != nil {
+		ctxLogger.Error(err)
+		return nil, s3.ErrInternalError
+	}
+	if b.S3ErrCode != s3.ErrNone {
+		return nil, b.S3ErrCode
+	}
+
+	return b, s3.ErrNone
+}
+
+// getBucket asks the mds the information of the bucket.
+func (h *handlers) getBucket(bucket string) (*nilrpc.MACGetBucketResponse, error) {
+	req := &nilrpc.MACGetBucketRequest{
+		BucketName: bucket,
+	}
+	res := &nilrpc.MACGetBucketResponse{}
+
+	if err := h.callMds(nilrpc.MdsAccountGetBucket, req, res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
 // RemoveBucketHandler handles the client request for removing a bucket.
+// Only the empty bucket can be removed by its owner.
 func (h *handlers) RemoveBucketHandler(w http.ResponseWriter, r *http.Request) {
-	_, err := h.requestEventFactory.CreateRequestEvent(w, r)
-	if err == client.ErrInvalidProtocol {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	ctxLogger := mlog.GetMethodLogger(logger, "handlers.RemoveBucketHandler")
+
+	req := h.authenticate(w, r)
+	if req == nil {
+		return
+	}
+
+	if h.ownedBucket(req, mux.Vars(r)["bucket"]) == nil {
+		return
+	}
+
+	res := &nilrpc.MACRemoveBucketResponse{}
+	if err := h.callMds(nilrpc.MdsAccountRemoveBucket, &nilrpc.MACRemoveBucketRequest{
+		BucketName: mux.Vars(r)["bucket"],
+	}, res); err != nil {
+		ctxLogger.Error(err)
+		req.SendInternalError()
+		return
+	}
+	if res.S3ErrCode != s3.ErrNone {
+		req.SendError(res.S3ErrCode)
+		return
 	}
 
-	http.Error(w, "not implemented", http.StatusNotImplemented)
+	s3.SendNoContent(w)
 }
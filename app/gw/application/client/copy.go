@@ -0,0 +1,297 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/chanyoung/nil/pkg/client"
+	"github.com/chanyoung/nil/pkg/nilrpc"
+	"github.com/chanyoung/nil/pkg/s3"
+	"github.com/chanyoung/nil/pkg/util/mlog"
+	"github.com/chanyoung/nil/pkg/util/uuid"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+)
+
+// copySourcePrefix is the header prefix of the conditions of the copy
+// source object.
+const copySourcePrefix = "X-Amz-Copy-Source-"
+
+// CopyObjectHandler handles the client request for creating an object by
+// copying the existing object. The object data is copied by the ds, so
+// it does not pass through the gateway unless either object is encrypted.
+func (h *handlers) CopyObjectHandler(w http.ResponseWriter, r *http.Request) {
+	ctxLogger := mlog.GetMethodLogger(logger, "handlers.CopyObjectHandler")
+
+	req := h.authenticate(w, r)
+	if req == nil {
+		return
+	}
+
+	vars := mux.Vars(r)
+	bucket, key := vars["bucket"], vars["object"]
+	if len(key) > maxKeyLength {
+		req.SendError(s3.ErrKeyTooLongError)
+		return
+	}
+	b := h.ownedBucket(req, bucket)
+	if b == nil {
+		return
+	}
+
+	directive := r.Header.Get("X-Amz-Metadata-Directive")
+	if directive != "" && directive != "COPY" && directive != "REPLACE" {
+		req.SendError(s3.ErrInvalidArgument)
+		return
+	}
+	taggingDirective := r.Header.Get("X-Amz-Tagging-Directive")
+	if taggingDirective != "" && taggingDirective != "COPY" && taggingDirective != "REPLACE" {
+		req.SendError(s3.ErrInvalidArgument)
+		return
+	}
+
+	srcBucket, srcKey, srcVersionID, code := s3.ParseCopySource(r.Header.Get("X-Amz-Copy-Source"))
+	if code != s3.ErrNone {
+		req.SendError(code)
+		return
+	}
+	enc, code := h.requestEncryption(r)
+	if code != s3.ErrNone {
+		req.SendError(code)
+		return
+	}
+	// Copying the object to itself is only allowed to change the metadata
+	// or the encryption.
+	if srcBucket == bucket && srcKey == key && directive != "REPLACE" && enc.meta.Type == "" {
+		req.SendError(s3.ErrInvalidRequest)
+		return
+	}
+
+	src, code := h.copySourceObject(req, srcBucket, srcKey, srcVersionID)
+	if code != s3.ErrNone {
+		req.SendError(code)
+		return
+	}
+	srcEnc, code := h.objectKey(r, src.Encryption, copySourcePrefix)
+	if code != s3.ErrNone {
+		req.SendError(code)
+		return
+	}
+	if src.Size > maxObjectSize {
+		req.SendError(s3.ErrInvalidRequest)
+		return
+	}
+
+	// The acl is not copied from the source but given by the request.
+	acl, code := requestACL(r.Header)
+	if code != s3.ErrNone {
+		req.SendError(code)
+		return
+	}
+	meta := objectMeta{
+		contentType: src.ContentType,
+		headers:     src.Headers,
+		metadata:    src.Metadata,
+		acl:         acl,
+	}
+	if directive == "REPLACE" {
+		if meta, code = requestMeta(r.Header); code != s3.ErrNone {
+			req.SendError(code)
+			return
+		}
+	}
+	// The tags are copied unless replaced, independently of the metadata.
+	meta.tags = src.Tags
+	if taggingDirective == "REPLACE" {
+		if meta.tags, code = s3.ParseTaggingHeader(r.Header); code != s3.ErrNone {
+			req.SendError(code)
+			return
+		}
+	}
+	// The lock is not copied from the source but given by the request or
+	// the default retention of the bucket.
+	if meta.lock, code = requestLock(r.Header, b); code != s3.ErrNone {
+		req.SendError(code)
+		return
+	}
+
+	loc, err := h.copyObjectData(bucket, srcBucket, src.Parts, 0, src.Size, srcEnc.key, enc)
+	if err != nil {
+		ctxLogger.Error(err)
+		req.SendInternalError()
+		return
+	}
+
+	versionID, code := h.recordObject(bucket, key, loc, meta, enc, false)
+	if code != s3.ErrNone {
+		req.SendError(code)
+		return
+	}
+
+	setCopySourceVersionHeader(w, src.VersionID)
+	setVersionHeaders(w, versionID, false)
+	setSSEHeaders(w, enc)
+	s3.SendResponse(w, s3.CopyObjectResult{
+		LastModified: s3.FormatTime(time.Now().UTC()),
+		ETag:         quoteETag(loc.etag),
+	})
+}
+
+// UploadPartCopyHandler handles the client request for uploading a part of
+// the multipart upload by copying the range of the existing object.
+func (h *handlers) UploadPartCopyHandler(w http.ResponseWriter, r *http.Request) {
+	ctxLogger := mlog.GetMethodLogger(logger, "handlers.UploadPartCopyHandler")
+
+	req := h.authenticate(w, r)
+	if req == nil {
+		return
+	}
+
+	q := r.URL.Query()
+	partNumber, err := strconv.Atoi(q.Get("partNumber"))
+	if err != nil || partNumber < 1 || partNumber > maxPartNumber {
+		req.SendError(s3.ErrInvalidArgument)
+		return
+	}
+
+	vars := mux.Vars(r)
+	bucket, key, uploadID := vars["bucket"], vars["object"], q.Get("uploadId")
+	if h.ownedBucket(req, bucket) == nil {
+		return
+	}
+
+	srcBucket, srcKey, srcVersionID, code := s3.ParseCopySource(r.Header.Get("X-Amz-Copy-Source"))
+	if code != s3.ErrNone {
+		req.SendError(code)
+		return
+	}
+
+	enc, code := h.uploadEncryption(r, bucket, key, uploadID)
+	if code != s3.ErrNone {
+		req.SendError(code)
+		return
+	}
+
+	src, code := h.copySourceObject(req, srcBucket, srcKey, srcVersionID)
+	if code != s3.ErrNone {
+		req.SendError(code)
+		return
+	}
+	srcEnc, code := h.objectKey(r, src.Encryption, copySourcePrefix)
+	if code != s3.ErrNone {
+		req.SendError(code)
+		return
+	}
+
+	offset, length, code := s3.ParseCopySourceRange(r.Header.Get("X-Amz-Copy-Source-Range"), src.Size)
+	if code != s3.ErrNone {
+		req.SendError(code)
+		return
+	}
+	if length > maxObjectSize {
+		req.SendError(s3.ErrInvalidRequest)
+		return
+	}
+
+	loc, err := h.copyObjectData(bucket, srcBucket, src.Parts, offset, length, srcEnc.key, enc)
+	if err != nil {
+		ctxLogger.Error(err)
+		req.SendInternalError()
+		return
+	}
+
+	if code := h.recordPart(bucket, key, uploadID, partNumber, loc); code != s3.ErrNone {
+		req.SendError(code)
+		return
+	}
+
+	setCopySourceVersionHeader(w, src.VersionID)
+	setSSEHeaders(w, enc)
+	s3.SendResponse(w, s3.CopyPartResult{
+		LastModified: s3.FormatTime(time.Now().UTC()),
+		ETag:         quoteETag(loc.etag),
+	})
+}
+
+// copySourceObject looks up the location of the version of the copy
+// source object and checks the requester can read it and the conditions of
+// the copy source.
+func (h *handlers) copySourceObject(req client.RequestEvent, bucket, key, versionID string) (*nilrpc.MOBObjectGetResponse, s3.ErrorCode) {
+	ctxLogger := mlog.GetMethodLogger(logger, "handlers.copySourceObject")
+
+	b, code := h.findBucket(bucket)
+	if code != s3.ErrNone {
+		return nil, code
+	}
+
+	src, err := h.getObjectLocation(bucket, key, versionID)
+	if err != nil {
+		ctxLogger.Error(err)
+		return nil, s3.ErrInternalError
+	}
+	if !objectReadable(req, b, key, src.S3ErrCode, src.ACL, src.Tags) {
+		return nil, s3.ErrAccessDenied
+	}
+	// The delete marker can not be the copy source.
+	if src.S3ErrCode == s3.ErrMethodNotAllowed {
+		return nil, s3.ErrInvalidRequest
+	}
+	if src.S3ErrCode != s3.ErrNone {
+		return nil, src.S3ErrCode
+	}
+
+	// Every failed condition of the copy source is a failed precondition.
+	if s3.CheckPreconditions(req.Request().Header, copySourcePrefix, src.ETag, src.LastModified) != s3.ErrNone {
+		return nil, s3.ErrPreconditionFailed
+	}
+
+	return src, s3.ErrNone
+}
+
+// setCopySourceVersionHeader sets the response header of the version of
+// the copy source object.
+func setCopySourceVersionHeader(w http.ResponseWriter, versionID string) {
+	if versionID != "" && versionID != s3.NullVersion {
+		w.Header().Set("X-Amz-Copy-Source-Version-Id", versionID)
+	}
+}
+
+// copyObjectData makes the one of the alive ds copy the given range of the
+// object into a new object data. The ds reads each part of the range from
+// the ds where the part is stored. If either data is encrypted, the data is
+// copied through the gateway instead, decrypted with the source key and
+// encrypted with the encryption of the new data.
+func (h *handlers) copyObjectData(bucket, srcBucket string, parts []nilrpc.MOBObjectPart, offset, length int64, srcKey []byte, enc *objectEncryption) (*objectLocation, error) {
+	if srcKey != nil || enc.key != nil {
+		body, err := h.openObject(srcBucket, parts, offset, length, srcKey)
+		if err != nil {
+			return nil, err
+		}
+		defer body.Close()
+
+		return h.writeObject(bucket, body, length, enc)
+	}
+
+	sources := make([]client.CopySource, 0)
+	for _, seg := range partSegments(parts, offset, length) {
+		sources = append(sources, client.CopySource{
+			DsID:     seg.part.DsID.String(),
+			Bucket:   srcBucket,
+			ObjectID: seg.part.ObjectID,
+			ChainID:  seg.part.EncodingGroupID.String(),
+			VolumeID: seg.part.VolumeID.String(),
+			Offset:   seg.offset,
+			Length:   seg.length,
+		})
+	}
+
+	body, err := json.Marshal(sources)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode copy sources")
+	}
+
+	return h.writeData(client.CopyToPrimary, bucket, uuid.Gen(), bytes.NewReader(body), int64(len(body)), length)
+}
@@ -0,0 +1,200 @@
+package client
+
+import (
+	"encoding/xml"
+	"net/http"
+
+	"github.com/chanyoung/nil/pkg/client"
+	"github.com/chanyoung/nil/pkg/nilrpc"
+	"github.com/chanyoung/nil/pkg/s3"
+	"github.com/chanyoung/nil/pkg/util/mlog"
+	"github.com/gorilla/mux"
+)
+
+// maxCORSBodySize is the maximum size of the request body of the put
+// bucket cors, which is enough for the maximum number of the rules.
+const maxCORSBodySize = 64 * 1024
+
+// PutBucketCORSHandler handles the client request for replacing the cors
+// rules of the bucket.
+func (h *handlers) PutBucketCORSHandler(w http.ResponseWriter, r *http.Request) {
+	req := h.authenticate(w, r)
+	if req == nil {
+		return
+	}
+
+	if h.ownedBucket(req, mux.Vars(r)["bucket"]) == nil {
+		return
+	}
+
+	conf := s3.CORSConfiguration{}
+	if code := readXMLBody(r, maxCORSBodySize, &conf); code != s3.ErrNone {
+		req.SendError(code)
+		return
+	}
+	if code := conf.Validate(); code != s3.ErrNone {
+		req.SendError(code)
+		return
+	}
+
+	// The configuration is stored without the namespace, which is added
+	// when it is returned to the client.
+	conf.Xmlns = ""
+	cors, err := xml.Marshal(conf)
+	if err != nil {
+		req.SendError(s3.ErrMalformedXML)
+		return
+	}
+
+	h.setBucketCORS(w, r, req, string(cors))
+}
+
+// GetBucketCORSHandler handles the client request for getting the cors
+// rules of the bucket.
+func (h *handlers) GetBucketCORSHandler(w http.ResponseWriter, r *http.Request) {
+	ctxLogger := mlog.GetMethodLogger(logger, "handlers.GetBucketCORSHandler")
+
+	req := h.authenticate(w, r)
+	if req == nil {
+		return
+	}
+
+	b := h.ownedBucket(req, mux.Vars(r)["bucket"])
+	if b == nil {
+		return
+	}
+	if b.CORS == "" {
+		req.SendError(s3.ErrNoSuchCORSConfiguration)
+		return
+	}
+
+	conf := s3.CORSConfiguration{}
+	if err := xml.Unmarshal([]byte(b.CORS), &conf); err != nil {
+		ctxLogger.Error(err)
+		req.SendInternalError()
+		return
+	}
+	conf.Xmlns = s3.Namespace
+
+	s3.SendResponse(w, conf)
+}
+
+// DeleteBucketCORSHandler handles the client request for removing the cors
+// rules of the bucket.
+func (h *handlers) DeleteBucketCORSHandler(w http.ResponseWriter, r *http.Request) {
+	req := h.authenticate(w, r)
+	if req == nil {
+		return
+	}
+
+	if h.ownedBucket(req, mux.Vars(r)["bucket"]) == nil {
+		return
+	}
+
+	h.setBucketCORS(w, r, req, "")
+}
+
+// setBucketCORS records the cors rules of the bucket in the mds and sends
+// the response. The empty cors removes the rules.
+func (h *handlers) setBucketCORS(w http.ResponseWriter, r *http.Request, req client.RequestEvent, cors string) {
+	ctxLogger := mlog.GetMethodLogger(logger, "handlers.setBucketCORS")
+
+	res := &nilrpc.MACSetBucketCORSResponse{}
+	if err := h.callMds(nilrpc.MdsAccountSetBucketCORS, &nilrpc.MACSetBucketCORSRequest{
+		BucketName: mux.Vars(r)["bucket"],
+		CORS:       cors,
+	}, res); err != nil {
+		ctxLogger.Error(err)
+		req.SendInternalError()
+		return
+	}
+	if res.S3ErrCode != s3.ErrNone {
+		req.SendError(res.S3ErrCode)
+		return
+	}
+
+	if cors == "" {
+		s3.SendNoContent(w)
+		return
+	}
+	req.SendSuccess()
+}
+
+// PreflightHandler handles the cors preflight request of the browser, which
+// asks whether the actual request is allowed by the cors rules of the
+// bucket. The preflight request is not signed.
+func (h *handlers) PreflightHandler(w http.ResponseWriter, r *http.Request) {
+	ctxLogger := mlog.GetMethodLogger(logger, "handlers.PreflightHandler")
+
+	req := h.authenticate(w, r)
+	if req == nil {
+		return
+	}
+
+	origin := r.Header.Get("Origin")
+	method := r.Header.Get("Access-Control-Request-Method")
+	if origin == "" || method == "" {
+		req.SendError(s3.ErrInvalidRequest)
+		return
+	}
+
+	b, code := h.findBucket(mux.Vars(r)["bucket"])
+	if code != s3.ErrNone {
+		req.SendError(code)
+		return
+	}
+	if b.CORS == "" {
+		req.SendError(s3.ErrAccessForbidden)
+		return
+	}
+
+	conf := s3.CORSConfiguration{}
+	if err := xml.Unmarshal([]byte(b.CORS), &conf); err != nil {
+		ctxLogger.Error(err)
+		req.SendInternalError()
+		return
+	}
+
+	headers := s3.ParseCORSHeaders(r.Header.Get("Access-Control-Request-Headers"))
+	rule := conf.Match(origin, method, headers)
+	if rule == nil {
+		req.SendError(s3.ErrAccessForbidden)
+		return
+	}
+
+	rule.SetHeaders(w.Header(), origin, true, headers)
+	w.WriteHeader(http.StatusOK)
+}
+
+// requestBucket returns the information of the bucket of the request, or
+// the error code if it can not be found. The response to the request from
+// the browser has the cors headers if the cors rules of the bucket allow
+// the request.
+func (h *handlers) requestBucket(req client.RequestEvent, bucket string) (*nilrpc.MACGetBucketResponse, s3.ErrorCode) {
+	b, code := h.findBucket(bucket)
+	if code == s3.ErrNone {
+		setCORSHeaders(req, b)
+	}
+	return b, code
+}
+
+// setCORSHeaders sets the cors headers of the response if the request has
+// the origin and the cors rules of the bucket allow it.
+func setCORSHeaders(req client.RequestEvent, b *nilrpc.MACGetBucketResponse) {
+	ctxLogger := mlog.GetMethodLogger(logger, "setCORSHeaders")
+
+	r := req.Request()
+	origin := r.Header.Get("Origin")
+	if origin == "" || b.CORS == "" {
+		return
+	}
+
+	conf := s3.CORSConfiguration{}
+	if err := xml.Unmarshal([]byte(b.CORS), &conf); err != nil {
+		ctxLogger.Error(err)
+		return
+	}
+	if rule := conf.Match(origin, r.Method, nil); rule != nil {
+		rule.SetHeaders(req.ResponseWriter().Header(), origin, false, nil)
+	}
+}
@@ -0,0 +1,132 @@
+package client
+
+import (
+	"net/http"
+
+	"github.com/chanyoung/nil/pkg/nilrpc"
+	"github.com/chanyoung/nil/pkg/s3"
+	"github.com/chanyoung/nil/pkg/util/mlog"
+	"github.com/gorilla/mux"
+)
+
+// maxDeleteBodySize is the maximum size of the request body of the delete
+// objects, which is enough for the maximum number of the longest keys.
+const maxDeleteBodySize = 2 * 1024 * 1024
+
+// DeleteObjectsHandler handles the client request for deleting multiple
+// objects at once. The objects are deleted from the mds in a single
+// transaction, and then their data are deleted in the ds. The versions
+// locked by the object lock fail on their own.
+func (h *handlers) DeleteObjectsHandler(w http.ResponseWriter, r *http.Request) {
+	ctxLogger := mlog.GetMethodLogger(logger, "handlers.DeleteObjectsHandler")
+
+	req := h.authenticate(w, r)
+	if req == nil {
+		return
+	}
+
+	bucket := mux.Vars(r)["bucket"]
+	b, code := h.requestBucket(req, bucket)
+	if code != s3.ErrNone {
+		req.SendError(code)
+		return
+	}
+
+	del := s3.Delete{}
+	if code := readXMLBody(r, maxDeleteBodySize, &del); code != s3.ErrNone {
+		req.SendError(code)
+		return
+	}
+	if len(del.Objects) == 0 || len(del.Objects) > s3.MaxDeleteObjects {
+		req.SendError(s3.ErrMalformedXML)
+		return
+	}
+
+	result := s3.DeleteResult{}
+	objs := make([]nilrpc.MOBObjectVersion, 0, len(del.Objects))
+	valid := make([]s3.ObjectIdentifier, 0, len(del.Objects))
+	for _, obj := range del.Objects {
+		if code := checkDeleteObject(obj); code != s3.ErrNone {
+			result.Errors = append(result.Errors, s3.NewDeleteError(obj, code))
+			continue
+		}
+		// Each object is authorized by the bucket policy on its own.
+		if !authorized(req, b, "s3:DeleteObject", obj.Key, b.Owner == req.AccessKey(), nil) {
+			result.Errors = append(result.Errors, s3.NewDeleteError(obj, s3.ErrAccessDenied))
+			continue
+		}
+		if _, code := bypassGovernance(req, b, obj.Key); code != s3.ErrNone {
+			result.Errors = append(result.Errors, s3.NewDeleteError(obj, code))
+			continue
+		}
+		objs = append(objs, nilrpc.MOBObjectVersion{Name: obj.Key, VersionID: obj.VersionId})
+		valid = append(valid, obj)
+	}
+
+	if len(objs) > 0 {
+		res := &nilrpc.MOBDeleteObjectsResponse{}
+		if err := h.callMds(nilrpc.MdsObjectDeleteObjects, &nilrpc.MOBDeleteObjectsRequest{
+			Bucket:           bucket,
+			Objects:          objs,
+			BypassGovernance: s3.BypassGovernance(r.Header),
+		}, res); err != nil {
+			ctxLogger.Error(err)
+			res.S3ErrCode = s3.ErrInternalError
+		}
+
+		switch res.S3ErrCode {
+		case s3.ErrNone:
+			// The metadata is already deleted and the objects are not
+			// reachable anymore. Failing to delete the data only leaves
+			// a garbage.
+			h.deleteParts(bucket, res.Parts)
+
+			for i, obj := range valid {
+				if code := res.Deleted[i].S3ErrCode; code != s3.ErrNone {
+					result.Errors = append(result.Errors, s3.NewDeleteError(obj, code))
+				} else if !del.Quiet {
+					result.Deleted = append(result.Deleted, deletedObject(obj, res.Deleted[i]))
+				}
+			}
+		case s3.ErrNoSuchBucket:
+			req.SendError(res.S3ErrCode)
+			return
+		default:
+			// The objects are deleted in a single transaction, so none of
+			// them is deleted.
+			for _, obj := range valid {
+				result.Errors = append(result.Errors, s3.NewDeleteError(obj, res.S3ErrCode))
+			}
+		}
+	}
+
+	s3.SendResponse(w, result)
+}
+
+// checkDeleteObject checks the object can be deleted by the delete objects
+// request.
+func checkDeleteObject(obj s3.ObjectIdentifier) s3.ErrorCode {
+	if obj.Key == "" {
+		return s3.ErrInvalidArgument
+	}
+	if len(obj.Key) > maxKeyLength {
+		return s3.ErrKeyTooLongError
+	}
+	return s3.ErrNone
+}
+
+// deletedObject returns the entry of the deleted object. The version id of
+// the delete marker is reported separately from the requested version.
+func deletedObject(obj s3.ObjectIdentifier, d nilrpc.MOBDeletedObject) s3.DeletedObject {
+	deleted := s3.DeletedObject{
+		Key:       obj.Key,
+		VersionId: obj.VersionId,
+	}
+	if d.DeleteMarker {
+		deleted.DeleteMarker = true
+		if d.VersionID != s3.NullVersion {
+			deleted.DeleteMarkerVersionId = d.VersionID
+		}
+	}
+	return deleted
+}
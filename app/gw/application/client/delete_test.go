@@ -0,0 +1,128 @@
+package client
+
+import (
+	"encoding/xml"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/chanyoung/nil/pkg/nilrpc"
+	"github.com/chanyoung/nil/pkg/s3"
+)
+
+func TestDeleteObjects(t *testing.T) {
+	testCases := []struct {
+		name    string
+		quiet   bool
+		mdsCode s3.ErrorCode
+		deleted []string
+		errors  []string
+		// removed is true if the data of the deleted objects is removed.
+		removed bool
+	}{
+		{
+			"verbose", false, s3.ErrNone,
+			[]string{"a", "b"},
+			[]string{":InvalidArgument", "keep/b:AccessDenied", "locked:AccessDenied"},
+			true,
+		},
+		{
+			"quiet", true, s3.ErrNone,
+			nil,
+			[]string{":InvalidArgument", "keep/b:AccessDenied", "locked:AccessDenied"},
+			true,
+		},
+		{
+			"mds failure", false, s3.ErrInternalError,
+			nil,
+			[]string{":InvalidArgument", "keep/b:AccessDenied", "a:InternalError", "b:InternalError", "locked:InternalError"},
+			false,
+		},
+	}
+
+	for _, c := range testCases {
+		g := newTestGateway(t)
+		g.mds.bucket.Policy = testPolicy(`{"Effect": "Deny", "Principal": "*", "Action": "s3:DeleteObject", "Resource": "arn:aws:s3:::bucket/keep/*"}`)
+		for _, oid := range []string{"a1", "a2", "b1", "locked", "keep"} {
+			g.ds.objects[oid] = []byte(oid)
+		}
+
+		// The object a has the parts in two volumes, and the locked object
+		// is not deleted by the mds.
+		var requested []string
+		g.mds.methods[nilrpc.MdsObjectDeleteObjects] = func(req, res interface{}) {
+			r := res.(*nilrpc.MOBDeleteObjectsResponse)
+			if r.S3ErrCode = c.mdsCode; r.S3ErrCode != s3.ErrNone {
+				return
+			}
+
+			for _, obj := range req.(*nilrpc.MOBDeleteObjectsRequest).Objects {
+				requested = append(requested, obj.Name)
+				d := nilrpc.MOBDeletedObject{VersionID: s3.NullVersion}
+				if obj.Name == "locked" {
+					d.S3ErrCode = s3.ErrAccessDenied
+				}
+				r.Deleted = append(r.Deleted, d)
+			}
+			r.Parts = []nilrpc.MOBObjectPart{
+				{EncodingGroupID: 1, VolumeID: 1, DsID: 1, ObjectID: "a1", Size: 2},
+				{EncodingGroupID: 1, VolumeID: 2, DsID: 2, ObjectID: "a2", Offset: 2, Size: 2},
+				{EncodingGroupID: 1, VolumeID: 1, DsID: 1, ObjectID: "b1", Size: 2},
+			}
+		}
+
+		body, err := xml.Marshal(s3.Delete{
+			Quiet: c.quiet,
+			Objects: []s3.ObjectIdentifier{
+				{Key: ""}, {Key: "a"}, {Key: "keep/b"}, {Key: "b"}, {Key: "locked"},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		w := g.do("POST", "/bucket?delete", "owner", nil, body)
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: expected status 200, got %d: %s", c.name, w.Code, w.Body)
+			continue
+		}
+
+		result := s3.DeleteResult{}
+		if err := xml.Unmarshal(w.Body.Bytes(), &result); err != nil {
+			t.Fatal(err)
+		}
+		var deleted, errors []string
+		for _, d := range result.Deleted {
+			deleted = append(deleted, d.Key)
+		}
+		for _, e := range result.Errors {
+			errors = append(errors, e.Key+":"+e.Code)
+		}
+		if !reflect.DeepEqual(deleted, c.deleted) {
+			t.Errorf("%s: expected deleted %v, got %v", c.name, c.deleted, deleted)
+		}
+		if !reflect.DeepEqual(errors, c.errors) {
+			t.Errorf("%s: expected errors %v, got %v", c.name, c.errors, errors)
+		}
+
+		// Only the valid and authorized objects are requested to the mds.
+		if c.mdsCode == s3.ErrNone && !reflect.DeepEqual(requested, []string{"a", "b", "locked"}) {
+			t.Errorf("%s: expected a, b and locked are requested to the mds, got %v", c.name, requested)
+		}
+
+		// The data is deleted in one request per volume.
+		if c.removed && g.ds.deletes != 2 {
+			t.Errorf("%s: expected 2 delete requests to the ds, got %d", c.name, g.ds.deletes)
+		}
+		for _, oid := range []string{"a1", "a2", "b1"} {
+			if g.ds.has(oid) == c.removed {
+				t.Errorf("%s: expected removed %t of the data %s", c.name, c.removed, oid)
+			}
+		}
+		for _, oid := range []string{"locked", "keep"} {
+			if !g.ds.has(oid) {
+				t.Errorf("%s: expected the data %s is kept", c.name, oid)
+			}
+		}
+	}
+}
@@ -1,15 +1,20 @@
 package client
 
 import (
+	"encoding/xml"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"net/rpc"
-	"strings"
 	"time"
 
 	"github.com/chanyoung/nil/app/gw/application/auth"
+	"github.com/chanyoung/nil/app/gw/domain/model/cred"
+	"github.com/chanyoung/nil/pkg/client"
 	"github.com/chanyoung/nil/pkg/client/request"
 	"github.com/chanyoung/nil/pkg/cmap"
 	"github.com/chanyoung/nil/pkg/nilrpc"
+	"github.com/chanyoung/nil/pkg/s3"
 	"github.com/chanyoung/nil/pkg/util/mlog"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
@@ -21,52 +26,198 @@ type handlers struct {
 	requestEventFactory *request.RequestEventFactory
 	authHandlers        auth.Handlers
 	cmapAPI             cmap.SlaveAPI
+
+	// callMds calls the procedure of the alive mds.
+	callMds func(method nilrpc.MethodName, req, res interface{}) error
+	// sendDs sends the request to the ds.
+	sendDs request.SendFunc
+
+	// orphans is the number of the object data which are failed to be
+	// rolled back, since the ds could not look up their locks.
+	orphans int64
 }
 
 // NewHandlers creates a client handlers with necessary dependencies.
 func NewHandlers(cmapAPI cmap.SlaveAPI, f *request.RequestEventFactory, authHandlers auth.Handlers) Handlers {
 	logger = mlog.GetPackageLogger("app/gw/application/client")
 
-	return &handlers{
+	h := &handlers{
 		requestEventFactory: f,
 		authHandlers:        authHandlers,
 		cmapAPI:             cmapAPI,
+		sendDs:              request.Send,
 	}
+	h.callMds = h.callAliveMds
+
+	return h
 }
 
-func (h *handlers) getObjectLocation(oid, bucket string) (*nilrpc.MOBObjectGetResponse, error) {
+// authenticate creates a request event and checks the signature of the
+// request. It sends the error response to the client and returns nil if
+// the request is not authenticated.
+func (h *handlers) authenticate(w http.ResponseWriter, r *http.Request) client.RequestEvent {
+	ctxLogger := mlog.GetMethodLogger(logger, "handlers.authenticate")
+
+	req, err := h.requestEventFactory.CreateRequestEvent(w, r)
+	if err == client.ErrInvalidProtocol {
+		ctxLogger.Error(err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil
+	}
+	if code := req.Validate(); code != s3.ErrNone {
+		req.SendError(code)
+		return nil
+	}
+	// The anonymous request has nothing to authenticate, and can access only
+	// what the acls grant everyone.
+	if req.Anonymous() {
+		return req
+	}
+
+	sk, err := h.authHandlers.GetSecretKey(cred.Key(req.AccessKey()))
+	if err == auth.ErrInternal {
+		req.SendInternalError()
+		return nil
+	} else if err == auth.ErrNoSuchKey {
+		req.SendNoSuchKey()
+		return nil
+	}
+
+	if req.Auth(sk.String()) == false {
+		req.SendIncorrectKey()
+		return nil
+	}
+
+	return req
+}
+
+// callAliveMds calls the given procedure to the alive mds.
+func (h *handlers) callAliveMds(method nilrpc.MethodName, req, res interface{}) error {
 	mds, err := h.cmapAPI.SearchCall().Node().Type(cmap.MDS).Status(cmap.NodeAlive).Do()
 	if err != nil {
-		return nil, errors.Wrap(err, "find alive mds failed")
+		return errors.Wrap(err, "find alive mds failed")
 	}
 
 	conn, err := nilrpc.Dial(mds.Addr.String(), nilrpc.RPCNil, time.Duration(2*time.Second))
 	if err != nil {
-		return nil, errors.Wrap(err, "dial to mds failed")
+		return errors.Wrap(err, "dial to mds failed")
 	}
 	defer conn.Close()
 
+	cli := rpc.NewClient(conn)
+	if err := cli.Call(method.String(), req, res); err != nil {
+		return errors.Wrap(err, "mds rpc client calling failed")
+	}
+
+	return nil
+}
+
+// getObjectLocation asks the mds where the version of the object is
+// stored. The empty version id means the latest version.
+func (h *handlers) getObjectLocation(bucket, key, versionID string) (*nilrpc.MOBObjectGetResponse, error) {
 	req := &nilrpc.MOBObjectGetRequest{
-		Name:   bucket + "." + strings.Replace(oid, "/", ".", -1),
-		Bucket: bucket,
+		Name:      key,
+		Bucket:    bucket,
+		VersionID: versionID,
 	}
 	res := &nilrpc.MOBObjectGetResponse{}
 
-	cli := rpc.NewClient(conn)
-	if err := cli.Call(nilrpc.MdsObjectGet.String(), req, res); err != nil {
-		logger.Errorf("%+v", req)
-		return nil, errors.Wrap(err, "mds rpc client calling failed")
+	if err := h.callMds(nilrpc.MdsObjectGet, req, res); err != nil {
+		return nil, err
 	}
 
 	return res, nil
 }
 
+// readXMLBody decodes the xml request body of at most max bytes into v and
+// verifies the digests of the whole body.
+func readXMLBody(r *http.Request, max int64, v interface{}) s3.ErrorCode {
+	body, code := s3.NewDigestReader(io.LimitReader(r.Body, max), r.Header)
+	if code != s3.ErrNone {
+		return code
+	}
+
+	if err := xml.NewDecoder(body).Decode(v); err != nil {
+		return s3.ErrMalformedXML
+	}
+	// Drain the rest of the body to verify the digests of the whole body.
+	if _, err := io.Copy(ioutil.Discard, body); err != nil {
+		return s3.ErrIncompleteBody
+	}
+	if code := finishBody(r.Body); code != s3.ErrNone {
+		return code
+	}
+	return body.Verify()
+}
+
+// readBody reads the request body of at most max bytes and verifies its
+// digests.
+func readBody(r *http.Request, max int64) ([]byte, s3.ErrorCode) {
+	body, code := s3.NewDigestReader(io.LimitReader(r.Body, max+1), r.Header)
+	if code != s3.ErrNone {
+		return nil, code
+	}
+
+	data, err := ioutil.ReadAll(body)
+	if err != nil {
+		return nil, s3.ErrIncompleteBody
+	}
+	if int64(len(data)) > max {
+		return nil, s3.ErrEntityTooLarge
+	}
+	if code := finishBody(r.Body); code != s3.ErrNone {
+		return nil, code
+	}
+	return data, body.Verify()
+}
+
 // Handlers is the interface that provides client http handlers.
 type Handlers interface {
+	ListBucketsHandler(w http.ResponseWriter, r *http.Request)
+
 	MakeBucketHandler(w http.ResponseWriter, r *http.Request)
+	HeadBucketHandler(w http.ResponseWriter, r *http.Request)
 	RemoveBucketHandler(w http.ResponseWriter, r *http.Request)
+	ListObjectsHandler(w http.ResponseWriter, r *http.Request)
+	ListMultipartUploadsHandler(w http.ResponseWriter, r *http.Request)
+	ListObjectVersionsHandler(w http.ResponseWriter, r *http.Request)
+	PutBucketVersioningHandler(w http.ResponseWriter, r *http.Request)
+	GetBucketVersioningHandler(w http.ResponseWriter, r *http.Request)
+	PutBucketLifecycleHandler(w http.ResponseWriter, r *http.Request)
+	GetBucketLifecycleHandler(w http.ResponseWriter, r *http.Request)
+	DeleteBucketLifecycleHandler(w http.ResponseWriter, r *http.Request)
+	PutBucketACLHandler(w http.ResponseWriter, r *http.Request)
+	GetBucketACLHandler(w http.ResponseWriter, r *http.Request)
+	PutBucketPolicyHandler(w http.ResponseWriter, r *http.Request)
+	GetBucketPolicyHandler(w http.ResponseWriter, r *http.Request)
+	DeleteBucketPolicyHandler(w http.ResponseWriter, r *http.Request)
+	PutBucketCORSHandler(w http.ResponseWriter, r *http.Request)
+	GetBucketCORSHandler(w http.ResponseWriter, r *http.Request)
+	DeleteBucketCORSHandler(w http.ResponseWriter, r *http.Request)
+	PreflightHandler(w http.ResponseWriter, r *http.Request)
 
 	PutObjectHandler(w http.ResponseWriter, r *http.Request)
+	CopyObjectHandler(w http.ResponseWriter, r *http.Request)
+	HeadObjectHandler(w http.ResponseWriter, r *http.Request)
 	GetObjectHandler(w http.ResponseWriter, r *http.Request)
 	DeleteObjectHandler(w http.ResponseWriter, r *http.Request)
+	DeleteObjectsHandler(w http.ResponseWriter, r *http.Request)
+	PutObjectACLHandler(w http.ResponseWriter, r *http.Request)
+	GetObjectACLHandler(w http.ResponseWriter, r *http.Request)
+	PutObjectTaggingHandler(w http.ResponseWriter, r *http.Request)
+	GetObjectTaggingHandler(w http.ResponseWriter, r *http.Request)
+	DeleteObjectTaggingHandler(w http.ResponseWriter, r *http.Request)
+	PutObjectLockConfigurationHandler(w http.ResponseWriter, r *http.Request)
+	GetObjectLockConfigurationHandler(w http.ResponseWriter, r *http.Request)
+	PutObjectRetentionHandler(w http.ResponseWriter, r *http.Request)
+	GetObjectRetentionHandler(w http.ResponseWriter, r *http.Request)
+	PutObjectLegalHoldHandler(w http.ResponseWriter, r *http.Request)
+	GetObjectLegalHoldHandler(w http.ResponseWriter, r *http.Request)
+
+	CreateMultipartUploadHandler(w http.ResponseWriter, r *http.Request)
+	UploadPartHandler(w http.ResponseWriter, r *http.Request)
+	UploadPartCopyHandler(w http.ResponseWriter, r *http.Request)
+	CompleteMultipartUploadHandler(w http.ResponseWriter, r *http.Request)
+	AbortMultipartUploadHandler(w http.ResponseWriter, r *http.Request)
+	ListPartsHandler(w http.ResponseWriter, r *http.Request)
 }
@@ -0,0 +1,277 @@
+package client
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/chanyoung/nil/app/gw/application/auth"
+	"github.com/chanyoung/nil/app/gw/domain/model/cred"
+	"github.com/chanyoung/nil/pkg/client"
+	"github.com/chanyoung/nil/pkg/client/request"
+	"github.com/chanyoung/nil/pkg/cmap"
+	"github.com/chanyoung/nil/pkg/nilrpc"
+	"github.com/chanyoung/nil/pkg/s3"
+	"github.com/chanyoung/nil/pkg/util/mlog"
+	"github.com/gorilla/mux"
+)
+
+func TestMain(m *testing.M) {
+	mlog.Init("stderr")
+	code := m.Run()
+	// The cluster map of the tests is saved in the working directory.
+	os.RemoveAll("cmap")
+	os.Exit(code)
+}
+
+// secretKeys are the secret keys of the users of the tests.
+var secretKeys = map[string]string{
+	"owner": "ownersecret",
+	"other": "othersecret",
+}
+
+type fakeAuth struct{}
+
+func (fakeAuth) GetSecretKey(access cred.Key) (cred.Key, error) {
+	sk, ok := secretKeys[access.String()]
+	if !ok {
+		return "", auth.ErrNoSuchKey
+	}
+	return cred.Key(sk), nil
+}
+
+// fakeMds answers the bucket to the get bucket calls, and the other calls
+// by the functions of their methods.
+type fakeMds struct {
+	bucket  nilrpc.MACGetBucketResponse
+	methods map[nilrpc.MethodName]func(req, res interface{})
+	calls   map[nilrpc.MethodName]int
+}
+
+func (m *fakeMds) call(method nilrpc.MethodName, req, res interface{}) error {
+	m.calls[method]++
+
+	if method == nilrpc.MdsAccountGetBucket {
+		if req.(*nilrpc.MACGetBucketRequest).BucketName != m.bucket.BucketName {
+			res.(*nilrpc.MACGetBucketResponse).S3ErrCode = s3.ErrNoSuchBucket
+			return nil
+		}
+		*res.(*nilrpc.MACGetBucketResponse) = m.bucket
+		return nil
+	}
+
+	f, ok := m.methods[method]
+	if !ok {
+		return fmt.Errorf("unexpected mds call: %s", method)
+	}
+	f(req, res)
+	return nil
+}
+
+// fakeDs keeps the object data in memory, regardless of the volume.
+type fakeDs struct {
+	objects map[string][]byte
+	// reads and deletes are the number of the requests.
+	reads   int
+	deletes int
+	// lockFailures is the number of the next delete requests whose object
+	// locks are unknown to the ds.
+	lockFailures int
+
+	mu sync.Mutex
+}
+
+func (d *fakeDs) send(reqType client.RequestType, method, url string, body io.Reader, headers client.Headers, contentLength int64) (*http.Response, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	w := httptest.NewRecorder()
+	switch reqType {
+	case client.WriteToPrimary:
+		data, err := ioutil.ReadAll(body)
+		if err != nil {
+			return nil, err
+		}
+		if int64(len(data)) != contentLength {
+			w.WriteHeader(http.StatusBadRequest)
+			break
+		}
+		d.objects[path.Base(url)] = data
+
+		sum := md5.Sum(data)
+		w.Header().Set("Volume-Id", "1")
+		w.Header().Set("ETag", hex.EncodeToString(sum[:]))
+
+	case client.ReadFromPrimary:
+		d.reads++
+		data, ok := d.objects[path.Base(url)]
+		var first, last int64
+		fmt.Sscanf(headers["Range"], "bytes=%d-%d", &first, &last)
+		if !ok || first > last || last >= int64(len(data)) {
+			w.WriteHeader(http.StatusInternalServerError)
+			break
+		}
+		w.Write(data[first : last+1])
+
+	case client.DeleteObjectsFromPrimary:
+		d.deletes++
+		var oids []string
+		if err := json.NewDecoder(body).Decode(&oids); err != nil {
+			return nil, err
+		}
+		result := client.DeleteObjectsResult{}
+		if d.lockFailures > 0 {
+			d.lockFailures--
+			for _, oid := range oids {
+				result.Errors = append(result.Errors, client.DeleteObjectError{ObjectID: oid, Message: "unknown lock", LockUnknown: true})
+			}
+			json.NewEncoder(w).Encode(result)
+			break
+		}
+		for _, oid := range oids {
+			if _, ok := d.objects[oid]; !ok {
+				result.Errors = append(result.Errors, client.DeleteObjectError{ObjectID: oid, Message: "no such object"})
+			}
+			delete(d.objects, oid)
+		}
+		json.NewEncoder(w).Encode(result)
+
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+
+	return w.Result(), nil
+}
+
+// has returns true if the ds has the data.
+func (d *fakeDs) has(oid string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	_, ok := d.objects[oid]
+	return ok
+}
+
+// testGateway serves the object requests by the handlers which are backed
+// by the fake mds and ds. The bucket of the tests is owned by the owner.
+type testGateway struct {
+	http.Handler
+	h   *handlers
+	mds *fakeMds
+	ds  *fakeDs
+}
+
+func newTestGateway(t *testing.T) *testGateway {
+	cmapService, err := cmap.NewService(mlog.GetPackageLogger("app/gw/application/client"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmapService.UpdateCMap(&cmap.CMap{
+		Version: 1,
+		Nodes: []cmap.Node{
+			{ID: 1, Type: cmap.DS, Stat: cmap.NodeAlive, Addr: "ds1"},
+			{ID: 2, Type: cmap.DS, Stat: cmap.NodeAlive, Addr: "ds2"},
+		},
+		MatrixIDs: []int{1},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	g := &testGateway{
+		mds: &fakeMds{
+			bucket: nilrpc.MACGetBucketResponse{
+				BucketName: "bucket",
+				Owner:      "owner",
+				ACL:        s3.ACLPrivate,
+			},
+			methods: make(map[nilrpc.MethodName]func(req, res interface{})),
+			calls:   make(map[nilrpc.MethodName]int),
+		},
+		ds: &fakeDs{objects: make(map[string][]byte)},
+	}
+
+	h := NewHandlers(cmapService.SlaveAPI(), request.NewRequestEventFactory(), fakeAuth{}).(*handlers)
+	h.callMds = g.mds.call
+	h.sendDs = g.ds.send
+	g.h = h
+
+	// The routes are named by the actions as the gateway does.
+	r := mux.NewRouter()
+	br := r.PathPrefix("/{bucket}").Subrouter()
+	or := br.PathPrefix("/{object:.+}").Subrouter()
+	br.Methods("POST").Queries("delete", "").Name("s3:DeleteObject").HandlerFunc(h.DeleteObjectsHandler)
+	or.Methods("PUT").Name("s3:PutObject").HandlerFunc(h.PutObjectHandler)
+	or.Methods("GET").Name("s3:GetObject").HandlerFunc(h.GetObjectHandler)
+	or.Methods("DELETE").Name("s3:DeleteObject").HandlerFunc(h.DeleteObjectHandler)
+	g.Handler = r
+
+	return g
+}
+
+// do serves the request of the user, which is signed by the signature v2.
+// The request of the empty user is anonymous.
+func (g *testGateway) do(method, target, user string, header map[string]string, body []byte) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, bytes.NewReader(body))
+	for k, v := range header {
+		r.Header.Set(k, v)
+	}
+	r.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	if user != "" {
+		signature := s3.GenSignatureV2(secretKeys[user], s3.GenStringToSignV2(r, r.URL.EscapedPath()))
+		r.Header.Set("Authorization", "AWS "+user+":"+signature)
+	}
+
+	w := httptest.NewRecorder()
+	g.ServeHTTP(w, r)
+	return w
+}
+
+// storedObject returns the mds function of getting the object which is
+// recorded by the put request.
+func storedObject(put *nilrpc.MOBObjectPutRequest) func(req, res interface{}) {
+	return func(req, res interface{}) {
+		*res.(*nilrpc.MOBObjectGetResponse) = nilrpc.MOBObjectGetResponse{
+			Parts: []nilrpc.MOBObjectPart{{
+				EncodingGroupID: put.EncodingGroup,
+				VolumeID:        put.Volume,
+				DsID:            put.DsID,
+				ObjectID:        put.ObjectID,
+				Size:            put.Size,
+			}},
+			Size:         put.Size,
+			ETag:         put.ETag,
+			LastModified: time.Now(),
+			ACL:          put.ACL,
+			Encryption:   put.Encryption,
+		}
+	}
+}
+
+// errorCode returns the code of the error response.
+func errorCode(w *httptest.ResponseRecorder) string {
+	e := struct{ Code string }{}
+	xml.Unmarshal(w.Body.Bytes(), &e)
+	return e.Code
+}
+
+// expectError checks the response is the error of the code.
+func expectError(t *testing.T, name string, w *httptest.ResponseRecorder, code s3.ErrorCode) {
+	t.Helper()
+
+	info := s3.GetErrorInfo(code)
+	if w.Code != info.HTTPCode || errorCode(w) != info.Code {
+		t.Errorf("%s: expected %d %s, got %d: %s", name, info.HTTPCode, info.Code, w.Code, w.Body)
+	}
+}
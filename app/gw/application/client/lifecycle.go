@@ -0,0 +1,121 @@
+package client
+
+import (
+	"encoding/xml"
+	"net/http"
+
+	"github.com/chanyoung/nil/pkg/client"
+	"github.com/chanyoung/nil/pkg/nilrpc"
+	"github.com/chanyoung/nil/pkg/s3"
+	"github.com/chanyoung/nil/pkg/util/mlog"
+	"github.com/gorilla/mux"
+)
+
+// maxLifecycleBodySize is the maximum size of the request body of the put
+// bucket lifecycle, which is enough for the maximum number of the rules.
+const maxLifecycleBodySize = 1024 * 1024
+
+// PutBucketLifecycleHandler handles the client request for replacing the
+// lifecycle rules of the bucket.
+func (h *handlers) PutBucketLifecycleHandler(w http.ResponseWriter, r *http.Request) {
+	req := h.authenticate(w, r)
+	if req == nil {
+		return
+	}
+
+	if h.ownedBucket(req, mux.Vars(r)["bucket"]) == nil {
+		return
+	}
+
+	conf := s3.LifecycleConfiguration{}
+	if code := readXMLBody(r, maxLifecycleBodySize, &conf); code != s3.ErrNone {
+		req.SendError(code)
+		return
+	}
+	if code := conf.Validate(); code != s3.ErrNone {
+		req.SendError(code)
+		return
+	}
+
+	// The configuration is stored without the namespace, which is added
+	// when it is returned to the client.
+	conf.Xmlns = ""
+	lifecycle, err := xml.Marshal(conf)
+	if err != nil {
+		req.SendError(s3.ErrMalformedXML)
+		return
+	}
+
+	h.setBucketLifecycle(w, r, req, string(lifecycle))
+}
+
+// GetBucketLifecycleHandler handles the client request for getting the
+// lifecycle rules of the bucket.
+func (h *handlers) GetBucketLifecycleHandler(w http.ResponseWriter, r *http.Request) {
+	ctxLogger := mlog.GetMethodLogger(logger, "handlers.GetBucketLifecycleHandler")
+
+	req := h.authenticate(w, r)
+	if req == nil {
+		return
+	}
+
+	b := h.ownedBucket(req, mux.Vars(r)["bucket"])
+	if b == nil {
+		return
+	}
+	if b.Lifecycle == "" {
+		req.SendError(s3.ErrNoSuchLifecycleConfiguration)
+		return
+	}
+
+	conf := s3.LifecycleConfiguration{}
+	if err := xml.Unmarshal([]byte(b.Lifecycle), &conf); err != nil {
+		ctxLogger.Error(err)
+		req.SendInternalError()
+		return
+	}
+	conf.Xmlns = s3.Namespace
+
+	s3.SendResponse(w, conf)
+}
+
+// DeleteBucketLifecycleHandler handles the client request for removing the
+// lifecycle rules of the bucket.
+func (h *handlers) DeleteBucketLifecycleHandler(w http.ResponseWriter, r *http.Request) {
+	req := h.authenticate(w, r)
+	if req == nil {
+		return
+	}
+
+	if h.ownedBucket(req, mux.Vars(r)["bucket"]) == nil {
+		return
+	}
+
+	h.setBucketLifecycle(w, r, req, "")
+}
+
+// setBucketLifecycle records the lifecycle rules of the bucket in the mds
+// and sends the response. The empty lifecycle removes the rules.
+func (h *handlers) setBucketLifecycle(w http.ResponseWriter, r *http.Request, req client.RequestEvent, lifecycle string) {
+	ctxLogger := mlog.GetMethodLogger(logger, "handlers.setBucketLifecycle")
+
+	res := &nilrpc.MACSetBucketLifecycleResponse{}
+	if err := h.callMds(nilrpc.MdsAccountSetBucketLifecycle, &nilrpc.MACSetBucketLifecycleRequest{
+		BucketName: mux.Vars(r)["bucket"],
+		Lifecycle:  lifecycle,
+	}, res); err != nil {
+		ctxLogger.Error(err)
+		req.SendInternalError()
+		return
+	}
+	if res.S3ErrCode != s3.ErrNone {
+		req.SendError(res.S3ErrCode)
+		return
+	}
+
+	if lifecycle == "" {
+		s3.SendNoContent(w)
+		return
+	}
+	req.SendSuccess()
+}
@@ -0,0 +1,204 @@
+package client
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/chanyoung/nil/pkg/nilrpc"
+	"github.com/chanyoung/nil/pkg/s3"
+	"github.com/chanyoung/nil/pkg/util/mlog"
+	"github.com/gorilla/mux"
+)
+
+// defaultMaxKeys is the number of keys returned when max-keys is not given.
+const defaultMaxKeys = 1000
+
+// ListBucketsHandler handles the client request for listing the buckets
+// owned by the requester.
+func (h *handlers) ListBucketsHandler(w http.ResponseWriter, r *http.Request) {
+	ctxLogger := mlog.GetMethodLogger(logger, "handlers.ListBucketsHandler")
+
+	req := h.authenticate(w, r)
+	if req == nil {
+		return
+	}
+	// The anonymous has no bucket to list.
+	if req.Anonymous() {
+		req.SendError(s3.ErrAccessDenied)
+		return
+	}
+
+	lreq := &nilrpc.MACListBucketsRequest{AccessKey: req.AccessKey()}
+	lres := &nilrpc.MACListBucketsResponse{}
+	if err := h.callMds(nilrpc.MdsAccountListBuckets, lreq, lres); err != nil {
+		ctxLogger.Error(err)
+		req.SendInternalError()
+		return
+	}
+	if lres.S3ErrCode != s3.ErrNone {
+		req.SendError(lres.S3ErrCode)
+		return
+	}
+
+	result := s3.ListAllMyBucketsResult{
+		Owner: s3.Owner{
+			ID:          req.AccessKey(),
+			DisplayName: lres.OwnerName,
+		},
+		Buckets: make([]s3.Bucket, len(lres.Buckets)),
+	}
+	for i, b := range lres.Buckets {
+		result.Buckets[i] = s3.Bucket{
+			Name:         b.Name,
+			CreationDate: s3.FormatTime(b.CreationDate),
+		}
+	}
+
+	s3.SendResponse(w, result)
+}
+
+// ListObjectsHandler handles the client request for listing the objects
+// in the bucket. It serves ListObjectsV2 if list-type is 2, and the
+// version 1 with marker otherwise.
+func (h *handlers) ListObjectsHandler(w http.ResponseWriter, r *http.Request) {
+	ctxLogger := mlog.GetMethodLogger(logger, "handlers.ListObjectsHandler")
+
+	req := h.authenticate(w, r)
+	if req == nil {
+		return
+	}
+
+	q := r.URL.Query()
+	v2 := q.Get("list-type") == "2"
+
+	maxKeys, ok := queryInt(q, "max-keys", defaultMaxKeys)
+	if !ok {
+		req.SendError(s3.ErrInvalidArgument)
+		return
+	}
+
+	encodingType := q.Get("encoding-type")
+	encode, ok := keyEncoder(encodingType)
+	if !ok {
+		req.SendError(s3.ErrInvalidArgument)
+		return
+	}
+
+	bucket := mux.Vars(r)["bucket"]
+	b := h.readableBucket(req, bucket)
+	if b == nil {
+		return
+	}
+
+	lreq := &nilrpc.MOBObjectListRequest{
+		Bucket:    bucket,
+		Prefix:    q.Get("prefix"),
+		Delimiter: q.Get("delimiter"),
+		MaxKeys:   maxKeys,
+	}
+	if v2 {
+		lreq.StartAfter = q.Get("start-after")
+		lreq.Token = q.Get("continuation-token")
+	} else {
+		lreq.StartAfter = q.Get("marker")
+	}
+	lres := &nilrpc.MOBObjectListResponse{}
+	if err := h.callMds(nilrpc.MdsObjectList, lreq, lres); err != nil {
+		ctxLogger.Error(err)
+		req.SendInternalError()
+		return
+	}
+	if lres.S3ErrCode != s3.ErrNone {
+		req.SendError(lres.S3ErrCode)
+		return
+	}
+
+	// Version 1 always shows the owner, version 2 only if requested.
+	var owner *s3.Owner
+	if !v2 || q.Get("fetch-owner") == "true" {
+		owner = &s3.Owner{ID: b.Owner, DisplayName: b.Owner}
+	}
+
+	result := s3.ListBucketResult{
+		Name:           bucket,
+		Prefix:         encode(lreq.Prefix),
+		MaxKeys:        maxKeys,
+		Delimiter:      encode(lreq.Delimiter),
+		EncodingType:   encodingType,
+		IsTruncated:    lres.IsTruncated,
+		Contents:       make([]s3.Object, len(lres.Objects)),
+		CommonPrefixes: make([]s3.CommonPrefix, len(lres.CommonPrefixes)),
+	}
+
+	// The last entry in name order, which is the next marker of version 1.
+	var last string
+	for i, o := range lres.Objects {
+		result.Contents[i] = s3.Object{
+			Key:          encode(o.Name),
+			LastModified: s3.FormatTime(o.LastModified),
+			ETag:         quoteETag(o.ETag),
+			Size:         o.Size,
+			StorageClass: "STANDARD",
+			Owner:        owner,
+		}
+		last = o.Name
+	}
+	for i, p := range lres.CommonPrefixes {
+		result.CommonPrefixes[i] = s3.CommonPrefix{Prefix: encode(p)}
+		if p > last {
+			last = p
+		}
+	}
+
+	if v2 {
+		keyCount := len(lres.Objects) + len(lres.CommonPrefixes)
+		result.KeyCount = &keyCount
+		result.StartAfter = encode(lreq.StartAfter)
+		result.ContinuationToken = lreq.Token
+		result.NextContinuationToken = lres.NextToken
+	} else {
+		marker := encode(lreq.StartAfter)
+		result.Marker = &marker
+		if lres.IsTruncated {
+			result.NextMarker = encode(last)
+		}
+	}
+
+	s3.SendResponse(w, result)
+}
+
+// queryInt returns the non-negative integer of the query parameter, which
+// is capped by the default value. It returns false if the value is invalid.
+func queryInt(q url.Values, name string, def int) (int, bool) {
+	v := q.Get(name)
+	if v == "" {
+		return def, true
+	}
+
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	if n > def {
+		n = def
+	}
+	return n, true
+}
+
+// keyEncoder returns the function which encodes the keys in the list
+// response with the given encoding type. It returns false if the encoding
+// type is not supported.
+func keyEncoder(encodingType string) (func(string) string, bool) {
+	switch encodingType {
+	case "":
+		return func(s string) string { return s }, true
+	case "url":
+		return func(s string) string {
+			return strings.Replace(url.QueryEscape(s), "+", "%20", -1)
+		}, true
+	default:
+		return nil, false
+	}
+}
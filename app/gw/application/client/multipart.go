@@ -0,0 +1,444 @@
+package client
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/chanyoung/nil/pkg/nilrpc"
+	"github.com/chanyoung/nil/pkg/s3"
+	"github.com/chanyoung/nil/pkg/util/mlog"
+	"github.com/gorilla/mux"
+)
+
+const (
+	// maxPartNumber is the maximum part number of the multipart upload.
+	maxPartNumber = 10000
+	// maxCompleteBodySize is the maximum size of the request body of the
+	// complete multipart upload, which is enough for the maximum number
+	// of parts.
+	maxCompleteBodySize = 2 * 1024 * 1024
+)
+
+// CreateMultipartUploadHandler handles the client request for initiating
+// a multipart upload.
+func (h *handlers) CreateMultipartUploadHandler(w http.ResponseWriter, r *http.Request) {
+	ctxLogger := mlog.GetMethodLogger(logger, "handlers.CreateMultipartUploadHandler")
+
+	req := h.authenticate(w, r)
+	if req == nil {
+		return
+	}
+
+	vars := mux.Vars(r)
+	bucket, key := vars["bucket"], vars["object"]
+	if len(key) > maxKeyLength {
+		req.SendError(s3.ErrKeyTooLongError)
+		return
+	}
+	b := h.ownedBucket(req, bucket)
+	if b == nil {
+		return
+	}
+
+	meta, code := requestMeta(r.Header)
+	if code != s3.ErrNone {
+		req.SendError(code)
+		return
+	}
+	if meta.lock, code = requestLock(r.Header, b); code != s3.ErrNone {
+		req.SendError(code)
+		return
+	}
+	enc, code := h.requestEncryption(r)
+	if code != s3.ErrNone {
+		req.SendError(code)
+		return
+	}
+
+	res := &nilrpc.MOBCreateUploadResponse{}
+	if err := h.callMds(nilrpc.MdsObjectCreateUpload, &nilrpc.MOBCreateUploadRequest{
+		Name:        key,
+		Bucket:      bucket,
+		ContentType: meta.contentType,
+		Headers:     meta.headers,
+		Metadata:    meta.metadata,
+		ACL:         meta.acl,
+		Tags:        meta.tags,
+		Lock:        meta.lock,
+		Encryption:  enc.meta,
+	}, res); err != nil {
+		ctxLogger.Error(err)
+		req.SendInternalError()
+		return
+	}
+	if res.S3ErrCode != s3.ErrNone {
+		req.SendError(res.S3ErrCode)
+		return
+	}
+
+	setSSEHeaders(w, enc)
+	s3.SendResponse(w, s3.InitiateMultipartUploadResult{
+		Bucket:   bucket,
+		Key:      key,
+		UploadId: res.UploadID,
+	})
+}
+
+// UploadPartHandler handles the client request for uploading a part of
+// the multipart upload. Each part is written as a separate data in the ds.
+func (h *handlers) UploadPartHandler(w http.ResponseWriter, r *http.Request) {
+	ctxLogger := mlog.GetMethodLogger(logger, "handlers.UploadPartHandler")
+
+	req := h.authenticate(w, r)
+	if req == nil {
+		return
+	}
+
+	q := r.URL.Query()
+	partNumber, err := strconv.Atoi(q.Get("partNumber"))
+	if err != nil || partNumber < 1 || partNumber > maxPartNumber {
+		req.SendError(s3.ErrInvalidArgument)
+		return
+	}
+	if r.ContentLength < 0 {
+		req.SendError(s3.ErrMissingContentLength)
+		return
+	}
+	if r.ContentLength > maxObjectSize {
+		req.SendError(s3.ErrEntityTooLarge)
+		return
+	}
+
+	vars := mux.Vars(r)
+	bucket, key, uploadID := vars["bucket"], vars["object"], q.Get("uploadId")
+	if h.ownedBucket(req, bucket) == nil {
+		return
+	}
+
+	// The part is encrypted as the upload is, which is given when the
+	// upload is initiated.
+	enc, code := h.uploadEncryption(r, bucket, key, uploadID)
+	if code != s3.ErrNone {
+		req.SendError(code)
+		return
+	}
+
+	body, code := s3.NewDigestReader(r.Body, r.Header)
+	if code != s3.ErrNone {
+		req.SendError(code)
+		return
+	}
+
+	loc, err := h.writeObject(bucket, body, r.ContentLength, enc)
+	if err != nil {
+		ctxLogger.Error(err)
+		req.SendError(bodyErrCode(r.Body))
+		return
+	}
+	if code := finishBody(r.Body); code != s3.ErrNone {
+		h.rollbackObjectData(bucket, loc)
+		req.SendError(code)
+		return
+	}
+	if code := body.Verify(); code != s3.ErrNone {
+		h.rollbackObjectData(bucket, loc)
+		req.SendError(code)
+		return
+	}
+
+	if code := h.recordPart(bucket, key, uploadID, partNumber, loc); code != s3.ErrNone {
+		req.SendError(code)
+		return
+	}
+
+	w.Header().Set("ETag", quoteETag(loc.etag))
+	setSSEHeaders(w, enc)
+	req.SendSuccess()
+}
+
+// recordPart records the written data as the part of the multipart upload
+// in the mds. The data is rolled back if it is not recorded, and the data
+// of the replaced part is deleted.
+func (h *handlers) recordPart(bucket, key, uploadID string, partNumber int, loc *objectLocation) s3.ErrorCode {
+	ctxLogger := mlog.GetMethodLogger(logger, "handlers.recordPart")
+
+	res := &nilrpc.MOBPutPartResponse{}
+	if err := h.callMds(nilrpc.MdsObjectPutPart, &nilrpc.MOBPutPartRequest{
+		Name:          key,
+		Bucket:        bucket,
+		UploadID:      uploadID,
+		PartNumber:    partNumber,
+		EncodingGroup: loc.encGrp,
+		Volume:        loc.vol,
+		DsID:          loc.ds,
+		ObjectID:      loc.oid,
+		Size:          loc.size,
+		ETag:          loc.etag,
+	}, res); err != nil {
+		ctxLogger.Error(err)
+		h.rollbackObjectData(bucket, loc)
+		return s3.ErrInternalError
+	}
+	if res.S3ErrCode != s3.ErrNone {
+		h.rollbackObjectData(bucket, loc)
+		return res.S3ErrCode
+	}
+	h.deleteParts(bucket, res.Obsolete)
+
+	return s3.ErrNone
+}
+
+// CompleteMultipartUploadHandler handles the client request for completing
+// the multipart upload by assembling the uploaded parts.
+func (h *handlers) CompleteMultipartUploadHandler(w http.ResponseWriter, r *http.Request) {
+	ctxLogger := mlog.GetMethodLogger(logger, "handlers.CompleteMultipartUploadHandler")
+
+	req := h.authenticate(w, r)
+	if req == nil {
+		return
+	}
+
+	vars := mux.Vars(r)
+	bucket, key := vars["bucket"], vars["object"]
+	if h.ownedBucket(req, bucket) == nil {
+		return
+	}
+
+	body := s3.CompleteMultipartUpload{}
+	if err := xml.NewDecoder(io.LimitReader(r.Body, maxCompleteBodySize)).Decode(&body); err != nil {
+		req.SendError(s3.ErrMalformedXML)
+		return
+	}
+
+	creq := &nilrpc.MOBCompleteUploadRequest{
+		Name:     key,
+		Bucket:   bucket,
+		UploadID: r.URL.Query().Get("uploadId"),
+		Parts:    make([]nilrpc.MOBCompletePart, len(body.Parts)),
+	}
+	for i, p := range body.Parts {
+		creq.Parts[i] = nilrpc.MOBCompletePart{
+			PartNumber: p.PartNumber,
+			ETag:       strings.Trim(p.ETag, "\""),
+		}
+	}
+
+	res := &nilrpc.MOBCompleteUploadResponse{}
+	if err := h.callMds(nilrpc.MdsObjectCompleteUpload, creq, res); err != nil {
+		ctxLogger.Error(err)
+		req.SendInternalError()
+		return
+	}
+	if res.S3ErrCode != s3.ErrNone {
+		req.SendError(res.S3ErrCode)
+		return
+	}
+	h.deleteParts(bucket, res.Obsolete)
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+
+	setVersionHeaders(w, res.VersionID, false)
+	setSSEHeaders(w, &objectEncryption{meta: res.Encryption})
+	s3.SendResponse(w, s3.CompleteMultipartUploadResult{
+		Location: scheme + "://" + r.Host + "/" + bucket + "/" + key,
+		Bucket:   bucket,
+		Key:      key,
+		ETag:     quoteETag(res.ETag),
+	})
+}
+
+// AbortMultipartUploadHandler handles the client request for aborting
+// the multipart upload. The data of the uploaded parts are deleted.
+func (h *handlers) AbortMultipartUploadHandler(w http.ResponseWriter, r *http.Request) {
+	ctxLogger := mlog.GetMethodLogger(logger, "handlers.AbortMultipartUploadHandler")
+
+	req := h.authenticate(w, r)
+	if req == nil {
+		return
+	}
+
+	vars := mux.Vars(r)
+	bucket := vars["bucket"]
+	if h.ownedBucket(req, bucket) == nil {
+		return
+	}
+
+	res := &nilrpc.MOBAbortUploadResponse{}
+	if err := h.callMds(nilrpc.MdsObjectAbortUpload, &nilrpc.MOBAbortUploadRequest{
+		Name:     vars["object"],
+		Bucket:   bucket,
+		UploadID: r.URL.Query().Get("uploadId"),
+	}, res); err != nil {
+		ctxLogger.Error(err)
+		req.SendInternalError()
+		return
+	}
+	if res.S3ErrCode != s3.ErrNone {
+		req.SendError(res.S3ErrCode)
+		return
+	}
+	h.deleteParts(bucket, res.Obsolete)
+
+	s3.SendNoContent(w)
+}
+
+// ListPartsHandler handles the client request for listing the uploaded
+// parts of the multipart upload.
+func (h *handlers) ListPartsHandler(w http.ResponseWriter, r *http.Request) {
+	ctxLogger := mlog.GetMethodLogger(logger, "handlers.ListPartsHandler")
+
+	req := h.authenticate(w, r)
+	if req == nil {
+		return
+	}
+
+	q := r.URL.Query()
+	marker, ok := queryInt(q, "part-number-marker", maxPartNumber)
+	if !ok {
+		req.SendError(s3.ErrInvalidArgument)
+		return
+	}
+	maxParts, ok := queryInt(q, "max-parts", defaultMaxKeys)
+	if !ok {
+		req.SendError(s3.ErrInvalidArgument)
+		return
+	}
+
+	vars := mux.Vars(r)
+	bucket, key := vars["bucket"], vars["object"]
+	if h.ownedBucket(req, bucket) == nil {
+		return
+	}
+	uploadID := q.Get("uploadId")
+
+	res := &nilrpc.MOBListPartsResponse{}
+	if err := h.callMds(nilrpc.MdsObjectListParts, &nilrpc.MOBListPartsRequest{
+		Name:     key,
+		Bucket:   bucket,
+		UploadID: uploadID,
+		Marker:   marker,
+		MaxParts: maxParts,
+	}, res); err != nil {
+		ctxLogger.Error(err)
+		req.SendInternalError()
+		return
+	}
+	if res.S3ErrCode != s3.ErrNone {
+		req.SendError(res.S3ErrCode)
+		return
+	}
+
+	owner := s3.Owner{ID: req.AccessKey(), DisplayName: req.AccessKey()}
+	result := s3.ListPartsResult{
+		Bucket:               bucket,
+		Key:                  key,
+		UploadId:             uploadID,
+		Initiator:            owner,
+		Owner:                owner,
+		StorageClass:         "STANDARD",
+		PartNumberMarker:     marker,
+		NextPartNumberMarker: res.NextMarker,
+		MaxParts:             maxParts,
+		IsTruncated:          res.IsTruncated,
+		Parts:                make([]s3.Part, len(res.Parts)),
+	}
+	for i, p := range res.Parts {
+		result.Parts[i] = s3.Part{
+			PartNumber:   p.PartNumber,
+			LastModified: s3.FormatTime(p.LastModified),
+			ETag:         quoteETag(p.ETag),
+			Size:         p.Size,
+		}
+	}
+
+	s3.SendResponse(w, result)
+}
+
+// ListMultipartUploadsHandler handles the client request for listing the
+// multipart uploads in progress in the bucket.
+func (h *handlers) ListMultipartUploadsHandler(w http.ResponseWriter, r *http.Request) {
+	ctxLogger := mlog.GetMethodLogger(logger, "handlers.ListMultipartUploadsHandler")
+
+	req := h.authenticate(w, r)
+	if req == nil {
+		return
+	}
+
+	q := r.URL.Query()
+	maxUploads, ok := queryInt(q, "max-uploads", defaultMaxKeys)
+	if !ok {
+		req.SendError(s3.ErrInvalidArgument)
+		return
+	}
+	encodingType := q.Get("encoding-type")
+	encode, ok := keyEncoder(encodingType)
+	if !ok {
+		req.SendError(s3.ErrInvalidArgument)
+		return
+	}
+
+	bucket := mux.Vars(r)["bucket"]
+	b := h.readableBucket(req, bucket)
+	if b == nil {
+		return
+	}
+
+	lreq := &nilrpc.MOBListUploadsRequest{
+		Bucket:         bucket,
+		Prefix:         q.Get("prefix"),
+		Delimiter:      q.Get("delimiter"),
+		KeyMarker:      q.Get("key-marker"),
+		UploadIDMarker: q.Get("upload-id-marker"),
+		MaxUploads:     maxUploads,
+	}
+	lres := &nilrpc.MOBListUploadsResponse{}
+	if err := h.callMds(nilrpc.MdsObjectListUploads, lreq, lres); err != nil {
+		ctxLogger.Error(err)
+		req.SendInternalError()
+		return
+	}
+	if lres.S3ErrCode != s3.ErrNone {
+		req.SendError(lres.S3ErrCode)
+		return
+	}
+
+	result := s3.ListMultipartUploadsResult{
+		Bucket:         bucket,
+		KeyMarker:      encode(lreq.KeyMarker),
+		UploadIdMarker: lreq.UploadIDMarker,
+		Prefix:         encode(lreq.Prefix),
+		Delimiter:      encode(lreq.Delimiter),
+		EncodingType:   encodingType,
+		MaxUploads:     maxUploads,
+		IsTruncated:    lres.IsTruncated,
+		Uploads:        make([]s3.Upload, len(lres.Uploads)),
+		CommonPrefixes: make([]s3.CommonPrefix, len(lres.CommonPrefixes)),
+	}
+	if lres.IsTruncated {
+		result.NextKeyMarker = encode(lres.NextKeyMarker)
+		result.NextUploadIdMarker = lres.NextUploadIDMarker
+	}
+
+	owner := s3.Owner{ID: b.Owner, DisplayName: b.Owner}
+	for i, u := range lres.Uploads {
+		result.Uploads[i] = s3.Upload{
+			Key:          encode(u.Name),
+			UploadId:     u.UploadID,
+			Initiator:    owner,
+			Owner:        owner,
+			StorageClass: "STANDARD",
+			Initiated:    s3.FormatTime(u.Initiated),
+		}
+	}
+	for i, p := range lres.CommonPrefixes {
+		result.CommonPrefixes[i] = s3.CommonPrefix{Prefix: encode(p)}
+	}
+
+	s3.SendResponse(w, result)
+}
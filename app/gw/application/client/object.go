@@ -1,37 +1,853 @@
 package client
 
 import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime/multipart"
 	"net/http"
+	"net/textproto"
+	"strconv"
+	"sync/atomic"
+	"time"
 
 	"github.com/chanyoung/nil/pkg/client"
+	"github.com/chanyoung/nil/pkg/client/request"
+	"github.com/chanyoung/nil/pkg/cmap"
+	"github.com/chanyoung/nil/pkg/nilrpc"
+	"github.com/chanyoung/nil/pkg/s3"
+	"github.com/chanyoung/nil/pkg/util/mlog"
+	"github.com/chanyoung/nil/pkg/util/uuid"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
 )
 
+const (
+	// maxKeyLength is the maximum length of the object key in bytes.
+	maxKeyLength = 1024
+	// maxObjectSize is the maximum size of the object which can be
+	// uploaded in a single put operation.
+	maxObjectSize = 5 * 1024 * 1024 * 1024
+
+	// defaultContentType is used when the client does not specify it.
+	defaultContentType = "binary/octet-stream"
+
+	// rollbackTries is the number of the tries to rollback the object data
+	// which is kept since the ds could not look up its lock.
+	rollbackTries = 3
+	// rollbackBackoff is the wait before retrying the rollback, which
+	// grows by each try.
+	rollbackBackoff = 100 * time.Millisecond
+)
+
+// objectLocation is the information where the object data is written.
+type objectLocation struct {
+	// encGrp is the encoding group of the data, which is left unset until
+	// the encoding groups have their members in the cluster map.
+	encGrp cmap.ID
+	vol    cmap.ID
+	ds     cmap.ID
+	oid    string
+	size   int64
+	etag   string
+}
+
+// objectMeta is the attributes of the object given by the client, which
+// are recorded with the object.
+type objectMeta struct {
+	contentType string
+	headers     map[string]string
+	metadata    map[string]string
+	acl         string
+	tags        map[string]string
+	lock        s3.ObjectLock
+}
+
+// objectAttrs is the attributes of the object which are sent to the client
+// in the response headers.
+type objectAttrs struct {
+	etag         string
+	lastModified time.Time
+	objectMeta
+}
+
 // PutObjectHandler handles the client request for creating an object.
 func (h *handlers) PutObjectHandler(w http.ResponseWriter, r *http.Request) {
-	_, err := h.requestEventFactory.CreateRequestEvent(w, r)
-	if err == client.ErrInvalidProtocol {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	ctxLogger := mlog.GetMethodLogger(logger, "handlers.PutObjectHandler")
+
+	req := h.authenticate(w, r)
+	if req == nil {
+		return
+	}
+
+	vars := mux.Vars(r)
+	bucket, key := vars["bucket"], vars["object"]
+	if len(key) > maxKeyLength {
+		req.SendError(s3.ErrKeyTooLongError)
+		return
+	}
+	if r.ContentLength < 0 {
+		req.SendError(s3.ErrMissingContentLength)
+		return
+	}
+	if r.ContentLength > maxObjectSize {
+		req.SendError(s3.ErrEntityTooLarge)
+		return
+	}
+	b := h.ownedBucket(req, bucket)
+	if b == nil {
+		return
 	}
 
-	http.Error(w, "not implemented", http.StatusNotImplemented)
+	meta, code := requestMeta(r.Header)
+	if code != s3.ErrNone {
+		req.SendError(code)
+		return
+	}
+	if meta.lock, code = requestLock(r.Header, b); code != s3.ErrNone {
+		req.SendError(code)
+		return
+	}
+
+	// Only the wildcard is supported for the conditional put, which
+	// creates the object only if it does not exist.
+	createOnly := false
+	switch r.Header.Get("If-None-Match") {
+	case "":
+	case "*":
+		createOnly = true
+	default:
+		req.SendError(s3.ErrNotImplemented)
+		return
+	}
+
+	enc, code := h.requestEncryption(r)
+	if code != s3.ErrNone {
+		req.SendError(code)
+		return
+	}
+
+	body, code := s3.NewDigestReader(r.Body, r.Header)
+	if code != s3.ErrNone {
+		req.SendError(code)
+		return
+	}
+
+	loc, err := h.writeObject(bucket, body, r.ContentLength, enc)
+	if err != nil {
+		ctxLogger.Error(err)
+		req.SendError(bodyErrCode(r.Body))
+		return
+	}
+	if code := finishBody(r.Body); code != s3.ErrNone {
+		h.rollbackObjectData(bucket, loc)
+		req.SendError(code)
+		return
+	}
+	if code := body.Verify(); code != s3.ErrNone {
+		h.rollbackObjectData(bucket, loc)
+		req.SendError(code)
+		return
+	}
+
+	versionID, code := h.recordObject(bucket, key, loc, meta, enc, createOnly)
+	if code != s3.ErrNone {
+		req.SendError(code)
+		return
+	}
+
+	w.Header().Set("ETag", quoteETag(loc.etag))
+	setVersionHeaders(w, versionID, false)
+	setSSEHeaders(w, enc)
+	req.SendSuccess()
+}
+
+// recordObject records the written object data as the object in the mds
+// and returns the version id of the object. The data is rolled back if it
+// is not recorded, and the data of the replaced object is deleted. If
+// createOnly is true, the object is not recorded when it already exists.
+func (h *handlers) recordObject(bucket, key string, loc *objectLocation, meta objectMeta, enc *objectEncryption, createOnly bool) (string, s3.ErrorCode) {
+	ctxLogger := mlog.GetMethodLogger(logger, "handlers.recordObject")
+
+	res := &nilrpc.MOBObjectPutResponse{}
+	if err := h.callMds(nilrpc.MdsObjectPut, &nilrpc.MOBObjectPutRequest{
+		Name:          key,
+		Bucket:        bucket,
+		EncodingGroup: loc.encGrp,
+		Volume:        loc.vol,
+		DsID:          loc.ds,
+		ObjectID:      loc.oid,
+		Size:          loc.size,
+		ETag:          loc.etag,
+		ContentType:   meta.contentType,
+		Headers:       meta.headers,
+		Metadata:      meta.metadata,
+		ACL:           meta.acl,
+		Encryption:    enc.meta,
+		Tags:          meta.tags,
+		Lock:          meta.lock,
+		CreateOnly:    createOnly,
+	}, res); err != nil {
+		ctxLogger.Error(err)
+		h.rollbackObjectData(bucket, loc)
+		return "", s3.ErrInternalError
+	}
+	if res.S3ErrCode != s3.ErrNone {
+		h.rollbackObjectData(bucket, loc)
+		return "", res.S3ErrCode
+	}
+	h.deleteParts(bucket, res.Obsolete)
+
+	return res.VersionID, s3.ErrNone
+}
+
+// setVersionHeaders sets the response headers of the version of the object.
+// The null version is not exposed to the client.
+func setVersionHeaders(w http.ResponseWriter, versionID string, deleteMarker bool) {
+	if versionID != "" && versionID != s3.NullVersion {
+		w.Header().Set("X-Amz-Version-Id", versionID)
+	}
+	if deleteMarker {
+		w.Header().Set("X-Amz-Delete-Marker", "true")
+	}
+}
+
+// writeObject streams the object data to the one of the alive ds. The data
+// is encrypted with the key derived from the data key of the encryption
+// and the id of the data.
+func (h *handlers) writeObject(bucket string, body io.Reader, size int64, enc *objectEncryption) (*objectLocation, error) {
+	oid := uuid.Gen()
+	if enc.key == nil {
+		return h.writeData(client.WriteToPrimary, bucket, oid, body, size, size)
+	}
+
+	// The etag of the sse-s3 object is the md5 of the plaintext as the
+	// object not encrypted. The etag of the sse-c object is not, since it
+	// would tell the digest of the data to whom does not have the key.
+	digest := md5.New()
+	if enc.meta.Type == s3.SSES3 {
+		body = io.TeeReader(body, digest)
+	}
+
+	encrypted, err := s3.NewEncryptReader(body, s3.SSEPartKey(enc.key, oid))
+	if err != nil {
+		return nil, err
+	}
+	loc, err := h.writeData(client.WriteToPrimary, bucket, oid, encrypted, s3.EncryptedSize(size), size)
+	if err != nil {
+		return nil, err
+	}
+
+	if enc.meta.Type == s3.SSES3 {
+		loc.etag = hex.EncodeToString(digest.Sum(nil))
+	}
+	return loc, nil
+}
+
+// writeData sends the request of the given type which writes the object
+// data of the size as the data with the id into a primary ds. The primary
+// ds selects the volume and replies it.
+func (h *handlers) writeData(reqType client.RequestType, bucket, oid string, body io.Reader, contentLength, size int64) (*objectLocation, error) {
+	// The members of the encoding groups are not in the cluster map yet,
+	// so the data is not written into any encoding group and any alive ds
+	// can be the primary of it.
+	ds, err := h.cmapAPI.SearchCall().Node().Type(cmap.DS).Status(cmap.NodeAlive).Random().Do()
+	if err != nil {
+		return nil, errors.Wrap(err, "find alive ds failed")
+	}
+
+	loc := &objectLocation{
+		ds:   ds.ID,
+		oid:  oid,
+		size: size,
+	}
+
+	headers := client.NewHeaders()
+	headers.SetLocalChainID(loc.encGrp.String())
+
+	if contentLength == 0 {
+		body = http.NoBody
+	}
+
+	resp, err := h.sendDs(
+		reqType, http.MethodPut,
+		dsObjectURL(ds, bucket, loc.oid),
+		body, headers, contentLength,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to send ds request")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ds returns http status code: %d", resp.StatusCode)
+	}
+
+	vol, err := strconv.ParseInt(resp.Header.Get("Volume-Id"), 10, 64)
+	if err != nil {
+		return nil, errors.Wrap(err, "ds returns invalid volume id")
+	}
+	loc.vol = cmap.ID(vol)
+	loc.etag = resp.Header.Get("ETag")
+
+	return loc, nil
+}
+
+// bodyErrCode returns the s3 error code of the failure while writing the
+// request body. Failures which are not caused by the body of the client are
+// the internal error.
+func bodyErrCode(body io.Reader) s3.ErrorCode {
+	cr, ok := body.(*s3.ChunkedReader)
+	if !ok {
+		return s3.ErrInternalError
+	}
+
+	switch cr.Err() {
+	case s3.ErrChunkSignatureMismatch:
+		return s3.ErrSignatureDoesNotMatch
+	case s3.ErrMalformedChunk, io.ErrUnexpectedEOF:
+		return s3.ErrIncompleteBody
+	default:
+		return s3.ErrInternalError
+	}
+}
+
+// finishBody checks the rest of the request body after the object data is
+// read. The aws-chunked body must end with its signed final chunk.
+func finishBody(body io.Reader) s3.ErrorCode {
+	cr, ok := body.(*s3.ChunkedReader)
+	if !ok || cr.Finish() == nil {
+		return s3.ErrNone
+	}
+	return bodyErrCode(body)
+}
+
+// rollbackObjectData deletes the written object data which could not
+// be recorded in the mds. The ds keeps the data if it could not look up
+// the lock of the data, so the rollback is retried. The data still kept
+// after the tries is orphaned and counted apart from the other failures.
+func (h *handlers) rollbackObjectData(bucket string, loc *objectLocation) {
+	ctxLogger := mlog.GetMethodLogger(logger, "handlers.rollbackObjectData")
+
+	objs := []request.ObjectData{{Ds: loc.ds, Volume: loc.vol, Oid: loc.oid}}
+	for try := 1; len(objs) > 0; try++ {
+		var unknown []request.ObjectData
+		for _, err := range request.DeleteObjectData(h.cmapAPI, h.sendDs, bucket, objs) {
+			if e, ok := err.(*request.LockUnknownError); ok {
+				unknown = append(unknown, e.Objs...)
+				continue
+			}
+			ctxLogger.Error(errors.Wrap(err, "failed to rollback object data"))
+		}
+
+		objs = unknown
+		if len(objs) == 0 || try == rollbackTries {
+			break
+		}
+		time.Sleep(time.Duration(try) * rollbackBackoff)
+	}
+
+	for _, o := range objs {
+		n := atomic.AddInt64(&h.orphans, 1)
+		ctxLogger.Errorf("orphaned object data of unknown lock: bucket %s, ds %s, volume %s, oid %s, %d orphaned in total",
+			bucket, o.Ds.String(), o.Volume.String(), o.Oid, n)
+	}
+}
+
+// dsObjectURL returns the url of the object data stored in the given ds.
+func dsObjectURL(ds cmap.Node, bucket, oid string) string {
+	return "https://" + ds.Addr.String() + "/" + bucket + "/" + oid
+}
+
+// quoteETag returns the etag in the quoted form of the http header.
+func quoteETag(etag string) string {
+	return "\"" + etag + "\""
 }
 
 // GetObjectHandler handles the client request for getting an object.
 func (h *handlers) GetObjectHandler(w http.ResponseWriter, r *http.Request) {
-	_, err := h.requestEventFactory.CreateRequestEvent(w, r)
-	if err == client.ErrInvalidProtocol {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	ctxLogger := mlog.GetMethodLogger(logger, "handlers.GetObjectHandler")
+
+	req := h.authenticate(w, r)
+	if req == nil {
+		return
+	}
+
+	vars := mux.Vars(r)
+	bucket := vars["bucket"]
+	b, code := h.requestBucket(req, bucket)
+	if code != s3.ErrNone {
+		req.SendError(code)
+		return
+	}
+
+	obj, err := h.getObjectLocation(bucket, vars["object"], r.URL.Query().Get("versionId"))
+	if err != nil {
+		ctxLogger.Error(err)
+		req.SendInternalError()
+		return
+	}
+	if !objectReadable(req, b, vars["object"], obj.S3ErrCode, obj.ACL, obj.Tags) {
+		req.SendError(s3.ErrAccessDenied)
+		return
+	}
+	setVersionHeaders(w, obj.VersionID, obj.DeleteMarker)
+	if obj.S3ErrCode != s3.ErrNone {
+		req.SendError(obj.S3ErrCode)
+		return
+	}
+
+	enc, code := h.objectKey(r, obj.Encryption, s3.SSEObjectPrefix)
+	if code != s3.ErrNone {
+		req.SendError(code)
+		return
+	}
+	setSSEHeaders(w, enc)
+
+	attrs := objectAttrs{
+		etag:         obj.ETag,
+		lastModified: obj.LastModified,
+		objectMeta: objectMeta{
+			contentType: obj.ContentType,
+			headers:     obj.Headers,
+			metadata:    obj.Metadata,
+			tags:        obj.Tags,
+			lock:        obj.Lock,
+		},
+	}
+	if !checkConditions(w, r, req, attrs) {
+		return
+	}
+
+	ranges, s3err := s3.ParseRange(r.Header.Get("Range"), obj.Size)
+	if s3err != s3.ErrNone {
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", obj.Size))
+		req.SendError(s3err)
+		return
+	}
+
+	if len(ranges) > 1 {
+		setObjectHeaders(w, attrs)
+		if err := h.writeObjectRanges(w, bucket, obj, ranges, enc.key); err != nil {
+			// The response header is already sent, only logging is possible.
+			ctxLogger.Error(err)
+		}
+		return
 	}
 
-	http.Error(w, "not implemented", http.StatusNotImplemented)
+	offset, length, status := int64(0), obj.Size, http.StatusOK
+	if len(ranges) == 1 {
+		offset, length, status = ranges[0].Start, ranges[0].Length, http.StatusPartialContent
+		w.Header().Set("Content-Range", ranges[0].ContentRange(obj.Size))
+	}
+
+	body, err := h.openObject(bucket, obj.Parts, offset, length, enc.key)
+	if err != nil {
+		ctxLogger.Error(err)
+		w.Header().Del("Content-Range")
+		req.SendInternalError()
+		return
+	}
+	defer body.Close()
+
+	setObjectHeaders(w, attrs)
+	w.Header().Set("Content-Length", strconv.FormatInt(length, 10))
+	w.WriteHeader(status)
+
+	if _, err := io.CopyN(w, body, length); err != nil {
+		ctxLogger.Error(errors.Wrap(err, "failed to copy object data from the ds"))
+	}
+}
+
+// writeObjectRanges writes the multiple ranges of the object in the
+// multipart/byteranges format.
+func (h *handlers) writeObjectRanges(w http.ResponseWriter, bucket string, obj *nilrpc.MOBObjectGetResponse, ranges []s3.Range, key []byte) error {
+	mw := multipart.NewWriter(w)
+	w.Header().Set("Content-Type", "multipart/byteranges; boundary="+mw.Boundary())
+	w.WriteHeader(http.StatusPartialContent)
+
+	for _, rg := range ranges {
+		part, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":  {contentTypeOf(obj.ContentType)},
+			"Content-Range": {rg.ContentRange(obj.Size)},
+		})
+		if err != nil {
+			return err
+		}
+
+		body, err := h.openObject(bucket, obj.Parts, rg.Start, rg.Length, key)
+		if err != nil {
+			return err
+		}
+		_, err = io.CopyN(part, body, rg.Length)
+		body.Close()
+		if err != nil {
+			return errors.Wrap(err, "failed to copy object data from the ds")
+		}
+	}
+
+	return mw.Close()
+}
+
+// openObject opens a stream which reads the given range of the object
+// from the ds. The object data is the concatenation of the parts, and each
+// part is read in turn. The first part is opened before returning, so the
+// caller can send the error response if the object is not readable. The
+// key is the data key of the encrypted object, which is nil otherwise.
+func (h *handlers) openObject(bucket string, parts []nilrpc.MOBObjectPart, offset, length int64, key []byte) (io.ReadCloser, error) {
+	if length == 0 {
+		return http.NoBody, nil
+	}
+
+	r := &partsReader{h: h, bucket: bucket, key: key, segs: partSegments(parts, offset, length)}
+	if err := r.next(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// partSegments returns the segments of the parts which make up the given
+// range of the object.
+func partSegments(parts []nilrpc.MOBObjectPart, offset, length int64) []partSegment {
+	segs := make([]partSegment, 0)
+	for _, p := range parts {
+		start, end := p.Offset, p.Offset+p.Size
+		if end <= offset || start >= offset+length {
+			continue
+		}
+		if start < offset {
+			start = offset
+		}
+		if end > offset+length {
+			end = offset + length
+		}
+		segs = append(segs, partSegment{
+			part:   p,
+			offset: start - p.Offset,
+			length: end - start,
+		})
+	}
+	return segs
+}
+
+// partSegment is the range of the part to be read.
+type partSegment struct {
+	part   nilrpc.MOBObjectPart
+	offset int64
+	length int64
+}
+
+// partsReader reads the segments of the parts in order.
+type partsReader struct {
+	h      *handlers
+	bucket string
+	key    []byte
+	segs   []partSegment
+	cur    io.ReadCloser
+}
+
+// next opens the next segment.
+func (r *partsReader) next() error {
+	if len(r.segs) == 0 {
+		return io.EOF
+	}
+
+	seg := r.segs[0]
+	body, err := r.h.openPart(r.bucket, seg.part, seg.offset, seg.length, r.key)
+	if err != nil {
+		return err
+	}
+
+	r.cur, r.segs = body, r.segs[1:]
+	return nil
+}
+
+func (r *partsReader) Read(p []byte) (int, error) {
+	for {
+		if r.cur == nil {
+			if err := r.next(); err != nil {
+				return 0, err
+			}
+		}
+
+		n, err := r.cur.Read(p)
+		if err == io.EOF {
+			r.cur.Close()
+			r.cur = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
+	}
+}
+
+func (r *partsReader) Close() error {
+	if r.cur == nil {
+		return nil
+	}
+	return r.cur.Close()
+}
+
+// openPart opens a stream which reads the given range of the part. The
+// encrypted part is read in the segments which cover the range, and is
+// decrypted with the key derived from the data key of the object.
+func (h *handlers) openPart(bucket string, p nilrpc.MOBObjectPart, offset, length int64, key []byte) (io.ReadCloser, error) {
+	if key == nil {
+		return h.openData(bucket, p, offset, length)
+	}
+
+	start, n := s3.EncryptedRange(offset, length, p.Size)
+	body, err := h.openData(bucket, p, start, n)
+	if err != nil {
+		return nil, err
+	}
+
+	decrypted, err := s3.NewDecryptReader(body, s3.SSEPartKey(key, p.ObjectID), offset)
+	if err != nil {
+		body.Close()
+		return nil, err
+	}
+
+	// The last segment can have more than the range.
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(decrypted, length), body}, nil
+}
+
+// openData opens a stream which reads the given range of the stored data
+// of the part.
+func (h *handlers) openData(bucket string, p nilrpc.MOBObjectPart, offset, length int64) (io.ReadCloser, error) {
+	ds, err := h.cmapAPI.SearchCall().Node().ID(p.DsID).Do()
+	if err != nil {
+		return nil, errors.Wrapf(err, "find ds failed: %s", p.DsID.String())
+	}
+
+	headers := client.NewHeaders()
+	headers.SetLocalChainID(p.EncodingGroupID.String())
+	headers.SetVolumeID(p.VolumeID.String())
+	headers.SetRange(offset, length)
+
+	resp, err := h.sendDs(
+		client.ReadFromPrimary, http.MethodGet,
+		dsObjectURL(ds, bucket, p.ObjectID),
+		nil, headers, 0,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to send ds request")
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("ds returns http status code: %d", resp.StatusCode)
+	}
+
+	return resp.Body, nil
+}
+
+// HeadObjectHandler handles the client request for getting the attributes
+// of an object without the object data.
+func (h *handlers) HeadObjectHandler(w http.ResponseWriter, r *http.Request) {
+	ctxLogger := mlog.GetMethodLogger(logger, "handlers.HeadObjectHandler")
+
+	req := h.authenticate(w, r)
+	if req == nil {
+		return
+	}
+
+	vars := mux.Vars(r)
+	b, code := h.requestBucket(req, vars["bucket"])
+	if code != s3.ErrNone {
+		req.SendError(code)
+		return
+	}
+
+	res := &nilrpc.MOBObjectHeadResponse{}
+	if err := h.callMds(nilrpc.MdsObjectHead, &nilrpc.MOBObjectHeadRequest{
+		Name:      vars["object"],
+		Bucket:    vars["bucket"],
+		VersionID: r.URL.Query().Get("versionId"),
+	}, res); err != nil {
+		ctxLogger.Error(err)
+		req.SendInternalError()
+		return
+	}
+	if !objectReadable(req, b, vars["object"], res.S3ErrCode, res.ACL, res.Tags) {
+		req.SendError(s3.ErrAccessDenied)
+		return
+	}
+	setVersionHeaders(w, res.VersionID, res.DeleteMarker)
+	if res.S3ErrCode != s3.ErrNone {
+		req.SendError(res.S3ErrCode)
+		return
+	}
+
+	// The key of the sse-c object is required even though the data is
+	// not read.
+	enc, code := h.objectKey(r, res.Encryption, s3.SSEObjectPrefix)
+	if code != s3.ErrNone {
+		req.SendError(code)
+		return
+	}
+	setSSEHeaders(w, enc)
+
+	attrs := objectAttrs{
+		etag:         res.ETag,
+		lastModified: res.LastModified,
+		objectMeta: objectMeta{
+			contentType: res.ContentType,
+			headers:     res.Headers,
+			metadata:    res.Metadata,
+			tags:        res.Tags,
+			lock:        res.Lock,
+		},
+	}
+	if !checkConditions(w, r, req, attrs) {
+		return
+	}
+
+	setObjectHeaders(w, attrs)
+	w.Header().Set("Content-Length", strconv.FormatInt(res.Size, 10))
+	w.WriteHeader(http.StatusOK)
+}
+
+// checkConditions evaluates the conditional headers of the read request
+// against the object. If the conditions do not hold, it sends the response
+// and returns false. The not modified response has no body but the
+// validators of the object.
+func checkConditions(w http.ResponseWriter, r *http.Request, req client.RequestEvent, attrs objectAttrs) bool {
+	switch code := s3.CheckPreconditions(r.Header, "", attrs.etag, attrs.lastModified); code {
+	case s3.ErrNone:
+		return true
+	case s3.ErrNotModified:
+		w.Header().Set("ETag", quoteETag(attrs.etag))
+		w.Header().Set("Last-Modified", attrs.lastModified.UTC().Format(http.TimeFormat))
+		s3.SendNotModified(w)
+	default:
+		req.SendError(code)
+	}
+	return false
+}
+
+// setObjectHeaders sets the common response headers of the object.
+func setObjectHeaders(w http.ResponseWriter, attrs objectAttrs) {
+	w.Header().Set("ETag", quoteETag(attrs.etag))
+	w.Header().Set("Last-Modified", attrs.lastModified.UTC().Format(http.TimeFormat))
+	w.Header().Set("Accept-Ranges", "bytes")
+	w.Header().Set("Content-Type", contentTypeOf(attrs.contentType))
+	for k, v := range attrs.headers {
+		w.Header().Set(k, v)
+	}
+	for k, v := range attrs.metadata {
+		w.Header().Set(s3.MetadataPrefix+k, v)
+	}
+	if len(attrs.tags) > 0 {
+		w.Header().Set(s3.TaggingCountHeader, strconv.Itoa(len(attrs.tags)))
+	}
+	s3.SetObjectLockHeaders(w.Header(), attrs.lock)
+}
+
+// requestMeta extracts the attributes of the object to be recorded from
+// the request headers.
+func requestMeta(header http.Header) (objectMeta, s3.ErrorCode) {
+	metadata, code := s3.UserMetadata(header)
+	if code != s3.ErrNone {
+		return objectMeta{}, code
+	}
+	acl, code := requestACL(header)
+	if code != s3.ErrNone {
+		return objectMeta{}, code
+	}
+	tags, code := s3.ParseTaggingHeader(header)
+	if code != s3.ErrNone {
+		return objectMeta{}, code
+	}
+
+	return objectMeta{
+		contentType: header.Get("Content-Type"),
+		headers:     s3.ObjectHeaders(header),
+		metadata:    metadata,
+		acl:         acl,
+		tags:        tags,
+	}, s3.ErrNone
+}
+
+// contentTypeOf returns the content type to be sent to the client.
+func contentTypeOf(contentType string) string {
+	if contentType == "" {
+		return defaultContentType
+	}
+	return contentType
 }
 
 // DeleteObjectHandler handles the client request for deleting an object.
+// Without the version id, the object in the versioned bucket is not
+// deleted but hidden by the delete marker. The version locked by the
+// object lock is not deleted.
 func (h *handlers) DeleteObjectHandler(w http.ResponseWriter, r *http.Request) {
-	_, err := h.requestEventFactory.CreateRequestEvent(w, r)
-	if err == client.ErrInvalidProtocol {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	ctxLogger := mlog.GetMethodLogger(logger, "handlers.DeleteObjectHandler")
+
+	req := h.authenticate(w, r)
+	if req == nil {
+		return
 	}
 
-	http.Error(w, "not implemented", http.StatusNotImplemented)
+	vars := mux.Vars(r)
+	bucket := vars["bucket"]
+	b := h.ownedBucket(req, bucket)
+	if b == nil {
+		return
+	}
+	bypass, code := bypassGovernance(req, b, vars["object"])
+	if code != s3.ErrNone {
+		req.SendError(code)
+		return
+	}
+
+	res := &nilrpc.MOBObjectDeleteResponse{}
+	if err := h.callMds(nilrpc.MdsObjectDelete, &nilrpc.MOBObjectDeleteRequest{
+		Name:             vars["object"],
+		Bucket:           bucket,
+		VersionID:        r.URL.Query().Get("versionId"),
+		BypassGovernance: bypass,
+	}, res); err != nil {
+		ctxLogger.Error(err)
+		req.SendInternalError()
+		return
+	}
+
+	switch res.S3ErrCode {
+	case s3.ErrNone:
+		setVersionHeaders(w, res.VersionID, res.DeleteMarker)
+	case s3.ErrNoSuchKey, s3.ErrNoSuchVersion:
+		// Deleting a not existing object is not an error.
+		s3.SendNoContent(w)
+		return
+	default:
+		req.SendError(res.S3ErrCode)
+		return
+	}
+
+	// The metadata is already deleted and the object is not reachable
+	// anymore. Failing to delete the data only leaves a garbage.
+	h.deleteParts(bucket, res.Parts)
+
+	s3.SendNoContent(w)
+}
+
+// deleteParts deletes the data of the parts which are not reachable
+// anymore. The failure is only logged, since it leaves just a garbage.
+func (h *handlers) deleteParts(bucket string, parts []nilrpc.MOBObjectPart) {
+	ctxLogger := mlog.GetMethodLogger(logger, "handlers.deleteParts")
+
+	objs := make([]request.ObjectData, len(parts))
+	for i, p := range parts {
+		objs[i] = request.ObjectData{Ds: p.DsID, Volume: p.VolumeID, Oid: p.ObjectID}
+	}
+
+	for _, err := range request.DeleteObjectData(h.cmapAPI, h.sendDs, bucket, objs) {
+		ctxLogger.Error(err)
+	}
 }
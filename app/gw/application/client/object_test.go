@@ -0,0 +1,235 @@
+package client
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/chanyoung/nil/pkg/nilrpc"
+	"github.com/chanyoung/nil/pkg/s3"
+)
+
+func TestConditionalPut(t *testing.T) {
+	testCases := []struct {
+		name        string
+		ifNoneMatch string
+		mdsCode     s3.ErrorCode
+		code        s3.ErrorCode
+		recorded    bool
+	}{
+		{"no condition", "", s3.ErrNone, s3.ErrNone, true},
+		{"not existing", "*", s3.ErrNone, s3.ErrNone, true},
+		{"existing", "*", s3.ErrPreconditionFailed, s3.ErrPreconditionFailed, false},
+		{"etag", `"0123"`, s3.ErrNone, s3.ErrNotImplemented, false},
+	}
+
+	for _, c := range testCases {
+		g := newTestGateway(t)
+
+		var put *nilrpc.MOBObjectPutRequest
+		g.mds.methods[nilrpc.MdsObjectPut] = func(req, res interface{}) {
+			put = req.(*nilrpc.MOBObjectPutRequest)
+			res.(*nilrpc.MOBObjectPutResponse).S3ErrCode = c.mdsCode
+		}
+
+		header := map[string]string{}
+		if c.ifNoneMatch != "" {
+			header["If-None-Match"] = c.ifNoneMatch
+		}
+		w := g.do("PUT", "/bucket/doc", "owner", header, []byte("object data"))
+
+		if c.code != s3.ErrNone {
+			expectError(t, c.name, w, c.code)
+		} else if w.Code != http.StatusOK {
+			t.Errorf("%s: expected status 200, got %d: %s", c.name, w.Code, w.Body)
+		}
+
+		// The unsupported condition is rejected before writing the data.
+		if c.code == s3.ErrNotImplemented {
+			if put != nil || len(g.ds.objects) != 0 {
+				t.Errorf("%s: expected the data is not written", c.name)
+			}
+			continue
+		}
+
+		if put == nil {
+			t.Errorf("%s: expected the object is requested to the mds", c.name)
+			continue
+		}
+		if put.CreateOnly != (c.ifNoneMatch == "*") {
+			t.Errorf("%s: expected create only %t, got %t", c.name, c.ifNoneMatch == "*", put.CreateOnly)
+		}
+		// The data of the object which is not recorded is rolled back.
+		if g.ds.has(put.ObjectID) != c.recorded {
+			t.Errorf("%s: expected the data is kept %t", c.name, c.recorded)
+		}
+	}
+}
+
+func TestRollbackUnknownLock(t *testing.T) {
+	testCases := []struct {
+		name         string
+		lockFailures int
+		kept         bool
+	}{
+		{"known lock", 0, false},
+		{"retried", rollbackTries - 1, false},
+		{"orphaned", rollbackTries, true},
+	}
+
+	for _, c := range testCases {
+		g := newTestGateway(t)
+		g.ds.lockFailures = c.lockFailures
+
+		var put *nilrpc.MOBObjectPutRequest
+		g.mds.methods[nilrpc.MdsObjectPut] = func(req, res interface{}) {
+			put = req.(*nilrpc.MOBObjectPutRequest)
+			res.(*nilrpc.MOBObjectPutResponse).S3ErrCode = s3.ErrPreconditionFailed
+		}
+
+		w := g.do("PUT", "/bucket/doc", "owner", map[string]string{"If-None-Match": "*"}, []byte("object data"))
+		expectError(t, c.name, w, s3.ErrPreconditionFailed)
+
+		if put == nil {
+			t.Errorf("%s: expected the object is requested to the mds", c.name)
+			continue
+		}
+		if g.ds.has(put.ObjectID) != c.kept {
+			t.Errorf("%s: expected the data is kept %t", c.name, c.kept)
+		}
+		if orphaned := g.h.orphans == 1; orphaned != c.kept {
+			t.Errorf("%s: expected the data is counted as orphaned %t, got %d orphans", c.name, c.kept, g.h.orphans)
+		}
+	}
+}
+
+// sseHeaders returns the sse-c headers of the customer key.
+func sseHeaders(key []byte) map[string]string {
+	sum := md5.Sum(key)
+	return map[string]string{
+		"X-Amz-Server-Side-Encryption-Customer-Algorithm": s3.SSEAlgorithm,
+		"X-Amz-Server-Side-Encryption-Customer-Key":       base64.StdEncoding.EncodeToString(key),
+		"X-Amz-Server-Side-Encryption-Customer-Key-Md5":   base64.StdEncoding.EncodeToString(sum[:]),
+	}
+}
+
+func TestGetObjectSSERange(t *testing.T) {
+	g := newTestGateway(t)
+
+	// The data spans three encrypted segments.
+	data := make([]byte, 150*1024)
+	key := make([]byte, s3.SSEKeySize)
+	rand.Read(data)
+	rand.Read(key)
+
+	var put *nilrpc.MOBObjectPutRequest
+	g.mds.methods[nilrpc.MdsObjectPut] = func(req, res interface{}) {
+		put = req.(*nilrpc.MOBObjectPutRequest)
+	}
+	if w := g.do("PUT", "/bucket/doc", "owner", sseHeaders(key), data); w.Code != http.StatusOK {
+		t.Fatalf("put: expected status 200, got %d: %s", w.Code, w.Body)
+	}
+
+	stored := g.ds.objects[put.ObjectID]
+	if int64(len(stored)) != s3.EncryptedSize(int64(len(data))) || bytes.Contains(stored, data[:1024]) {
+		t.Fatal("expected the data is stored encrypted")
+	}
+	if put.Encryption.Type != s3.SSEC || put.Size != int64(len(data)) {
+		t.Fatalf("expected the sse-c object of the plaintext size, got %+v", put)
+	}
+	g.mds.methods[nilrpc.MdsObjectGet] = storedObject(put)
+
+	testCases := []struct {
+		rng         string
+		first, last int
+	}{
+		{"bytes=10-99", 10, 99},
+		{"bytes=65000-140000", 65000, 140000},
+		{"bytes=65536-65536", 65536, 65536},
+		{"bytes=-100", len(data) - 100, len(data) - 1},
+		{"bytes=140000-", 140000, len(data) - 1},
+	}
+
+	for _, c := range testCases {
+		header := sseHeaders(key)
+		header["Range"] = c.rng
+		w := g.do("GET", "/bucket/doc", "owner", header, nil)
+
+		if w.Code != http.StatusPartialContent {
+			t.Errorf("%s: expected status 206, got %d: %s", c.rng, w.Code, w.Body)
+			continue
+		}
+		contentRange := fmt.Sprintf("bytes %d-%d/%d", c.first, c.last, len(data))
+		if cr := w.Header().Get("Content-Range"); cr != contentRange {
+			t.Errorf("%s: expected content range %s, got %s", c.rng, contentRange, cr)
+		}
+		if !bytes.Equal(w.Body.Bytes(), data[c.first:c.last+1]) {
+			t.Errorf("%s: read data is different from the range of the object", c.rng)
+		}
+	}
+
+	// The object can not be read without its key.
+	wrongKey := make([]byte, s3.SSEKeySize)
+	rand.Read(wrongKey)
+	header := sseHeaders(wrongKey)
+	header["Range"] = "bytes=0-9"
+	expectError(t, "wrong key", g.do("GET", "/bucket/doc", "owner", header, nil), s3.ErrAccessDenied)
+	expectError(t, "no key", g.do("GET", "/bucket/doc", "owner", map[string]string{"Range": "bytes=0-9"}, nil), s3.ErrInvalidRequest)
+}
+
+func TestDeleteLockedObject(t *testing.T) {
+	bypassPolicy := testPolicy(`{"Effect": "Allow", "Principal": {"AWS": "other"}, "Action": "s3:DeleteObject", "Resource": "arn:aws:s3:::bucket/*"}`)
+
+	testCases := []struct {
+		name   string
+		user   string
+		bypass bool
+		code   s3.ErrorCode
+		// called is true if the deletion is requested to the mds.
+		called bool
+	}{
+		{"locked", "owner", false, s3.ErrAccessDenied, true},
+		{"bypass by owner", "owner", true, s3.ErrNone, true},
+		{"locked by other", "other", false, s3.ErrAccessDenied, true},
+		{"bypass by other", "other", true, s3.ErrAccessDenied, false},
+	}
+
+	for _, c := range testCases {
+		g := newTestGateway(t)
+		g.mds.bucket.Policy = bypassPolicy
+		g.ds.objects["oid"] = []byte("object data")
+
+		// The version is retained in the governance mode.
+		g.mds.methods[nilrpc.MdsObjectDelete] = func(req, res interface{}) {
+			r := res.(*nilrpc.MOBObjectDeleteResponse)
+			if !req.(*nilrpc.MOBObjectDeleteRequest).BypassGovernance {
+				r.S3ErrCode = s3.ErrAccessDenied
+				return
+			}
+			r.VersionID = "v1"
+			r.Parts = []nilrpc.MOBObjectPart{{EncodingGroupID: 1, VolumeID: 1, DsID: 1, ObjectID: "oid", Size: 11}}
+		}
+
+		header := map[string]string{}
+		if c.bypass {
+			header["X-Amz-Bypass-Governance-Retention"] = "true"
+		}
+		w := g.do("DELETE", "/bucket/doc?versionId=v1", c.user, header, nil)
+
+		if c.code != s3.ErrNone {
+			expectError(t, c.name, w, c.code)
+		} else if w.Code != http.StatusNoContent {
+			t.Errorf("%s: expected status 204, got %d: %s", c.name, w.Code, w.Body)
+		}
+		if called := g.mds.calls[nilrpc.MdsObjectDelete] == 1; called != c.called {
+			t.Errorf("%s: expected the mds is called %t, got %t", c.name, c.called, called)
+		}
+		if deleted := !g.ds.has("oid"); deleted != (c.code == s3.ErrNone) {
+			t.Errorf("%s: expected the data is deleted %t, got %t", c.name, c.code == s3.ErrNone, deleted)
+		}
+	}
+}
@@ -0,0 +1,332 @@
+package client
+
+import (
+	"encoding/xml"
+	"net/http"
+	"time"
+
+	"github.com/chanyoung/nil/pkg/client"
+	"github.com/chanyoung/nil/pkg/nilrpc"
+	"github.com/chanyoung/nil/pkg/s3"
+	"github.com/chanyoung/nil/pkg/util/mlog"
+	"github.com/gorilla/mux"
+)
+
+// bypassGovernanceAction is the action which allows the requester to
+// shorten, remove or delete the retention in the governance mode.
+const bypassGovernanceAction = "s3:BypassGovernanceRetention"
+
+// PutObjectLockConfigurationHandler handles the client request for enabling
+// the object lock of the bucket and replacing its default retention. The
+// object lock can be enabled only on the versioned bucket.
+func (h *handlers) PutObjectLockConfigurationHandler(w http.ResponseWriter, r *http.Request) {
+	ctxLogger := mlog.GetMethodLogger(logger, "handlers.PutObjectLockConfigurationHandler")
+
+	req := h.authenticate(w, r)
+	if req == nil {
+		return
+	}
+
+	if h.ownedBucket(req, mux.Vars(r)["bucket"]) == nil {
+		return
+	}
+
+	conf := s3.ObjectLockConfiguration{}
+	if code := readXMLBody(r, s3.MaxObjectLockSize, &conf); code != s3.ErrNone {
+		req.SendError(code)
+		return
+	}
+	if code := conf.Validate(); code != s3.ErrNone {
+		req.SendError(code)
+		return
+	}
+	objectLock, err := marshalObjectLock(conf)
+	if err != nil {
+		req.SendError(s3.ErrMalformedXML)
+		return
+	}
+
+	res := &nilrpc.MACSetBucketObjectLockResponse{}
+	if err := h.callMds(nilrpc.MdsAccountSetBucketObjectLock, &nilrpc.MACSetBucketObjectLockRequest{
+		BucketName: mux.Vars(r)["bucket"],
+		ObjectLock: objectLock,
+	}, res); err != nil {
+		ctxLogger.Error(err)
+		req.SendInternalError()
+		return
+	}
+	if res.S3ErrCode != s3.ErrNone {
+		req.SendError(res.S3ErrCode)
+		return
+	}
+
+	req.SendSuccess()
+}
+
+// GetObjectLockConfigurationHandler handles the client request for getting
+// the object lock configuration of the bucket.
+func (h *handlers) GetObjectLockConfigurationHandler(w http.ResponseWriter, r *http.Request) {
+	ctxLogger := mlog.GetMethodLogger(logger, "handlers.GetObjectLockConfigurationHandler")
+
+	req := h.authenticate(w, r)
+	if req == nil {
+		return
+	}
+
+	b := h.ownedBucket(req, mux.Vars(r)["bucket"])
+	if b == nil {
+		return
+	}
+	if b.ObjectLock == "" {
+		req.SendError(s3.ErrObjectLockConfigurationNotFoundError)
+		return
+	}
+
+	conf, err := bucketObjectLock(b)
+	if err != nil {
+		ctxLogger.Error(err)
+		req.SendInternalError()
+		return
+	}
+	conf.Xmlns = s3.Namespace
+
+	s3.SendResponse(w, conf)
+}
+
+// PutObjectRetentionHandler handles the client request for replacing the
+// retention of the version of the object. The retention in effect can only
+// be extended, unless it is in the governance mode and the requester is
+// allowed to bypass it.
+func (h *handlers) PutObjectRetentionHandler(w http.ResponseWriter, r *http.Request) {
+	ctxLogger := mlog.GetMethodLogger(logger, "handlers.PutObjectRetentionHandler")
+
+	req := h.authenticate(w, r)
+	if req == nil {
+		return
+	}
+
+	retention := s3.ObjectRetention{}
+	if code := readXMLBody(r, s3.MaxObjectLockSize, &retention); code != s3.ErrNone {
+		req.SendError(code)
+		return
+	}
+	mode, until, code := retention.Parse(time.Now())
+	if code != s3.ErrNone {
+		req.SendError(code)
+		return
+	}
+
+	vars := mux.Vars(r)
+	b, obj := h.lockedObject(w, req)
+	if obj == nil {
+		return
+	}
+	bypass, code := bypassGovernance(req, b, vars["object"])
+	if code != s3.ErrNone {
+		req.SendError(code)
+		return
+	}
+
+	res := &nilrpc.MOBSetRetentionResponse{}
+	if err := h.callMds(nilrpc.MdsObjectSetRetention, &nilrpc.MOBSetRetentionRequest{
+		Name:             vars["object"],
+		Bucket:           vars["bucket"],
+		VersionID:        obj.VersionID,
+		Mode:             mode,
+		RetainUntil:      until,
+		BypassGovernance: bypass,
+	}, res); err != nil {
+		ctxLogger.Error(err)
+		req.SendInternalError()
+		return
+	}
+	if res.S3ErrCode != s3.ErrNone {
+		req.SendError(res.S3ErrCode)
+		return
+	}
+
+	req.SendSuccess()
+}
+
+// GetObjectRetentionHandler handles the client request for getting the
+// retention of the version of the object.
+func (h *handlers) GetObjectRetentionHandler(w http.ResponseWriter, r *http.Request) {
+	req := h.authenticate(w, r)
+	if req == nil {
+		return
+	}
+
+	_, obj := h.lockedObject(w, req)
+	if obj == nil {
+		return
+	}
+	if obj.Lock.Mode == "" {
+		req.SendError(s3.ErrNoSuchObjectLockConfiguration)
+		return
+	}
+
+	s3.SendResponse(w, s3.NewObjectRetention(obj.Lock))
+}
+
+// PutObjectLegalHoldHandler handles the client request for turning the
+// legal hold of the version of the object on or off.
+func (h *handlers) PutObjectLegalHoldHandler(w http.ResponseWriter, r *http.Request) {
+	ctxLogger := mlog.GetMethodLogger(logger, "handlers.PutObjectLegalHoldHandler")
+
+	req := h.authenticate(w, r)
+	if req == nil {
+		return
+	}
+
+	hold := s3.LegalHold{}
+	if code := readXMLBody(r, s3.MaxObjectLockSize, &hold); code != s3.ErrNone {
+		req.SendError(code)
+		return
+	}
+	on, code := hold.On()
+	if code != s3.ErrNone {
+		req.SendError(code)
+		return
+	}
+
+	_, obj := h.lockedObject(w, req)
+	if obj == nil {
+		return
+	}
+
+	vars := mux.Vars(r)
+	res := &nilrpc.MOBSetLegalHoldResponse{}
+	if err := h.callMds(nilrpc.MdsObjectSetLegalHold, &nilrpc.MOBSetLegalHoldRequest{
+		Name:      vars["object"],
+		Bucket:    vars["bucket"],
+		VersionID: obj.VersionID,
+		LegalHold: on,
+	}, res); err != nil {
+		ctxLogger.Error(err)
+		req.SendInternalError()
+		return
+	}
+	if res.S3ErrCode != s3.ErrNone {
+		req.SendError(res.S3ErrCode)
+		return
+	}
+
+	req.SendSuccess()
+}
+
+// GetObjectLegalHoldHandler handles the client request for getting the
+// legal hold of the version of the object.
+func (h *handlers) GetObjectLegalHoldHandler(w http.ResponseWriter, r *http.Request) {
+	req := h.authenticate(w, r)
+	if req == nil {
+		return
+	}
+
+	_, obj := h.lockedObject(w, req)
+	if obj == nil {
+		return
+	}
+
+	s3.SendResponse(w, s3.NewLegalHold(obj.Lock.LegalHold))
+}
+
+// lockedObject returns the bucket and the version of the object of the
+// retention or the legal hold request if the requester is allowed the
+// action of the route on it. The bucket must have the object lock enabled.
+// Otherwise it sends the error response and returns nil.
+func (h *handlers) lockedObject(w http.ResponseWriter, req client.RequestEvent) (*nilrpc.MACGetBucketResponse, *nilrpc.MOBObjectHeadResponse) {
+	ctxLogger := mlog.GetMethodLogger(logger, "handlers.lockedObject")
+
+	r := req.Request()
+	vars := mux.Vars(r)
+	b := h.ownedBucket(req, vars["bucket"])
+	if b == nil {
+		return nil, nil
+	}
+	if b.ObjectLock == "" {
+		req.SendError(s3.ErrInvalidRequest)
+		return nil, nil
+	}
+
+	res := &nilrpc.MOBObjectHeadResponse{}
+	if err := h.callMds(nilrpc.MdsObjectHead, &nilrpc.MOBObjectHeadRequest{
+		Name:      vars["object"],
+		Bucket:    vars["bucket"],
+		VersionID: r.URL.Query().Get("versionId"),
+	}, res); err != nil {
+		ctxLogger.Error(err)
+		req.SendInternalError()
+		return nil, nil
+	}
+
+	setVersionHeaders(w, res.VersionID, res.DeleteMarker)
+	if res.S3ErrCode != s3.ErrNone {
+		req.SendError(res.S3ErrCode)
+		return nil, nil
+	}
+
+	return b, res
+}
+
+// requestLock returns the lock of the object to be created in the bucket,
+// which is given by the request headers. The default retention of the
+// bucket applies if the request does not give the retention. The lock
+// can not be given unless the object lock of the bucket is enabled.
+func requestLock(header http.Header, b *nilrpc.MACGetBucketResponse) (s3.ObjectLock, s3.ErrorCode) {
+	ctxLogger := mlog.GetMethodLogger(logger, "requestLock")
+
+	now := time.Now()
+	lock, given, code := s3.ParseObjectLockHeaders(header, now)
+	if code != s3.ErrNone {
+		return s3.ObjectLock{}, code
+	}
+	if b.ObjectLock == "" {
+		if given {
+			return s3.ObjectLock{}, s3.ErrInvalidRequest
+		}
+		return s3.ObjectLock{}, s3.ErrNone
+	}
+
+	if lock.Mode == "" {
+		conf, err := bucketObjectLock(b)
+		if err != nil {
+			ctxLogger.Error(err)
+			return s3.ObjectLock{}, s3.ErrInternalError
+		}
+		d := conf.DefaultLock(now)
+		lock.Mode, lock.RetainUntil = d.Mode, d.RetainUntil
+	}
+
+	return lock, s3.ErrNone
+}
+
+// bypassGovernance returns whether the request bypasses the retention in
+// the governance mode of the object with the key. The requester who asks
+// to bypass it must be allowed to. The compliance mode and the legal hold
+// are never bypassed, which is left to the mds.
+func bypassGovernance(req client.RequestEvent, b *nilrpc.MACGetBucketResponse, key string) (bool, s3.ErrorCode) {
+	if !s3.BypassGovernance(req.Request().Header) {
+		return false, s3.ErrNone
+	}
+	if !authorized(req, b, bypassGovernanceAction, key, b.Owner == req.AccessKey(), nil) {
+		return false, s3.ErrAccessDenied
+	}
+	return true, s3.ErrNone
+}
+
+// bucketObjectLock returns the object lock configuration of the bucket,
+// which is validated before it is stored.
+func bucketObjectLock(b *nilrpc.MACGetBucketResponse) (s3.ObjectLockConfiguration, error) {
+	conf := s3.ObjectLockConfiguration{}
+	err := xml.Unmarshal([]byte(b.ObjectLock), &conf)
+	return conf, err
+}
+
+// marshalObjectLock returns the object lock configuration to be stored,
+// which is stored without the namespace and gets it when it is returned
+// to the client.
+func marshalObjectLock(conf s3.ObjectLockConfiguration) (string, error) {
+	conf.Xmlns = ""
+	objectLock, err := xml.Marshal(conf)
+	return string(objectLock), err
+}
@@ -0,0 +1,217 @@
+package client
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/chanyoung/nil/pkg/client"
+	"github.com/chanyoung/nil/pkg/nilrpc"
+	"github.com/chanyoung/nil/pkg/s3"
+	"github.com/chanyoung/nil/pkg/util/mlog"
+	"github.com/gorilla/mux"
+)
+
+// policyActions is the actions of managing the bucket policy, which the
+// owner is always allowed so as not to be locked out by the policy.
+var policyActions = map[string]bool{
+	"s3:PutBucketPolicy":    true,
+	"s3:GetBucketPolicy":    true,
+	"s3:DeleteBucketPolicy": true,
+}
+
+// PutBucketPolicyHandler handles the client request for replacing the
+// bucket policy.
+func (h *handlers) PutBucketPolicyHandler(w http.ResponseWriter, r *http.Request) {
+	req := h.authenticate(w, r)
+	if req == nil {
+		return
+	}
+
+	b := h.ownedBucket(req, mux.Vars(r)["bucket"])
+	if b == nil {
+		return
+	}
+
+	doc, code := readBody(r, s3.MaxPolicySize)
+	if code == s3.ErrEntityTooLarge {
+		code = s3.ErrMalformedPolicy
+	}
+	if code != s3.ErrNone {
+		req.SendError(code)
+		return
+	}
+	if _, code := s3.ParsePolicy(doc, b.BucketName); code != s3.ErrNone {
+		req.SendError(code)
+		return
+	}
+
+	// The document is stored as given, which is returned to the client.
+	h.setBucketPolicy(w, r, req, string(doc))
+}
+
+// GetBucketPolicyHandler handles the client request for getting the bucket
+// policy.
+func (h *handlers) GetBucketPolicyHandler(w http.ResponseWriter, r *http.Request) {
+	req := h.authenticate(w, r)
+	if req == nil {
+		return
+	}
+
+	b := h.ownedBucket(req, mux.Vars(r)["bucket"])
+	if b == nil {
+		return
+	}
+	if b.Policy == "" {
+		req.SendError(s3.ErrNoSuchBucketPolicy)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(b.Policy))
+}
+
+// DeleteBucketPolicyHandler handles the client request for removing the
+// bucket policy.
+func (h *handlers) DeleteBucketPolicyHandler(w http.ResponseWriter, r *http.Request) {
+	req := h.authenticate(w, r)
+	if req == nil {
+		return
+	}
+
+	if h.ownedBucket(req, mux.Vars(r)["bucket"]) == nil {
+		return
+	}
+
+	h.setBucketPolicy(w, r, req, "")
+}
+
+// setBucketPolicy records the bucket policy in the mds and sends the
+// response. The empty policy removes the policy.
+func (h *handlers) setBucketPolicy(w http.ResponseWriter, r *http.Request, req client.RequestEvent, policy string) {
+	ctxLogger := mlog.GetMethodLogger(logger, "handlers.setBucketPolicy")
+
+	res := &nilrpc.MACSetBucketPolicyResponse{}
+	if err := h.callMds(nilrpc.MdsAccountSetBucketPolicy, &nilrpc.MACSetBucketPolicyRequest{
+		BucketName: mux.Vars(r)["bucket"],
+		Policy:     policy,
+	}, res); err != nil {
+		ctxLogger.Error(err)
+		req.SendInternalError()
+		return
+	}
+	if res.S3ErrCode != s3.ErrNone {
+		req.SendError(res.S3ErrCode)
+		return
+	}
+
+	s3.SendNoContent(w)
+}
+
+// accessible returns true if the requester is allowed the action of the
+// route on the bucket, or on the object of the route if any. The granted
+// is whether the owner or the acls allow the access.
+func accessible(req client.RequestEvent, b *nilrpc.MACGetBucketResponse, granted bool) bool {
+	r := req.Request()
+	return authorized(req, b, routeAction(r), mux.Vars(r)["object"], granted, nil)
+}
+
+// authorized applies the bucket policy to the access of the action on the
+// bucket, or on the object if the key is not empty. The explicit deny of
+// the policy overrides the grant of the owner and the acls, and the
+// explicit allow grants what they do not. The conditions are the values
+// of the condition keys which are not known from the request itself, such
+// as the tags of the object.
+func authorized(req client.RequestEvent, b *nilrpc.MACGetBucketResponse, action, key string, granted bool, conditions map[string]string) bool {
+	if granted && req.AccessKey() == b.Owner && policyActions[action] {
+		return true
+	}
+
+	switch evaluatePolicy(req, b, action, key, conditions) {
+	case s3.PolicyAllow:
+		return true
+	case s3.PolicyDeny:
+		return false
+	default:
+		return granted
+	}
+}
+
+// evaluatePolicy returns the effect of the bucket policy on the action of
+// the requester on the bucket, or on the object if the key is not empty.
+// The conditions are added to the ones of the request.
+func evaluatePolicy(req client.RequestEvent, b *nilrpc.MACGetBucketResponse, action, key string, conditions map[string]string) s3.PolicyEffect {
+	ctxLogger := mlog.GetMethodLogger(logger, "evaluatePolicy")
+
+	if b.Policy == "" || action == "" {
+		return s3.PolicyNoMatch
+	}
+
+	p, code := b.ParsedPolicy()
+	if code != s3.ErrNone {
+		// The policy is validated before it is stored, so this is not
+		// expected. Deny rather than ignoring what the owner intended.
+		ctxLogger.Errorf("invalid policy of the bucket %s", b.BucketName)
+		return s3.PolicyDeny
+	}
+
+	resource := s3.ResourcePrefix + b.BucketName
+	if key != "" {
+		resource += "/" + key
+	}
+	requestConditions := policyConditions(req.Request())
+	for k, v := range conditions {
+		requestConditions[k] = v
+	}
+	return p.Evaluate(&s3.PolicyRequest{
+		Principal:  req.AccessKey(),
+		Action:     action,
+		Resource:   resource,
+		Conditions: requestConditions,
+	})
+}
+
+// policyConditions returns the values of the condition keys of the request.
+func policyConditions(r *http.Request) map[string]string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+
+	conditions := map[string]string{
+		s3.ConditionSourceIP:        ip,
+		s3.ConditionSecureTransport: strconv.FormatBool(r.TLS != nil),
+	}
+	if q := r.URL.Query(); q["prefix"] != nil {
+		conditions[s3.ConditionPrefix] = q.Get("prefix")
+	}
+	// The invalid tags are rejected by the handler.
+	if tags, code := s3.ParseTaggingHeader(r.Header); code == s3.ErrNone {
+		tagConditions(conditions, s3.ConditionRequestObjectTag, tags)
+	}
+	return conditions
+}
+
+// tagConditions adds the values of the condition keys of the tags, which
+// are the tag keys with the prefix, to the conditions. The conditions are
+// allocated if nil.
+func tagConditions(conditions map[string]string, prefix string, tags map[string]string) map[string]string {
+	if conditions == nil {
+		conditions = make(map[string]string, len(tags))
+	}
+	for k, v := range tags {
+		conditions[prefix+k] = v
+	}
+	return conditions
+}
+
+// routeAction returns the action of the request, which is the name of the
+// matched route.
+func routeAction(r *http.Request) string {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return ""
+	}
+	return route.GetName()
+}
@@ -0,0 +1,132 @@
+package client
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	"github.com/chanyoung/nil/pkg/nilrpc"
+	"github.com/chanyoung/nil/pkg/s3"
+)
+
+// testPolicy returns the bucket policy of the statement.
+func testPolicy(statement string) string {
+	return `{"Version": "2012-10-17", "Statement": [` + statement + `]}`
+}
+
+func TestGetObjectAccess(t *testing.T) {
+	data := []byte("object data")
+
+	testCases := []struct {
+		name   string
+		user   string
+		acl    string
+		policy string
+		code   s3.ErrorCode
+	}{
+		{"owner", "owner", s3.ACLPrivate, "", s3.ErrNone},
+		{"other of private", "other", s3.ACLPrivate, "", s3.ErrAccessDenied},
+		{"anonymous of private", "", s3.ACLPrivate, "", s3.ErrAccessDenied},
+		{"other of public-read", "other", s3.ACLPublicRead, "", s3.ErrNone},
+		{"anonymous of public-read", "", s3.ACLPublicRead, "", s3.ErrNone},
+		{
+			"other allowed by policy", "other", s3.ACLPrivate,
+			testPolicy(`{"Effect": "Allow", "Principal": {"AWS": ["other"]}, "Action": "s3:GetObject", "Resource": "arn:aws:s3:::bucket/*"}`),
+			s3.ErrNone,
+		},
+		{
+			"owner denied by policy", "owner", s3.ACLPrivate,
+			testPolicy(`{"Effect": "Deny", "Principal": "*", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::bucket/doc"}`),
+			s3.ErrAccessDenied,
+		},
+		{
+			"public-read denied by policy", "", s3.ACLPublicRead,
+			testPolicy(`{"Effect": "Deny", "Principal": "*", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::bucket/*"}`),
+			s3.ErrAccessDenied,
+		},
+		{
+			"policy of other object", "owner", s3.ACLPrivate,
+			testPolicy(`{"Effect": "Deny", "Principal": "*", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::bucket/other"}`),
+			s3.ErrNone,
+		},
+	}
+
+	for _, c := range testCases {
+		g := newTestGateway(t)
+		g.mds.bucket.Policy = c.policy
+		g.ds.objects["oid"] = data
+		g.mds.methods[nilrpc.MdsObjectGet] = storedObject(&nilrpc.MOBObjectPutRequest{
+			EncodingGroup: 1,
+			Volume:        1,
+			DsID:          1,
+			ObjectID:      "oid",
+			Size:          int64(len(data)),
+			ACL:           c.acl,
+		})
+
+		w := g.do("GET", "/bucket/doc", c.user, nil, nil)
+		if c.code != s3.ErrNone {
+			expectError(t, c.name, w, c.code)
+			if g.ds.reads != 0 {
+				t.Errorf("%s: expected the denied object is not read from the ds", c.name)
+			}
+			continue
+		}
+
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: expected status 200, got %d: %s", c.name, w.Code, w.Body)
+			continue
+		}
+		if !bytes.Equal(w.Body.Bytes(), data) {
+			t.Errorf("%s: expected the object data, got %q", c.name, w.Body)
+		}
+	}
+}
+
+func TestPutObjectAccess(t *testing.T) {
+	testCases := []struct {
+		name   string
+		user   string
+		acl    string
+		policy string
+		code   s3.ErrorCode
+	}{
+		{"owner", "owner", s3.ACLPrivate, "", s3.ErrNone},
+		{"other", "other", s3.ACLPrivate, "", s3.ErrAccessDenied},
+		{"other of public-read", "other", s3.ACLPublicRead, "", s3.ErrAccessDenied},
+		{"anonymous", "", s3.ACLPrivate, "", s3.ErrAccessDenied},
+		{
+			"other allowed by policy", "other", s3.ACLPrivate,
+			testPolicy(`{"Effect": "Allow", "Principal": {"AWS": "other"}, "Action": "s3:PutObject", "Resource": "arn:aws:s3:::bucket/*"}`),
+			s3.ErrNone,
+		},
+		{
+			"owner denied by policy", "owner", s3.ACLPrivate,
+			testPolicy(`{"Effect": "Deny", "Principal": "*", "Action": "s3:PutObject", "Resource": "arn:aws:s3:::bucket/*"}`),
+			s3.ErrAccessDenied,
+		},
+	}
+
+	for _, c := range testCases {
+		g := newTestGateway(t)
+		g.mds.bucket.ACL = c.acl
+		g.mds.bucket.Policy = c.policy
+		g.mds.methods[nilrpc.MdsObjectPut] = func(req, res interface{}) {}
+
+		w := g.do("PUT", "/bucket/doc", c.user, nil, []byte("object data"))
+		if c.code != s3.ErrNone {
+			expectError(t, c.name, w, c.code)
+			if len(g.ds.objects) != 0 || g.mds.calls[nilrpc.MdsObjectPut] != 0 {
+				t.Errorf("%s: expected the denied object is not written", c.name)
+			}
+			continue
+		}
+
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: expected status 200, got %d: %s", c.name, w.Code, w.Body)
+		}
+		if g.mds.calls[nilrpc.MdsObjectPut] != 1 {
+			t.Errorf("%s: expected the object is recorded", c.name)
+		}
+	}
+}
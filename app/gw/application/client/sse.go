@@ -0,0 +1,150 @@
+package client
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+
+	"github.com/chanyoung/nil/pkg/nilrpc"
+	"github.com/chanyoung/nil/pkg/s3"
+	"github.com/chanyoung/nil/pkg/util/mlog"
+)
+
+// objectEncryption is the server side encryption of the object data.
+type objectEncryption struct {
+	// meta is recorded with the object in the mds.
+	meta nilrpc.MOBEncryption
+	// key is the data key of the object, which is nil if the data is not
+	// encrypted.
+	key []byte
+	// customerKeyMD5 is the md5 of the sse-c key given by the request,
+	// which is sent back to the client.
+	customerKeyMD5 string
+}
+
+// requestEncryption returns the encryption of the new object requested by
+// the headers. The data key of sse-s3 is generated and sealed by the mds,
+// and the data key of sse-c is derived from the customer key with a new
+// salt.
+func (h *handlers) requestEncryption(r *http.Request) (*objectEncryption, s3.ErrorCode) {
+	ctxLogger := mlog.GetMethodLogger(logger, "handlers.requestEncryption")
+
+	sse, code := s3.ParseSSE(r.Header)
+	if code != s3.ErrNone {
+		return nil, code
+	}
+
+	switch sse.Type {
+	case s3.SSES3:
+		res := &nilrpc.MACGenerateDataKeyResponse{}
+		if err := h.callMds(nilrpc.MdsAccountGenerateDataKey, &nilrpc.MACGenerateDataKeyRequest{}, res); err != nil {
+			ctxLogger.Error(err)
+			return nil, s3.ErrInternalError
+		}
+		if res.S3ErrCode != s3.ErrNone {
+			return nil, res.S3ErrCode
+		}
+
+		return &objectEncryption{
+			meta: nilrpc.MOBEncryption{Type: s3.SSES3, Key: res.SealedKey},
+			key:  res.Key,
+		}, s3.ErrNone
+
+	case s3.SSEC:
+		salt := make([]byte, s3.SSEKeySize)
+		if _, err := rand.Read(salt); err != nil {
+			ctxLogger.Error(err)
+			return nil, s3.ErrInternalError
+		}
+
+		return &objectEncryption{
+			meta: nilrpc.MOBEncryption{
+				Type:   s3.SSEC,
+				Key:    base64.StdEncoding.EncodeToString(salt),
+				KeyMD5: s3.SSECustomerKeyDigest(sse.CustomerKey, salt),
+			},
+			key:            s3.SSECustomerDataKey(sse.CustomerKey, salt),
+			customerKeyMD5: sse.CustomerKeyMD5,
+		}, s3.ErrNone
+
+	default:
+		return &objectEncryption{}, s3.ErrNone
+	}
+}
+
+// objectKey returns the encryption of the stored object data with its
+// data key. The sse-c key must be given by the headers with the prefix if
+// the object is encrypted with it, and must not be given otherwise.
+func (h *handlers) objectKey(r *http.Request, meta nilrpc.MOBEncryption, prefix string) (*objectEncryption, s3.ErrorCode) {
+	ctxLogger := mlog.GetMethodLogger(logger, "handlers.objectKey")
+
+	sse, code := s3.ParseSSECustomerKey(r.Header, prefix)
+	if code != s3.ErrNone {
+		return nil, code
+	}
+	if (sse.Type == s3.SSEC) != (meta.Type == s3.SSEC) {
+		return nil, s3.ErrInvalidRequest
+	}
+
+	enc := &objectEncryption{meta: meta, customerKeyMD5: sse.CustomerKeyMD5}
+	switch meta.Type {
+	case "":
+	case s3.SSES3:
+		res := &nilrpc.MACDecryptDataKeyResponse{}
+		if err := h.callMds(nilrpc.MdsAccountDecryptDataKey, &nilrpc.MACDecryptDataKeyRequest{
+			SealedKey: meta.Key,
+		}, res); err != nil {
+			ctxLogger.Error(err)
+			return nil, s3.ErrInternalError
+		}
+		if res.S3ErrCode != s3.ErrNone {
+			return nil, res.S3ErrCode
+		}
+		enc.key = res.Key
+
+	case s3.SSEC:
+		salt, err := base64.StdEncoding.DecodeString(meta.Key)
+		if err != nil {
+			ctxLogger.Error(err)
+			return nil, s3.ErrInternalError
+		}
+		digest := s3.SSECustomerKeyDigest(sse.CustomerKey, salt)
+		if subtle.ConstantTimeCompare([]byte(digest), []byte(meta.KeyMD5)) != 1 {
+			return nil, s3.ErrAccessDenied
+		}
+		enc.key = s3.SSECustomerDataKey(sse.CustomerKey, salt)
+
+	default:
+		ctxLogger.Errorf("unknown encryption type: %s", meta.Type)
+		return nil, s3.ErrInternalError
+	}
+
+	return enc, s3.ErrNone
+}
+
+// uploadEncryption returns the encryption of the multipart upload with its
+// data key, which the parts of the upload are encrypted with.
+func (h *handlers) uploadEncryption(r *http.Request, bucket, key, uploadID string) (*objectEncryption, s3.ErrorCode) {
+	ctxLogger := mlog.GetMethodLogger(logger, "handlers.uploadEncryption")
+
+	res := &nilrpc.MOBGetUploadResponse{}
+	if err := h.callMds(nilrpc.MdsObjectGetUpload, &nilrpc.MOBGetUploadRequest{
+		Name:     key,
+		Bucket:   bucket,
+		UploadID: uploadID,
+	}, res); err != nil {
+		ctxLogger.Error(err)
+		return nil, s3.ErrInternalError
+	}
+	if res.S3ErrCode != s3.ErrNone {
+		return nil, res.S3ErrCode
+	}
+
+	return h.objectKey(r, res.Encryption, s3.SSEObjectPrefix)
+}
+
+// setSSEHeaders sets the response headers of the encryption.
+func setSSEHeaders(w http.ResponseWriter, enc *objectEncryption) {
+	s3.SetSSEHeaders(w.Header(), enc.meta.Type, enc.customerKeyMD5)
+}
@@ -0,0 +1,143 @@
+package client
+
+import (
+	"net/http"
+
+	"github.com/chanyoung/nil/pkg/client"
+	"github.com/chanyoung/nil/pkg/nilrpc"
+	"github.com/chanyoung/nil/pkg/s3"
+	"github.com/chanyoung/nil/pkg/util/mlog"
+	"github.com/gorilla/mux"
+)
+
+// PutObjectTaggingHandler handles the client request for replacing the
+// tags of the version of the object.
+func (h *handlers) PutObjectTaggingHandler(w http.ResponseWriter, r *http.Request) {
+	req := h.authenticate(w, r)
+	if req == nil {
+		return
+	}
+
+	tagging := s3.Tagging{}
+	if code := readXMLBody(r, s3.MaxTaggingSize, &tagging); code != s3.ErrNone {
+		req.SendError(code)
+		return
+	}
+	tags, code := tagging.Map()
+	if code != s3.ErrNone {
+		req.SendError(code)
+		return
+	}
+
+	obj := h.taggingObject(w, req, tags)
+	if obj == nil {
+		return
+	}
+
+	if h.setObjectTags(req, obj.VersionID, tags) {
+		req.SendSuccess()
+	}
+}
+
+// GetObjectTaggingHandler handles the client request for getting the tags
+// of the version of the object.
+func (h *handlers) GetObjectTaggingHandler(w http.ResponseWriter, r *http.Request) {
+	req := h.authenticate(w, r)
+	if req == nil {
+		return
+	}
+
+	obj := h.taggingObject(w, req, nil)
+	if obj == nil {
+		return
+	}
+
+	s3.SendResponse(w, s3.NewTagging(obj.Tags))
+}
+
+// DeleteObjectTaggingHandler handles the client request for removing the
+// tags of the version of the object.
+func (h *handlers) DeleteObjectTaggingHandler(w http.ResponseWriter, r *http.Request) {
+	req := h.authenticate(w, r)
+	if req == nil {
+		return
+	}
+
+	obj := h.taggingObject(w, req, nil)
+	if obj == nil {
+		return
+	}
+
+	if h.setObjectTags(req, obj.VersionID, nil) {
+		s3.SendNoContent(w)
+	}
+}
+
+// taggingObject returns the version of the object of the tagging request
+// if the requester is allowed the action of the route on it. The policy
+// conditions on the tags refer to the tags of the version and the tags
+// given by the request. Otherwise it sends the error response and returns
+// nil. The requester who is not allowed is not told whether the version
+// exists.
+func (h *handlers) taggingObject(w http.ResponseWriter, req client.RequestEvent, tags map[string]string) *nilrpc.MOBObjectHeadResponse {
+	ctxLogger := mlog.GetMethodLogger(logger, "handlers.taggingObject")
+
+	r := req.Request()
+	vars := mux.Vars(r)
+	b, code := h.requestBucket(req, vars["bucket"])
+	if code != s3.ErrNone {
+		req.SendError(code)
+		return nil
+	}
+
+	res := &nilrpc.MOBObjectHeadResponse{}
+	if err := h.callMds(nilrpc.MdsObjectHead, &nilrpc.MOBObjectHeadRequest{
+		Name:      vars["object"],
+		Bucket:    vars["bucket"],
+		VersionID: r.URL.Query().Get("versionId"),
+	}, res); err != nil {
+		ctxLogger.Error(err)
+		req.SendInternalError()
+		return nil
+	}
+
+	conditions := tagConditions(nil, s3.ConditionExistingObjectTag, res.Tags)
+	tagConditions(conditions, s3.ConditionRequestObjectTag, tags)
+	if !authorized(req, b, routeAction(r), vars["object"], b.Owner == req.AccessKey(), conditions) {
+		req.SendError(s3.ErrAccessDenied)
+		return nil
+	}
+
+	setVersionHeaders(w, res.VersionID, res.DeleteMarker)
+	if res.S3ErrCode != s3.ErrNone {
+		req.SendError(res.S3ErrCode)
+		return nil
+	}
+
+	return res
+}
+
+// setObjectTags records the tags of the version of the object of the
+// request. It returns false after sending the error response if failed.
+func (h *handlers) setObjectTags(req client.RequestEvent, versionID string, tags map[string]string) bool {
+	ctxLogger := mlog.GetMethodLogger(logger, "handlers.setObjectTags")
+
+	vars := mux.Vars(req.Request())
+	res := &nilrpc.MOBSetTagsResponse{}
+	if err := h.callMds(nilrpc.MdsObjectSetTags, &nilrpc.MOBSetTagsRequest{
+		Name:      vars["object"],
+		Bucket:    vars["bucket"],
+		VersionID: versionID,
+		Tags:      tags,
+	}, res); err != nil {
+		ctxLogger.Error(err)
+		req.SendInternalError()
+		return false
+	}
+	if res.S3ErrCode != s3.ErrNone {
+		req.SendError(res.S3ErrCode)
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,161 @@
+package client
+
+import (
+	"net/http"
+
+	"github.com/chanyoung/nil/pkg/nilrpc"
+	"github.com/chanyoung/nil/pkg/s3"
+	"github.com/chanyoung/nil/pkg/util/mlog"
+	"github.com/gorilla/mux"
+)
+
+// maxVersioningBodySize is the maximum size of the request body of the put
+// bucket versioning.
+const maxVersioningBodySize = 4 * 1024
+
+// PutBucketVersioningHandler handles the client request for enabling or
+// suspending the versioning of the bucket.
+func (h *handlers) PutBucketVersioningHandler(w http.ResponseWriter, r *http.Request) {
+	ctxLogger := mlog.GetMethodLogger(logger, "handlers.PutBucketVersioningHandler")
+
+	req := h.authenticate(w, r)
+	if req == nil {
+		return
+	}
+
+	if h.ownedBucket(req, mux.Vars(r)["bucket"]) == nil {
+		return
+	}
+
+	conf := s3.VersioningConfiguration{}
+	if code := readXMLBody(r, maxVersioningBodySize, &conf); code != s3.ErrNone {
+		req.SendError(code)
+		return
+	}
+	// The mfa delete is not supported.
+	if conf.MfaDelete == "Enabled" {
+		req.SendError(s3.ErrNotImplemented)
+		return
+	}
+
+	res := &nilrpc.MACSetBucketVersioningResponse{}
+	if err := h.callMds(nilrpc.MdsAccountSetBucketVersioning, &nilrpc.MACSetBucketVersioningRequest{
+		BucketName: mux.Vars(r)["bucket"],
+		Versioning: conf.Status,
+	}, res); err != nil {
+		ctxLogger.Error(err)
+		req.SendInternalError()
+		return
+	}
+	if res.S3ErrCode != s3.ErrNone {
+		req.SendError(res.S3ErrCode)
+		return
+	}
+
+	req.SendSuccess()
+}
+
+// GetBucketVersioningHandler handles the client request for getting the
+// versioning state of the bucket.
+func (h *handlers) GetBucketVersioningHandler(w http.ResponseWriter, r *http.Request) {
+	req := h.authenticate(w, r)
+	if req == nil {
+		return
+	}
+
+	b := h.ownedBucket(req, mux.Vars(r)["bucket"])
+	if b == nil {
+		return
+	}
+
+	s3.SendResponse(w, s3.VersioningConfiguration{
+		Xmlns:  s3.Namespace,
+		Status: b.Versioning,
+	})
+}
+
+// ListObjectVersionsHandler handles the client request for listing the
+// versions and the delete markers of the objects in the bucket.
+func (h *handlers) ListObjectVersionsHandler(w http.ResponseWriter, r *http.Request) {
+	ctxLogger := mlog.GetMethodLogger(logger, "handlers.ListObjectVersionsHandler")
+
+	req := h.authenticate(w, r)
+	if req == nil {
+		return
+	}
+
+	q := r.URL.Query()
+	maxKeys, ok := queryInt(q, "max-keys", defaultMaxKeys)
+	if !ok {
+		req.SendError(s3.ErrInvalidArgument)
+		return
+	}
+	encodingType := q.Get("encoding-type")
+	encode, ok := keyEncoder(encodingType)
+	if !ok {
+		req.SendError(s3.ErrInvalidArgument)
+		return
+	}
+	// The version id marker is only meaningful after the key marker.
+	if q.Get("version-id-marker") != "" && q.Get("key-marker") == "" {
+		req.SendError(s3.ErrInvalidArgument)
+		return
+	}
+
+	bucket := mux.Vars(r)["bucket"]
+	b := h.readableBucket(req, bucket)
+	if b == nil {
+		return
+	}
+
+	lreq := &nilrpc.MOBListVersionsRequest{
+		Bucket:          bucket,
+		Prefix:          q.Get("prefix"),
+		Delimiter:       q.Get("delimiter"),
+		KeyMarker:       q.Get("key-marker"),
+		VersionIDMarker: q.Get("version-id-marker"),
+		MaxKeys:         maxKeys,
+	}
+	lres := &nilrpc.MOBListVersionsResponse{}
+	if err := h.callMds(nilrpc.MdsObjectListVersions, lreq, lres); err != nil {
+		ctxLogger.Error(err)
+		req.SendInternalError()
+		return
+	}
+	if lres.S3ErrCode != s3.ErrNone {
+		req.SendError(lres.S3ErrCode)
+		return
+	}
+
+	result := s3.ListVersionsResult{
+		Name:            bucket,
+		Prefix:          encode(lreq.Prefix),
+		KeyMarker:       encode(lreq.KeyMarker),
+		VersionIdMarker: lreq.VersionIDMarker,
+		MaxKeys:         maxKeys,
+		Delimiter:       encode(lreq.Delimiter),
+		EncodingType:    encodingType,
+		IsTruncated:     lres.IsTruncated,
+		Versions:        make([]s3.ObjectVersion, len(lres.Versions)),
+		CommonPrefixes:  make([]s3.CommonPrefix, len(lres.CommonPrefixes)),
+	}
+	if lres.IsTruncated {
+		result.NextKeyMarker = encode(lres.NextKeyMarker)
+		result.NextVersionIdMarker = lres.NextVersionIDMarker
+	}
+
+	owner := s3.Owner{ID: b.Owner, DisplayName: b.Owner}
+	for i, v := range lres.Versions {
+		lastModified := s3.FormatTime(v.LastModified)
+		if v.DeleteMarker {
+			result.Versions[i] = s3.NewDeleteMarker(encode(v.Name), v.VersionID, v.IsLatest, lastModified, owner)
+			continue
+		}
+		result.Versions[i] = s3.NewVersion(encode(v.Name), v.VersionID, v.IsLatest, lastModified, quoteETag(v.ETag), v.Size, owner)
+	}
+	for i, p := range lres.CommonPrefixes {
+		result.CommonPrefixes[i] = s3.CommonPrefix{Prefix: encode(p)}
+	}
+
+	s3.SendResponse(w, result)
+}
@@ -54,7 +54,7 @@ func NewDeliveryService(cfg *config.Gw, ah admin.Handlers, ch client.Handlers) (
 	m.RegisterLayer(httpL)
 
 	// 4. Create a http handler.
-	h := makeHandler(ch)
+	h := makeHandler(ch, parseBaseDomains(cfg.BaseDomains))
 
 	// 5. Create http server.
 	hsrv := &http.Server{
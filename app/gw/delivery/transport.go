@@ -2,30 +2,106 @@ package delivery
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/chanyoung/nil/app/gw/application/client"
+	"github.com/chanyoung/nil/pkg/s3"
 	"github.com/gorilla/mux"
 )
 
-func makeHandler(ch client.Handlers) http.Handler {
+func makeHandler(ch client.Handlers, baseDomains []string) http.Handler {
 	r := mux.NewRouter()
 
-	// API routers.
+	// API routers. Each route is named after the action of the request,
+	// which the bucket policy is evaluated on.
 	ar := r.PathPrefix("/").Subrouter()
 	br := ar.PathPrefix("/{bucket}").Subrouter()
 	or := br.PathPrefix("/{object:.+}").Subrouter()
 
-	// Bucket request handlers
-	br.Methods("HEAD").HandlerFunc(ch.MakeBucketHandler)
-	br.Methods("PUT").HandlerFunc(ch.MakeBucketHandler)
-	br.Methods("DELETE").HandlerFunc(ch.RemoveBucketHandler)
+	// Service request handlers
+	ar.Methods("GET").Path("/").Name("s3:ListAllMyBuckets").HandlerFunc(ch.ListBucketsHandler)
+
+	// Bucket request handlers. The cors preflight request is not an action
+	// of the bucket policy.
+	br.Methods("OPTIONS").HandlerFunc(ch.PreflightHandler)
+	br.Methods("HEAD").Name("s3:ListBucket").HandlerFunc(ch.HeadBucketHandler)
+	br.Methods("PUT").Queries("versioning", "").Name("s3:PutBucketVersioning").HandlerFunc(ch.PutBucketVersioningHandler)
+	br.Methods("PUT").Queries("lifecycle", "").Name("s3:PutLifecycleConfiguration").HandlerFunc(ch.PutBucketLifecycleHandler)
+	br.Methods("PUT").Queries("acl", "").Name("s3:PutBucketAcl").HandlerFunc(ch.PutBucketACLHandler)
+	br.Methods("PUT").Queries("policy", "").Name("s3:PutBucketPolicy").HandlerFunc(ch.PutBucketPolicyHandler)
+	br.Methods("PUT").Queries("cors", "").Name("s3:PutBucketCORS").HandlerFunc(ch.PutBucketCORSHandler)
+	br.Methods("PUT").Queries("object-lock", "").Name("s3:PutBucketObjectLockConfiguration").HandlerFunc(ch.PutObjectLockConfigurationHandler)
+	br.Methods("PUT").Name("s3:CreateBucket").HandlerFunc(ch.MakeBucketHandler)
+	br.Methods("DELETE").Queries("lifecycle", "").Name("s3:PutLifecycleConfiguration").HandlerFunc(ch.DeleteBucketLifecycleHandler)
+	br.Methods("DELETE").Queries("policy", "").Name("s3:DeleteBucketPolicy").HandlerFunc(ch.DeleteBucketPolicyHandler)
+	br.Methods("DELETE").Queries("cors", "").Name("s3:PutBucketCORS").HandlerFunc(ch.DeleteBucketCORSHandler)
+	br.Methods("DELETE").Name("s3:DeleteBucket").HandlerFunc(ch.RemoveBucketHandler)
+	br.Methods("POST").Queries("delete", "").Name("s3:DeleteObject").HandlerFunc(ch.DeleteObjectsHandler)
+	br.Methods("GET").Queries("uploads", "").Name("s3:ListBucketMultipartUploads").HandlerFunc(ch.ListMultipartUploadsHandler)
+	br.Methods("GET").Queries("versioning", "").Name("s3:GetBucketVersioning").HandlerFunc(ch.GetBucketVersioningHandler)
+	br.Methods("GET").Queries("versions", "").Name("s3:ListBucketVersions").HandlerFunc(ch.ListObjectVersionsHandler)
+	br.Methods("GET").Queries("lifecycle", "").Name("s3:GetLifecycleConfiguration").HandlerFunc(ch.GetBucketLifecycleHandler)
+	br.Methods("GET").Queries("acl", "").Name("s3:GetBucketAcl").HandlerFunc(ch.GetBucketACLHandler)
+	br.Methods("GET").Queries("policy", "").Name("s3:GetBucketPolicy").HandlerFunc(ch.GetBucketPolicyHandler)
+	br.Methods("GET").Queries("cors", "").Name("s3:GetBucketCORS").HandlerFunc(ch.GetBucketCORSHandler)
+	br.Methods("GET").Queries("object-lock", "").Name("s3:GetBucketObjectLockConfiguration").HandlerFunc(ch.GetObjectLockConfigurationHandler)
+	br.Methods("GET").Name("s3:ListBucket").HandlerFunc(ch.ListObjectsHandler)
+
+	// Multipart upload request handlers
+	or.Methods("POST").Queries("uploads", "").Name("s3:PutObject").HandlerFunc(ch.CreateMultipartUploadHandler)
+	or.Methods("PUT").Queries("partNumber", "", "uploadId", "").Headers("X-Amz-Copy-Source", "").Name("s3:PutObject").HandlerFunc(ch.UploadPartCopyHandler)
+	or.Methods("PUT").Queries("partNumber", "", "uploadId", "").Name("s3:PutObject").HandlerFunc(ch.UploadPartHandler)
+	or.Methods("POST").Queries("uploadId", "").Name("s3:PutObject").HandlerFunc(ch.CompleteMultipartUploadHandler)
+	or.Methods("DELETE").Queries("uploadId", "").Name("s3:AbortMultipartUpload").HandlerFunc(ch.AbortMultipartUploadHandler)
+	or.Methods("GET").Queries("uploadId", "").Name("s3:ListMultipartUploadParts").HandlerFunc(ch.ListPartsHandler)
 
 	// Object request handlers
-	or.Methods("PUT").HandlerFunc(ch.PutObjectHandler)
-	or.Methods("GET").HandlerFunc(ch.GetObjectHandler)
-	or.Methods("DELETE").HandlerFunc(ch.DeleteObjectHandler)
+	or.Methods("OPTIONS").HandlerFunc(ch.PreflightHandler)
+	or.Methods("HEAD").Name("s3:GetObject").HandlerFunc(ch.HeadObjectHandler)
+	or.Methods("PUT").Queries("acl", "").Name("s3:PutObjectAcl").HandlerFunc(ch.PutObjectACLHandler)
+	or.Methods("GET").Queries("acl", "").Name("s3:GetObjectAcl").HandlerFunc(ch.GetObjectACLHandler)
+	or.Methods("PUT").Queries("tagging", "").Name("s3:PutObjectTagging").HandlerFunc(ch.PutObjectTaggingHandler)
+	or.Methods("GET").Queries("tagging", "").Name("s3:GetObjectTagging").HandlerFunc(ch.GetObjectTaggingHandler)
+	or.Methods("DELETE").Queries("tagging", "").Name("s3:DeleteObjectTagging").HandlerFunc(ch.DeleteObjectTaggingHandler)
+	or.Methods("PUT").Queries("retention", "").Name("s3:PutObjectRetention").HandlerFunc(ch.PutObjectRetentionHandler)
+	or.Methods("GET").Queries("retention", "").Name("s3:GetObjectRetention").HandlerFunc(ch.GetObjectRetentionHandler)
+	or.Methods("PUT").Queries("legal-hold", "").Name("s3:PutObjectLegalHold").HandlerFunc(ch.PutObjectLegalHoldHandler)
+	or.Methods("GET").Queries("legal-hold", "").Name("s3:GetObjectLegalHold").HandlerFunc(ch.GetObjectLegalHoldHandler)
+	or.Methods("PUT").Headers("X-Amz-Copy-Source", "").Name("s3:PutObject").HandlerFunc(ch.CopyObjectHandler)
+	or.Methods("PUT").Name("s3:PutObject").HandlerFunc(ch.PutObjectHandler)
+	or.Methods("GET").Name("s3:GetObject").HandlerFunc(ch.GetObjectHandler)
+	or.Methods("DELETE").Name("s3:DeleteObject").HandlerFunc(ch.DeleteObjectHandler)
+
+	return virtualHosted(r, baseDomains)
+}
 
-	return r
+// virtualHosted rewrites the path of the virtual-hosted-style request into
+// the path-style, so that the bucket in the host is routed as same as the
+// bucket in the path. The request uri is left as it is sent by the client,
+// because it is signed with signature v4.
+func virtualHosted(h http.Handler, baseDomains []string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if bucket, ok := s3.BucketFromHost(r.Host, baseDomains); ok {
+			r.URL.Path = "/" + bucket + r.URL.Path
+			if r.URL.RawPath != "" {
+				r.URL.RawPath = "/" + bucket + r.URL.RawPath
+			}
+		}
+
+		h.ServeHTTP(w, r)
+	})
+}
+
+// parseBaseDomains parses the comma separated base domains.
+func parseBaseDomains(domains string) []string {
+	parsed := make([]string, 0)
+	for _, d := range strings.Split(domains, ",") {
+		d = strings.ToLower(strings.Trim(strings.TrimSpace(d), "."))
+		if d != "" {
+			parsed = append(parsed, d)
+		}
+	}
+	return parsed
 }
 
 // httpTypeBytes returns rpc type bytes which is used to multiplexing.
@@ -34,6 +110,7 @@ func httpTypeBytes() []byte {
 		0x44, // 'D' of DELETE
 		0x47, // 'G' of GET
 		0x48, // 'H' of HEAD,
+		0x4F, // 'O' of OPTIONS
 		0x50, // 'P' of POST, PUT
 	}
 }
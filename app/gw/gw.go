@@ -4,6 +4,7 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/chanyoung/nil/app/gw/application/admin"
 	"github.com/chanyoung/nil/app/gw/application/auth"
@@ -40,7 +41,13 @@ func Bootstrap(cfg config.Gw) error {
 	authCache := inmem.NewCredRepository()
 
 	// Setup request event factory.
-	requestEventFactory := request.NewRequestEventFactory()
+	factoryOpts := []request.EventFactoryOption{
+		request.WithRegion(cfg.LocalClusterRegion),
+	}
+	if t, err := time.ParseDuration(cfg.ClockSkew); err == nil {
+		factoryOpts = append(factoryOpts, request.WithClockSkew(t))
+	}
+	requestEventFactory := request.NewRequestEventFactory(factoryOpts...)
 
 	// Setup cluster map service.
 	// This service is maintained by cluster domain, however the all domains
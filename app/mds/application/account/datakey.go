@@ -0,0 +1,151 @@
+package account
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+
+	"github.com/chanyoung/nil/app/mds/domain/model/masterkey"
+	"github.com/chanyoung/nil/pkg/nilrpc"
+	"github.com/chanyoung/nil/pkg/s3"
+	"github.com/chanyoung/nil/pkg/util/mlog"
+)
+
+// errInvalidSealedKey is used when the sealed key is not the one which is
+// sealed by the master key.
+var errInvalidSealedKey = errors.New("invalid sealed key")
+
+// GenerateDataKey generates a new data key of the object encrypted by the
+// server and seals it with the master key. The master key is created by
+// the leader at the first use.
+func (s *service) GenerateDataKey(req *nilrpc.MACGenerateDataKeyRequest, res *nilrpc.MACGenerateDataKeyResponse) error {
+	ctxLogger := mlog.GetMethodLogger(logger, "service.GenerateDataKey")
+
+	mk, err := s.findMasterKey()
+	if err == masterkey.ErrNotExist {
+		if forwarded, err := s.forwardToLeader(nilrpc.MdsAccountGenerateDataKey, req, res); forwarded || err != nil {
+			return err
+		}
+		mk, err = s.createMasterKey()
+	}
+	if err != nil {
+		ctxLogger.Error(err)
+		res.S3ErrCode = s3.ErrInternalError
+		return nil
+	}
+
+	key := make([]byte, s3.SSEKeySize)
+	if _, err := rand.Read(key); err != nil {
+		ctxLogger.Error(err)
+		res.S3ErrCode = s3.ErrInternalError
+		return nil
+	}
+	sealed, err := sealKey(mk, key)
+	if err != nil {
+		ctxLogger.Error(err)
+		res.S3ErrCode = s3.ErrInternalError
+		return nil
+	}
+
+	res.S3ErrCode = s3.ErrNone
+	res.Key = key
+	res.SealedKey = sealed
+	return nil
+}
+
+// DecryptDataKey unseals the data key of the object with the master key.
+func (s *service) DecryptDataKey(req *nilrpc.MACDecryptDataKeyRequest, res *nilrpc.MACDecryptDataKeyResponse) error {
+	ctxLogger := mlog.GetMethodLogger(logger, "service.DecryptDataKey")
+
+	mk, err := s.findMasterKey()
+	if err != nil {
+		ctxLogger.Error(err)
+		res.S3ErrCode = s3.ErrInternalError
+		return nil
+	}
+
+	key, err := openKey(mk, req.SealedKey)
+	if err != nil {
+		ctxLogger.Error(err)
+		res.S3ErrCode = s3.ErrInternalError
+		return nil
+	}
+
+	res.S3ErrCode = s3.ErrNone
+	res.Key = key
+	return nil
+}
+
+// findMasterKey returns the master key, which is cached once it is found.
+func (s *service) findMasterKey() (masterkey.Key, error) {
+	s.masterKeyMu.Lock()
+	defer s.masterKeyMu.Unlock()
+
+	if s.masterKey != nil {
+		return s.masterKey, nil
+	}
+
+	mk, err := s.mkr.Find()
+	if err != nil {
+		return nil, err
+	}
+	s.masterKey = mk
+	return mk, nil
+}
+
+// createMasterKey creates the master key and returns it. If the master key
+// is created concurrently, the one which is saved first is returned.
+func (s *service) createMasterKey() (masterkey.Key, error) {
+	mk, err := masterkey.Gen()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.mkr.Create(mk); err != nil {
+		return nil, err
+	}
+	return s.findMasterKey()
+}
+
+// sealKey encrypts the data key with the master key. The sealed key is the
+// random nonce followed by the encrypted key, encoded in base64.
+func sealKey(mk masterkey.Key, key []byte) (string, error) {
+	aead, err := keyAEAD(mk)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(aead.Seal(nonce, nonce, key, nil)), nil
+}
+
+// openKey decrypts the data key sealed by sealKey.
+func openKey(mk masterkey.Key, sealed string) ([]byte, error) {
+	aead, err := keyAEAD(mk)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil || len(b) < aead.NonceSize() {
+		return nil, errInvalidSealedKey
+	}
+	key, err := aead.Open(nil, b[:aead.NonceSize()], b[aead.NonceSize():], nil)
+	if err != nil {
+		return nil, errInvalidSealedKey
+	}
+	return key, nil
+}
+
+// keyAEAD returns the aes-gcm cipher of the master key.
+func keyAEAD(mk masterkey.Key) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(mk)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,40 @@
+package account
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/chanyoung/nil/app/mds/domain/model/masterkey"
+)
+
+func TestSealKey(t *testing.T) {
+	mk, err := masterkey.Gen()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := bytes.Repeat([]byte{'k'}, 32)
+
+	sealed, err := sealKey(mk, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opened, err := openKey(mk, sealed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(opened, key) {
+		t.Errorf("expected %x, got %x", key, opened)
+	}
+
+	// The key sealed by the other master key is not opened.
+	other, err := masterkey.Gen()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := openKey(other, sealed); err != errInvalidSealedKey {
+		t.Errorf("expected %v, got %v", errInvalidSealedKey, err)
+	}
+	if _, err := openKey(mk, "invalid"); err != errInvalidSealedKey {
+		t.Errorf("expected %v, got %v", errInvalidSealedKey, err)
+	}
+}
@@ -2,9 +2,11 @@ package account
 
 import (
 	"net/rpc"
+	"sync"
 	"time"
 
 	"github.com/chanyoung/nil/app/mds/domain/model/bucket"
+	"github.com/chanyoung/nil/app/mds/domain/model/masterkey"
 	"github.com/chanyoung/nil/app/mds/domain/model/region"
 	"github.com/chanyoung/nil/app/mds/domain/model/user"
 	"github.com/chanyoung/nil/app/mds/domain/service/raft"
@@ -25,10 +27,16 @@ type service struct {
 	rgr region.Repository
 	usr user.Repository
 	bkr bucket.Repository
+	mkr masterkey.Repository
+
+	// masterKey is the cached master key, which is never changed once
+	// it is created.
+	masterKey   masterkey.Key
+	masterKeyMu sync.Mutex
 }
 
 // NewService creates a user service with necessary dependencies.
-func NewService(cfg *config.Mds, rss raft.SimpleService, rgr region.Repository, usr user.Repository, bkr bucket.Repository) Service {
+func NewService(cfg *config.Mds, rss raft.SimpleService, rgr region.Repository, usr user.Repository, bkr bucket.Repository, mkr masterkey.Repository) Service {
 	logger = mlog.GetPackageLogger("app/mds/usecase/admin")
 
 	sv := &service{
@@ -37,6 +45,7 @@ func NewService(cfg *config.Mds, rss raft.SimpleService, rgr region.Repository,
 		rgr: rgr,
 		usr: usr,
 		bkr: bkr,
+		mkr: mkr,
 	}
 
 	return sv
@@ -83,30 +92,9 @@ func (s *service) AddUser(req *nilrpc.MACAddUserRequest, res *nilrpc.MACAddUserR
 
 // MakeBucket creates a bucket with the given name.
 func (s *service) MakeBucket(req *nilrpc.MACMakeBucketRequest, res *nilrpc.MACMakeBucketResponse) error {
-	// Bucket is the globally shared metadata.
-	// If this node is not a leader but has received a request, it forwards
-	// the request to the leader node instead.
-	leader, err := s.rss.Leader()
-	if err != nil {
+	if forwarded, err := s.forwardToLeader(nilrpc.MdsAccountMakeBucket, req, res); forwarded || err != nil {
 		return err
 	}
-	if !leader {
-		leaderEndPoint, err := s.rss.LeaderEndPoint()
-		if err != nil {
-			return err
-		}
-
-		conn, err := nilrpc.Dial(leaderEndPoint, nilrpc.RPCNil, time.Duration(2*time.Second))
-		if err != nil {
-			return err
-		}
-		defer conn.Close()
-
-		cli := rpc.NewClient(conn)
-		defer cli.Close()
-
-		return cli.Call(nilrpc.MdsAccountMakeBucket.String(), req, res)
-	}
 
 	u, err := s.usr.FindByAk(user.Key(req.AccessKey))
 	if err != nil {
@@ -118,10 +106,25 @@ func (s *service) MakeBucket(req *nilrpc.MACMakeBucketRequest, res *nilrpc.MACMa
 		return err
 	}
 
+	acl := req.ACL
+	if acl == "" {
+		acl = s3.ACLPrivate
+	}
+
+	// The object lock needs the versioning, which can not be suspended.
+	var versioning bucket.Versioning
+	if req.ObjectLock != "" {
+		versioning = bucket.VersioningEnabled
+	}
+
 	err = s.bkr.Save(&bucket.Bucket{
-		Name:   bucket.Name(req.BucketName),
-		User:   bucket.ID(u.ID),
-		Region: bucket.ID(r.ID),
+		Name:       bucket.Name(req.BucketName),
+		User:       bucket.ID(u.ID),
+		Region:     bucket.ID(r.ID),
+		Created:    time.Now().UTC(),
+		ACL:        acl,
+		Versioning: versioning,
+		ObjectLock: req.ObjectLock,
 	})
 
 	switch err {
@@ -136,6 +139,389 @@ func (s *service) MakeBucket(req *nilrpc.MACMakeBucketRequest, res *nilrpc.MACMa
 	return err
 }
 
+// RemoveBucket removes the empty bucket.
+func (s *service) RemoveBucket(req *nilrpc.MACRemoveBucketRequest, res *nilrpc.MACRemoveBucketResponse) (err error) {
+	ctxLogger := mlog.GetMethodLogger(logger, "service.RemoveBucket")
+
+	if req.Region == "" {
+		req.Region = s.cfg.Raft.LocalClusterRegion
+	}
+
+	b, code := s.regionBucket(req.Region, req.BucketName)
+	if code != s3.ErrNone {
+		res.S3ErrCode = code
+		return nil
+	}
+
+	// Objects are the local metadata of each region, so only the node in
+	// the region of the bucket can tell whether the bucket is empty. The
+	// empty bucket is locked against new objects until it is removed, so
+	// it is still empty when the removal is applied on every node.
+	if req.Region == s.cfg.Raft.LocalClusterRegion {
+		empty, err := s.bkr.LockEmpty(b.ID)
+		if err != nil {
+			ctxLogger.Error(err)
+			res.S3ErrCode = s3.ErrInternalError
+			return nil
+		}
+		if !empty {
+			res.S3ErrCode = s3.ErrBucketNotEmpty
+			return nil
+		}
+
+		defer func() {
+			if err == nil && res.S3ErrCode == s3.ErrNone {
+				return
+			}
+			if err := s.bkr.Unlock(b.ID); err != nil {
+				ctxLogger.Error(err)
+			}
+		}()
+	}
+
+	if forwarded, err := s.forwardToLeader(nilrpc.MdsAccountRemoveBucket, req, res); forwarded || err != nil {
+		return err
+	}
+
+	switch err := s.bkr.Delete(b.ID); err {
+	case nil:
+		res.S3ErrCode = s3.ErrNone
+	case bucket.ErrNotEmpty:
+		res.S3ErrCode = s3.ErrBucketNotEmpty
+	default:
+		ctxLogger.Error(err)
+		res.S3ErrCode = s3.ErrInternalError
+	}
+
+	return nil
+}
+
+// SetBucketVersioning changes the versioning state of the bucket.
+func (s *service) SetBucketVersioning(req *nilrpc.MACSetBucketVersioningRequest, res *nilrpc.MACSetBucketVersioningResponse) error {
+	ctxLogger := mlog.GetMethodLogger(logger, "service.SetBucketVersioning")
+
+	if req.Region == "" {
+		req.Region = s.cfg.Raft.LocalClusterRegion
+	}
+
+	v := bucket.Versioning(req.Versioning)
+	if v != bucket.VersioningEnabled && v != bucket.VersioningSuspended {
+		res.S3ErrCode = s3.ErrIllegalVersioningConfigurationException
+		return nil
+	}
+
+	b, code := s.regionBucket(req.Region, req.BucketName)
+	if code != s3.ErrNone {
+		res.S3ErrCode = code
+		return nil
+	}
+
+	// The versioning of the bucket which has the object lock can not be
+	// suspended, so the locked versions are not replaced.
+	if b.ObjectLock != "" && v != bucket.VersioningEnabled {
+		res.S3ErrCode = s3.ErrInvalidBucketState
+		return nil
+	}
+
+	if forwarded, err := s.forwardToLeader(nilrpc.MdsAccountSetBucketVersioning, req, res); forwarded || err != nil {
+		return err
+	}
+
+	if err := s.bkr.SetVersioning(b.ID, v); err != nil {
+		ctxLogger.Error(err)
+		res.S3ErrCode = s3.ErrInternalError
+		return nil
+	}
+
+	res.S3ErrCode = s3.ErrNone
+	return nil
+}
+
+// SetBucketLifecycle replaces the lifecycle configuration of the bucket.
+// The configuration is validated by the gateway.
+func (s *service) SetBucketLifecycle(req *nilrpc.MACSetBucketLifecycleRequest, res *nilrpc.MACSetBucketLifecycleResponse) error {
+	ctxLogger := mlog.GetMethodLogger(logger, "service.SetBucketLifecycle")
+
+	if req.Region == "" {
+		req.Region = s.cfg.Raft.LocalClusterRegion
+	}
+
+	b, code := s.regionBucket(req.Region, req.BucketName)
+	if code != s3.ErrNone {
+		res.S3ErrCode = code
+		return nil
+	}
+
+	if forwarded, err := s.forwardToLeader(nilrpc.MdsAccountSetBucketLifecycle, req, res); forwarded || err != nil {
+		return err
+	}
+
+	if err := s.bkr.SetLifecycle(b.ID, req.Lifecycle); err != nil {
+		ctxLogger.Error(err)
+		res.S3ErrCode = s3.ErrInternalError
+		return nil
+	}
+
+	res.S3ErrCode = s3.ErrNone
+	return nil
+}
+
+// SetBucketACL replaces the canned acl of the bucket. The acl is validated
+// by the gateway.
+func (s *service) SetBucketACL(req *nilrpc.MACSetBucketACLRequest, res *nilrpc.MACSetBucketACLResponse) error {
+	ctxLogger := mlog.GetMethodLogger(logger, "service.SetBucketACL")
+
+	if req.Region == "" {
+		req.Region = s.cfg.Raft.LocalClusterRegion
+	}
+
+	b, code := s.regionBucket(req.Region, req.BucketName)
+	if code != s3.ErrNone {
+		res.S3ErrCode = code
+		return nil
+	}
+
+	if forwarded, err := s.forwardToLeader(nilrpc.MdsAccountSetBucketACL, req, res); forwarded || err != nil {
+		return err
+	}
+
+	if err := s.bkr.SetACL(b.ID, req.ACL); err != nil {
+		ctxLogger.Error(err)
+		res.S3ErrCode = s3.ErrInternalError
+		return nil
+	}
+
+	res.S3ErrCode = s3.ErrNone
+	return nil
+}
+
+// SetBucketPolicy replaces the bucket policy of the bucket. The policy is
+// validated by the gateway, and the empty policy removes it.
+func (s *service) SetBucketPolicy(req *nilrpc.MACSetBucketPolicyRequest, res *nilrpc.MACSetBucketPolicyResponse) error {
+	ctxLogger := mlog.GetMethodLogger(logger, "service.SetBucketPolicy")
+
+	if req.Region == "" {
+		req.Region = s.cfg.Raft.LocalClusterRegion
+	}
+
+	b, code := s.regionBucket(req.Region, req.BucketName)
+	if code != s3.ErrNone {
+		res.S3ErrCode = code
+		return nil
+	}
+
+	if forwarded, err := s.forwardToLeader(nilrpc.MdsAccountSetBucketPolicy, req, res); forwarded || err != nil {
+		return err
+	}
+
+	if err := s.bkr.SetPolicy(b.ID, req.Policy); err != nil {
+		ctxLogger.Error(err)
+		res.S3ErrCode = s3.ErrInternalError
+		return nil
+	}
+
+	res.S3ErrCode = s3.ErrNone
+	return nil
+}
+
+// SetBucketCORS replaces the cors configuration of the bucket. The
+// configuration is validated by the gateway, and the empty configuration
+// removes the cors rules.
+func (s *service) SetBucketCORS(req *nilrpc.MACSetBucketCORSRequest, res *nilrpc.MACSetBucketCORSResponse) error {
+	ctxLogger := mlog.GetMethodLogger(logger, "service.SetBucketCORS")
+
+	if req.Region == "" {
+		req.Region = s.cfg.Raft.LocalClusterRegion
+	}
+
+	b, code := s.regionBucket(req.Region, req.BucketName)
+	if code != s3.ErrNone {
+		res.S3ErrCode = code
+		return nil
+	}
+
+	if forwarded, err := s.forwardToLeader(nilrpc.MdsAccountSetBucketCORS, req, res); forwarded || err != nil {
+		return err
+	}
+
+	if err := s.bkr.SetCORS(b.ID, req.CORS); err != nil {
+		ctxLogger.Error(err)
+		res.S3ErrCode = s3.ErrInternalError
+		return nil
+	}
+
+	res.S3ErrCode = s3.ErrNone
+	return nil
+}
+
+// SetBucketObjectLock replaces the object lock configuration of the bucket.
+// The configuration is validated by the gateway. The object lock can be
+// enabled only on the versioned bucket, and can not be disabled.
+func (s *service) SetBucketObjectLock(req *nilrpc.MACSetBucketObjectLockRequest, res *nilrpc.MACSetBucketObjectLockResponse) error {
+	ctxLogger := mlog.GetMethodLogger(logger, "service.SetBucketObjectLock")
+
+	if req.Region == "" {
+		req.Region = s.cfg.Raft.LocalClusterRegion
+	}
+
+	b, code := s.regionBucket(req.Region, req.BucketName)
+	if code != s3.ErrNone {
+		res.S3ErrCode = code
+		return nil
+	}
+	if req.ObjectLock == "" || b.Versioning != bucket.VersioningEnabled {
+		res.S3ErrCode = s3.ErrInvalidBucketState
+		return nil
+	}
+
+	if forwarded, err := s.forwardToLeader(nilrpc.MdsAccountSetBucketObjectLock, req, res); forwarded || err != nil {
+		return err
+	}
+
+	if err := s.bkr.SetObjectLock(b.ID, req.ObjectLock); err != nil {
+		ctxLogger.Error(err)
+		res.S3ErrCode = s3.ErrInternalError
+		return nil
+	}
+
+	res.S3ErrCode = s3.ErrNone
+	return nil
+}
+
+// forwardToLeader forwards the request to the leader node if this node is
+// not a leader, since the bucket is the globally shared metadata which is
+// changed only by the leader. It returns true if the request is forwarded.
+func (s *service) forwardToLeader(method nilrpc.MethodName, req, res interface{}) (bool, error) {
+	leader, err := s.rss.Leader()
+	if err != nil {
+		return false, err
+	}
+	if leader {
+		return false, nil
+	}
+
+	leaderEndPoint, err := s.rss.LeaderEndPoint()
+	if err != nil {
+		return false, err
+	}
+
+	conn, err := nilrpc.Dial(leaderEndPoint, nilrpc.RPCNil, time.Duration(2*time.Second))
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	cli := rpc.NewClient(conn)
+	defer cli.Close()
+
+	return true, cli.Call(method.String(), req, res)
+}
+
+// regionBucket finds the bucket in the given region. The requests to
+// change the bucket are authorized by the gateway, which applies the bucket
+// policy as well as the ownership, so the requester is not checked again.
+func (s *service) regionBucket(regionName, bucketName string) (*bucket.Bucket, s3.ErrorCode) {
+	ctxLogger := mlog.GetMethodLogger(logger, "service.regionBucket")
+
+	r, err := s.rgr.FindByName(region.Name(regionName))
+	if err == region.ErrNotExist {
+		return nil, s3.ErrNoSuchBucket
+	} else if err != nil {
+		ctxLogger.Error(err)
+		return nil, s3.ErrInternalError
+	}
+
+	b, err := s.bkr.FindByName(bucket.Name(bucketName), bucket.ID(r.ID))
+	if err == bucket.ErrNotExist {
+		return nil, s3.ErrNoSuchBucket
+	} else if err != nil {
+		ctxLogger.Error(err)
+		return nil, s3.ErrInternalError
+	}
+
+	return b, s3.ErrNone
+}
+
+// GetBucket returns the information of the bucket with the given name.
+// Bucket is the globally shared metadata, so any node can answer.
+func (s *service) GetBucket(req *nilrpc.MACGetBucketRequest, res *nilrpc.MACGetBucketResponse) error {
+	ctxLogger := mlog.GetMethodLogger(logger, "service.GetBucket")
+
+	b, err := s.findBucket(req.BucketName)
+	if err == bucket.ErrNotExist {
+		res.S3ErrCode = s3.ErrNoSuchBucket
+		return nil
+	} else if err != nil {
+		ctxLogger.Error(err)
+		res.S3ErrCode = s3.ErrInternalError
+		return nil
+	}
+
+	u, err := s.usr.FindByID(user.ID(b.User))
+	if err != nil {
+		ctxLogger.Error(err)
+		res.S3ErrCode = s3.ErrInternalError
+		return nil
+	}
+
+	res.S3ErrCode = s3.ErrNone
+	res.BucketName = b.Name.String()
+	res.Owner = u.Access.String()
+	res.Region = s.cfg.Raft.LocalClusterRegion
+	res.Versioning = string(b.Versioning)
+	res.Lifecycle = b.Lifecycle
+	res.ACL = b.ACL
+	res.Policy = b.Policy
+	res.CORS = b.CORS
+	res.ObjectLock = b.ObjectLock
+
+	return nil
+}
+
+// ListBuckets returns the buckets owned by the given access key.
+func (s *service) ListBuckets(req *nilrpc.MACListBucketsRequest, res *nilrpc.MACListBucketsResponse) error {
+	ctxLogger := mlog.GetMethodLogger(logger, "service.ListBuckets")
+
+	u, err := s.usr.FindByAk(user.Key(req.AccessKey))
+	if err == user.ErrNotExist {
+		res.S3ErrCode = s3.ErrInvalidAccessKeyId
+		return nil
+	} else if err != nil {
+		ctxLogger.Error(err)
+		res.S3ErrCode = s3.ErrInternalError
+		return nil
+	}
+
+	buckets, err := s.bkr.FindByUser(bucket.ID(u.ID))
+	if err != nil {
+		ctxLogger.Error(err)
+		res.S3ErrCode = s3.ErrInternalError
+		return nil
+	}
+
+	res.S3ErrCode = s3.ErrNone
+	res.OwnerName = u.Name.String()
+	res.Buckets = make([]nilrpc.MACBucket, len(buckets))
+	for i, b := range buckets {
+		res.Buckets[i] = nilrpc.MACBucket{
+			Name:         b.Name.String(),
+			CreationDate: b.Created,
+		}
+	}
+
+	return nil
+}
+
+// findBucket finds the bucket with the given name in the local region.
+func (s *service) findBucket(name string) (*bucket.Bucket, error) {
+	r, err := s.rgr.FindByName(region.Name(s.cfg.Raft.LocalClusterRegion))
+	if err != nil {
+		return nil, err
+	}
+
+	return s.bkr.FindByName(bucket.Name(name), bucket.ID(r.ID))
+}
+
 // GetCredential returns matching secret key with the given access key.
 func (s *service) GetCredential(req *nilrpc.MACGetCredentialRequest, res *nilrpc.MACGetCredentialResponse) error {
 	res.AccessKey = req.AccessKey
@@ -157,5 +543,16 @@ func (s *service) GetCredential(req *nilrpc.MACGetCredentialRequest, res *nilrpc
 type Service interface {
 	AddUser(req *nilrpc.MACAddUserRequest, res *nilrpc.MACAddUserResponse) error
 	MakeBucket(req *nilrpc.MACMakeBucketRequest, res *nilrpc.MACMakeBucketResponse) error
+	RemoveBucket(req *nilrpc.MACRemoveBucketRequest, res *nilrpc.MACRemoveBucketResponse) error
 	GetCredential(req *nilrpc.MACGetCredentialRequest, res *nilrpc.MACGetCredentialResponse) error
+	GetBucket(req *nilrpc.MACGetBucketRequest, res *nilrpc.MACGetBucketResponse) error
+	ListBuckets(req *nilrpc.MACListBucketsRequest, res *nilrpc.MACListBucketsResponse) error
+	SetBucketVersioning(req *nilrpc.MACSetBucketVersioningRequest, res *nilrpc.MACSetBucketVersioningResponse) error
+	SetBucketLifecycle(req *nilrpc.MACSetBucketLifecycleRequest, res *nilrpc.MACSetBucketLifecycleResponse) error
+	SetBucketACL(req *nilrpc.MACSetBucketACLRequest, res *nilrpc.MACSetBucketACLResponse) error
+	SetBucketPolicy(req *nilrpc.MACSetBucketPolicyRequest, res *nilrpc.MACSetBucketPolicyResponse) error
+	SetBucketCORS(req *nilrpc.MACSetBucketCORSRequest, res *nilrpc.MACSetBucketCORSResponse) error
+	SetBucketObjectLock(req *nilrpc.MACSetBucketObjectLockRequest, res *nilrpc.MACSetBucketObjectLockResponse) error
+	GenerateDataKey(req *nilrpc.MACGenerateDataKeyRequest, res *nilrpc.MACGenerateDataKeyResponse) error
+	DecryptDataKey(req *nilrpc.MACDecryptDataKeyRequest, res *nilrpc.MACDecryptDataKeyResponse) error
 }
@@ -0,0 +1,160 @@
+package account
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/chanyoung/nil/app/mds/domain/model/bucket"
+	"github.com/chanyoung/nil/app/mds/domain/model/region"
+	"github.com/chanyoung/nil/pkg/nilrpc"
+	"github.com/chanyoung/nil/pkg/s3"
+	"github.com/chanyoung/nil/pkg/util/config"
+	"github.com/chanyoung/nil/pkg/util/mlog"
+)
+
+func TestMain(m *testing.M) {
+	mlog.Init("stderr")
+	os.Exit(m.Run())
+}
+
+// leaderService is the raft service of the leader node.
+type leaderService struct{}
+
+func (leaderService) Leader() (bool, error)           { return true, nil }
+func (leaderService) LeaderEndPoint() (string, error) { return "", nil }
+
+// memRegions is a region repository which has the only region.
+type memRegions struct {
+	region *region.Region
+}
+
+func (r *memRegions) FindByID(id region.ID) (*region.Region, error) {
+	if id != r.region.ID {
+		return nil, region.ErrNotExist
+	}
+	return r.region, nil
+}
+
+func (r *memRegions) FindByName(name region.Name) (*region.Region, error) {
+	if name != r.region.Name {
+		return nil, region.ErrNotExist
+	}
+	return r.region, nil
+}
+
+func (r *memRegions) Create(*region.Region) error { return nil }
+
+// memBuckets is a bucket repository which keeps the buckets and the number
+// of the objects in each bucket.
+type memBuckets struct {
+	bucket.Repository
+
+	buckets   map[bucket.Name]*bucket.Bucket
+	objects   map[bucket.ID]int
+	locked    map[bucket.ID]bool
+	deleteErr error
+}
+
+func (r *memBuckets) FindByName(name bucket.Name, region bucket.ID) (*bucket.Bucket, error) {
+	b, ok := r.buckets[name]
+	if !ok || b.Region != region {
+		return nil, bucket.ErrNotExist
+	}
+	return b, nil
+}
+
+func (r *memBuckets) LockEmpty(id bucket.ID) (bool, error) {
+	if r.objects[id] > 0 {
+		return false, nil
+	}
+	r.locked[id] = true
+	return true, nil
+}
+
+func (r *memBuckets) Unlock(id bucket.ID) error {
+	delete(r.locked, id)
+	return nil
+}
+
+func (r *memBuckets) Delete(id bucket.ID) error {
+	if r.deleteErr != nil {
+		return r.deleteErr
+	}
+	for name, b := range r.buckets {
+		if b.ID == id {
+			delete(r.buckets, name)
+		}
+	}
+	delete(r.locked, id)
+	return nil
+}
+
+func newTestService() (*service, *memBuckets) {
+	cfg := &config.Mds{}
+	cfg.Raft.LocalClusterRegion = "local"
+
+	buckets := &memBuckets{
+		buckets: map[bucket.Name]*bucket.Bucket{
+			"empty": {ID: 1, Name: "empty", Region: 1},
+			"full":  {ID: 2, Name: "full", Region: 1},
+		},
+		objects: map[bucket.ID]int{2: 1},
+		locked:  make(map[bucket.ID]bool),
+	}
+	regions := &memRegions{region: &region.Region{ID: 1, Name: "local"}}
+
+	s := NewService(cfg, leaderService{}, regions, nil, buckets, nil).(*service)
+	return s, buckets
+}
+
+func TestRemoveBucket(t *testing.T) {
+	s, buckets := newTestService()
+
+	testCases := []struct {
+		bucket string
+		code   s3.ErrorCode
+	}{
+		{"full", s3.ErrBucketNotEmpty},
+		{"empty", s3.ErrNone},
+		{"empty", s3.ErrNoSuchBucket},
+	}
+
+	for _, c := range testCases {
+		res := &nilrpc.MACRemoveBucketResponse{}
+		if err := s.RemoveBucket(&nilrpc.MACRemoveBucketRequest{BucketName: c.bucket}, res); err != nil {
+			t.Fatal(err)
+		}
+		if res.S3ErrCode != c.code {
+			t.Errorf("remove %s: expected %v, got %v", c.bucket, c.code, res.S3ErrCode)
+		}
+	}
+
+	if _, ok := buckets.buckets["full"]; !ok {
+		t.Error("expected the bucket which is not empty is not removed")
+	}
+	if len(buckets.locked) != 0 {
+		t.Errorf("expected no bucket is locked, got %v", buckets.locked)
+	}
+}
+
+func TestRemoveBucketFailure(t *testing.T) {
+	s, buckets := newTestService()
+	buckets.deleteErr = errors.New("raft is not available")
+
+	res := &nilrpc.MACRemoveBucketResponse{}
+	if err := s.RemoveBucket(&nilrpc.MACRemoveBucketRequest{BucketName: "empty"}, res); err != nil {
+		t.Fatal(err)
+	}
+	if res.S3ErrCode != s3.ErrInternalError {
+		t.Errorf("expected %v, got %v", s3.ErrInternalError, res.S3ErrCode)
+	}
+
+	// The bucket which is failed to be removed accepts objects again.
+	if buckets.locked[1] {
+		t.Error("expected the bucket is unlocked")
+	}
+	if _, ok := buckets.buckets["empty"]; !ok {
+		t.Error("expected the bucket is not removed")
+	}
+}
@@ -0,0 +1,11 @@
+package lifecycle
+
+import "time"
+
+// Repository provides access to the lease of the lifecycle worker.
+type Repository interface {
+	// AcquireLease acquires the lease of the region for the holder until
+	// the duration is passed, or renews it if the holder already has it.
+	// It returns false if the lease of the other holder is not expired.
+	AcquireLease(region, holder string, d time.Duration) (bool, error)
+}
@@ -0,0 +1,171 @@
+package lifecycle
+
+import (
+	"math"
+	"time"
+
+	"github.com/chanyoung/nil/app/mds/application/object"
+	"github.com/chanyoung/nil/pkg/s3"
+)
+
+// listBatch is the number of entries read from the store at once.
+const listBatch = 1000
+
+// applyRule applies the actions of the rule to the objects and the uploads
+// of the bucket which match the rule.
+func (s *service) applyRule(bucket string, rule *s3.LifecycleRule, now time.Time) error {
+	if rule.Expiration != nil || rule.NoncurrentVersionExpiration != nil {
+		if err := s.expireVersions(bucket, rule, now); err != nil {
+			return err
+		}
+	}
+	if rule.AbortIncompleteMultipartUpload != nil {
+		if err := s.abortUploads(bucket, rule, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// expireVersions removes the expired versions of the objects. The versions
+// of the same name are listed from the newest, so the version becomes
+// noncurrent when the previous one was created.
+func (s *service) expireVersions(bucket string, rule *s3.LifecycleRule, now time.Time) error {
+	var (
+		afterName string
+		afterSeq  int64 = math.MaxInt64
+
+		// prev is the previous version of the same name.
+		prev *object.ObjInfo
+		// marker is the latest delete marker which expires if no version
+		// of the same name remains.
+		marker    *object.ObjInfo
+		remaining int
+	)
+
+	// expireMarker removes the pending delete marker if it is alone.
+	expireMarker := func() error {
+		if marker == nil || remaining > 0 {
+			return nil
+		}
+		_, err := s.deleteVersion(bucket, marker.Name, marker.VersionID)
+		return err
+	}
+
+	for {
+		versions, err := s.store.ListVersions(bucket, rule.KeyPrefix(), afterName, afterSeq, listBatch)
+		if err != nil {
+			return err
+		}
+
+		for _, o := range versions {
+			if prev == nil || prev.Name != o.Name {
+				if err := expireMarker(); err != nil {
+					return err
+				}
+				prev, marker, remaining = nil, nil, 0
+			}
+
+			expired, err := s.expireVersion(bucket, rule, o, prev, now)
+			if err != nil {
+				return err
+			}
+			if !expired {
+				if o.Latest && o.DeleteMarker && rule.Expiration != nil && rule.Expiration.ExpiredObjectDeleteMarker {
+					marker = o
+				} else {
+					remaining++
+				}
+			}
+			prev = o
+		}
+
+		if len(versions) < listBatch {
+			return expireMarker()
+		}
+		last := versions[len(versions)-1]
+		afterName, afterSeq = last.Name, last.Seq
+	}
+}
+
+// expireVersion removes the version if it is expired by the rule and
+// returns true if it is removed. The prev is the newer version of the same
+// name, or nil if the version is latest.
+func (s *service) expireVersion(bucket string, rule *s3.LifecycleRule, o, prev *object.ObjInfo, now time.Time) (bool, error) {
+	if !rule.Match(o.Name, o.Tags) {
+		return false, nil
+	}
+
+	if o.Latest {
+		if o.DeleteMarker || rule.Expiration == nil || !rule.Expiration.Expired(o.LastModified, now) {
+			return false, nil
+		}
+		// Deleting the latest version without the version id leaves the
+		// delete marker in the versioned bucket.
+		return s.deleteVersion(bucket, o.Name, "")
+	}
+
+	e := rule.NoncurrentVersionExpiration
+	if e == nil || prev == nil || now.Before(s3.ExpirationTime(prev.LastModified, e.NoncurrentDays)) {
+		return false, nil
+	}
+	return s.deleteVersion(bucket, o.Name, o.VersionID)
+}
+
+// deleteVersion removes the version of the object and its data, and
+// returns true if the version is gone. The version which is already
+// removed is ignored. The version locked by the object lock is kept, since
+// the lifecycle never bypasses the retention.
+func (s *service) deleteVersion(bucket, name, versionID string) (bool, error) {
+	_, obsolete, err := s.store.Delete(bucket, name, versionID, false)
+	switch err {
+	case nil:
+	case object.ErrNotExist, object.ErrNoSuchVersion:
+		return true, nil
+	case object.ErrLocked:
+		return false, nil
+	default:
+		return false, err
+	}
+
+	s.deleteParts(bucket, obsolete)
+	return true, nil
+}
+
+// abortUploads aborts the multipart uploads which are not completed within
+// the days from their initiation.
+func (s *service) abortUploads(bucket string, rule *s3.LifecycleRule, now time.Time) error {
+	var (
+		afterName string
+		afterSeq  int64
+	)
+	days := rule.AbortIncompleteMultipartUpload.DaysAfterInitiation
+
+	for {
+		uploads, err := s.store.ListUploads(bucket, rule.KeyPrefix(), afterName, afterSeq, listBatch)
+		if err != nil {
+			return err
+		}
+
+		for _, u := range uploads {
+			if !rule.Match(u.Name, nil) || now.Before(s3.ExpirationTime(u.Initiated, days)) {
+				continue
+			}
+
+			parts, err := s.store.AbortUpload(bucket, u.Name, u.ID)
+			switch err {
+			case nil:
+				s.deleteParts(bucket, parts)
+			case object.ErrNoSuchUpload:
+			default:
+				return err
+			}
+		}
+
+		if len(uploads) < listBatch {
+			return nil
+		}
+		last := uploads[len(uploads)-1]
+		afterName, afterSeq = last.Name, last.Seq
+	}
+}
@@ -0,0 +1,283 @@
+package lifecycle
+
+import (
+	"math"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chanyoung/nil/app/mds/application/object"
+	"github.com/chanyoung/nil/pkg/s3"
+	"github.com/chanyoung/nil/pkg/util/mlog"
+)
+
+func TestMain(m *testing.M) {
+	mlog.Init("stderr")
+	os.Exit(m.Run())
+}
+
+// memStore is a Repository of the versioned bucket which keeps the
+// versions and the uploads in memory.
+type memStore struct {
+	object.Repository
+
+	versions []*object.ObjInfo
+	uploads  []*object.UploadInfo
+	seq      int64
+}
+
+func (m *memStore) version(name string, latest, deleteMarker bool, lastModified time.Time) {
+	m.seq++
+	m.versions = append(m.versions, &object.ObjInfo{
+		Name:         name,
+		VersionID:    strconv.FormatInt(m.seq, 10),
+		Latest:       latest,
+		DeleteMarker: deleteMarker,
+		Seq:          m.seq,
+		LastModified: lastModified,
+		Oid:          name,
+	})
+}
+
+func (m *memStore) Delete(bucket, name, versionID string, bypassGovernance bool) (*object.ObjInfo, []object.ObjPart, error) {
+	if versionID == "" {
+		for _, v := range m.versions {
+			v.Latest = v.Latest && v.Name != name
+		}
+		m.version(name, true, true, time.Now())
+		return m.versions[len(m.versions)-1], nil, nil
+	}
+
+	for i, v := range m.versions {
+		if v.Name == name && v.VersionID == versionID {
+			if v.Lock.Protected(bypassGovernance, time.Now()) {
+				return nil, nil, object.ErrLocked
+			}
+			m.versions = append(m.versions[:i], m.versions[i+1:]...)
+			return v, v.Locations(), nil
+		}
+	}
+	return nil, nil, object.ErrNoSuchVersion
+}
+
+func (m *memStore) ListVersions(bucket, prefix, afterName string, afterSeq int64, limit int) ([]*object.ObjInfo, error) {
+	sort.Slice(m.versions, func(i, j int) bool {
+		a, b := m.versions[i], m.versions[j]
+		return a.Name < b.Name || (a.Name == b.Name && a.Seq > b.Seq)
+	})
+	objs := make([]*object.ObjInfo, 0)
+	for _, v := range m.versions {
+		if len(objs) == limit {
+			break
+		}
+		after := v.Name > afterName || (v.Name == afterName && v.Seq < afterSeq)
+		if after && strings.HasPrefix(v.Name, prefix) {
+			objs = append(objs, v)
+		}
+	}
+	return objs, nil
+}
+
+func (m *memStore) AbortUpload(bucket, name, uploadID string) ([]object.ObjPart, error) {
+	for i, u := range m.uploads {
+		if u.ID == uploadID {
+			m.uploads = append(m.uploads[:i], m.uploads[i+1:]...)
+			return nil, nil
+		}
+	}
+	return nil, object.ErrNoSuchUpload
+}
+
+func (m *memStore) ListUploads(bucket, prefix, afterName string, afterSeq int64, limit int) ([]*object.UploadInfo, error) {
+	uploads := make([]*object.UploadInfo, 0)
+	for _, u := range m.uploads {
+		if len(uploads) == limit {
+			break
+		}
+		after := u.Name > afterName || (u.Name == afterName && u.Seq > afterSeq)
+		if after && strings.HasPrefix(u.Name, prefix) {
+			uploads = append(uploads, u)
+		}
+	}
+	return uploads, nil
+}
+
+// names returns the names and the kinds of the remaining versions in order.
+func (m *memStore) names() []string {
+	versions, _ := m.ListVersions("bucket", "", "", math.MaxInt64, len(m.versions))
+	names := make([]string, 0)
+	for _, v := range versions {
+		kind := "noncurrent"
+		if v.DeleteMarker {
+			kind = "marker"
+		} else if v.Latest {
+			kind = "latest"
+		}
+		names = append(names, v.Name+":"+kind)
+	}
+	return names
+}
+
+func TestExpireVersions(t *testing.T) {
+	now := time.Date(2020, 3, 10, 12, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+
+	m := &memStore{}
+	// Expired current version which leaves the delete marker.
+	m.version("logs/a", true, false, now.Add(-10*day))
+	// Noncurrent versions, one of which has been noncurrent long enough.
+	m.version("logs/b", false, false, now.Add(-20*day))
+	m.version("logs/b", false, false, now.Add(-8*day))
+	m.version("logs/b", true, false, now.Add(-1*day))
+	// Delete marker whose noncurrent version expires in the same pass.
+	m.version("logs/c", false, false, now.Add(-30*day))
+	m.version("logs/c", true, true, now.Add(-7*day))
+	// Out of the prefix.
+	m.version("data/d", true, false, now.Add(-10*day))
+
+	var deleted []string
+	s := &service{
+		store: m,
+		deleteParts: func(bucket string, parts []object.ObjPart) {
+			for _, p := range parts {
+				deleted = append(deleted, p.Oid)
+			}
+		},
+	}
+
+	prefix := "logs/"
+	rule := &s3.LifecycleRule{
+		Status:                      "Enabled",
+		Prefix:                      &prefix,
+		Expiration:                  &s3.LifecycleExpiration{Days: 7},
+		NoncurrentVersionExpiration: &s3.NoncurrentVersionExpiration{NoncurrentDays: 5},
+	}
+	if err := s.applyRule("bucket", rule, now); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"data/d:latest",
+		"logs/a:marker", "logs/a:noncurrent",
+		"logs/b:latest", "logs/b:noncurrent",
+		"logs/c:marker",
+	}
+	if names := m.names(); !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected %v, got %v", expected, names)
+	}
+	if expected := []string{"logs/b", "logs/c"}; !reflect.DeepEqual(deleted, expected) {
+		t.Errorf("expected deleted data %v, got %v", expected, deleted)
+	}
+
+	// The delete marker which is left alone is removed.
+	rule = &s3.LifecycleRule{
+		Status:     "Enabled",
+		Prefix:     &prefix,
+		Expiration: &s3.LifecycleExpiration{ExpiredObjectDeleteMarker: true},
+	}
+	if err := s.applyRule("bucket", rule, now); err != nil {
+		t.Fatal(err)
+	}
+
+	expected = []string{
+		"data/d:latest",
+		"logs/a:marker", "logs/a:noncurrent",
+		"logs/b:latest", "logs/b:noncurrent",
+	}
+	if names := m.names(); !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected %v, got %v", expected, names)
+	}
+}
+
+func TestExpireTaggedVersions(t *testing.T) {
+	now := time.Date(2020, 3, 10, 12, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+
+	m := &memStore{}
+	m.version("a", true, false, now.Add(-10*day))
+	m.version("b", true, false, now.Add(-10*day))
+	m.version("c", true, false, now.Add(-10*day))
+	m.versions[0].Tags = map[string]string{"retention": "1w", "class": "log"}
+	m.versions[1].Tags = map[string]string{"retention": "1y"}
+	s := &service{store: m, deleteParts: func(string, []object.ObjPart) {}}
+
+	// Only the versions which have the tag are expired.
+	rule := &s3.LifecycleRule{
+		Status:     "Enabled",
+		Filter:     &s3.LifecycleFilter{Tag: &s3.Tag{Key: "retention", Value: "1w"}},
+		Expiration: &s3.LifecycleExpiration{Days: 7},
+	}
+	if err := s.applyRule("bucket", rule, now); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"a:marker", "a:noncurrent",
+		"b:latest",
+		"c:latest",
+	}
+	if names := m.names(); !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected %v, got %v", expected, names)
+	}
+}
+
+func TestExpireLockedVersions(t *testing.T) {
+	now := time.Date(2020, 3, 10, 12, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+
+	m := &memStore{}
+	m.version("a", false, false, now.Add(-30*day))
+	m.version("a", true, true, now.Add(-20*day))
+	m.version("b", false, false, now.Add(-30*day))
+	m.version("b", true, false, now.Add(-20*day))
+	m.versions[0].Lock = s3.ObjectLock{LegalHold: true}
+	m.versions[2].Lock = s3.ObjectLock{
+		Mode:        s3.RetentionGovernance,
+		RetainUntil: time.Now().Add(day),
+	}
+	s := &service{store: m, deleteParts: func(string, []object.ObjPart) {}}
+
+	// The locked versions are kept, and so is the delete marker of them.
+	rule := &s3.LifecycleRule{
+		Status:                      "Enabled",
+		Expiration:                  &s3.LifecycleExpiration{ExpiredObjectDeleteMarker: true},
+		NoncurrentVersionExpiration: &s3.NoncurrentVersionExpiration{NoncurrentDays: 5},
+	}
+	if err := s.applyRule("bucket", rule, now); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"a:marker", "a:noncurrent",
+		"b:latest", "b:noncurrent",
+	}
+	if names := m.names(); !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected %v, got %v", expected, names)
+	}
+}
+
+func TestAbortUploads(t *testing.T) {
+	now := time.Date(2020, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	m := &memStore{uploads: []*object.UploadInfo{
+		{ID: "old", Name: "a", Seq: 1, Initiated: now.AddDate(0, 0, -3)},
+		{ID: "new", Name: "a", Seq: 2, Initiated: now.AddDate(0, 0, -1)},
+	}}
+	s := &service{store: m, deleteParts: func(string, []object.ObjPart) {}}
+
+	rule := &s3.LifecycleRule{
+		Status:                         "Enabled",
+		AbortIncompleteMultipartUpload: &s3.AbortIncompleteMultipartUpload{DaysAfterInitiation: 2},
+	}
+	if err := s.applyRule("bucket", rule, now); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(m.uploads) != 1 || m.uploads[0].ID != "new" {
+		t.Errorf("expected only the new upload remains, got %v", m.uploads)
+	}
+}
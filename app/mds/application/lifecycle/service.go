@@ -0,0 +1,153 @@
+package lifecycle
+
+import (
+	"encoding/xml"
+	"time"
+
+	"github.com/chanyoung/nil/app/mds/application/object"
+	"github.com/chanyoung/nil/app/mds/domain/model/bucket"
+	"github.com/chanyoung/nil/app/mds/domain/model/region"
+	"github.com/chanyoung/nil/pkg/client/request"
+	"github.com/chanyoung/nil/pkg/cmap"
+	"github.com/chanyoung/nil/pkg/s3"
+	"github.com/chanyoung/nil/pkg/util/config"
+	"github.com/chanyoung/nil/pkg/util/mlog"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+var logger *logrus.Entry
+
+// defaultPeriod is the period of evaluating the lifecycle rules if it is
+// not configured.
+const defaultPeriod = 1 * time.Hour
+
+// leasePeriods is the number of the periods the lease of the worker lasts
+// for, so the holder renews the lease before it expires.
+const leasePeriods = 2
+
+type service struct {
+	cfg     *config.Mds
+	cmapAPI cmap.SlaveAPI
+	rgr     region.Repository
+	bkr     bucket.Repository
+	store   object.Repository
+	lr      Repository
+
+	// deleteParts deletes the data of the expired objects and uploads.
+	deleteParts func(bucket string, parts []object.ObjPart)
+}
+
+// NewService creates a lifecycle service with necessary dependencies.
+func NewService(cfg *config.Mds, cmapAPI cmap.SlaveAPI, rgr region.Repository, bkr bucket.Repository, store object.Repository, lr Repository) Service {
+	logger = mlog.GetPackageLogger("app/mds/application/lifecycle")
+
+	s := &service{
+		cfg:     cfg,
+		cmapAPI: cmapAPI,
+		rgr:     rgr,
+		bkr:     bkr,
+		store:   store,
+		lr:      lr,
+	}
+	s.deleteParts = s.deleteObjectData
+
+	return s
+}
+
+// Run starts to evaluate the lifecycle rules of the buckets periodically.
+// Every mds of the region runs the worker, but only the one which holds the
+// lease of the region evaluates the rules.
+func (s *service) Run() {
+	period := defaultPeriod
+	if t, err := time.ParseDuration(s.cfg.Lifecycle); err == nil && t > 0 {
+		period = t
+	}
+
+	go s.runPeriodically(period)
+}
+
+func (s *service) runPeriodically(period time.Duration) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if s.holdLease(leasePeriods * period) {
+			s.evaluate(time.Now().UTC())
+		}
+	}
+}
+
+// holdLease acquires or renews the lease of the local region for the
+// duration, and returns true if this mds holds it.
+func (s *service) holdLease(d time.Duration) bool {
+	ctxLogger := mlog.GetMethodLogger(logger, "service.holdLease")
+
+	ok, err := s.lr.AcquireLease(s.cfg.Raft.LocalClusterRegion, s.cfg.ID, d)
+	if err != nil {
+		ctxLogger.Error(errors.Wrap(err, "failed to acquire lease of local region"))
+		return false
+	}
+	return ok
+}
+
+// evaluate applies the lifecycle rules of the buckets in the local region.
+// Objects are the local metadata of each region, so each region expires
+// its own objects.
+func (s *service) evaluate(now time.Time) {
+	ctxLogger := mlog.GetMethodLogger(logger, "service.evaluate")
+
+	r, err := s.rgr.FindByName(region.Name(s.cfg.Raft.LocalClusterRegion))
+	if err != nil {
+		ctxLogger.Error(errors.Wrap(err, "failed to find local region"))
+		return
+	}
+
+	buckets, err := s.bkr.FindByRegion(bucket.ID(r.ID))
+	if err != nil {
+		ctxLogger.Error(errors.Wrap(err, "failed to find buckets of local region"))
+		return
+	}
+
+	for _, b := range buckets {
+		if b.Lifecycle == "" {
+			continue
+		}
+
+		conf := s3.LifecycleConfiguration{}
+		if err := xml.Unmarshal([]byte(b.Lifecycle), &conf); err != nil {
+			ctxLogger.Error(errors.Wrapf(err, "invalid lifecycle of bucket: %s", b.Name))
+			continue
+		}
+
+		for i := range conf.Rules {
+			rule := &conf.Rules[i]
+			if !rule.Enabled() {
+				continue
+			}
+			if err := s.applyRule(b.Name.String(), rule, now); err != nil {
+				ctxLogger.Error(errors.Wrapf(err, "failed to apply lifecycle rule %q of bucket: %s", rule.ID, b.Name))
+			}
+		}
+	}
+}
+
+// deleteObjectData deletes the data of the parts in the ds. The failure is
+// only logged, since it leaves just a garbage.
+func (s *service) deleteObjectData(bucket string, parts []object.ObjPart) {
+	ctxLogger := mlog.GetMethodLogger(logger, "service.deleteObjectData")
+
+	objs := make([]request.ObjectData, len(parts))
+	for i, p := range parts {
+		objs[i] = request.ObjectData{Ds: p.Node, Volume: p.Vol, Oid: p.Oid}
+	}
+
+	for _, err := range request.DeleteObjectData(s.cmapAPI, request.Send, bucket, objs) {
+		ctxLogger.Error(err)
+	}
+}
+
+// Service is the interface that provides lifecycle domain's service.
+type Service interface {
+	Run()
+}
@@ -1,8 +1,11 @@
 package object
 
 import (
+	"time"
+
 	"github.com/chanyoung/nil/pkg/cmap"
 	"github.com/chanyoung/nil/pkg/nilrpc"
+	"github.com/chanyoung/nil/pkg/s3"
 	"github.com/chanyoung/nil/pkg/util/mlog"
 	"github.com/sirupsen/logrus"
 )
@@ -29,29 +32,494 @@ type ObjInfo struct {
 	EncGrp cmap.ID
 	Vol    cmap.ID
 	Node   cmap.ID
+
+	// VersionID is the id of the version, which is NullVersion if the
+	// object is put while the versioning is not enabled. Latest is true
+	// for the current version of the object.
+	VersionID string
+	Latest    bool
+	// DeleteMarker is true if the version is the marker of the deleted
+	// object, which has no data.
+	DeleteMarker bool
+	// Seq orders the versions of the same name in the order of creation.
+	Seq int64
+
+	// Oid is the id of the object data stored in the ds.
+	Oid          string
+	Size         int64
+	ETag         string
+	LastModified time.Time
+	ContentType  string
+	Headers      map[string]string
+	Metadata     map[string]string
+	// ACL is the canned acl of the object, which is empty for the delete
+	// marker.
+	ACL string
+	// Encryption is how the data of the object is encrypted.
+	Encryption Encryption
+	// Tags is the tags of the object, which is empty for the delete
+	// marker.
+	Tags map[string]string
+	// Lock is the retention and the legal hold of the version, which
+	// protect it from being removed or overwritten.
+	Lock s3.ObjectLock
+
+	// Parts is the data of the object assembled by the multipart upload.
+	// The object which is put at once does not have parts.
+	Parts []ObjPart
+}
+
+// Locations returns the data of the object in order.
+func (o *ObjInfo) Locations() []ObjPart {
+	if o.DeleteMarker {
+		return nil
+	}
+	if len(o.Parts) > 0 {
+		return o.Parts
+	}
+
+	return []ObjPart{{
+		Number: 1,
+		EncGrp: o.EncGrp,
+		Vol:    o.Vol,
+		Node:   o.Node,
+		Oid:    o.Oid,
+		Size:   o.Size,
+		ETag:   o.ETag,
+	}}
+}
+
+// Encryption is how the object data is encrypted by the gateway. The data
+// keys are not stored but derived or unsealed by the gateway.
+type Encryption struct {
+	// Type is s3.SSES3 or s3.SSEC, or empty if the data is not encrypted.
+	Type string
+	// Key is the data key sealed by the master key for SSE-S3, or the salt
+	// which derives the data key from the customer key for SSE-C.
+	Key string
+	// KeyMD5 is the salted md5 of the customer key for SSE-C.
+	KeyMD5 string
+}
+
+// ObjPart is a piece of the object data stored in the ds.
+type ObjPart struct {
+	Number int
+	EncGrp cmap.ID
+	Vol    cmap.ID
+	Node   cmap.ID
+	Oid    string
+
+	// Offset is the position of the part in the assembled object.
+	Offset       int64
+	Size         int64
+	ETag         string
+	LastModified time.Time
+}
+
+// rpcParts converts the parts into the form of the rpc response.
+func rpcParts(parts []ObjPart) []nilrpc.MOBObjectPart {
+	res := make([]nilrpc.MOBObjectPart, len(parts))
+	for i, p := range parts {
+		res[i] = nilrpc.MOBObjectPart{
+			EncodingGroupID: p.EncGrp,
+			VolumeID:        p.Vol,
+			DsID:            p.Node,
+			ObjectID:        p.Oid,
+			Offset:          p.Offset,
+			Size:            p.Size,
+		}
+	}
+	return res
 }
 
+// Put records the location and the attributes of the written object.
+// If the object already exists, it is overwritten with the new one unless
+// the request is create only.
 func (h *handlers) Put(req *nilrpc.MOBObjectPutRequest, res *nilrpc.MOBObjectPutResponse) error {
-	// return h.store.Put(&ObjInfo{
-	// 	Name:   req.Name,
-	// 	Bucket: req.Bucket,
-	// 	EncGrp: req.EncodingGroup,
-	// 	Vol:    req.Volume,
-	// })
+	ctxLogger := mlog.GetMethodLogger(logger, "handlers.Put")
+
+	o := &ObjInfo{
+		Name:         req.Name,
+		Bucket:       req.Bucket,
+		EncGrp:       req.EncodingGroup,
+		Vol:          req.Volume,
+		Node:         req.DsID,
+		Oid:          req.ObjectID,
+		Size:         req.Size,
+		ETag:         req.ETag,
+		LastModified: time.Now().UTC(),
+		ContentType:  req.ContentType,
+		Headers:      req.Headers,
+		Metadata:     req.Metadata,
+		ACL:          req.ACL,
+		Encryption:   Encryption(req.Encryption),
+		Tags:         req.Tags,
+		Lock:         req.Lock,
+	}
+
+	var obsolete []ObjPart
+	var err error
+	if req.CreateOnly {
+		err = h.store.Create(o)
+	} else {
+		obsolete, err = h.store.Put(o)
+	}
+
+	switch err {
+	case nil:
+		res.S3ErrCode = s3.ErrNone
+		res.VersionID = o.VersionID
+		res.Obsolete = rpcParts(obsolete)
+	case ErrNoSuchBucket:
+		res.S3ErrCode = s3.ErrNoSuchBucket
+	case ErrExist:
+		res.S3ErrCode = s3.ErrPreconditionFailed
+	case ErrLocked:
+		res.S3ErrCode = s3.ErrAccessDenied
+	default:
+		ctxLogger.Error(err)
+		res.S3ErrCode = s3.ErrInternalError
+	}
+
 	return nil
 }
 
+// Get returns the location and the attributes of the requested object.
 func (h *handlers) Get(req *nilrpc.MOBObjectGetRequest, res *nilrpc.MOBObjectGetResponse) error {
-	// o, err := h.store.Get(req.Name)
-	// if err != nil {
-	// 	return nil
-	// }
+	ctxLogger := mlog.GetMethodLogger(logger, "handlers.Get")
 
-	// res.EncodingGroupID = o.EncGrp
-	// res.VolumeID = o.Vol
-	// res.DsID = o.Node
+	o, code := h.getVersion(ctxLogger, req.Bucket, req.Name, req.VersionID)
+	res.S3ErrCode = code
+	if o != nil {
+		res.VersionID = o.VersionID
+		res.DeleteMarker = o.DeleteMarker
+	}
+	if code != s3.ErrNone {
+		return nil
+	}
 
-	// return nil
+	res.Parts = rpcParts(o.Locations())
+	res.Size = o.Size
+	res.ETag = o.ETag
+	res.LastModified = o.LastModified
+	res.ContentType = o.ContentType
+	res.Headers = o.Headers
+	res.Metadata = o.Metadata
+	res.ACL = o.ACL
+	res.Encryption = nilrpc.MOBEncryption(o.Encryption)
+	res.Tags = o.Tags
+	res.Lock = o.Lock
+
+	return nil
+}
+
+// Head returns the attributes of the requested object.
+func (h *handlers) Head(req *nilrpc.MOBObjectHeadRequest, res *nilrpc.MOBObjectHeadResponse) error {
+	ctxLogger := mlog.GetMethodLogger(logger, "handlers.Head")
+
+	o, code := h.getVersion(ctxLogger, req.Bucket, req.Name, req.VersionID)
+	res.S3ErrCode = code
+	if o != nil {
+		res.VersionID = o.VersionID
+		res.DeleteMarker = o.DeleteMarker
+	}
+	if code != s3.ErrNone {
+		return nil
+	}
+
+	res.Size = o.Size
+	res.ETag = o.ETag
+	res.LastModified = o.LastModified
+	res.ContentType = o.ContentType
+	res.Headers = o.Headers
+	res.Metadata = o.Metadata
+	res.ACL = o.ACL
+	res.Encryption = nilrpc.MOBEncryption(o.Encryption)
+	res.Tags = o.Tags
+	res.Lock = o.Lock
+
+	return nil
+}
+
+// SetACL replaces the canned acl of the requested version of the object.
+func (h *handlers) SetACL(req *nilrpc.MOBSetACLRequest, res *nilrpc.MOBSetACLResponse) error {
+	ctxLogger := mlog.GetMethodLogger(logger, "handlers.SetACL")
+
+	o, err := h.store.SetACL(req.Bucket, req.Name, req.VersionID, req.ACL)
+	switch err {
+	case nil:
+	case ErrNoSuchBucket:
+		res.S3ErrCode = s3.ErrNoSuchBucket
+		return nil
+	case ErrNotExist:
+		res.S3ErrCode = s3.ErrNoSuchKey
+		return nil
+	case ErrNoSuchVersion:
+		res.S3ErrCode = s3.ErrNoSuchVersion
+		return nil
+	default:
+		ctxLogger.Error(err)
+		res.S3ErrCode = s3.ErrInternalError
+		return nil
+	}
+
+	res.VersionID = o.VersionID
+	// The delete marker does not have the acl.
+	if o.DeleteMarker {
+		res.S3ErrCode = s3.ErrMethodNotAllowed
+		if req.VersionID == "" {
+			res.S3ErrCode = s3.ErrNoSuchKey
+		}
+		return nil
+	}
+
+	res.S3ErrCode = s3.ErrNone
+	return nil
+}
+
+// SetTags replaces the tags of the requested version of the object. The
+// empty tags remove the tags.
+func (h *handlers) SetTags(req *nilrpc.MOBSetTagsRequest, res *nilrpc.MOBSetTagsResponse) error {
+	ctxLogger := mlog.GetMethodLogger(logger, "handlers.SetTags")
+
+	o, err := h.store.SetTags(req.Bucket, req.Name, req.VersionID, req.Tags)
+	switch err {
+	case nil:
+	case ErrNoSuchBucket:
+		res.S3ErrCode = s3.ErrNoSuchBucket
+		return nil
+	case ErrNotExist:
+		res.S3ErrCode = s3.ErrNoSuchKey
+		return nil
+	case ErrNoSuchVersion:
+		res.S3ErrCode = s3.ErrNoSuchVersion
+		return nil
+	default:
+		ctxLogger.Error(err)
+		res.S3ErrCode = s3.ErrInternalError
+		return nil
+	}
+
+	res.VersionID = o.VersionID
+	// The delete marker does not have the tags.
+	if o.DeleteMarker {
+		res.S3ErrCode = s3.ErrMethodNotAllowed
+		if req.VersionID == "" {
+			res.S3ErrCode = s3.ErrNoSuchKey
+		}
+		return nil
+	}
+
+	res.S3ErrCode = s3.ErrNone
+	return nil
+}
+
+// SetRetention replaces the retention of the requested version of the
+// object. The retention in effect can only be extended unless the
+// governance mode is bypassed.
+func (h *handlers) SetRetention(req *nilrpc.MOBSetRetentionRequest, res *nilrpc.MOBSetRetentionResponse) error {
+	ctxLogger := mlog.GetMethodLogger(logger, "handlers.SetRetention")
+
+	o, err := h.store.SetRetention(req.Bucket, req.Name, req.VersionID, req.Mode, req.RetainUntil, req.BypassGovernance)
+	switch err {
+	case nil:
+	case ErrNoSuchBucket:
+		res.S3ErrCode = s3.ErrNoSuchBucket
+		return nil
+	case ErrNotExist:
+		res.S3ErrCode = s3.ErrNoSuchKey
+		return nil
+	case ErrNoSuchVersion:
+		res.S3ErrCode = s3.ErrNoSuchVersion
+		return nil
+	case ErrLocked:
+		res.S3ErrCode = s3.ErrAccessDenied
+		return nil
+	default:
+		ctxLogger.Error(err)
+		res.S3ErrCode = s3.ErrInternalError
+		return nil
+	}
+
+	res.VersionID = o.VersionID
+	// The delete marker does not have the retention.
+	if o.DeleteMarker {
+		res.S3ErrCode = s3.ErrMethodNotAllowed
+		if req.VersionID == "" {
+			res.S3ErrCode = s3.ErrNoSuchKey
+		}
+		return nil
+	}
+
+	res.S3ErrCode = s3.ErrNone
+	return nil
+}
+
+// SetLegalHold turns the legal hold of the requested version of the
+// object on or off.
+func (h *handlers) SetLegalHold(req *nilrpc.MOBSetLegalHoldRequest, res *nilrpc.MOBSetLegalHoldResponse) error {
+	ctxLogger := mlog.GetMethodLogger(logger, "handlers.SetLegalHold")
+
+	o, err := h.store.SetLegalHold(req.Bucket, req.Name, req.VersionID, req.LegalHold)
+	switch err {
+	case nil:
+	case ErrNoSuchBucket:
+		res.S3ErrCode = s3.ErrNoSuchBucket
+		return nil
+	case ErrNotExist:
+		res.S3ErrCode = s3.ErrNoSuchKey
+		return nil
+	case ErrNoSuchVersion:
+		res.S3ErrCode = s3.ErrNoSuchVersion
+		return nil
+	default:
+		ctxLogger.Error(err)
+		res.S3ErrCode = s3.ErrInternalError
+		return nil
+	}
+
+	res.VersionID = o.VersionID
+	// The delete marker does not have the legal hold.
+	if o.DeleteMarker {
+		res.S3ErrCode = s3.ErrMethodNotAllowed
+		if req.VersionID == "" {
+			res.S3ErrCode = s3.ErrNoSuchKey
+		}
+		return nil
+	}
+
+	res.S3ErrCode = s3.ErrNone
+	return nil
+}
+
+// getVersion returns the requested version of the object. The delete
+// marker is returned with the error, since it is not the object itself
+// but tells the client the object is deleted.
+func (h *handlers) getVersion(ctxLogger *logrus.Entry, bucket, name, versionID string) (*ObjInfo, s3.ErrorCode) {
+	o, err := h.store.Get(bucket, name, versionID)
+	switch err {
+	case nil:
+	case ErrNoSuchBucket:
+		return nil, s3.ErrNoSuchBucket
+	case ErrNotExist:
+		return nil, s3.ErrNoSuchKey
+	case ErrNoSuchVersion:
+		return nil, s3.ErrNoSuchVersion
+	default:
+		ctxLogger.Error(err)
+		return nil, s3.ErrInternalError
+	}
+
+	if o.DeleteMarker {
+		if versionID == "" {
+			return o, s3.ErrNoSuchKey
+		}
+		return o, s3.ErrMethodNotAllowed
+	}
+
+	return o, s3.ErrNone
+}
+
+// Delete deletes the version of the object and returns the location of
+// the deleted object data.
+func (h *handlers) Delete(req *nilrpc.MOBObjectDeleteRequest, res *nilrpc.MOBObjectDeleteResponse) error {
+	ctxLogger := mlog.GetMethodLogger(logger, "handlers.Delete")
+
+	o, obsolete, err := h.store.Delete(req.Bucket, req.Name, req.VersionID, req.BypassGovernance)
+	switch err {
+	case nil:
+		res.S3ErrCode = s3.ErrNone
+	case ErrNoSuchBucket:
+		res.S3ErrCode = s3.ErrNoSuchBucket
+		return nil
+	case ErrNotExist:
+		res.S3ErrCode = s3.ErrNoSuchKey
+		return nil
+	case ErrNoSuchVersion:
+		res.S3ErrCode = s3.ErrNoSuchVersion
+		return nil
+	case ErrLocked:
+		res.S3ErrCode = s3.ErrAccessDenied
+		return nil
+	default:
+		ctxLogger.Error(err)
+		res.S3ErrCode = s3.ErrInternalError
+		return nil
+	}
+
+	res.VersionID = o.VersionID
+	res.DeleteMarker = o.DeleteMarker
+	res.Parts = rpcParts(obsolete)
+
+	return nil
+}
+
+// DeleteObjects deletes the requested objects at once and returns the
+// location of the deleted objects.
+func (h *handlers) DeleteObjects(req *nilrpc.MOBDeleteObjectsRequest, res *nilrpc.MOBDeleteObjectsResponse) error {
+	ctxLogger := mlog.GetMethodLogger(logger, "handlers.DeleteObjects")
+
+	objs := make([]*ObjInfo, len(req.Objects))
+	for i, o := range req.Objects {
+		objs[i] = &ObjInfo{Name: o.Name, Bucket: req.Bucket, VersionID: o.VersionID}
+	}
+
+	deleted, obsolete, err := h.store.DeleteObjects(req.Bucket, objs, req.BypassGovernance)
+	switch err {
+	case nil:
+		res.S3ErrCode = s3.ErrNone
+	case ErrNoSuchBucket:
+		res.S3ErrCode = s3.ErrNoSuchBucket
+		return nil
+	default:
+		ctxLogger.Error(err)
+		res.S3ErrCode = s3.ErrInternalError
+		return nil
+	}
+
+	res.Deleted = make([]nilrpc.MOBDeletedObject, len(deleted))
+	for i, d := range deleted {
+		if d.Err == ErrLocked {
+			res.Deleted[i] = nilrpc.MOBDeletedObject{
+				S3ErrCode: s3.ErrAccessDenied,
+				VersionID: req.Objects[i].VersionID,
+			}
+			continue
+		}
+		// The object which does not exist is regarded as deleted.
+		if d.Object == nil {
+			res.Deleted[i].VersionID = req.Objects[i].VersionID
+			continue
+		}
+		res.Deleted[i] = nilrpc.MOBDeletedObject{
+			VersionID:    d.Object.VersionID,
+			DeleteMarker: d.Object.DeleteMarker,
+		}
+	}
+	res.Parts = rpcParts(obsolete)
+
+	return nil
+}
+
+// GetDataLocks returns the object locks of the versions which own the data
+// in the ds. The ds refuses to delete the data of the locked versions.
+func (h *handlers) GetDataLocks(req *nilrpc.MOBGetDataLocksRequest, res *nilrpc.MOBGetDataLocksResponse) error {
+	ctxLogger := mlog.GetMethodLogger(logger, "handlers.GetDataLocks")
+
+	locks, err := h.store.DataLocks(req.Bucket, req.ObjectIDs)
+	switch err {
+	case nil:
+	case ErrNoSuchBucket:
+		res.S3ErrCode = s3.ErrNoSuchBucket
+		return nil
+	default:
+		ctxLogger.Error(err)
+		res.S3ErrCode = s3.ErrInternalError
+		return nil
+	}
+
+	res.S3ErrCode = s3.ErrNone
+	res.Locks = locks
 	return nil
 }
 
@@ -74,6 +542,23 @@ func (h *handlers) SetChunk(req *nilrpc.MOBSetChunkRequest, res *nilrpc.MOBSetCh
 type Handlers interface {
 	Put(req *nilrpc.MOBObjectPutRequest, res *nilrpc.MOBObjectPutResponse) error
 	Get(req *nilrpc.MOBObjectGetRequest, res *nilrpc.MOBObjectGetResponse) error
+	Head(req *nilrpc.MOBObjectHeadRequest, res *nilrpc.MOBObjectHeadResponse) error
+	SetACL(req *nilrpc.MOBSetACLRequest, res *nilrpc.MOBSetACLResponse) error
+	SetTags(req *nilrpc.MOBSetTagsRequest, res *nilrpc.MOBSetTagsResponse) error
+	SetRetention(req *nilrpc.MOBSetRetentionRequest, res *nilrpc.MOBSetRetentionResponse) error
+	SetLegalHold(req *nilrpc.MOBSetLegalHoldRequest, res *nilrpc.MOBSetLegalHoldResponse) error
+	Delete(req *nilrpc.MOBObjectDeleteRequest, res *nilrpc.MOBObjectDeleteResponse) error
+	DeleteObjects(req *nilrpc.MOBDeleteObjectsRequest, res *nilrpc.MOBDeleteObjectsResponse) error
+	GetDataLocks(req *nilrpc.MOBGetDataLocksRequest, res *nilrpc.MOBGetDataLocksResponse) error
+	List(req *nilrpc.MOBObjectListRequest, res *nilrpc.MOBObjectListResponse) error
+	ListVersions(req *nilrpc.MOBListVersionsRequest, res *nilrpc.MOBListVersionsResponse) error
+	CreateUpload(req *nilrpc.MOBCreateUploadRequest, res *nilrpc.MOBCreateUploadResponse) error
+	GetUpload(req *nilrpc.MOBGetUploadRequest, res *nilrpc.MOBGetUploadResponse) error
+	PutPart(req *nilrpc.MOBPutPartRequest, res *nilrpc.MOBPutPartResponse) error
+	CompleteUpload(req *nilrpc.MOBCompleteUploadRequest, res *nilrpc.MOBCompleteUploadResponse) error
+	AbortUpload(req *nilrpc.MOBAbortUploadRequest, res *nilrpc.MOBAbortUploadResponse) error
+	ListParts(req *nilrpc.MOBListPartsRequest, res *nilrpc.MOBListPartsResponse) error
+	ListUploads(req *nilrpc.MOBListUploadsRequest, res *nilrpc.MOBListUploadsResponse) error
 	GetChunk(req *nilrpc.MOBGetChunkRequest, res *nilrpc.MOBGetChunkResponse) error
 	SetChunk(req *nilrpc.MOBSetChunkRequest, res *nilrpc.MOBSetChunkResponse) error
 }
@@ -0,0 +1,273 @@
+package object
+
+import (
+	"encoding/base64"
+	"math"
+	"strings"
+
+	"github.com/chanyoung/nil/pkg/nilrpc"
+	"github.com/chanyoung/nil/pkg/s3"
+	"github.com/chanyoung/nil/pkg/util/mlog"
+)
+
+const (
+	// maxListKeys is the maximum number of entries in a single list response.
+	maxListKeys = 1000
+
+	// listBatchSize is the number of objects read from the store at once.
+	listBatchSize = 1000
+)
+
+// List returns the objects and the common prefixes of the bucket in name
+// order. Objects whose names share the same prefix up to the delimiter are
+// rolled up into one common prefix, which counts as a single entry.
+func (h *handlers) List(req *nilrpc.MOBObjectListRequest, res *nilrpc.MOBObjectListResponse) error {
+	ctxLogger := mlog.GetMethodLogger(logger, "handlers.List")
+
+	maxKeys := req.MaxKeys
+	if maxKeys < 0 || maxKeys > maxListKeys {
+		maxKeys = maxListKeys
+	}
+
+	// from is the smallest name which is not listed yet.
+	from := req.Prefix
+	if req.Token != "" {
+		token, err := decodeListToken(req.Token)
+		if err != nil {
+			res.S3ErrCode = s3.ErrInvalidArgument
+			return nil
+		}
+		from = token
+	} else if req.StartAfter != "" {
+		from = nameAfter(req.StartAfter, req.Prefix, req.Delimiter)
+	}
+	if from < req.Prefix {
+		from = req.Prefix
+	}
+
+	res.S3ErrCode = s3.ErrNone
+	res.Objects = make([]nilrpc.MOBObjectEntry, 0)
+	res.CommonPrefixes = make([]string, 0)
+
+	count := 0
+	for {
+		objs, err := h.store.List(req.Bucket, req.Prefix, from, listBatchSize)
+		switch err {
+		case nil:
+		case ErrNoSuchBucket:
+			res.S3ErrCode = s3.ErrNoSuchBucket
+			return nil
+		default:
+			ctxLogger.Error(err)
+			res.S3ErrCode = s3.ErrInternalError
+			return nil
+		}
+
+		for _, o := range objs {
+			// Skip the rest of the rolled up common prefix.
+			if o.Name < from {
+				continue
+			}
+
+			// Found one more entry than requested.
+			if count == maxKeys {
+				res.IsTruncated = maxKeys > 0
+				if res.IsTruncated {
+					res.NextToken = encodeListToken(from)
+				}
+				return nil
+			}
+			count++
+
+			if p := commonPrefix(o.Name, req.Prefix, req.Delimiter); p != "" {
+				res.CommonPrefixes = append(res.CommonPrefixes, p)
+
+				next, ok := PrefixSuccessor(p)
+				if !ok {
+					return nil
+				}
+				from = next
+				continue
+			}
+
+			res.Objects = append(res.Objects, nilrpc.MOBObjectEntry{
+				Name:         o.Name,
+				Size:         o.Size,
+				ETag:         o.ETag,
+				LastModified: o.LastModified,
+			})
+			from = o.Name + "\x00"
+		}
+
+		if len(objs) < listBatchSize {
+			return nil
+		}
+	}
+}
+
+// nameAfter returns the smallest name which comes after the marker. If the
+// marker is a common prefix, the names rolled up into it are skipped too.
+func nameAfter(marker, prefix, delimiter string) string {
+	if isCommonPrefix(marker, prefix, delimiter) {
+		if next, ok := PrefixSuccessor(marker); ok {
+			return next
+		}
+	}
+	return marker + "\x00"
+}
+
+// isCommonPrefix returns true if the marker is the common prefix of the
+// listing, which is given back to continue the listing after it.
+func isCommonPrefix(marker, prefix, delimiter string) bool {
+	return marker != "" &&
+		strings.HasPrefix(marker, prefix) &&
+		commonPrefix(marker, prefix, delimiter) == marker
+}
+
+// commonPrefix returns the prefix of the name up to the first delimiter
+// after the listing prefix. It returns empty string if the name does not
+// have to be rolled up.
+func commonPrefix(name, prefix, delimiter string) string {
+	if delimiter == "" {
+		return ""
+	}
+
+	i := strings.Index(name[len(prefix):], delimiter)
+	if i < 0 {
+		return ""
+	}
+
+	return name[:len(prefix)+i+len(delimiter)]
+}
+
+// PrefixSuccessor returns the smallest string which is greater than every
+// string that starts with the given prefix. It returns false if there is no
+// such string, that is the prefix is empty or consists of only 0xff bytes.
+func PrefixSuccessor(prefix string) (string, bool) {
+	b := []byte(prefix)
+	for i := len(b) - 1; i >= 0; i-- {
+		if b[i] != 0xff {
+			b[i]++
+			return string(b[:i+1]), true
+		}
+	}
+	return "", false
+}
+
+// encodeListToken makes an opaque continuation token from the position
+// where the next listing starts.
+func encodeListToken(from string) string {
+	return base64.URLEncoding.EncodeToString([]byte(from))
+}
+
+// decodeListToken returns the position of the continuation token.
+func decodeListToken(token string) (string, error) {
+	b, err := base64.URLEncoding.DecodeString(token)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// ListVersions returns the versions of the objects in the bucket in order of
+// the name and from the newest version. The delete markers are listed too.
+// Like listing objects, the names which share the same prefix up to the
+// delimiter are rolled up into one common prefix.
+func (h *handlers) ListVersions(req *nilrpc.MOBListVersionsRequest, res *nilrpc.MOBListVersionsResponse) error {
+	ctxLogger := mlog.GetMethodLogger(logger, "handlers.ListVersions")
+
+	maxKeys := req.MaxKeys
+	if maxKeys < 0 || maxKeys > maxListKeys {
+		maxKeys = maxListKeys
+	}
+
+	// The listing starts after the version of afterName and afterSeq. The
+	// versions of the same name are ordered from the newest, so the zero
+	// sequence skips all versions of the name.
+	afterName, afterSeq := req.KeyMarker, int64(0)
+	if req.KeyMarker != "" && req.VersionIDMarker != "" {
+		o, err := h.store.Get(req.Bucket, req.KeyMarker, req.VersionIDMarker)
+		switch err {
+		case nil:
+			afterSeq = o.Seq
+		case ErrNotExist, ErrNoSuchVersion:
+			res.S3ErrCode = s3.ErrInvalidArgument
+			return nil
+		case ErrNoSuchBucket:
+			res.S3ErrCode = s3.ErrNoSuchBucket
+			return nil
+		default:
+			ctxLogger.Error(err)
+			res.S3ErrCode = s3.ErrInternalError
+			return nil
+		}
+	} else if isCommonPrefix(req.KeyMarker, req.Prefix, req.Delimiter) {
+		if next, ok := PrefixSuccessor(req.KeyMarker); ok {
+			afterName, afterSeq = next, math.MaxInt64
+		}
+	}
+	if afterName < req.Prefix {
+		afterName, afterSeq = req.Prefix, math.MaxInt64
+	}
+
+	res.S3ErrCode = s3.ErrNone
+	res.Versions = make([]nilrpc.MOBVersionEntry, 0)
+	res.CommonPrefixes = make([]string, 0)
+
+	count := 0
+	for {
+		objs, err := h.store.ListVersions(req.Bucket, req.Prefix, afterName, afterSeq, listBatchSize)
+		switch err {
+		case nil:
+		case ErrNoSuchBucket:
+			res.S3ErrCode = s3.ErrNoSuchBucket
+			return nil
+		default:
+			ctxLogger.Error(err)
+			res.S3ErrCode = s3.ErrInternalError
+			return nil
+		}
+
+		for _, o := range objs {
+			// Skip the rest of the rolled up common prefix.
+			if o.Name < afterName {
+				continue
+			}
+
+			// Found one more entry than requested.
+			if count == maxKeys {
+				res.IsTruncated = maxKeys > 0
+				return nil
+			}
+			count++
+
+			if p := commonPrefix(o.Name, req.Prefix, req.Delimiter); p != "" {
+				res.CommonPrefixes = append(res.CommonPrefixes, p)
+				res.NextKeyMarker, res.NextVersionIDMarker = p, ""
+
+				next, ok := PrefixSuccessor(p)
+				if !ok {
+					return nil
+				}
+				afterName, afterSeq = next, math.MaxInt64
+				continue
+			}
+
+			res.Versions = append(res.Versions, nilrpc.MOBVersionEntry{
+				Name:         o.Name,
+				VersionID:    o.VersionID,
+				IsLatest:     o.Latest,
+				DeleteMarker: o.DeleteMarker,
+				Size:         o.Size,
+				ETag:         o.ETag,
+				LastModified: o.LastModified,
+			})
+			res.NextKeyMarker, res.NextVersionIDMarker = o.Name, o.VersionID
+			afterName, afterSeq = o.Name, o.Seq
+		}
+
+		if len(objs) < listBatchSize {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,288 @@
+package object
+
+import (
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chanyoung/nil/pkg/nilrpc"
+	"github.com/chanyoung/nil/pkg/s3"
+	"github.com/chanyoung/nil/pkg/util/mlog"
+)
+
+func TestMain(m *testing.M) {
+	mlog.Init("stderr")
+	os.Exit(m.Run())
+}
+
+// memStore is a Repository which keeps the object names and the versions
+// in memory.
+type memStore struct {
+	names    []string
+	versions []*ObjInfo
+}
+
+func (m *memStore) Put(o *ObjInfo) ([]ObjPart, error) { return nil, nil }
+func (m *memStore) Create(o *ObjInfo) error           { return nil }
+func (m *memStore) Get(bucket, name, versionID string) (*ObjInfo, error) {
+	for _, v := range m.versions {
+		if v.Name == name && v.VersionID == versionID {
+			return v, nil
+		}
+	}
+	return nil, ErrNoSuchVersion
+}
+func (m *memStore) SetACL(bucket, name, versionID, acl string) (*ObjInfo, error) {
+	return nil, ErrNotExist
+}
+func (m *memStore) SetTags(bucket, name, versionID string, tags map[string]string) (*ObjInfo, error) {
+	return nil, ErrNotExist
+}
+func (m *memStore) SetRetention(bucket, name, versionID, mode string, until time.Time, bypassGovernance bool) (*ObjInfo, error) {
+	return nil, ErrNotExist
+}
+func (m *memStore) SetLegalHold(bucket, name, versionID string, on bool) (*ObjInfo, error) {
+	return nil, ErrNotExist
+}
+func (m *memStore) Delete(bucket, name, versionID string, bypassGovernance bool) (*ObjInfo, []ObjPart, error) {
+	return nil, nil, ErrNotExist
+}
+func (m *memStore) DeleteObjects(bucket string, objs []*ObjInfo, bypassGovernance bool) ([]DeleteResult, []ObjPart, error) {
+	return make([]DeleteResult, len(objs)), nil, nil
+}
+func (m *memStore) DataLocks(bucket string, oids []string) (map[string]s3.ObjectLock, error) {
+	return map[string]s3.ObjectLock{}, nil
+}
+func (m *memStore) ListVersions(bucket, prefix, afterName string, afterSeq int64, limit int) ([]*ObjInfo, error) {
+	sort.Slice(m.versions, func(i, j int) bool {
+		a, b := m.versions[i], m.versions[j]
+		return a.Name < b.Name || (a.Name == b.Name && a.Seq > b.Seq)
+	})
+	objs := make([]*ObjInfo, 0)
+	for _, v := range m.versions {
+		if len(objs) == limit {
+			break
+		}
+		after := v.Name > afterName || (v.Name == afterName && v.Seq < afterSeq)
+		if after && strings.HasPrefix(v.Name, prefix) {
+			objs = append(objs, v)
+		}
+	}
+	return objs, nil
+}
+
+func (m *memStore) CreateUpload(u *UploadInfo) error { return nil }
+func (m *memStore) GetUpload(bucket, name, uploadID string) (*UploadInfo, error) {
+	return nil, ErrNoSuchUpload
+}
+func (m *memStore) PutPart(bucket, name, uploadID string, p *ObjPart) ([]ObjPart, error) {
+	return nil, ErrNoSuchUpload
+}
+func (m *memStore) ListParts(u *UploadInfo, marker, limit int) ([]*ObjPart, error) {
+	return nil, nil
+}
+func (m *memStore) CompleteUpload(uploadID string, o *ObjInfo) ([]ObjPart, error) {
+	return nil, ErrNoSuchUpload
+}
+func (m *memStore) AbortUpload(bucket, name, uploadID string) ([]ObjPart, error) {
+	return nil, ErrNoSuchUpload
+}
+func (m *memStore) ListUploads(bucket, prefix, afterName string, afterSeq int64, limit int) ([]*UploadInfo, error) {
+	return nil, nil
+}
+
+func (m *memStore) List(bucket, prefix, from string, limit int) ([]*ObjInfo, error) {
+	if bucket != "bucket" {
+		return nil, ErrNoSuchBucket
+	}
+
+	sort.Strings(m.names)
+	objs := make([]*ObjInfo, 0)
+	for _, n := range m.names {
+		if len(objs) == limit {
+			break
+		}
+		if n >= from && strings.HasPrefix(n, prefix) {
+			objs = append(objs, &ObjInfo{Name: n})
+		}
+	}
+	return objs, nil
+}
+
+func TestList(t *testing.T) {
+	h := NewHandlers(&memStore{names: []string{
+		"a", "b/1", "b/2", "b/c/3", "c", "d/4",
+	}}).(*handlers)
+
+	testCases := []struct {
+		req       nilrpc.MOBObjectListRequest
+		objects   []string
+		prefixes  []string
+		truncated bool
+	}{
+		{nilrpc.MOBObjectListRequest{MaxKeys: maxListKeys}, []string{"a", "b/1", "b/2", "b/c/3", "c", "d/4"}, nil, false},
+		{nilrpc.MOBObjectListRequest{Delimiter: "/", MaxKeys: maxListKeys}, []string{"a", "c"}, []string{"b/", "d/"}, false},
+		{nilrpc.MOBObjectListRequest{Prefix: "b/", Delimiter: "/", MaxKeys: maxListKeys}, []string{"b/1", "b/2"}, []string{"b/c/"}, false},
+		{nilrpc.MOBObjectListRequest{StartAfter: "b/2", MaxKeys: maxListKeys}, []string{"b/c/3", "c", "d/4"}, nil, false},
+		{nilrpc.MOBObjectListRequest{Delimiter: "/", MaxKeys: 2}, []string{"a"}, []string{"b/"}, true},
+		{nilrpc.MOBObjectListRequest{MaxKeys: 0}, nil, nil, false},
+	}
+
+	for i, c := range testCases {
+		c.req.Bucket = "bucket"
+
+		res := &nilrpc.MOBObjectListResponse{}
+		h.List(&c.req, res)
+		if res.S3ErrCode != s3.ErrNone {
+			t.Fatalf("case %d: unexpected error %d", i, res.S3ErrCode)
+		}
+
+		var objects []string
+		for _, o := range res.Objects {
+			objects = append(objects, o.Name)
+		}
+		var prefixes []string
+		prefixes = append(prefixes, res.CommonPrefixes...)
+
+		if !reflect.DeepEqual(objects, c.objects) {
+			t.Errorf("case %d: expected objects %v, got %v", i, c.objects, objects)
+		}
+		if !reflect.DeepEqual(prefixes, c.prefixes) {
+			t.Errorf("case %d: expected prefixes %v, got %v", i, c.prefixes, prefixes)
+		}
+		if res.IsTruncated != c.truncated {
+			t.Errorf("case %d: expected truncated %v, got %v", i, c.truncated, res.IsTruncated)
+		}
+	}
+}
+
+func TestListContinuation(t *testing.T) {
+	h := NewHandlers(&memStore{names: []string{
+		"a", "b/1", "b/2", "c",
+	}}).(*handlers)
+
+	var names []string
+	req := &nilrpc.MOBObjectListRequest{Bucket: "bucket", Delimiter: "/", MaxKeys: 1}
+	for {
+		res := &nilrpc.MOBObjectListResponse{}
+		h.List(req, res)
+		for _, o := range res.Objects {
+			names = append(names, o.Name)
+		}
+		names = append(names, res.CommonPrefixes...)
+		if !res.IsTruncated {
+			break
+		}
+		req.Token = res.NextToken
+	}
+
+	if expected := []string{"a", "b/", "c"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected %v, got %v", expected, names)
+	}
+}
+
+func TestListNoSuchBucket(t *testing.T) {
+	h := NewHandlers(&memStore{}).(*handlers)
+
+	res := &nilrpc.MOBObjectListResponse{}
+	h.List(&nilrpc.MOBObjectListRequest{Bucket: "none"}, res)
+	if res.S3ErrCode != s3.ErrNoSuchBucket {
+		t.Errorf("expected no such bucket, got %d", res.S3ErrCode)
+	}
+}
+
+func TestListVersions(t *testing.T) {
+	h := NewHandlers(&memStore{versions: []*ObjInfo{
+		{Name: "a", VersionID: "a1", Seq: 1},
+		{Name: "a", VersionID: "a2", Seq: 4, Latest: true},
+		{Name: "b/1", VersionID: "b1", Seq: 2, Latest: true},
+		{Name: "c", VersionID: "c1", Seq: 3, Latest: true, DeleteMarker: true},
+	}}).(*handlers)
+
+	testCases := []struct {
+		req      nilrpc.MOBListVersionsRequest
+		versions []string
+		prefixes []string
+	}{
+		{nilrpc.MOBListVersionsRequest{MaxKeys: maxListKeys}, []string{"a2", "a1", "b1", "c1"}, nil},
+		{nilrpc.MOBListVersionsRequest{Delimiter: "/", MaxKeys: maxListKeys}, []string{"a2", "a1", "c1"}, []string{"b/"}},
+		{nilrpc.MOBListVersionsRequest{Prefix: "a", MaxKeys: maxListKeys}, []string{"a2", "a1"}, nil},
+		{nilrpc.MOBListVersionsRequest{KeyMarker: "a", MaxKeys: maxListKeys}, []string{"b1", "c1"}, nil},
+		{nilrpc.MOBListVersionsRequest{KeyMarker: "a", VersionIDMarker: "a2", MaxKeys: maxListKeys}, []string{"a1", "b1", "c1"}, nil},
+		{nilrpc.MOBListVersionsRequest{KeyMarker: "b/", Delimiter: "/", MaxKeys: maxListKeys}, []string{"c1"}, nil},
+	}
+
+	for i, c := range testCases {
+		c.req.Bucket = "bucket"
+
+		res := &nilrpc.MOBListVersionsResponse{}
+		h.ListVersions(&c.req, res)
+		if res.S3ErrCode != s3.ErrNone {
+			t.Fatalf("case %d: unexpected error %d", i, res.S3ErrCode)
+		}
+
+		var versions []string
+		for _, v := range res.Versions {
+			versions = append(versions, v.VersionID)
+		}
+		var prefixes []string
+		prefixes = append(prefixes, res.CommonPrefixes...)
+
+		if !reflect.DeepEqual(versions, c.versions) {
+			t.Errorf("case %d: expected versions %v, got %v", i, c.versions, versions)
+		}
+		if !reflect.DeepEqual(prefixes, c.prefixes) {
+			t.Errorf("case %d: expected prefixes %v, got %v", i, c.prefixes, prefixes)
+		}
+	}
+}
+
+func TestListVersionsContinuation(t *testing.T) {
+	h := NewHandlers(&memStore{versions: []*ObjInfo{
+		{Name: "a", VersionID: "a1", Seq: 1},
+		{Name: "a", VersionID: "a2", Seq: 3, Latest: true},
+		{Name: "b", VersionID: "b1", Seq: 2, Latest: true},
+	}}).(*handlers)
+
+	var versions []string
+	req := &nilrpc.MOBListVersionsRequest{Bucket: "bucket", MaxKeys: 1}
+	for {
+		res := &nilrpc.MOBListVersionsResponse{}
+		h.ListVersions(req, res)
+		for _, v := range res.Versions {
+			versions = append(versions, v.VersionID)
+		}
+		if !res.IsTruncated {
+			break
+		}
+		req.KeyMarker, req.VersionIDMarker = res.NextKeyMarker, res.NextVersionIDMarker
+	}
+
+	if expected := []string{"a2", "a1", "b1"}; !reflect.DeepEqual(versions, expected) {
+		t.Errorf("expected %v, got %v", expected, versions)
+	}
+}
+
+func TestPrefixSuccessor(t *testing.T) {
+	testCases := []struct {
+		prefix string
+		next   string
+		ok     bool
+	}{
+		{"", "", false},
+		{"a", "b", true},
+		{"a/", "a0", true},
+		{"a\xff", "b", true},
+		{"\xff\xff", "", false},
+	}
+
+	for _, c := range testCases {
+		next, ok := PrefixSuccessor(c.prefix)
+		if next != c.next || ok != c.ok {
+			t.Errorf("%q: expected (%q, %v), got (%q, %v)", c.prefix, c.next, c.ok, next, ok)
+		}
+	}
+}
@@ -0,0 +1,388 @@
+package object
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/chanyoung/nil/pkg/nilrpc"
+	"github.com/chanyoung/nil/pkg/s3"
+	"github.com/chanyoung/nil/pkg/util/mlog"
+	"github.com/chanyoung/nil/pkg/util/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// minPartSize is the minimum size of each part except the last one.
+	minPartSize = 5 * 1024 * 1024
+	// maxMultipartSize is the maximum size of the assembled object.
+	maxMultipartSize = 5 * 1024 * 1024 * 1024 * 1024
+	// maxParts is the maximum number of parts in a multipart upload.
+	maxParts = 10000
+)
+
+// UploadInfo holds the information of the multipart upload in progress.
+type UploadInfo struct {
+	ID     string
+	Name   string
+	Bucket string
+
+	// Seq is the sequence number which orders the uploads of the same name
+	// in the order of initiation.
+	Seq         int64
+	Initiated   time.Time
+	ContentType string
+	Headers     map[string]string
+	Metadata    map[string]string
+	ACL         string
+	Encryption  Encryption
+	Tags        map[string]string
+	Lock        s3.ObjectLock
+}
+
+// CreateUpload initiates a multipart upload and returns its id.
+func (h *handlers) CreateUpload(req *nilrpc.MOBCreateUploadRequest, res *nilrpc.MOBCreateUploadResponse) error {
+	ctxLogger := mlog.GetMethodLogger(logger, "handlers.CreateUpload")
+
+	u := &UploadInfo{
+		ID:          uuid.Gen(),
+		Name:        req.Name,
+		Bucket:      req.Bucket,
+		Initiated:   time.Now().UTC(),
+		ContentType: req.ContentType,
+		Headers:     req.Headers,
+		Metadata:    req.Metadata,
+		ACL:         req.ACL,
+		Encryption:  Encryption(req.Encryption),
+		Tags:        req.Tags,
+		Lock:        req.Lock,
+	}
+
+	switch err := h.store.CreateUpload(u); err {
+	case nil:
+		res.S3ErrCode = s3.ErrNone
+		res.UploadID = u.ID
+	case ErrNoSuchBucket:
+		res.S3ErrCode = s3.ErrNoSuchBucket
+	default:
+		ctxLogger.Error(err)
+		res.S3ErrCode = s3.ErrInternalError
+	}
+
+	return nil
+}
+
+// GetUpload returns the attributes of the multipart upload which are
+// required to upload the parts.
+func (h *handlers) GetUpload(req *nilrpc.MOBGetUploadRequest, res *nilrpc.MOBGetUploadResponse) error {
+	ctxLogger := mlog.GetMethodLogger(logger, "handlers.GetUpload")
+
+	u, err := h.store.GetUpload(req.Bucket, req.Name, req.UploadID)
+	if err != nil {
+		res.S3ErrCode = uploadErrCode(ctxLogger, err)
+		return nil
+	}
+
+	res.S3ErrCode = s3.ErrNone
+	res.Encryption = nilrpc.MOBEncryption(u.Encryption)
+
+	return nil
+}
+
+// PutPart records the location of the uploaded part. The part which is
+// uploaded before with the same number is replaced.
+func (h *handlers) PutPart(req *nilrpc.MOBPutPartRequest, res *nilrpc.MOBPutPartResponse) error {
+	ctxLogger := mlog.GetMethodLogger(logger, "handlers.PutPart")
+
+	obsolete, err := h.store.PutPart(req.Bucket, req.Name, req.UploadID, &ObjPart{
+		Number:       req.PartNumber,
+		EncGrp:       req.EncodingGroup,
+		Vol:          req.Volume,
+		Node:         req.DsID,
+		Oid:          req.ObjectID,
+		Size:         req.Size,
+		ETag:         req.ETag,
+		LastModified: time.Now().UTC(),
+	})
+
+	switch err {
+	case nil:
+		res.S3ErrCode = s3.ErrNone
+		res.Obsolete = rpcParts(obsolete)
+	case ErrNoSuchUpload:
+		res.S3ErrCode = s3.ErrNoSuchUpload
+	default:
+		ctxLogger.Error(err)
+		res.S3ErrCode = s3.ErrInternalError
+	}
+
+	return nil
+}
+
+// CompleteUpload assembles the given parts into the object. The etag of
+// the object is the md5 of the concatenated md5 of the parts followed by
+// the number of parts.
+func (h *handlers) CompleteUpload(req *nilrpc.MOBCompleteUploadRequest, res *nilrpc.MOBCompleteUploadResponse) error {
+	ctxLogger := mlog.GetMethodLogger(logger, "handlers.CompleteUpload")
+
+	u, err := h.store.GetUpload(req.Bucket, req.Name, req.UploadID)
+	if err != nil {
+		res.S3ErrCode = uploadErrCode(ctxLogger, err)
+		return nil
+	}
+
+	uploaded, err := h.store.ListParts(u, 0, 0)
+	if err != nil {
+		ctxLogger.Error(err)
+		res.S3ErrCode = s3.ErrInternalError
+		return nil
+	}
+	byNumber := make(map[int]*ObjPart, len(uploaded))
+	for _, p := range uploaded {
+		byNumber[p.Number] = p
+	}
+
+	o, code := assemble(u, req.Parts, byNumber)
+	if code != s3.ErrNone {
+		res.S3ErrCode = code
+		return nil
+	}
+
+	obsolete, err := h.store.CompleteUpload(req.UploadID, o)
+	if err != nil {
+		res.S3ErrCode = uploadErrCode(ctxLogger, err)
+		return nil
+	}
+
+	res.S3ErrCode = s3.ErrNone
+	res.ETag = o.ETag
+	res.VersionID = o.VersionID
+	res.Encryption = nilrpc.MOBEncryption(o.Encryption)
+	res.Obsolete = rpcParts(obsolete)
+
+	return nil
+}
+
+// assemble builds the object from the requested parts of the upload.
+func assemble(u *UploadInfo, requested []nilrpc.MOBCompletePart, uploaded map[int]*ObjPart) (*ObjInfo, s3.ErrorCode) {
+	if len(requested) == 0 || len(requested) > maxParts {
+		return nil, s3.ErrMalformedXML
+	}
+
+	o := &ObjInfo{
+		Name:         u.Name,
+		Bucket:       u.Bucket,
+		LastModified: time.Now().UTC(),
+		ContentType:  u.ContentType,
+		Headers:      u.Headers,
+		Metadata:     u.Metadata,
+		ACL:          u.ACL,
+		Encryption:   u.Encryption,
+		Tags:         u.Tags,
+		Lock:         u.Lock,
+		Parts:        make([]ObjPart, len(requested)),
+	}
+
+	digests := md5.New()
+	for i, r := range requested {
+		if i > 0 && r.PartNumber <= requested[i-1].PartNumber {
+			return nil, s3.ErrInvalidPartOrder
+		}
+
+		p, ok := uploaded[r.PartNumber]
+		if !ok || p.ETag != r.ETag {
+			return nil, s3.ErrInvalidPart
+		}
+		if i < len(requested)-1 && p.Size < minPartSize {
+			return nil, s3.ErrEntityTooSmall
+		}
+
+		sum, err := hex.DecodeString(p.ETag)
+		if err != nil {
+			return nil, s3.ErrInvalidPart
+		}
+		digests.Write(sum)
+
+		o.Parts[i] = *p
+		o.Parts[i].Offset = o.Size
+		o.Size += p.Size
+	}
+	if o.Size > maxMultipartSize {
+		return nil, s3.ErrEntityTooLarge
+	}
+
+	o.ETag = fmt.Sprintf("%s-%d", hex.EncodeToString(digests.Sum(nil)), len(requested))
+
+	return o, s3.ErrNone
+}
+
+// AbortUpload aborts the multipart upload and returns the location of the
+// uploaded parts.
+func (h *handlers) AbortUpload(req *nilrpc.MOBAbortUploadRequest, res *nilrpc.MOBAbortUploadResponse) error {
+	ctxLogger := mlog.GetMethodLogger(logger, "handlers.AbortUpload")
+
+	obsolete, err := h.store.AbortUpload(req.Bucket, req.Name, req.UploadID)
+	if err != nil {
+		res.S3ErrCode = uploadErrCode(ctxLogger, err)
+		return nil
+	}
+
+	res.S3ErrCode = s3.ErrNone
+	res.Obsolete = rpcParts(obsolete)
+
+	return nil
+}
+
+// ListParts returns the parts of the multipart upload in order.
+func (h *handlers) ListParts(req *nilrpc.MOBListPartsRequest, res *nilrpc.MOBListPartsResponse) error {
+	ctxLogger := mlog.GetMethodLogger(logger, "handlers.ListParts")
+
+	maxParts := req.MaxParts
+	if maxParts < 0 || maxParts > maxListKeys {
+		maxParts = maxListKeys
+	}
+
+	u, err := h.store.GetUpload(req.Bucket, req.Name, req.UploadID)
+	if err != nil {
+		res.S3ErrCode = uploadErrCode(ctxLogger, err)
+		return nil
+	}
+
+	res.S3ErrCode = s3.ErrNone
+	res.Parts = make([]nilrpc.MOBPartEntry, 0)
+	if maxParts == 0 {
+		return nil
+	}
+
+	// Read one more part to know whether the list is truncated.
+	parts, err := h.store.ListParts(u, req.Marker, maxParts+1)
+	if err != nil {
+		ctxLogger.Error(err)
+		res.S3ErrCode = s3.ErrInternalError
+		return nil
+	}
+	if len(parts) > maxParts {
+		parts = parts[:maxParts]
+		res.IsTruncated = true
+	}
+
+	for _, p := range parts {
+		res.Parts = append(res.Parts, nilrpc.MOBPartEntry{
+			PartNumber:   p.Number,
+			Size:         p.Size,
+			ETag:         p.ETag,
+			LastModified: p.LastModified,
+		})
+		res.NextMarker = p.Number
+	}
+
+	return nil
+}
+
+// ListUploads returns the multipart uploads in progress in order of the
+// name and the initiation. Like listing objects, the names which share the
+// same prefix up to the delimiter are rolled up into one common prefix.
+func (h *handlers) ListUploads(req *nilrpc.MOBListUploadsRequest, res *nilrpc.MOBListUploadsResponse) error {
+	ctxLogger := mlog.GetMethodLogger(logger, "handlers.ListUploads")
+
+	maxUploads := req.MaxUploads
+	if maxUploads < 0 || maxUploads > maxListKeys {
+		maxUploads = maxListKeys
+	}
+
+	// The listing starts after the upload of afterName and afterSeq.
+	afterName, afterSeq := req.KeyMarker, int64(math.MaxInt64)
+	if req.KeyMarker != "" && req.UploadIDMarker != "" {
+		u, err := h.store.GetUpload(req.Bucket, req.KeyMarker, req.UploadIDMarker)
+		switch err {
+		case nil:
+			afterSeq = u.Seq
+		case ErrNoSuchUpload:
+			res.S3ErrCode = s3.ErrInvalidArgument
+			return nil
+		default:
+			res.S3ErrCode = uploadErrCode(ctxLogger, err)
+			return nil
+		}
+	} else if isCommonPrefix(req.KeyMarker, req.Prefix, req.Delimiter) {
+		if next, ok := PrefixSuccessor(req.KeyMarker); ok {
+			afterName, afterSeq = next, 0
+		}
+	}
+	if afterName < req.Prefix {
+		afterName, afterSeq = req.Prefix, 0
+	}
+
+	res.S3ErrCode = s3.ErrNone
+	res.Uploads = make([]nilrpc.MOBUploadEntry, 0)
+	res.CommonPrefixes = make([]string, 0)
+
+	count := 0
+	for {
+		uploads, err := h.store.ListUploads(req.Bucket, req.Prefix, afterName, afterSeq, listBatchSize)
+		if err != nil {
+			res.S3ErrCode = uploadErrCode(ctxLogger, err)
+			return nil
+		}
+
+		for _, u := range uploads {
+			// Skip the rest of the rolled up common prefix.
+			if u.Name < afterName {
+				continue
+			}
+
+			// Found one more entry than requested.
+			if count == maxUploads {
+				res.IsTruncated = maxUploads > 0
+				return nil
+			}
+			count++
+
+			if p := commonPrefix(u.Name, req.Prefix, req.Delimiter); p != "" {
+				res.CommonPrefixes = append(res.CommonPrefixes, p)
+				res.NextKeyMarker, res.NextUploadIDMarker = p, ""
+
+				next, ok := PrefixSuccessor(p)
+				if !ok {
+					return nil
+				}
+				afterName, afterSeq = next, 0
+				continue
+			}
+
+			res.Uploads = append(res.Uploads, nilrpc.MOBUploadEntry{
+				Name:      u.Name,
+				UploadID:  u.ID,
+				Initiated: u.Initiated,
+			})
+			res.NextKeyMarker, res.NextUploadIDMarker = u.Name, u.ID
+			afterName, afterSeq = u.Name, u.Seq
+		}
+
+		if len(uploads) < listBatchSize {
+			return nil
+		}
+	}
+}
+
+// uploadErrCode converts the error of the multipart upload into the s3
+// error code. Unknown errors are logged as the internal error.
+func uploadErrCode(ctxLogger *logrus.Entry, err error) s3.ErrorCode {
+	switch err {
+	case nil:
+		return s3.ErrNone
+	case ErrNoSuchBucket:
+		return s3.ErrNoSuchBucket
+	case ErrNoSuchUpload:
+		return s3.ErrNoSuchUpload
+	case ErrInvalidPart:
+		return s3.ErrInvalidPart
+	case ErrLocked:
+		return s3.ErrAccessDenied
+	default:
+		ctxLogger.Error(err)
+		return s3.ErrInternalError
+	}
+}
@@ -0,0 +1,53 @@
+package object
+
+import (
+	"testing"
+
+	"github.com/chanyoung/nil/pkg/nilrpc"
+	"github.com/chanyoung/nil/pkg/s3"
+)
+
+func TestAssemble(t *testing.T) {
+	u := &UploadInfo{Name: "key", Bucket: "bucket"}
+	uploaded := map[int]*ObjPart{
+		1: {Number: 1, Oid: "a", Size: minPartSize, ETag: "0cc175b9c0f1b6a831c399e269772661"},
+		2: {Number: 2, Oid: "b", Size: 1, ETag: "92eb5ffee6ae2fec3ad71c777531578f"},
+		3: {Number: 3, Oid: "c", Size: 1, ETag: "4a8a08f09d37b73795649038408b5f33"},
+	}
+
+	o, code := assemble(u, []nilrpc.MOBCompletePart{
+		{PartNumber: 1, ETag: uploaded[1].ETag},
+		{PartNumber: 2, ETag: uploaded[2].ETag},
+	}, uploaded)
+	if code != s3.ErrNone {
+		t.Fatalf("expected no error, got %d", code)
+	}
+	if o.Size != minPartSize+1 {
+		t.Errorf("expected size %d, got %d", minPartSize+1, o.Size)
+	}
+	if o.Parts[1].Offset != minPartSize {
+		t.Errorf("expected offset %d, got %d", minPartSize, o.Parts[1].Offset)
+	}
+	if expected := "96e024ba2074fe77e8e965ba43a704be-2"; o.ETag != expected {
+		t.Errorf("expected etag %s, got %s", expected, o.ETag)
+	}
+
+	a := nilrpc.MOBCompletePart{PartNumber: 1, ETag: "0cc175b9c0f1b6a831c399e269772661"}
+	b := nilrpc.MOBCompletePart{PartNumber: 2, ETag: "92eb5ffee6ae2fec3ad71c777531578f"}
+	c := nilrpc.MOBCompletePart{PartNumber: 3, ETag: "4a8a08f09d37b73795649038408b5f33"}
+	testCases := []struct {
+		parts []nilrpc.MOBCompletePart
+		code  s3.ErrorCode
+	}{
+		{nil, s3.ErrMalformedXML},
+		{[]nilrpc.MOBCompletePart{a, a}, s3.ErrInvalidPartOrder},
+		{[]nilrpc.MOBCompletePart{{PartNumber: 1, ETag: b.ETag}}, s3.ErrInvalidPart},
+		{[]nilrpc.MOBCompletePart{{PartNumber: 4, ETag: b.ETag}}, s3.ErrInvalidPart},
+		{[]nilrpc.MOBCompletePart{b, c}, s3.ErrEntityTooSmall},
+	}
+	for i, tc := range testCases {
+		if _, code := assemble(u, tc.parts, uploaded); code != tc.code {
+			t.Errorf("case %d: expected error %d, got %d", i, tc.code, code)
+		}
+	}
+}
@@ -1,9 +1,121 @@
 package object
 
+import (
+	"errors"
+	"time"
+
+	"github.com/chanyoung/nil/pkg/s3"
+)
+
+var (
+	// ErrNoSuchBucket is used when the bucket of the object does not exist.
+	ErrNoSuchBucket = errors.New("no such bucket")
+
+	// ErrNotExist is used when there is no object with the given name.
+	ErrNotExist = errors.New("no such object")
+
+	// ErrNoSuchVersion is used when there is no version of the object with
+	// the given version id.
+	ErrNoSuchVersion = errors.New("no such version")
+
+	// ErrExist is used when the object to be created already exists.
+	ErrExist = errors.New("object already exists")
+
+	// ErrNoSuchUpload is used when there is no multipart upload with the
+	// given id.
+	ErrNoSuchUpload = errors.New("no such upload")
+
+	// ErrInvalidPart is used when the part to complete the multipart upload
+	// is not the one which is uploaded.
+	ErrInvalidPart = errors.New("invalid part")
+
+	// ErrLocked is used when the version to be removed, overwritten or
+	// released is protected by the object lock.
+	ErrLocked = errors.New("object is locked")
+)
+
+// NullVersion is the version id of the object which is put while the
+// versioning of the bucket is not enabled.
+const NullVersion = "null"
+
+// DeleteResult is the result of deleting one of the objects at once.
+type DeleteResult struct {
+	// Object is the removed version or the delete marker, which is nil if
+	// the object does not exist or is not deleted.
+	Object *ObjInfo
+	// Err is ErrLocked if the version is protected by the object lock.
+	Err error
+}
+
 // Repository provides access to object database.
 type Repository interface {
-	// Put(o *ObjInfo) error
-	// Get(name string) (*ObjInfo, error)
+	// Put records the object as the latest version and returns the data
+	// of the replaced null version. The version id of the object is set
+	// according to the versioning state of the bucket.
+	Put(o *ObjInfo) ([]ObjPart, error)
+	// Create records the object only if there is no object with the same
+	// name. It fails with ErrExist otherwise.
+	Create(o *ObjInfo) error
+	// Get returns the version of the object. The empty version id means
+	// the latest version, which can be a delete marker.
+	Get(bucket, name, versionID string) (*ObjInfo, error)
+	// SetACL replaces the canned acl of the version of the object and
+	// returns the version. The delete marker is returned unchanged.
+	SetACL(bucket, name, versionID, acl string) (*ObjInfo, error)
+	// SetTags replaces the tags of the version of the object and returns
+	// the version. The delete marker is returned unchanged.
+	SetTags(bucket, name, versionID string, tags map[string]string) (*ObjInfo, error)
+	// SetRetention replaces the retention of the version of the object and
+	// returns the version. It fails with ErrLocked if the retention in
+	// effect is shortened or removed, which is allowed only for the
+	// governance mode with bypassGovernance. The delete marker is returned
+	// unchanged.
+	SetRetention(bucket, name, versionID, mode string, until time.Time, bypassGovernance bool) (*ObjInfo, error)
+	// SetLegalHold turns the legal hold of the version of the object on or
+	// off and returns the version. The delete marker is returned unchanged.
+	SetLegalHold(bucket, name, versionID string, on bool) (*ObjInfo, error)
+	// Delete removes the version of the object. If the version is not
+	// given in the versioned bucket, it puts a delete marker instead. It
+	// returns the removed version or the delete marker, and the data to
+	// be deleted. It fails with ErrLocked if the version is protected by
+	// the object lock, where only the governance mode is bypassed with
+	// bypassGovernance.
+	Delete(bucket, name, versionID string, bypassGovernance bool) (*ObjInfo, []ObjPart, error)
+	// DeleteObjects deletes the objects at once as Delete. The name and
+	// the version id of the objects are given, and the results are
+	// returned in the same order. The object of the result is nil if the
+	// object does not exist.
+	DeleteObjects(bucket string, objs []*ObjInfo, bypassGovernance bool) ([]DeleteResult, []ObjPart, error)
+	// DataLocks returns the object locks of the versions which own the data
+	// stored as the object ids in the ds, keyed by the object id. The object
+	// id which no version owns is not in the map.
+	DataLocks(bucket string, oids []string) (map[string]s3.ObjectLock, error)
+	// List returns at most limit latest objects in name order whose names
+	// start with the prefix and are not less than from. The delete markers
+	// are not listed.
+	List(bucket, prefix, from string, limit int) ([]*ObjInfo, error)
+	// ListVersions returns at most limit versions in order of name and
+	// from the newest, which start with the prefix and come after the
+	// given name and sequence. The versions have their tags.
+	ListVersions(bucket, prefix, afterName string, afterSeq int64, limit int) ([]*ObjInfo, error)
+
+	CreateUpload(u *UploadInfo) error
+	GetUpload(bucket, name, uploadID string) (*UploadInfo, error)
+	// PutPart records the part and returns the data of the replaced part.
+	PutPart(bucket, name, uploadID string, p *ObjPart) ([]ObjPart, error)
+	// ListParts returns at most limit parts whose number is greater than
+	// marker in order.
+	ListParts(u *UploadInfo, marker, limit int) ([]*ObjPart, error)
+	// CompleteUpload records the object assembled from the parts of the
+	// upload and removes the upload. It returns the data of the parts which
+	// are not used and the data of the overwritten object.
+	CompleteUpload(uploadID string, o *ObjInfo) ([]ObjPart, error)
+	// AbortUpload removes the upload and returns the data of its parts.
+	AbortUpload(bucket, name, uploadID string) ([]ObjPart, error)
+	// ListUploads returns at most limit uploads in order of name and
+	// sequence, which start with the prefix and come after the given
+	// name and sequence.
+	ListUploads(bucket, prefix, afterName string, afterSeq int64, limit int) ([]*UploadInfo, error)
 	// GetChunk(eg cmap.ID) (cID string, err error)
 	// SetChunk(cID string, egID cmap.ID, status string) error
 }
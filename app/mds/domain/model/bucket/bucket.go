@@ -3,19 +3,46 @@ package bucket
 import (
 	"errors"
 	"strconv"
+	"time"
 )
 
 var (
 	// ErrDuplicateEntry is used when they try to save already existed.
 	ErrDuplicateEntry = errors.New("duplicated entry exists")
+
+	// ErrNotExist is used when there is no matched bucket with the search condition.
+	ErrNotExist = errors.New("no bucket match with the given condition")
+
+	// ErrNotEmpty is used when the bucket to be removed still has objects.
+	ErrNotEmpty = errors.New("bucket is not empty")
+
+	// ErrInternal is used when the internal error is occured.
+	ErrInternal = errors.New("internal error")
 )
 
 // Bucket is an entity of bucket.
 type Bucket struct {
-	ID     ID
-	Name   Name
-	User   ID
-	Region ID
+	ID         ID
+	Name       Name
+	User       ID
+	Region     ID
+	Created    time.Time
+	Versioning Versioning
+	// Lifecycle is the lifecycle configuration of the bucket in xml. It is
+	// empty if the bucket has no lifecycle rules.
+	Lifecycle string
+	// ACL is the canned acl of the bucket.
+	ACL string
+	// Policy is the bucket policy document in json. It is empty if the
+	// bucket has no policy.
+	Policy string
+	// CORS is the cors configuration of the bucket in xml. It is empty if
+	// the bucket has no cors rules.
+	CORS string
+	// ObjectLock is the object lock configuration of the bucket in xml. It
+	// is empty if the object lock is not enabled, and the bucket which has
+	// it is always versioned.
+	ObjectLock string
 }
 
 // ID is the ID of bucket, user, region.
@@ -32,7 +59,46 @@ func (n Name) String() string {
 	return string(n)
 }
 
+// Versioning is the versioning state of the bucket. The bucket which has
+// never been versioned has the empty state, and it can not go back to the
+// empty state once versioned.
+type Versioning string
+
+const (
+	// VersioningEnabled keeps every version of the objects.
+	VersioningEnabled Versioning = "Enabled"
+	// VersioningSuspended keeps the existing versions, but the new object
+	// replaces the null version.
+	VersioningSuspended Versioning = "Suspended"
+)
+
 // Repository provides to access bucket databse.
 type Repository interface {
+	FindByName(name Name, region ID) (*Bucket, error)
+	FindByUser(user ID) ([]*Bucket, error)
+	// FindByRegion returns the buckets in the region.
+	FindByRegion(region ID) ([]*Bucket, error)
+	// LockEmpty returns true if the bucket has neither objects nor
+	// multipart uploads in progress in the local region. The empty bucket
+	// is locked so that no objects or uploads are created in it, until it
+	// is deleted or unlocked.
+	LockEmpty(id ID) (bool, error)
+	// Unlock releases the lock of the bucket taken by LockEmpty.
+	Unlock(id ID) error
 	Save(*Bucket) error
+	// SetVersioning changes the versioning state of the bucket.
+	SetVersioning(id ID, v Versioning) error
+	// SetLifecycle replaces the lifecycle configuration of the bucket. The
+	// empty configuration removes the lifecycle rules.
+	SetLifecycle(id ID, lifecycle string) error
+	// SetACL replaces the canned acl of the bucket.
+	SetACL(id ID, acl string) error
+	// SetPolicy replaces the bucket policy. The empty policy removes it.
+	SetPolicy(id ID, policy string) error
+	// SetCORS replaces the cors configuration of the bucket. The empty
+	// configuration removes the cors rules.
+	SetCORS(id ID, cors string) error
+	// SetObjectLock replaces the object lock configuration of the bucket.
+	SetObjectLock(id ID, objectLock string) error
+	Delete(id ID) error
 }
@@ -0,0 +1,39 @@
+package masterkey
+
+import (
+	"crypto/rand"
+	"errors"
+)
+
+var (
+	// ErrNotExist is used when the master key is not created yet.
+	ErrNotExist = errors.New("master key does not exist")
+
+	// ErrInternal is used when the internal error is occured.
+	ErrInternal = errors.New("internal error")
+)
+
+// Size is the size of the master key in bytes.
+const Size = 32
+
+// Key is the master key of the cluster, which seals the data keys of the
+// objects encrypted by the server.
+type Key []byte
+
+// Gen generates a new random master key.
+func Gen() (Key, error) {
+	k := make(Key, Size)
+	if _, err := rand.Read(k); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// Repository provides access to the master key database.
+type Repository interface {
+	// Find returns the master key, or ErrNotExist if it is not created.
+	Find() (Key, error)
+	// Create saves the key as the master key if it does not exist yet.
+	// The key which is saved first is kept.
+	Create(Key) error
+}
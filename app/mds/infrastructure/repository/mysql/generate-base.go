@@ -1,7 +1,16 @@
 package mysql
 
 // generateSQLBase is the query list of SQL statements required to build the nil backend.
+// The columns added to the existing tables are not listed here, but in the
+// schemaMigrations, so the tables of the old schema are upgraded as well.
 var generateSQLBase = []string{
+	`
+		CREATE TABLE IF NOT EXISTS schema_version (
+			sv_id tinyint unsigned NOT NULL,
+			sv_version int unsigned NOT NULL,
+			PRIMARY KEY (sv_id)
+		) ENGINE=InnoDB DEFAULT CHARSET=ascii
+	`,
 	`
 		CREATE TABLE IF NOT EXISTS region (
 			rg_id int unsigned NOT NULL AUTO_INCREMENT,
@@ -32,6 +41,122 @@ var generateSQLBase = []string{
 			FOREIGN KEY (bk_region) REFERENCES region (rg_id)
 		) ENGINE=InnoDB DEFAULT CHARSET=ascii
 	`,
+	`
+		CREATE TABLE IF NOT EXISTS bucket_removal (
+			br_bucket int unsigned NOT NULL,
+			PRIMARY KEY (br_bucket),
+			FOREIGN KEY (br_bucket) REFERENCES bucket (bk_id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=ascii
+	`,
+	`
+		CREATE TABLE IF NOT EXISTS lifecycle_lease (
+			ll_region varchar(32) CHARACTER SET ascii NOT NULL,
+			ll_holder varchar(64) CHARACTER SET ascii NOT NULL,
+			ll_expires datetime NOT NULL,
+			PRIMARY KEY (ll_region)
+		) ENGINE=InnoDB DEFAULT CHARSET=ascii
+	`,
+	`
+		CREATE TABLE IF NOT EXISTS master_key (
+			mk_id tinyint unsigned NOT NULL,
+			mk_key varchar(64) CHARACTER SET ascii NOT NULL,
+			PRIMARY KEY (mk_id)
+		) ENGINE=InnoDB DEFAULT CHARSET=ascii
+	`,
+	`
+		CREATE TABLE IF NOT EXISTS object (
+			obj_id bigint unsigned NOT NULL AUTO_INCREMENT,
+			obj_name varbinary(1024) NOT NULL,
+			obj_bucket int unsigned NOT NULL,
+			obj_version_id varchar(48) CHARACTER SET ascii NOT NULL DEFAULT 'null',
+			obj_latest tinyint(1) NOT NULL DEFAULT 1,
+			obj_delete_marker tinyint(1) NOT NULL DEFAULT 0,
+			obj_encoding_group bigint NOT NULL,
+			obj_volume bigint NOT NULL,
+			obj_ds bigint NOT NULL,
+			obj_oid varchar(48) CHARACTER SET ascii NOT NULL,
+			obj_size bigint unsigned NOT NULL,
+			obj_etag varchar(64) CHARACTER SET ascii NOT NULL,
+			obj_last_modified datetime NOT NULL,
+			obj_content_type varchar(255) CHARACTER SET ascii NOT NULL DEFAULT '',
+			obj_headers text CHARACTER SET utf8mb4,
+			obj_metadata text CHARACTER SET utf8mb4,
+			obj_acl varchar(32) CHARACTER SET ascii NOT NULL DEFAULT 'private',
+			obj_sse varchar(16) CHARACTER SET ascii NOT NULL DEFAULT '',
+			obj_sse_key varchar(128) CHARACTER SET ascii NOT NULL DEFAULT '',
+			obj_sse_key_md5 varchar(32) CHARACTER SET ascii NOT NULL DEFAULT '',
+			obj_lock_mode varchar(16) CHARACTER SET ascii NOT NULL DEFAULT '',
+			obj_lock_until datetime,
+			obj_legal_hold tinyint(1) NOT NULL DEFAULT 0,
+			PRIMARY KEY (obj_id),
+			UNIQUE KEY (obj_bucket, obj_name, obj_version_id),
+			KEY (obj_oid),
+			FOREIGN KEY (obj_bucket) REFERENCES bucket (bk_id)
+		) ENGINE=InnoDB DEFAULT CHARSET=ascii
+	`,
+	`
+		CREATE TABLE IF NOT EXISTS object_part (
+			op_object bigint unsigned NOT NULL,
+			op_number int unsigned NOT NULL,
+			op_offset bigint unsigned NOT NULL,
+			op_encoding_group bigint NOT NULL,
+			op_volume bigint NOT NULL,
+			op_ds bigint NOT NULL,
+			op_oid varchar(48) CHARACTER SET ascii NOT NULL,
+			op_size bigint unsigned NOT NULL,
+			PRIMARY KEY (op_object, op_number),
+			KEY (op_oid),
+			FOREIGN KEY (op_object) REFERENCES object (obj_id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=ascii
+	`,
+	`
+		CREATE TABLE IF NOT EXISTS object_tag (
+			ot_object bigint unsigned NOT NULL,
+			ot_key varchar(128) CHARACTER SET utf8mb4 COLLATE utf8mb4_bin NOT NULL,
+			ot_value varchar(256) CHARACTER SET utf8mb4 COLLATE utf8mb4_bin NOT NULL DEFAULT '',
+			PRIMARY KEY (ot_object, ot_key),
+			FOREIGN KEY (ot_object) REFERENCES object (obj_id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=ascii
+	`,
+	`
+		CREATE TABLE IF NOT EXISTS multipart_upload (
+			mu_id bigint unsigned NOT NULL AUTO_INCREMENT,
+			mu_upload_id varchar(48) CHARACTER SET ascii NOT NULL,
+			mu_bucket int unsigned NOT NULL,
+			mu_name varbinary(1024) NOT NULL,
+			mu_initiated datetime NOT NULL,
+			mu_content_type varchar(255) CHARACTER SET ascii NOT NULL DEFAULT '',
+			mu_headers text CHARACTER SET utf8mb4,
+			mu_metadata text CHARACTER SET utf8mb4,
+			mu_acl varchar(32) CHARACTER SET ascii NOT NULL DEFAULT 'private',
+			mu_sse varchar(16) CHARACTER SET ascii NOT NULL DEFAULT '',
+			mu_sse_key varchar(128) CHARACTER SET ascii NOT NULL DEFAULT '',
+			mu_sse_key_md5 varchar(32) CHARACTER SET ascii NOT NULL DEFAULT '',
+			mu_tags text CHARACTER SET utf8mb4,
+			mu_lock_mode varchar(16) CHARACTER SET ascii NOT NULL DEFAULT '',
+			mu_lock_until datetime,
+			mu_legal_hold tinyint(1) NOT NULL DEFAULT 0,
+			PRIMARY KEY (mu_id),
+			UNIQUE KEY (mu_upload_id),
+			KEY (mu_bucket, mu_name),
+			FOREIGN KEY (mu_bucket) REFERENCES bucket (bk_id)
+		) ENGINE=InnoDB DEFAULT CHARSET=ascii
+	`,
+	`
+		CREATE TABLE IF NOT EXISTS multipart_part (
+			mp_upload bigint unsigned NOT NULL,
+			mp_number int unsigned NOT NULL,
+			mp_encoding_group bigint NOT NULL,
+			mp_volume bigint NOT NULL,
+			mp_ds bigint NOT NULL,
+			mp_oid varchar(48) CHARACTER SET ascii NOT NULL,
+			mp_size bigint unsigned NOT NULL,
+			mp_etag varchar(64) CHARACTER SET ascii NOT NULL,
+			mp_last_modified datetime NOT NULL,
+			PRIMARY KEY (mp_upload, mp_number),
+			FOREIGN KEY (mp_upload) REFERENCES multipart_upload (mu_id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=ascii
+	`,
 	`
 		CREATE TABLE IF NOT EXISTS node (
 			node_id int unsigned NOT NULL AUTO_INCREMENT,
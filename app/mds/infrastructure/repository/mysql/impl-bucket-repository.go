@@ -1,12 +1,39 @@
 package mysql
 
 import (
+	"database/sql"
 	"fmt"
 
 	"github.com/chanyoung/nil/app/mds/domain/model/bucket"
+	"github.com/chanyoung/nil/app/mds/infrastructure/repository"
+	"github.com/chanyoung/nil/pkg/util/mlog"
 	"github.com/go-sql-driver/mysql"
+	"github.com/pkg/errors"
 )
 
+// bucketColumns is the columns of the bucket which are read by scanBucket.
+const bucketColumns = `
+			bk_id, bk_name, bk_user, bk_region, bk_created, bk_versioning,
+			COALESCE(bk_lifecycle, ''), bk_acl, COALESCE(bk_policy, ''),
+			COALESCE(bk_cors, ''), COALESCE(bk_object_lock, '')
+		`
+
+// scanBucket reads the bucket from the row of the bucketColumns.
+func scanBucket(row interface{ Scan(...interface{}) error }) (*bucket.Bucket, error) {
+	b := &bucket.Bucket{}
+	err := row.Scan(&b.ID, &b.Name, &b.User, &b.Region, &b.Created, &b.Versioning, &b.Lifecycle, &b.ACL, &b.Policy, &b.CORS, &b.ObjectLock)
+	return b, err
+}
+
+// sqlString returns the string literal of the arbitrary text, which is
+// safe to be embedded in the query published to the cluster.
+func sqlString(s string) string {
+	if s == "" {
+		return "NULL"
+	}
+	return fmt.Sprintf("X'%x'", s)
+}
+
 type bucketRepository struct {
 	s *Store
 }
@@ -18,6 +45,167 @@ func NewBucketRepository(s *Store) bucket.Repository {
 	}
 }
 
+func (r *bucketRepository) FindByName(name bucket.Name, region bucket.ID) (*bucket.Bucket, error) {
+	ctxLogger := mlog.GetMethodLogger(logger, "bucketRepository.FindByName")
+
+	q := `
+		SELECT` + bucketColumns + `
+		FROM
+			bucket
+		WHERE
+			bk_name = ? AND bk_region = ?
+		`
+
+	row := r.s.QueryRow(repository.NotTx, q, name.String(), region.String())
+	if row == nil {
+		return nil, bucket.ErrInternal
+	}
+
+	b, err := scanBucket(row)
+	if err == sql.ErrNoRows {
+		err = bucket.ErrNotExist
+	} else if err != nil {
+		ctxLogger.Error(errors.Wrapf(err, "failed to find bucket by name: %s", name.String()))
+		err = bucket.ErrInternal
+	}
+
+	return b, err
+}
+
+func (r *bucketRepository) FindByUser(user bucket.ID) ([]*bucket.Bucket, error) {
+	ctxLogger := mlog.GetMethodLogger(logger, "bucketRepository.FindByUser")
+
+	q := `
+		SELECT` + bucketColumns + `
+		FROM
+			bucket
+		WHERE
+			bk_user = ?
+		ORDER BY
+			bk_name
+		`
+
+	buckets, err := r.findBuckets(q, user.String())
+	if err != nil {
+		ctxLogger.Error(errors.Wrapf(err, "failed to find buckets by user: %s", user.String()))
+		return nil, bucket.ErrInternal
+	}
+
+	return buckets, nil
+}
+
+func (r *bucketRepository) FindByRegion(region bucket.ID) ([]*bucket.Bucket, error) {
+	ctxLogger := mlog.GetMethodLogger(logger, "bucketRepository.FindByRegion")
+
+	q := `
+		SELECT` + bucketColumns + `
+		FROM
+			bucket
+		WHERE
+			bk_region = ?
+		ORDER BY
+			bk_name
+		`
+
+	buckets, err := r.findBuckets(q, region.String())
+	if err != nil {
+		ctxLogger.Error(errors.Wrapf(err, "failed to find buckets by region: %s", region.String()))
+		return nil, bucket.ErrInternal
+	}
+
+	return buckets, nil
+}
+
+// findBuckets returns the buckets selected by the query.
+func (r *bucketRepository) findBuckets(q string, args ...interface{}) ([]*bucket.Bucket, error) {
+	rows, err := r.s.Query(repository.NotTx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	buckets := make([]*bucket.Bucket, 0)
+	for rows.Next() {
+		b, err := scanBucket(rows)
+		if err != nil {
+			return nil, err
+		}
+		buckets = append(buckets, b)
+	}
+
+	return buckets, rows.Err()
+}
+
+func (r *bucketRepository) LockEmpty(id bucket.ID) (bool, error) {
+	ctxLogger := mlog.GetMethodLogger(logger, "bucketRepository.LockEmpty")
+
+	tx, err := r.s.Begin()
+	if err != nil {
+		return false, err
+	}
+
+	empty, err := r.lockEmpty(tx, id)
+	if err != nil {
+		ctxLogger.Error(errors.Wrapf(err, "failed to lock empty bucket: %s", id.String()))
+		r.s.Rollback(tx)
+		return false, bucket.ErrInternal
+	}
+
+	return empty, r.s.Commit(tx)
+}
+
+// lockEmpty checks the bucket is empty and marks it as being removed in
+// the transaction. The writes of the objects hold the shared lock of the
+// bucket, so the exclusive lock waits for the writes in progress, and the
+// later writes find the mark.
+func (r *bucketRepository) lockEmpty(txid repository.TxID, id bucket.ID) (bool, error) {
+	q := `
+		SELECT bk_id
+		FROM bucket
+		WHERE bk_id = ?
+		FOR UPDATE
+		`
+	row := r.s.QueryRow(txid, q, id.String())
+	if row == nil {
+		return false, fmt.Errorf("mysql not connected yet")
+	}
+	var locked string
+	if err := row.Scan(&locked); err != nil {
+		return false, err
+	}
+
+	q = `
+		SELECT
+			NOT EXISTS (SELECT 1 FROM object WHERE obj_bucket = ?)
+			AND NOT EXISTS (SELECT 1 FROM multipart_upload WHERE mu_bucket = ?)
+		`
+	row = r.s.QueryRow(txid, q, id.String(), id.String())
+	var empty bool
+	if err := row.Scan(&empty); err != nil || !empty {
+		return false, err
+	}
+
+	q = `
+		INSERT IGNORE INTO bucket_removal (br_bucket)
+		VALUES (?)
+		`
+	if _, err := r.s.Execute(txid, q, id.String()); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (r *bucketRepository) Unlock(id bucket.ID) error {
+	q := `
+		DELETE FROM bucket_removal
+		WHERE br_bucket = ?
+		`
+
+	_, err := r.s.Execute(repository.NotTx, q, id.String())
+	return err
+}
+
 func (r *bucketRepository) Save(b *bucket.Bucket) error {
 	if b.ID.String() == "" {
 		return r.update(b)
@@ -40,9 +228,14 @@ func (r *bucketRepository) update(b *bucket.Bucket) error {
 func (r *bucketRepository) create(b *bucket.Bucket) error {
 	q := fmt.Sprintf(
 		`
-		INSERT INTO bucket (bk_name, bk_user, bk_region)
-		VALUES ('%s', '%s', '%s')
+		INSERT INTO bucket (
+			bk_name, bk_user, bk_region, bk_created, bk_acl,
+			bk_versioning, bk_object_lock
+		)
+		VALUES ('%s', '%s', '%s', '%s', '%s', '%s', %s)
 		`, b.Name.String(), b.User.String(), b.Region.String(),
+		b.Created.UTC().Format("2006-01-02 15:04:05"), b.ACL,
+		string(b.Versioning), sqlString(b.ObjectLock),
 	)
 
 	_, err := r.s.PublishCommand("execute", q)
@@ -65,3 +258,102 @@ func (r *bucketRepository) create(b *bucket.Bucket) error {
 		return err
 	}
 }
+
+func (r *bucketRepository) SetVersioning(id bucket.ID, v bucket.Versioning) error {
+	q := fmt.Sprintf(
+		`
+		UPDATE bucket
+		SET bk_versioning = '%s'
+		WHERE bk_id = '%s'
+		`, string(v), id.String(),
+	)
+
+	_, err := r.s.PublishCommand("execute", q)
+	return err
+}
+
+func (r *bucketRepository) SetLifecycle(id bucket.ID, lifecycle string) error {
+	q := fmt.Sprintf(
+		`
+		UPDATE bucket
+		SET bk_lifecycle = %s
+		WHERE bk_id = '%s'
+		`, sqlString(lifecycle), id.String(),
+	)
+
+	_, err := r.s.PublishCommand("execute", q)
+	return err
+}
+
+func (r *bucketRepository) SetACL(id bucket.ID, acl string) error {
+	q := fmt.Sprintf(
+		`
+		UPDATE bucket
+		SET bk_acl = '%s'
+		WHERE bk_id = '%s'
+		`, acl, id.String(),
+	)
+
+	_, err := r.s.PublishCommand("execute", q)
+	return err
+}
+
+func (r *bucketRepository) SetPolicy(id bucket.ID, policy string) error {
+	q := fmt.Sprintf(
+		`
+		UPDATE bucket
+		SET bk_policy = %s
+		WHERE bk_id = '%s'
+		`, sqlString(policy), id.String(),
+	)
+
+	_, err := r.s.PublishCommand("execute", q)
+	return err
+}
+
+func (r *bucketRepository) SetCORS(id bucket.ID, cors string) error {
+	q := fmt.Sprintf(
+		`
+		UPDATE bucket
+		SET bk_cors = %s
+		WHERE bk_id = '%s'
+		`, sqlString(cors), id.String(),
+	)
+
+	_, err := r.s.PublishCommand("execute", q)
+	return err
+}
+
+func (r *bucketRepository) SetObjectLock(id bucket.ID, objectLock string) error {
+	q := fmt.Sprintf(
+		`
+		UPDATE bucket
+		SET bk_object_lock = %s
+		WHERE bk_id = '%s'
+		`, sqlString(objectLock), id.String(),
+	)
+
+	_, err := r.s.PublishCommand("execute", q)
+	return err
+}
+
+func (r *bucketRepository) Delete(id bucket.ID) error {
+	q := fmt.Sprintf(
+		`
+		DELETE FROM bucket
+		WHERE bk_id = '%s'
+		`, id.String(),
+	)
+
+	// The mark of the removal in the local region is deleted in cascade.
+	_, err := r.s.PublishCommand("execute", q)
+	if err == nil {
+		return nil
+	}
+
+	// The objects refer the bucket which is not empty.
+	if mysqlError, ok := err.(*mysql.MySQLError); ok && mysqlError.Number == 1451 {
+		return bucket.ErrNotEmpty
+	}
+	return err
+}
@@ -0,0 +1,71 @@
+package mysql
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/chanyoung/nil/app/mds/domain/model/masterkey"
+	"github.com/chanyoung/nil/app/mds/infrastructure/repository"
+	"github.com/chanyoung/nil/pkg/util/mlog"
+	"github.com/pkg/errors"
+)
+
+// masterKeyID is the row id of the master key, since the cluster has only
+// one master key.
+const masterKeyID = 1
+
+type masterKeyRepository struct {
+	s *Store
+}
+
+// NewMasterKeyRepository returns a new instance of a mysql master key
+// repository.
+func NewMasterKeyRepository(s *Store) masterkey.Repository {
+	return &masterKeyRepository{
+		s: s,
+	}
+}
+
+func (r *masterKeyRepository) Find() (masterkey.Key, error) {
+	ctxLogger := mlog.GetMethodLogger(logger, "masterKeyRepository.Find")
+
+	q := fmt.Sprintf(
+		`
+		SELECT
+			mk_key
+		FROM
+			master_key
+		WHERE
+			mk_id='%d'
+		`, masterKeyID,
+	)
+
+	var encoded string
+	err := r.s.QueryRow(repository.NotTx, q).Scan(&encoded)
+	if err == sql.ErrNoRows {
+		return nil, masterkey.ErrNotExist
+	} else if err != nil {
+		ctxLogger.Error(errors.Wrap(err, "failed to find master key"))
+		return nil, masterkey.ErrInternal
+	}
+
+	k, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(k) != masterkey.Size {
+		ctxLogger.Error("invalid master key")
+		return nil, masterkey.ErrInternal
+	}
+
+	return masterkey.Key(k), nil
+}
+
+func (r *masterKeyRepository) Create(k masterkey.Key) error {
+	q := fmt.Sprintf(
+		`
+		INSERT IGNORE INTO master_key (mk_id, mk_key)
+		VALUES ('%d', '%s')
+		`, masterKeyID, base64.StdEncoding.EncodeToString(k),
+	)
+	_, err := r.s.PublishCommand("execute", q)
+	return err
+}
@@ -0,0 +1,81 @@
+package mysql
+
+import (
+	"time"
+
+	"github.com/chanyoung/nil/app/mds/application/lifecycle"
+	"github.com/chanyoung/nil/app/mds/infrastructure/repository"
+)
+
+type lifecycleStore struct {
+	*Store
+}
+
+// NewLifecycleRepository returns a new instance of a lifecycle repository.
+func NewLifecycleRepository(s *Store) lifecycle.Repository {
+	return &lifecycleStore{
+		Store: s,
+	}
+}
+
+func (s *lifecycleStore) AcquireLease(region, holder string, d time.Duration) (bool, error) {
+	tx, err := s.Begin()
+	if err != nil {
+		return false, err
+	}
+
+	ok, err := s.acquireLease(tx, region, holder, d)
+	if err != nil {
+		s.Rollback(tx)
+		return false, err
+	}
+
+	return ok, s.Commit(tx)
+}
+
+// acquireLease locks the lease of the region in the transaction and takes
+// it if it is held by the holder or is expired.
+func (s *lifecycleStore) acquireLease(txid repository.TxID, region, holder string, d time.Duration) (bool, error) {
+	// The lease which is never held is expired.
+	_, err := s.Execute(txid,
+		`
+		INSERT IGNORE INTO lifecycle_lease (ll_region, ll_holder, ll_expires)
+		VALUES (?, '', NOW())
+		`, region,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	var (
+		cur     string
+		expired bool
+	)
+	err = s.QueryRow(txid,
+		`
+		SELECT ll_holder, ll_expires <= NOW()
+		FROM lifecycle_lease
+		WHERE ll_region = ?
+		FOR UPDATE
+		`, region,
+	).Scan(&cur, &expired)
+	if err != nil {
+		return false, err
+	}
+	if cur != holder && !expired {
+		return false, nil
+	}
+
+	_, err = s.Execute(txid,
+		`
+		UPDATE lifecycle_lease
+		SET ll_holder = ?, ll_expires = NOW() + INTERVAL ? SECOND
+		WHERE ll_region = ?
+		`, holder, int64(d/time.Second), region,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
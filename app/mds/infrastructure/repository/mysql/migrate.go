@@ -0,0 +1,93 @@
+package mysql
+
+import (
+	"database/sql"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// schemaMigrations is the list of SQL statements which upgrade the schema
+// built by generateSQLBase. The version of the schema is the number of the
+// applied migrations, which is recorded in the schema_version table.
+// Append a new migration to change the existing table; never modify or
+// reorder the ones already released.
+var schemaMigrations = []string{
+	// 1: Bucket creation date. The existing buckets are dated by the
+	// migration. The buckets of the user are looked up by the index of
+	// the foreign key on bk_user.
+	`
+		ALTER TABLE bucket
+			ADD COLUMN bk_created datetime NOT NULL DEFAULT CURRENT_TIMESTAMP
+	`,
+	// 2: Bucket versioning.
+	`
+		ALTER TABLE bucket
+			ADD COLUMN bk_versioning varchar(16) CHARACTER SET ascii NOT NULL DEFAULT ''
+	`,
+	// 3: Bucket lifecycle.
+	`
+		ALTER TABLE bucket
+			ADD COLUMN bk_lifecycle mediumtext CHARACTER SET utf8mb4
+	`,
+	// 4: Bucket ACL.
+	`
+		ALTER TABLE bucket
+			ADD COLUMN bk_acl varchar(32) CHARACTER SET ascii NOT NULL DEFAULT 'private'
+	`,
+	// 5: Bucket policy.
+	`
+		ALTER TABLE bucket
+			ADD COLUMN bk_policy mediumtext CHARACTER SET utf8mb4
+	`,
+	// 6: Bucket CORS.
+	`
+		ALTER TABLE bucket
+			ADD COLUMN bk_cors mediumtext CHARACTER SET utf8mb4
+	`,
+	// 7: Bucket object lock.
+	`
+		ALTER TABLE bucket
+			ADD COLUMN bk_object_lock text CHARACTER SET utf8mb4
+	`,
+}
+
+// migrate applies the migrations which are newer than the version of the
+// schema, and records the new version after each of them.
+func migrate(db *sql.DB, migrations []string) error {
+	if _, err := db.Exec("INSERT IGNORE INTO schema_version (sv_id, sv_version) VALUES (1, 0)"); err != nil {
+		return err
+	}
+
+	var version int
+	if err := db.QueryRow("SELECT sv_version FROM schema_version WHERE sv_id = 1").Scan(&version); err != nil {
+		return err
+	}
+
+	for ; version < len(migrations); version++ {
+		// MySQL commits the schema change implicitly, so the migration
+		// can be applied already if the version was failed to be
+		// recorded. The duplicate column or key means it is applied.
+		if _, err := db.Exec(migrations[version]); err != nil && !applied(err) {
+			return err
+		}
+
+		if _, err := db.Exec("UPDATE schema_version SET sv_version = ? WHERE sv_id = 1", version+1); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// applied returns true if the error means the schema change is already
+// applied.
+func applied(err error) bool {
+	mysqlError, ok := err.(*mysql.MySQLError)
+	if !ok {
+		return false
+	}
+
+	// 1060: Duplicate column name.
+	// 1061: Duplicate key name.
+	return mysqlError.Number == 1060 || mysqlError.Number == 1061
+}
@@ -0,0 +1,417 @@
+package mysql
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/chanyoung/nil/app/mds/application/object"
+	"github.com/chanyoung/nil/app/mds/infrastructure/repository"
+)
+
+func (s *objectStore) CreateUpload(u *object.UploadInfo) error {
+	tx, err := s.Begin()
+	if err != nil {
+		return err
+	}
+
+	if err := s.createUpload(tx, u); err != nil {
+		s.Rollback(tx)
+		return err
+	}
+
+	return s.Commit(tx)
+}
+
+// createUpload records the upload in the transaction, which holds the lock
+// of the bucket so that it is not removed meanwhile.
+func (s *objectStore) createUpload(txid repository.TxID, u *object.UploadInfo) error {
+	bkID, err := s.bucketID(txid, u.Bucket)
+	if err != nil {
+		return err
+	}
+
+	q := `
+		INSERT INTO multipart_upload (
+			mu_upload_id, mu_bucket, mu_name, mu_initiated,
+			mu_content_type, mu_headers, mu_metadata, mu_acl,
+			mu_sse, mu_sse_key, mu_sse_key_md5, mu_tags,
+			mu_lock_mode, mu_lock_until, mu_legal_hold
+		)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`
+
+	headers, err := json.Marshal(u.Headers)
+	if err != nil {
+		return err
+	}
+	meta, err := json.Marshal(u.Metadata)
+	if err != nil {
+		return err
+	}
+	tags, err := json.Marshal(u.Tags)
+	if err != nil {
+		return err
+	}
+
+	r, err := s.Execute(
+		txid, q,
+		u.ID, bkID, u.Name, u.Initiated, u.ContentType, string(headers), string(meta), u.ACL,
+		u.Encryption.Type, u.Encryption.Key, u.Encryption.KeyMD5, string(tags),
+		u.Lock.Mode, lockUntil(u.Lock), u.Lock.LegalHold,
+	)
+	if err != nil {
+		return err
+	}
+
+	u.Seq, err = r.LastInsertId()
+	return err
+}
+
+func (s *objectStore) GetUpload(bucket, name, uploadID string) (*object.UploadInfo, error) {
+	q := `
+		SELECT
+			mu_id, mu_initiated, mu_content_type, mu_headers, mu_metadata, mu_acl,
+			mu_sse, mu_sse_key, mu_sse_key_md5, mu_tags,
+			mu_lock_mode, mu_lock_until, mu_legal_hold
+		FROM
+			multipart_upload
+			JOIN bucket ON mu_bucket = bk_id
+			JOIN region ON bk_region = rg_id
+		WHERE
+			mu_upload_id = ? AND mu_name = ? AND bk_name = ? AND rg_name = ?
+		`
+
+	row := s.QueryRow(repository.NotTx, q, uploadID, name, bucket, s.cfg.Raft.LocalClusterRegion)
+	if row == nil {
+		return nil, fmt.Errorf("mysql not connected yet")
+	}
+
+	u := &object.UploadInfo{ID: uploadID, Name: name, Bucket: bucket}
+	var headers, meta, tags sql.NullString
+	var until sql.NullTime
+	err := row.Scan(
+		&u.Seq, &u.Initiated, &u.ContentType, &headers, &meta, &u.ACL,
+		&u.Encryption.Type, &u.Encryption.Key, &u.Encryption.KeyMD5, &tags,
+		&u.Lock.Mode, &until, &u.Lock.LegalHold,
+	)
+	if err == sql.ErrNoRows {
+		if _, err := s.bucketID(repository.NotTx, bucket); err != nil {
+			return nil, err
+		}
+		return nil, object.ErrNoSuchUpload
+	} else if err != nil {
+		return nil, err
+	}
+
+	if headers.Valid {
+		if err := json.Unmarshal([]byte(headers.String), &u.Headers); err != nil {
+			return nil, err
+		}
+	}
+	if meta.Valid {
+		if err := json.Unmarshal([]byte(meta.String), &u.Metadata); err != nil {
+			return nil, err
+		}
+	}
+	if tags.Valid {
+		if err := json.Unmarshal([]byte(tags.String), &u.Tags); err != nil {
+			return nil, err
+		}
+	}
+	if until.Valid {
+		u.Lock.RetainUntil = until.Time.UTC()
+	}
+
+	return u, nil
+}
+
+// lockUpload returns the sequence of the upload and locks it until the
+// end of the transaction. Any change on the parts of the upload must hold
+// this lock, so that the parts are not changed while completing or
+// aborting the upload.
+func (s *objectStore) lockUpload(txid repository.TxID, bucket, name, uploadID string) (int64, error) {
+	q := `
+		SELECT
+			mu_id
+		FROM
+			multipart_upload
+			JOIN bucket ON mu_bucket = bk_id
+			JOIN region ON bk_region = rg_id
+		WHERE
+			mu_upload_id = ? AND mu_name = ? AND bk_name = ? AND rg_name = ?
+		FOR UPDATE
+		`
+
+	row := s.QueryRow(txid, q, uploadID, name, bucket, s.cfg.Raft.LocalClusterRegion)
+	if row == nil {
+		return 0, fmt.Errorf("mysql not connected yet")
+	}
+
+	var seq int64
+	err := row.Scan(&seq)
+	if err == sql.ErrNoRows {
+		return 0, object.ErrNoSuchUpload
+	} else if err != nil {
+		return 0, err
+	}
+
+	return seq, nil
+}
+
+func (s *objectStore) PutPart(bucket, name, uploadID string, p *object.ObjPart) ([]object.ObjPart, error) {
+	tx, err := s.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	obsolete, err := s.putPart(tx, bucket, name, uploadID, p)
+	if err != nil {
+		s.Rollback(tx)
+		return nil, err
+	}
+
+	return obsolete, s.Commit(tx)
+}
+
+func (s *objectStore) putPart(txid repository.TxID, bucket, name, uploadID string, p *object.ObjPart) ([]object.ObjPart, error) {
+	seq, err := s.lockUpload(txid, bucket, name, uploadID)
+	if err != nil {
+		return nil, err
+	}
+
+	old, err := s.uploadParts(txid, seq, p.Number-1, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	q := `
+		INSERT INTO multipart_part (
+			mp_upload, mp_number, mp_encoding_group, mp_volume, mp_ds,
+			mp_oid, mp_size, mp_etag, mp_last_modified
+		)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			mp_encoding_group = VALUES(mp_encoding_group),
+			mp_volume = VALUES(mp_volume),
+			mp_ds = VALUES(mp_ds),
+			mp_oid = VALUES(mp_oid),
+			mp_size = VALUES(mp_size),
+			mp_etag = VALUES(mp_etag),
+			mp_last_modified = VALUES(mp_last_modified)
+		`
+
+	_, err = s.Execute(
+		txid, q,
+		seq, p.Number, p.EncGrp, p.Vol, p.Node, p.Oid, p.Size, p.ETag, p.LastModified,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// The part uploaded before with the same number is replaced.
+	obsolete := make([]object.ObjPart, 0, 1)
+	for _, o := range old {
+		if o.Number == p.Number {
+			obsolete = append(obsolete, *o)
+		}
+	}
+
+	return obsolete, nil
+}
+
+func (s *objectStore) ListParts(u *object.UploadInfo, marker, limit int) ([]*object.ObjPart, error) {
+	return s.uploadParts(repository.NotTx, u.Seq, marker, limit)
+}
+
+// uploadParts returns at most limit parts of the upload whose part number
+// is greater than marker. If limit is not positive, all parts are returned.
+func (s *objectStore) uploadParts(txid repository.TxID, seq int64, marker, limit int) ([]*object.ObjPart, error) {
+	q := `
+		SELECT
+			mp_number, mp_encoding_group, mp_volume, mp_ds, mp_oid,
+			mp_size, mp_etag, mp_last_modified
+		FROM
+			multipart_part
+		WHERE
+			mp_upload = ? AND mp_number > ?
+		ORDER BY
+			mp_number
+		`
+	args := []interface{}{seq, marker}
+	if limit > 0 {
+		q += " LIMIT ?"
+		args = append(args, limit)
+	}
+
+	rows, err := s.Query(txid, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	parts := make([]*object.ObjPart, 0)
+	for rows.Next() {
+		p := &object.ObjPart{}
+		err := rows.Scan(
+			&p.Number, &p.EncGrp, &p.Vol, &p.Node, &p.Oid,
+			&p.Size, &p.ETag, &p.LastModified,
+		)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, p)
+	}
+
+	return parts, rows.Err()
+}
+
+func (s *objectStore) CompleteUpload(uploadID string, o *object.ObjInfo) ([]object.ObjPart, error) {
+	tx, err := s.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	obsolete, err := s.completeUpload(tx, uploadID, o)
+	if err != nil {
+		s.Rollback(tx)
+		return nil, err
+	}
+
+	return obsolete, s.Commit(tx)
+}
+
+func (s *objectStore) completeUpload(txid repository.TxID, uploadID string, o *object.ObjInfo) ([]object.ObjPart, error) {
+	seq, err := s.lockUpload(txid, o.Bucket, o.Name, uploadID)
+	if err != nil {
+		return nil, err
+	}
+
+	uploaded, err := s.uploadParts(txid, seq, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	// The parts must not have been replaced after the object is built.
+	used := make(map[int]string, len(o.Parts))
+	for _, p := range o.Parts {
+		used[p.Number] = p.Oid
+	}
+	obsolete := make([]object.ObjPart, 0)
+	found := 0
+	for _, p := range uploaded {
+		oid, ok := used[p.Number]
+		if !ok {
+			obsolete = append(obsolete, *p)
+			continue
+		}
+		if oid != p.Oid {
+			return nil, object.ErrInvalidPart
+		}
+		found++
+	}
+	if found != len(o.Parts) {
+		return nil, object.ErrInvalidPart
+	}
+
+	replaced, err := s.put(txid, o)
+	if err != nil {
+		return nil, err
+	}
+	obsolete = append(obsolete, replaced...)
+
+	// The parts of the upload are deleted in cascade.
+	q := `
+		DELETE FROM multipart_upload
+		WHERE mu_id = ?
+		`
+	if _, err := s.Execute(txid, q, seq); err != nil {
+		return nil, err
+	}
+
+	return obsolete, nil
+}
+
+func (s *objectStore) AbortUpload(bucket, name, uploadID string) ([]object.ObjPart, error) {
+	tx, err := s.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	obsolete, err := s.abortUpload(tx, bucket, name, uploadID)
+	if err != nil {
+		s.Rollback(tx)
+		return nil, err
+	}
+
+	return obsolete, s.Commit(tx)
+}
+
+func (s *objectStore) abortUpload(txid repository.TxID, bucket, name, uploadID string) ([]object.ObjPart, error) {
+	seq, err := s.lockUpload(txid, bucket, name, uploadID)
+	if err != nil {
+		return nil, err
+	}
+
+	parts, err := s.uploadParts(txid, seq, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	q := `
+		DELETE FROM multipart_upload
+		WHERE mu_id = ?
+		`
+	if _, err := s.Execute(txid, q, seq); err != nil {
+		return nil, err
+	}
+
+	obsolete := make([]object.ObjPart, len(parts))
+	for i, p := range parts {
+		obsolete[i] = *p
+	}
+
+	return obsolete, nil
+}
+
+func (s *objectStore) ListUploads(bucket, prefix, afterName string, afterSeq int64, limit int) ([]*object.UploadInfo, error) {
+	id, err := s.bucketID(repository.NotTx, bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	q := `
+		SELECT
+			mu_id, mu_upload_id, mu_name, mu_initiated
+		FROM
+			multipart_upload
+		WHERE
+			mu_bucket = ?
+			AND (mu_name > ? OR (mu_name = ? AND mu_id > ?))
+			AND mu_name >= ?
+		`
+	args := []interface{}{id, afterName, afterName, afterSeq, prefix}
+	if end, ok := object.PrefixSuccessor(prefix); ok {
+		q += " AND mu_name < ?"
+		args = append(args, end)
+	}
+	q += " ORDER BY mu_name, mu_id LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := s.Query(repository.NotTx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	uploads := make([]*object.UploadInfo, 0)
+	for rows.Next() {
+		u := &object.UploadInfo{Bucket: bucket}
+		if err := rows.Scan(&u.Seq, &u.ID, &u.Name, &u.Initiated); err != nil {
+			return nil, err
+		}
+		uploads = append(uploads, u)
+	}
+
+	return uploads, rows.Err()
+}
@@ -1,9 +1,40 @@
 package mysql
 
 import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
 	"github.com/chanyoung/nil/app/mds/application/object"
+	"github.com/chanyoung/nil/app/mds/domain/model/bucket"
+	"github.com/chanyoung/nil/app/mds/infrastructure/repository"
+	"github.com/chanyoung/nil/pkg/s3"
+	"github.com/chanyoung/nil/pkg/util/uuid"
+	"github.com/go-sql-driver/mysql"
 )
 
+// insertPartsBatch is the number of parts inserted by a single query.
+const insertPartsBatch = 1000
+
+// dataLocksBatch is the number of object ids whose locks are looked up by a
+// single query.
+const dataLocksBatch = 1000
+
+// insertObject is the query which inserts the row of the latest version.
+const insertObject = `
+		INSERT INTO object (
+			obj_name, obj_bucket, obj_version_id, obj_latest, obj_delete_marker,
+			obj_encoding_group, obj_volume, obj_ds,
+			obj_oid, obj_size, obj_etag, obj_last_modified,
+			obj_content_type, obj_headers, obj_metadata, obj_acl,
+			obj_sse, obj_sse_key, obj_sse_key_md5,
+			obj_lock_mode, obj_lock_until, obj_legal_hold
+		)
+		VALUES (?, ?, ?, 1, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`
+
 type objectStore struct {
 	*Store
 }
@@ -15,105 +46,876 @@ func NewObjectRepository(s *Store) object.Repository {
 	}
 }
 
-// func (s *objectStore) Put(o *object.ObjInfo) error {
-// 	q := fmt.Sprintf(
-// 		`
-// 		SELECT
-// 			egv_role
-// 		FROM
-// 			encoding_group_volume
-// 		WHERE
-// 			egv_volume=%d AND egv_encoding_group=%d
-// 		`, o.Vol, o.EncGrp,
-// 	)
+func (s *objectStore) Put(o *object.ObjInfo) ([]object.ObjPart, error) {
+	tx, err := s.Begin()
+	if err != nil {
+		return nil, err
+	}
 
-// 	var role int
-// 	if err := s.QueryRow(repository.NotTx, q).Scan(&role); err != nil {
-// 		return err
-// 	}
+	obsolete, err := s.put(tx, o)
+	if err != nil {
+		s.Rollback(tx)
+		return nil, err
+	}
 
-// 	q = fmt.Sprintf(
-// 		`
-// 		INSERT INTO object (obj_name, obj_bucket, obj_encoding_group, obj_role)
-// 		SELECT '%s', b.bk_id, '%d', '%d'
-// 		FROM bucket b
-// 		WHERE bk_name = '%s'
-// 		`, o.Name, o.EncGrp, role, o.Bucket,
-// 	)
+	return obsolete, s.Commit(tx)
+}
 
-// 	_, err := s.Execute(repository.NotTx, q)
-// 	return err
-// }
+// put records the object in the transaction and returns the data of
+// the replaced null version.
+func (s *objectStore) put(txid repository.TxID, o *object.ObjInfo) ([]object.ObjPart, error) {
+	bkID, versioning, err := s.bucketInfo(txid, o.Bucket)
+	if err != nil {
+		return nil, err
+	}
 
-// func (s *objectStore) Get(name string) (*object.ObjInfo, error) {
-// 	o := &object.ObjInfo{}
+	return s.putVersion(txid, bkID, versioning, o)
+}
 
-// 	q := fmt.Sprintf(
-// 		`
-// 		SELECT
-// 			obj_encoding_group, obj_role
-// 		FROM
-// 			object
-// 		WHERE
-// 			obj_name = '%s'
-// 		`, name,
-// 	)
+// putVersion records the object as the latest version. If the versioning
+// of the bucket is not enabled, the object gets the null version id and
+// replaces the existing null version, whose data is returned. The null
+// version protected by the object lock is not replaced.
+func (s *objectStore) putVersion(txid repository.TxID, bkID int64, versioning bucket.Versioning, o *object.ObjInfo) ([]object.ObjPart, error) {
+	var obsolete []object.ObjPart
+	if versioning == bucket.VersioningEnabled {
+		o.VersionID = uuid.Gen()
+	} else {
+		o.VersionID = object.NullVersion
 
-// 	var role int
-// 	row := s.QueryRow(repository.NotTx, q)
-// 	if row == nil {
-// 		return nil, fmt.Errorf("mysql not connected yet")
-// 	}
+		old, err := s.get(txid, o.Bucket, o.Name, object.NullVersion, true)
+		if err == nil {
+			if old.Lock.Protected(false, time.Now()) {
+				return nil, object.ErrLocked
+			}
 
-// 	err := row.Scan(&o.EncGrp, &role)
-// 	if err != nil {
-// 		return nil, err
-// 	}
+			// The parts of the replaced version are deleted in cascade.
+			q := `
+				DELETE FROM object
+				WHERE obj_id = ?
+				`
+			if _, err := s.Execute(txid, q, old.Seq); err != nil {
+				return nil, err
+			}
+			obsolete = old.Locations()
+		} else if err != object.ErrNoSuchVersion {
+			return nil, err
+		}
+	}
 
-// 	q = fmt.Sprintf(
-// 		`
-// 		SELECT
-// 			egv_volume
-// 		FROM
-// 			encoding_group_volume
-// 		WHERE
-// 			egv_encoding_group=%d AND egv_role=%d
-// 		`, o.EncGrp, role,
-// 	)
+	q := `
+		UPDATE object
+		SET obj_latest = 0
+		WHERE obj_bucket = ? AND obj_name = ? AND obj_latest = 1
+		`
+	if _, err := s.Execute(txid, q, bkID, o.Name); err != nil {
+		return nil, err
+	}
 
-// 	row = s.QueryRow(repository.NotTx, q)
-// 	if row == nil {
-// 		return nil, fmt.Errorf("mysql not connected yet")
-// 	}
+	args, err := objectArgs(o, bkID)
+	if err != nil {
+		return nil, err
+	}
+	r, err := s.Execute(txid, insertObject, args...)
+	if err != nil {
+		return nil, err
+	}
+	if o.Seq, err = r.LastInsertId(); err != nil {
+		return nil, err
+	}
+	o.Latest = true
 
-// 	err = row.Scan(&o.Vol)
-// 	if err != nil {
-// 		return nil, err
-// 	}
+	if len(o.Parts) > 0 {
+		if err := s.insertParts(txid, o.Seq, o.Parts); err != nil {
+			return nil, err
+		}
+	}
+	if len(o.Tags) > 0 {
+		if err := s.insertTags(txid, o.Seq, o.Tags); err != nil {
+			return nil, err
+		}
+	}
 
-// 	q = fmt.Sprintf(
-// 		`
-// 		SELECT
-// 			vl_node
-// 		FROM
-// 			volume
-// 		WHERE
-// 			vl_id = '%d'
-// 		`, o.Vol,
-// 	)
+	return obsolete, nil
+}
 
-// 	row = s.QueryRow(repository.NotTx, q)
-// 	if row == nil {
-// 		return nil, fmt.Errorf("mysql not connected yet")
-// 	}
+// objectArgs returns the arguments of the insertObject query.
+func objectArgs(o *object.ObjInfo, bkID int64) ([]interface{}, error) {
+	headers, err := json.Marshal(o.Headers)
+	if err != nil {
+		return nil, err
+	}
+	meta, err := json.Marshal(o.Metadata)
+	if err != nil {
+		return nil, err
+	}
 
-// 	err = row.Scan(&o.Node)
-// 	if err != nil {
-// 		return nil, err
-// 	}
+	return []interface{}{
+		o.Name, bkID, o.VersionID, o.DeleteMarker,
+		o.EncGrp, o.Vol, o.Node, o.Oid, o.Size, o.ETag, o.LastModified,
+		o.ContentType, string(headers), string(meta), o.ACL,
+		o.Encryption.Type, o.Encryption.Key, o.Encryption.KeyMD5,
+		o.Lock.Mode, lockUntil(o.Lock), o.Lock.LegalHold,
+	}, nil
+}
 
-// 	return o, nil
-// }
+// lockUntil returns the retention date of the lock to be stored, which is
+// nil if the version is not retained. The date is rounded up to the second
+// so that the version is not released earlier than requested.
+func lockUntil(l s3.ObjectLock) interface{} {
+	if l.Mode == "" {
+		return nil
+	}
+
+	t := l.RetainUntil.UTC()
+	if r := t.Truncate(time.Second); r.Before(t) {
+		t = r.Add(time.Second)
+	}
+	return t
+}
+
+func (s *objectStore) Create(o *object.ObjInfo) error {
+	tx, err := s.Begin()
+	if err != nil {
+		return err
+	}
+
+	if err := s.create(tx, o); err != nil {
+		s.Rollback(tx)
+		return err
+	}
+
+	return s.Commit(tx)
+}
+
+// create puts the object in the transaction only if the latest version of
+// the name does not exist or is a delete marker. The lock on the latest
+// version makes the check of the existence atomic.
+func (s *objectStore) create(txid repository.TxID, o *object.ObjInfo) error {
+	latest, err := s.get(txid, o.Bucket, o.Name, "", true)
+	if err == nil && !latest.DeleteMarker {
+		return object.ErrExist
+	} else if err != nil && err != object.ErrNotExist {
+		return err
+	}
+
+	_, err = s.put(txid, o)
+	// The concurrent creation of the same name either conflicts on the
+	// null version or is chosen as the victim of the deadlock while both
+	// wait for the lock of the same name.
+	if mysqlError, ok := err.(*mysql.MySQLError); ok &&
+		(mysqlError.Number == 1062 || mysqlError.Number == 1213) {
+		return object.ErrExist
+	}
+	return err
+}
+
+// insertParts inserts the parts of the multipart object.
+func (s *objectStore) insertParts(txid repository.TxID, id int64, parts []object.ObjPart) error {
+	for len(parts) > 0 {
+		n := len(parts)
+		if n > insertPartsBatch {
+			n = insertPartsBatch
+		}
+
+		values := make([]string, n)
+		args := make([]interface{}, 0, n*8)
+		for i, p := range parts[:n] {
+			values[i] = "(?, ?, ?, ?, ?, ?, ?, ?)"
+			args = append(args, id, p.Number, p.Offset, p.EncGrp, p.Vol, p.Node, p.Oid, p.Size)
+		}
+
+		q := `
+			INSERT INTO object_part (
+				op_object, op_number, op_offset, op_encoding_group,
+				op_volume, op_ds, op_oid, op_size
+			)
+			VALUES ` + strings.Join(values, ", ")
+		if _, err := s.Execute(txid, q, args...); err != nil {
+			return err
+		}
+
+		parts = parts[n:]
+	}
+
+	return nil
+}
+
+// insertTags inserts the tags of the object.
+func (s *objectStore) insertTags(txid repository.TxID, id int64, tags map[string]string) error {
+	values := make([]string, 0, len(tags))
+	args := make([]interface{}, 0, len(tags)*3)
+	for k, v := range tags {
+		values = append(values, "(?, ?, ?)")
+		args = append(args, id, k, v)
+	}
+
+	q := `
+		INSERT INTO object_tag (ot_object, ot_key, ot_value)
+		VALUES ` + strings.Join(values, ", ")
+	_, err := s.Execute(txid, q, args...)
+	return err
+}
+
+func (s *objectStore) Get(bucket, name, versionID string) (*object.ObjInfo, error) {
+	return s.get(repository.NotTx, bucket, name, versionID, false)
+}
+
+// get returns the version of the object, or the latest version if the
+// version id is empty. The row id is set as the sequence of the version.
+// If forUpdate is true, the row is locked until the end of the transaction.
+func (s *objectStore) get(txid repository.TxID, bucket, name, versionID string, forUpdate bool) (*object.ObjInfo, error) {
+	q := `
+		SELECT
+			obj_id, obj_version_id, obj_latest, obj_delete_marker,
+			obj_encoding_group, obj_volume, obj_ds, obj_oid,
+			obj_size, obj_etag, obj_last_modified,
+			obj_content_type, obj_headers, obj_metadata, obj_acl,
+			obj_sse, obj_sse_key, obj_sse_key_md5,
+			obj_lock_mode, obj_lock_until, obj_legal_hold
+		FROM
+			object
+			JOIN bucket ON obj_bucket = bk_id
+			JOIN region ON bk_region = rg_id
+		WHERE
+			bk_name = ? AND rg_name = ? AND obj_name = ?
+		`
+	args := []interface{}{bucket, s.cfg.Raft.LocalClusterRegion, name}
+	if versionID == "" {
+		q += " AND obj_latest = 1"
+	} else {
+		q += " AND obj_version_id = ?"
+		args = append(args, versionID)
+	}
+	if forUpdate {
+		q += " FOR UPDATE"
+	}
+
+	row := s.QueryRow(txid, q, args...)
+	if row == nil {
+		return nil, fmt.Errorf("mysql not connected yet")
+	}
+
+	o := &object.ObjInfo{Name: name, Bucket: bucket}
+	var headers, meta sql.NullString
+	var until sql.NullTime
+	err := row.Scan(
+		&o.Seq, &o.VersionID, &o.Latest, &o.DeleteMarker,
+		&o.EncGrp, &o.Vol, &o.Node, &o.Oid, &o.Size, &o.ETag, &o.LastModified,
+		&o.ContentType, &headers, &meta, &o.ACL,
+		&o.Encryption.Type, &o.Encryption.Key, &o.Encryption.KeyMD5,
+		&o.Lock.Mode, &until, &o.Lock.LegalHold,
+	)
+	if err == sql.ErrNoRows {
+		return nil, s.notExist(txid, bucket, versionID)
+	} else if err != nil {
+		return nil, err
+	}
+
+	if headers.Valid {
+		if err := json.Unmarshal([]byte(headers.String), &o.Headers); err != nil {
+			return nil, err
+		}
+	}
+	if meta.Valid {
+		if err := json.Unmarshal([]byte(meta.String), &o.Metadata); err != nil {
+			return nil, err
+		}
+	}
+	if until.Valid {
+		o.Lock.RetainUntil = until.Time.UTC()
+	}
+
+	// The multipart object does not have its own data but the parts.
+	if o.Oid == "" && !o.DeleteMarker {
+		if o.Parts, err = s.objectParts(txid, o.Seq); err != nil {
+			return nil, err
+		}
+	}
+	if !o.DeleteMarker {
+		tags, err := s.objectTags(txid, []*object.ObjInfo{o})
+		if err != nil {
+			return nil, err
+		}
+		o.Tags = tags[o.Seq]
+	}
+
+	return o, nil
+}
+
+func (s *objectStore) SetACL(bucket, name, versionID, acl string) (*object.ObjInfo, error) {
+	tx, err := s.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	o, err := s.get(tx, bucket, name, versionID, true)
+	if err != nil {
+		s.Rollback(tx)
+		return nil, err
+	}
+
+	if !o.DeleteMarker {
+		q := `
+			UPDATE object
+			SET obj_acl = ?
+			WHERE obj_id = ?
+			`
+		if _, err := s.Execute(tx, q, acl, o.Seq); err != nil {
+			s.Rollback(tx)
+			return nil, err
+		}
+		o.ACL = acl
+	}
+
+	return o, s.Commit(tx)
+}
+
+func (s *objectStore) SetTags(bucket, name, versionID string, tags map[string]string) (*object.ObjInfo, error) {
+	tx, err := s.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	o, err := s.get(tx, bucket, name, versionID, true)
+	if err != nil {
+		s.Rollback(tx)
+		return nil, err
+	}
+
+	if !o.DeleteMarker {
+		q := `
+			DELETE FROM object_tag
+			WHERE ot_object = ?
+			`
+		if _, err := s.Execute(tx, q, o.Seq); err != nil {
+			s.Rollback(tx)
+			return nil, err
+		}
+		if len(tags) > 0 {
+			if err := s.insertTags(tx, o.Seq, tags); err != nil {
+				s.Rollback(tx)
+				return nil, err
+			}
+		}
+		o.Tags = tags
+	}
+
+	return o, s.Commit(tx)
+}
+
+func (s *objectStore) SetRetention(bucket, name, versionID, mode string, until time.Time, bypassGovernance bool) (*object.ObjInfo, error) {
+	tx, err := s.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	o, err := s.get(tx, bucket, name, versionID, true)
+	if err != nil {
+		s.Rollback(tx)
+		return nil, err
+	}
+
+	if !o.DeleteMarker {
+		if !o.Lock.AllowsRetention(mode, until, bypassGovernance, time.Now()) {
+			s.Rollback(tx)
+			return nil, object.ErrLocked
+		}
+
+		lock := s3.ObjectLock{Mode: mode, RetainUntil: until, LegalHold: o.Lock.LegalHold}
+		q := `
+			UPDATE object
+			SET obj_lock_mode = ?, obj_lock_until = ?
+			WHERE obj_id = ?
+			`
+		if _, err := s.Execute(tx, q, lock.Mode, lockUntil(lock), o.Seq); err != nil {
+			s.Rollback(tx)
+			return nil, err
+		}
+		o.Lock = lock
+	}
+
+	return o, s.Commit(tx)
+}
+
+func (s *objectStore) SetLegalHold(bucket, name, versionID string, on bool) (*object.ObjInfo, error) {
+	tx, err := s.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	o, err := s.get(tx, bucket, name, versionID, true)
+	if err != nil {
+		s.Rollback(tx)
+		return nil, err
+	}
+
+	if !o.DeleteMarker {
+		q := `
+			UPDATE object
+			SET obj_legal_hold = ?
+			WHERE obj_id = ?
+			`
+		if _, err := s.Execute(tx, q, on, o.Seq); err != nil {
+			s.Rollback(tx)
+			return nil, err
+		}
+		o.Lock.LegalHold = on
+	}
+
+	return o, s.Commit(tx)
+}
+
+// objectParts returns the parts of the multipart object in order.
+func (s *objectStore) objectParts(txid repository.TxID, id int64) ([]object.ObjPart, error) {
+	q := `
+		SELECT
+			op_number, op_offset, op_encoding_group, op_volume, op_ds,
+			op_oid, op_size
+		FROM
+			object_part
+		WHERE
+			op_object = ?
+		ORDER BY
+			op_number
+		`
+
+	rows, err := s.Query(txid, q, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	parts := make([]object.ObjPart, 0)
+	for rows.Next() {
+		var p object.ObjPart
+		if err := rows.Scan(&p.Number, &p.Offset, &p.EncGrp, &p.Vol, &p.Node, &p.Oid, &p.Size); err != nil {
+			return nil, err
+		}
+		parts = append(parts, p)
+	}
+
+	return parts, rows.Err()
+}
+
+// objectTags returns the tags of the objects by their sequences. The
+// objects which have no tags are absent.
+func (s *objectStore) objectTags(txid repository.TxID, objs []*object.ObjInfo) (map[int64]map[string]string, error) {
+	tags := make(map[int64]map[string]string)
+	if len(objs) == 0 {
+		return tags, nil
+	}
+
+	marks := make([]string, len(objs))
+	args := make([]interface{}, len(objs))
+	for i, o := range objs {
+		marks[i] = "?"
+		args[i] = o.Seq
+	}
+
+	q := `
+		SELECT
+			ot_object, ot_key, ot_value
+		FROM
+			object_tag
+		WHERE
+			ot_object IN (` + strings.Join(marks, ", ") + `)
+		`
+
+	rows, err := s.Query(txid, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var k, v string
+		if err := rows.Scan(&id, &k, &v); err != nil {
+			return nil, err
+		}
+		if tags[id] == nil {
+			tags[id] = make(map[string]string)
+		}
+		tags[id][k] = v
+	}
+
+	return tags, rows.Err()
+}
+
+func (s *objectStore) Delete(bucket, name, versionID string, bypassGovernance bool) (*object.ObjInfo, []object.ObjPart, error) {
+	tx, err := s.Begin()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	bkID, versioning, err := s.bucketInfo(tx, bucket)
+	if err != nil {
+		s.Rollback(tx)
+		return nil, nil, err
+	}
+
+	o, obsolete, err := s.delete(tx, bkID, versioning, bucket, name, versionID, bypassGovernance)
+	if err != nil {
+		s.Rollback(tx)
+		return nil, nil, err
+	}
+
+	return o, obsolete, s.Commit(tx)
+}
+
+// delete removes the version of the object in the transaction. If the
+// version is not given in the bucket which has ever been versioned, a
+// delete marker is put as the latest version instead. It returns the
+// removed version or the delete marker, and the data to be deleted. The
+// version protected by the object lock is not removed.
+func (s *objectStore) delete(txid repository.TxID, bkID int64, versioning bucket.Versioning, bkName, name, versionID string, bypassGovernance bool) (*object.ObjInfo, []object.ObjPart, error) {
+	if versionID == "" && versioning != "" {
+		m := &object.ObjInfo{
+			Name:         name,
+			Bucket:       bkName,
+			DeleteMarker: true,
+			LastModified: time.Now().UTC(),
+		}
+		obsolete, err := s.putVersion(txid, bkID, versioning, m)
+		if err != nil {
+			return nil, nil, err
+		}
+		return m, obsolete, nil
+	}
+
+	o, err := s.get(txid, bkName, name, versionID, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	if o.Lock.Protected(bypassGovernance, time.Now()) {
+		return nil, nil, object.ErrLocked
+	}
+
+	// The parts of the version are deleted in cascade.
+	q := `
+		DELETE FROM object
+		WHERE obj_id = ?
+		`
+	if _, err := s.Execute(txid, q, o.Seq); err != nil {
+		return nil, nil, err
+	}
+
+	// The newest one of the remaining versions becomes the latest.
+	if o.Latest {
+		q = `
+			UPDATE object
+			SET obj_latest = 1
+			WHERE obj_bucket = ? AND obj_name = ?
+			ORDER BY obj_id DESC
+			LIMIT 1
+			`
+		if _, err := s.Execute(txid, q, bkID, name); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return o, o.Locations(), nil
+}
+
+func (s *objectStore) DeleteObjects(bucket string, objs []*object.ObjInfo, bypassGovernance bool) ([]object.DeleteResult, []object.ObjPart, error) {
+	tx, err := s.Begin()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	deleted, obsolete, err := s.deleteObjects(tx, bucket, objs, bypassGovernance)
+	if err != nil {
+		s.Rollback(tx)
+		return nil, nil, err
+	}
+
+	return deleted, obsolete, s.Commit(tx)
+}
+
+// deleteObjects deletes the objects in the transaction as delete and
+// returns the results in the order of the objects. The locked versions
+// fail on their own without failing the others.
+func (s *objectStore) deleteObjects(txid repository.TxID, bkName string, objs []*object.ObjInfo, bypassGovernance bool) ([]object.DeleteResult, []object.ObjPart, error) {
+	bkID, versioning, err := s.bucketInfo(txid, bkName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	deleted := make([]object.DeleteResult, len(objs))
+	obsolete := make([]object.ObjPart, 0)
+	// The same object requested again gets the result of the first one.
+	seen := make(map[string]object.DeleteResult, len(objs))
+	for i, o := range objs {
+		key := o.Name + "\x00" + o.VersionID
+		if d, ok := seen[key]; ok {
+			deleted[i] = d
+			continue
+		}
+
+		d, parts, err := s.delete(txid, bkID, versioning, bkName, o.Name, o.VersionID, bypassGovernance)
+		switch err {
+		case nil, object.ErrNotExist, object.ErrNoSuchVersion:
+			deleted[i].Object = d
+		case object.ErrLocked:
+			deleted[i].Err = err
+		default:
+			return nil, nil, err
+		}
+		seen[key] = deleted[i]
+		obsolete = append(obsolete, parts...)
+	}
+
+	return deleted, obsolete, nil
+}
+
+func (s *objectStore) DataLocks(bucket string, oids []string) (map[string]s3.ObjectLock, error) {
+	id, err := s.bucketID(repository.NotTx, bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	locks := make(map[string]s3.ObjectLock, len(oids))
+	for len(oids) > 0 {
+		n := len(oids)
+		if n > dataLocksBatch {
+			n = dataLocksBatch
+		}
+		if err := s.dataLocks(id, oids[:n], locks); err != nil {
+			return nil, err
+		}
+		oids = oids[n:]
+	}
+
+	return locks, nil
+}
+
+// dataLocks adds the object locks of the versions which own the data of the
+// object ids to the locks.
+func (s *objectStore) dataLocks(id int64, oids []string, locks map[string]s3.ObjectLock) error {
+	marks := make([]string, len(oids))
+	args := make([]interface{}, 0, 2*len(oids)+2)
+	args = append(args, id)
+	for i, oid := range oids {
+		marks[i] = "?"
+		args = append(args, oid)
+	}
+	args = append(args, id)
+	for _, oid := range oids {
+		args = append(args, oid)
+	}
+
+	// The data is owned by the version which is put at once, or by the
+	// part of the multipart object.
+	in := strings.Join(marks, ", ")
+	q := `
+		SELECT
+			obj_oid, obj_lock_mode, obj_lock_until, obj_legal_hold
+		FROM
+			object
+		WHERE
+			obj_bucket = ? AND obj_oid IN (` + in + `)
+		UNION ALL
+		SELECT
+			op_oid, obj_lock_mode, obj_lock_until, obj_legal_hold
+		FROM
+			object_part JOIN object ON op_object = obj_id
+		WHERE
+			obj_bucket = ? AND op_oid IN (` + in + `)
+		`
+
+	rows, err := s.Query(repository.NotTx, q, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var oid string
+		var lock s3.ObjectLock
+		var until sql.NullTime
+		if err := rows.Scan(&oid, &lock.Mode, &until, &lock.LegalHold); err != nil {
+			return err
+		}
+		if until.Valid {
+			lock.RetainUntil = until.Time.UTC()
+		}
+		locks[oid] = lock
+	}
+
+	return rows.Err()
+}
+
+func (s *objectStore) List(bucket, prefix, from string, limit int) ([]*object.ObjInfo, error) {
+	id, err := s.bucketID(repository.NotTx, bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	// Compare names as a range instead of LIKE, so the query can scan
+	// the (obj_bucket, obj_name) index from the start position directly.
+	q := `
+		SELECT
+			obj_name, obj_size, obj_etag, obj_last_modified
+		FROM
+			object
+		WHERE
+			obj_bucket = ? AND obj_name >= ?
+			AND obj_latest = 1 AND obj_delete_marker = 0
+		`
+	args := []interface{}{id, from}
+	if end, ok := object.PrefixSuccessor(prefix); ok {
+		q += " AND obj_name < ?"
+		args = append(args, end)
+	}
+	q += " ORDER BY obj_name LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := s.Query(repository.NotTx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	objs := make([]*object.ObjInfo, 0)
+	for rows.Next() {
+		o := &object.ObjInfo{Bucket: bucket}
+		if err := rows.Scan(&o.Name, &o.Size, &o.ETag, &o.LastModified); err != nil {
+			return nil, err
+		}
+		objs = append(objs, o)
+	}
+
+	return objs, rows.Err()
+}
+
+func (s *objectStore) ListVersions(bucket, prefix, afterName string, afterSeq int64, limit int) ([]*object.ObjInfo, error) {
+	id, err := s.bucketID(repository.NotTx, bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	q := `
+		SELECT
+			obj_id, obj_name, obj_version_id, obj_latest, obj_delete_marker,
+			obj_size, obj_etag, obj_last_modified
+		FROM
+			object
+		WHERE
+			obj_bucket = ?
+			AND (obj_name > ? OR (obj_name = ? AND obj_id < ?))
+			AND obj_name >= ?
+		`
+	args := []interface{}{id, afterName, afterName, afterSeq, prefix}
+	if end, ok := object.PrefixSuccessor(prefix); ok {
+		q += " AND obj_name < ?"
+		args = append(args, end)
+	}
+	q += " ORDER BY obj_name, obj_id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := s.Query(repository.NotTx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	objs := make([]*object.ObjInfo, 0)
+	for rows.Next() {
+		o := &object.ObjInfo{Bucket: bucket}
+		err := rows.Scan(
+			&o.Seq, &o.Name, &o.VersionID, &o.Latest, &o.DeleteMarker,
+			&o.Size, &o.ETag, &o.LastModified,
+		)
+		if err != nil {
+			return nil, err
+		}
+		objs = append(objs, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	tags, err := s.objectTags(repository.NotTx, objs)
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range objs {
+		o.Tags = tags[o.Seq]
+	}
+
+	return objs, nil
+}
+
+// notExist returns the proper error when the requested object is not found.
+// It distinguishes whether the bucket, the object or only the requested
+// version does not exist.
+func (s *objectStore) notExist(txid repository.TxID, bucket, versionID string) error {
+	if _, err := s.bucketID(txid, bucket); err != nil {
+		return err
+	}
+	if versionID != "" {
+		return object.ErrNoSuchVersion
+	}
+	return object.ErrNotExist
+}
+
+// bucketID returns the id of the bucket in the local region.
+func (s *objectStore) bucketID(txid repository.TxID, bucket string) (int64, error) {
+	id, _, err := s.bucketInfo(txid, bucket)
+	return id, err
+}
+
+// bucketInfo returns the id and the versioning state of the bucket in the
+// local region. In the transaction, the bucket is locked in share mode so
+// that it is not removed until the transaction ends, and the empty bucket
+// being removed is regarded as not existing.
+func (s *objectStore) bucketInfo(txid repository.TxID, bkName string) (int64, bucket.Versioning, error) {
+	q := `
+		SELECT
+			bk_id, bk_versioning
+		FROM
+			bucket
+			JOIN region ON bk_region = rg_id
+		WHERE
+			bk_name = ? AND rg_name = ?
+		`
+	if txid != repository.NotTx {
+		q += " LOCK IN SHARE MODE"
+	}
+
+	row := s.QueryRow(txid, q, bkName, s.cfg.Raft.LocalClusterRegion)
+	if row == nil {
+		return 0, "", fmt.Errorf("mysql not connected yet")
+	}
+
+	var id int64
+	var versioning bucket.Versioning
+	err := row.Scan(&id, &versioning)
+	if err == sql.ErrNoRows {
+		return 0, "", object.ErrNoSuchBucket
+	} else if err != nil {
+		return 0, "", err
+	}
+
+	if txid != repository.NotTx {
+		q = `
+			SELECT COUNT(*)
+			FROM bucket_removal
+			WHERE br_bucket = ?
+			LOCK IN SHARE MODE
+			`
+		var removing int
+		if err := s.QueryRow(txid, q, id).Scan(&removing); err != nil {
+			return 0, "", err
+		}
+		if removing > 0 {
+			return 0, "", object.ErrNoSuchBucket
+		}
+	}
+
+	return id, versioning, nil
+}
 
 // func (s *objectStore) GetChunk(eg cmap.ID) (cID string, err error) {
 // 	q := fmt.Sprintf(
@@ -58,9 +58,9 @@ func (s *Store) Query(txid repository.TxID, query string, args ...interface{}) (
 }
 
 // Execute executes a query in the local cluster.
-func (s *Store) Execute(txid repository.TxID, query string) (sql.Result, error) {
+func (s *Store) Execute(txid repository.TxID, query string, args ...interface{}) (sql.Result, error) {
 	if s.db == nil {
 		return nil, fmt.Errorf("mysql is not connected yet")
 	}
-	return s.db.execute(txid, query)
+	return s.db.execute(txid, query, args...)
 }
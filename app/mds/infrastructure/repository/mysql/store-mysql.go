@@ -23,7 +23,7 @@ type mySQL struct {
 func newMySQL(cfg *config.Mds) (*mySQL, error) {
 	db, err := sql.Open(
 		"mysql",
-		fmt.Sprintf("%s:%s@tcp(%s:%s)/%s",
+		fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?parseTime=true",
 			cfg.MySQLUser,
 			cfg.MySQLPassword,
 			cfg.MySQLHost,
@@ -61,29 +61,30 @@ func (m *mySQL) init() error {
 		}
 	}
 
-	return nil
+	// Upgrades the tables of the old schema.
+	return migrate(m.db, schemaMigrations)
 }
 
 // Execute executes query.
-func (m *mySQL) execute(txid repository.TxID, query string) (sql.Result, error) {
-	if txid != "" {
-		return m.db.Exec(query)
+func (m *mySQL) execute(txid repository.TxID, query string, args ...interface{}) (sql.Result, error) {
+	if txid == repository.NotTx {
+		return m.db.Exec(query, args...)
 	}
 
-	tx, err := m.getTx(txid)
+	tx, err := m.lookupTx(txid)
 	if err != nil {
 		return nil, err
 	}
-	return tx.Exec(query)
+	return tx.Exec(query, args...)
 }
 
 // QueryRow executes a query that is expected to return at most one row.
 func (m *mySQL) queryRow(txid repository.TxID, query string, args ...interface{}) *sql.Row {
-	if txid != "" {
+	if txid == repository.NotTx {
 		return m.db.QueryRow(query, args...)
 	}
 
-	tx, err := m.getTx(txid)
+	tx, err := m.lookupTx(txid)
 	if err != nil {
 		return nil
 	}
@@ -92,11 +93,11 @@ func (m *mySQL) queryRow(txid repository.TxID, query string, args ...interface{}
 
 // Query executes a query that returns rows.
 func (m *mySQL) query(txid repository.TxID, query string, args ...interface{}) (*sql.Rows, error) {
-	if txid != "" {
+	if txid == repository.NotTx {
 		return m.db.Query(query, args...)
 	}
 
-	tx, err := m.getTx(txid)
+	tx, err := m.lookupTx(txid)
 	if err != nil {
 		return nil, err
 	}
@@ -124,6 +125,16 @@ func (m *mySQL) begin() (txid repository.TxID, err error) {
 	return
 }
 
+// lookupTx returns the transaction with the given ID.
+func (m *mySQL) lookupTx(txid repository.TxID) (*sql.Tx, error) {
+	m.txl.RLock()
+	defer m.txl.RUnlock()
+
+	return m.getTx(txid)
+}
+
+// getTx returns the transaction with the given ID.
+// The caller must hold the lock of the transaction map.
 func (m *mySQL) getTx(txid repository.TxID) (*sql.Tx, error) {
 	tx, ok := m.txs[txid]
 	if ok == false {
@@ -149,15 +160,17 @@ func (m *mySQL) commit(txid repository.TxID) error {
 	m.txl.Lock()
 	defer m.txl.Unlock()
 
+	defer delete(m.txs, txid)
+
 	tx, err := m.getTx(txid)
 	if err != nil {
 		return err
 	}
 
-	// Delete Tx from the map only when succeess the transaction.
-	err = tx.Commit()
-	if err == nil {
-		defer delete(m.txs, txid)
+	// The transaction is finished even if the commit fails, so it is
+	// rolled back and removed from the map either way.
+	if err = tx.Commit(); err != nil {
+		tx.Rollback()
 	}
 	return err
 }
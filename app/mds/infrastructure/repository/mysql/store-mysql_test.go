@@ -0,0 +1,213 @@
+package mysql
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/chanyoung/nil/app/mds/infrastructure/repository"
+)
+
+// fakeDB is a database which only keeps the rows inserted by the
+// "INSERT <value>" statement. The rows inserted in a transaction become
+// visible only after the transaction is committed.
+type fakeDB struct {
+	rows       []string
+	failCommit bool
+	mu         sync.Mutex
+}
+
+func (d *fakeDB) Open(name string) (driver.Conn, error) {
+	return &fakeConn{db: d}, nil
+}
+
+type fakeConn struct {
+	db      *fakeDB
+	tx      bool
+	pending []string
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{conn: c, query: query}, nil
+}
+
+func (c *fakeConn) Close() error { return nil }
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	c.tx = true
+	return c, nil
+}
+
+func (c *fakeConn) Commit() error {
+	defer c.end()
+
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+
+	if c.db.failCommit {
+		return errors.New("commit failed")
+	}
+	c.db.rows = append(c.db.rows, c.pending...)
+	return nil
+}
+
+func (c *fakeConn) Rollback() error {
+	c.end()
+	return nil
+}
+
+func (c *fakeConn) end() {
+	c.tx = false
+	c.pending = nil
+}
+
+type fakeStmt struct {
+	conn  *fakeConn
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	v := strings.TrimPrefix(s.query, "INSERT ")
+	if s.conn.tx {
+		s.conn.pending = append(s.conn.pending, v)
+		return driver.RowsAffected(1), nil
+	}
+
+	s.conn.db.mu.Lock()
+	defer s.conn.db.mu.Unlock()
+	s.conn.db.rows = append(s.conn.db.rows, v)
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.conn.db.mu.Lock()
+	defer s.conn.db.mu.Unlock()
+
+	return &fakeRows{rows: append([]string(nil), s.conn.db.rows...)}, nil
+}
+
+type fakeRows struct {
+	rows []string
+}
+
+func (r *fakeRows) Columns() []string { return []string{"value"} }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	dest[0], r.rows = r.rows[0], r.rows[1:]
+	return nil
+}
+
+var testDB = &fakeDB{}
+
+func init() {
+	sql.Register("fakemysql", testDB)
+}
+
+func newTestMySQL(t *testing.T) *mySQL {
+	testDB.mu.Lock()
+	testDB.rows = nil
+	testDB.failCommit = false
+	testDB.mu.Unlock()
+
+	db, err := sql.Open("fakemysql", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &mySQL{
+		db:  db,
+		txs: make(map[repository.TxID]*sql.Tx),
+	}
+}
+
+func countRows(t *testing.T, m *mySQL) int {
+	rows, err := m.query(repository.NotTx, "SELECT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	n := 0
+	for rows.Next() {
+		n++
+	}
+	return n
+}
+
+func TestTransaction(t *testing.T) {
+	m := newTestMySQL(t)
+	defer m.close()
+
+	tx, err := m.begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.execute(tx, "INSERT rolledback"); err != nil {
+		t.Fatal(err)
+	}
+	if n := countRows(t, m); n != 0 {
+		t.Errorf("expected the uncommitted write is not visible, got %d rows", n)
+	}
+	if err := m.rollback(tx); err != nil {
+		t.Fatal(err)
+	}
+	if n := countRows(t, m); n != 0 {
+		t.Errorf("expected the rolled back write is not visible, got %d rows", n)
+	}
+
+	tx, err = m.begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.execute(tx, "INSERT committed"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.commit(tx); err != nil {
+		t.Fatal(err)
+	}
+	if n := countRows(t, m); n != 1 {
+		t.Errorf("expected the committed write is visible, got %d rows", n)
+	}
+
+	if _, err := m.execute(tx, "INSERT finished"); err == nil {
+		t.Error("expected the finished transaction can not be used")
+	}
+}
+
+func TestCommitFailure(t *testing.T) {
+	m := newTestMySQL(t)
+	defer m.close()
+
+	tx, err := m.begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.execute(tx, "INSERT failed"); err != nil {
+		t.Fatal(err)
+	}
+
+	testDB.mu.Lock()
+	testDB.failCommit = true
+	testDB.mu.Unlock()
+
+	if err := m.commit(tx); err == nil {
+		t.Fatal("expected the commit fails")
+	}
+	if len(m.txs) != 0 {
+		t.Errorf("expected the failed transaction is removed, got %d transactions", len(m.txs))
+	}
+	if n := countRows(t, m); n != 0 {
+		t.Errorf("expected the failed write is not visible, got %d rows", n)
+	}
+}
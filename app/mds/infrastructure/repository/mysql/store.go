@@ -59,7 +59,7 @@ func (s *Store) Rollback(txid repository.TxID) error {
 }
 
 // Commit commits the transaction with the given ID.
-// Auto remove the transaction only when the transaction has been succeeded.
+// The transaction is removed whether the commit succeeds or not.
 func (s *Store) Commit(txid repository.TxID) error {
 	return s.db.commit(txid)
 }
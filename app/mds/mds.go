@@ -8,11 +8,13 @@ import (
 
 	"github.com/chanyoung/nil/app/mds/application/account"
 	"github.com/chanyoung/nil/app/mds/application/gencoding"
+	"github.com/chanyoung/nil/app/mds/application/lifecycle"
 	"github.com/chanyoung/nil/app/mds/application/membership"
 	"github.com/chanyoung/nil/app/mds/application/object"
 	"github.com/chanyoung/nil/app/mds/delivery"
 	"github.com/chanyoung/nil/app/mds/domain/model/bucket"
 	"github.com/chanyoung/nil/app/mds/domain/model/clustermap"
+	"github.com/chanyoung/nil/app/mds/domain/model/masterkey"
 	"github.com/chanyoung/nil/app/mds/domain/model/region"
 	"github.com/chanyoung/nil/app/mds/domain/model/user"
 	"github.com/chanyoung/nil/app/mds/domain/service/raft"
@@ -47,8 +49,10 @@ func Bootstrap(cfg config.Mds) error {
 		clustermapRepository clustermap.Repository
 		userRepository       user.Repository
 		bucketRepository     bucket.Repository
+		masterKeyRepository  masterkey.Repository
 		objectStore          object.Repository
 		gencodingStore       gencoding.Repository
+		lifecycleStore       lifecycle.Repository
 		raftService          raft.Service
 		raftSimpleService    raft.SimpleService
 	)
@@ -58,8 +62,10 @@ func Bootstrap(cfg config.Mds) error {
 		clustermapRepository = mysql.NewClusterMapRepository(store)
 		userRepository = mysql.NewUserRepository(store)
 		bucketRepository = mysql.NewBucketRepository(store)
+		masterKeyRepository = mysql.NewMasterKeyRepository(store)
 		objectStore = mysql.NewObjectRepository(store)
 		gencodingStore = mysql.NewGencodingRepository(store)
+		lifecycleStore = mysql.NewLifecycleRepository(store)
 		raftService = store.NewRaftService()
 		raftSimpleService = raftService.NewRaftSimpleService()
 	} else {
@@ -76,13 +82,14 @@ func Bootstrap(cfg config.Mds) error {
 	}
 
 	// Setup application handlers.
-	accountService := account.NewService(&cfg, raftSimpleService, regionRepository, userRepository, bucketRepository)
+	accountService := account.NewService(&cfg, raftSimpleService, regionRepository, userRepository, bucketRepository, masterKeyRepository)
 	membershipService := membership.NewService(&cfg, cmapService.MasterAPI(), raftService, regionRepository, clustermapRepository)
 	objectHandlers := object.NewHandlers(objectStore)
 	gencodingService, err := gencoding.NewService(&cfg, cmapService.SlaveAPI(), gencodingStore)
 	if err != nil {
 		return errors.Wrap(err, "failed to create global encoding service")
 	}
+	lifecycleService := lifecycle.NewService(&cfg, cmapService.SlaveAPI(), regionRepository, bucketRepository, objectStore, lifecycleStore)
 
 	// Setup delivery service.
 	delivery, err := delivery.SetupDeliveryService(
@@ -93,6 +100,9 @@ func Bootstrap(cfg config.Mds) error {
 	}
 	ctxLogger.Info("bootstrap mds succeeded")
 
+	// Start to expire the objects by the lifecycle rules of the buckets.
+	lifecycleService.Run()
+
 	// Make channel for Ctrl-C or other terminate signal is received.
 	sigc := make(chan os.Signal, 1)
 	signal.Notify(sigc, syscall.SIGINT, syscall.SIGTERM, os.Interrupt, os.Kill)
@@ -37,6 +37,9 @@ func init() {
 	gwCmd.Flags().StringVarP(&gwCfg.FirstMds, "first-mds", "", config.Get("gw.first_mds"), "mds address to get local cluster information in initialize routine")
 
 	gwCmd.Flags().StringVarP(&gwCfg.WorkDir, "work-dir", "", config.Get("gw.work_dir"), "working directory")
+	gwCmd.Flags().StringVarP(&gwCfg.BaseDomains, "base-domains", "", config.Get("gw.base_domains"), "comma separated domains for virtual-hosted-style bucket addressing")
+	gwCmd.Flags().StringVarP(&gwCfg.ClockSkew, "clock-skew", "", config.Get("gw.clock_skew"), "maximum difference between the signed request time and the gateway clock")
+	gwCmd.Flags().StringVarP(&gwCfg.LocalClusterRegion, "raft-local-cluster-region", "", config.Get("raft.local_cluster_region"), "region name of the local cluster")
 
 	gwCmd.Flags().StringVarP(&gwCfg.Security.CertsDir, "secure-certs-dir", "", config.Get("security.certs_dir"), "directory path of secure configuration files")
 	gwCmd.Flags().StringVarP(&gwCfg.Security.RootCAPem, "secure-rootca-pem", "", config.Get("security.rootca_pem"), "file name of rootCA.pem")
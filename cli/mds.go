@@ -47,6 +47,7 @@ func init() {
 	mdsCmd.Flags().StringVarP(&mdscfg.MySQLDatabase, "mysql-database", "", config.Get("mds.mysql_database"), "mysql schema name")
 
 	mdsCmd.Flags().StringVarP(&mdscfg.Rebalance, "rebalance", "", config.Get("mds.rebalance"), "peroid for checking balance of the cluster")
+	mdsCmd.Flags().StringVarP(&mdscfg.Lifecycle, "lifecycle", "", config.Get("mds.lifecycle"), "period for evaluating lifecycle rules of the buckets")
 
 	mdsCmd.Flags().StringVarP(&mdscfg.Raft.LocalClusterAddr, "raft-local-cluster-addr", "", config.Get("raft.local_cluster_addr"), "raft local cluster end point")
 	mdsCmd.Flags().StringVarP(&mdscfg.Raft.LocalClusterRegion, "raft-local-cluster-region", "", config.Get("raft.local_cluster_region"), "region name of the local cluster")
@@ -1,6 +1,10 @@
 package client
 
-import "net/http"
+import (
+	"net/http"
+
+	"github.com/chanyoung/nil/pkg/s3"
+)
 
 type RequestEvent interface {
 	// Getter
@@ -8,12 +12,15 @@ type RequestEvent interface {
 	ResponseWriter() http.ResponseWriter
 	Request() *http.Request
 	AccessKey() string
+	// Anonymous returns true if the request is not signed.
+	Anonymous() bool
 	Region() string
 	Bucket() string
 	Type() RequestType
 	MD5() string
 
 	// Method includes business logic.
+	Validate() s3.ErrorCode
 	Auth(secretKey string) bool
 	// CopyAuthHeader copy headers which is used to authenticate.
 	CopyAuthHeader() map[string]string
@@ -24,4 +31,5 @@ type RequestEvent interface {
 	SendIncorrectKey()
 	SendNoSuchKey()
 	SendInvalidURI()
+	SendError(code s3.ErrorCode)
 }
@@ -1,6 +1,9 @@
 package client
 
-import "net/http"
+import (
+	"fmt"
+	"net/http"
+)
 
 // RequestType represents the type of request.
 type RequestType string
@@ -8,8 +11,18 @@ type RequestType string
 const (
 	// WriteToPrimary means the request is head to primary ds and will write a single object.
 	WriteToPrimary RequestType = "WriteToPrimary"
+	// CopyToPrimary means the request is head to primary ds and will write a single object
+	// whose data is copied from the objects in the other ds.
+	CopyToPrimary RequestType = "CopyToPrimary"
 	// WriteToFollower means the request is head to followers and will wirte a single object.
 	WriteToFollower RequestType = "WriteToFollower"
+	// ReadFromPrimary means the request is head to primary ds and will read a single object.
+	ReadFromPrimary RequestType = "ReadFromPrimary"
+	// DeleteFromPrimary means the request is head to primary ds and will delete a single object.
+	DeleteFromPrimary RequestType = "DeleteFromPrimary"
+	// DeleteObjectsFromPrimary means the request is head to primary ds and will delete the objects
+	// in a single volume.
+	DeleteObjectsFromPrimary RequestType = "DeleteObjectsFromPrimary"
 	// UnknownType means the type of the request is unknown.
 	UnknownType RequestType = "unknown"
 )
@@ -18,6 +31,34 @@ func (t RequestType) String() string {
 	return string(t)
 }
 
+// CopySource is the range of the object data in the ds, which is copied by
+// the CopyToPrimary request. The ds which copies the data finds the source
+// ds by its id in the cluster map.
+type CopySource struct {
+	DsID     string
+	Bucket   string
+	ObjectID string
+	ChainID  string
+	VolumeID string
+	Offset   int64
+	Length   int64
+}
+
+// DeleteObjectsResult is the result of the DeleteObjectsFromPrimary request,
+// which lists the objects failed to be deleted.
+type DeleteObjectsResult struct {
+	Errors []DeleteObjectError
+}
+
+// DeleteObjectError is the object which is failed to be deleted.
+type DeleteObjectError struct {
+	ObjectID string
+	Message  string
+	// LockUnknown is true if the object is kept since the lock of its
+	// version could not be looked up. Deleting it again may succeed.
+	LockUnknown bool
+}
+
 // Headers holds the information which needs to handle requests.
 type Headers map[string]string
 
@@ -46,6 +87,11 @@ func (h Headers) SetMD5(md5sum string) {
 	h["Md5"] = md5sum
 }
 
+// SetRange set the byte range of the object which will be read.
+func (h Headers) SetRange(offset, length int64) {
+	h["Range"] = fmt.Sprintf("bytes=%d-%d", offset, offset+length-1)
+}
+
 // Request is the client request for rest API calling.
 type Request interface {
 	Send() (*http.Response, error)
@@ -0,0 +1,133 @@
+package request
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/chanyoung/nil/pkg/client"
+	"github.com/chanyoung/nil/pkg/cmap"
+	"github.com/pkg/errors"
+)
+
+// ObjectData is the location of the object data in the ds.
+type ObjectData struct {
+	Ds     cmap.ID
+	Volume cmap.ID
+	Oid    string
+}
+
+// LockUnknownError is the error of the object data which is kept, since the
+// ds could not look up the locks of the versions which own the data.
+// Deleting the data again may succeed.
+type LockUnknownError struct {
+	Objs []ObjectData
+}
+
+func (e *LockUnknownError) Error() string {
+	return fmt.Sprintf("failed to get the locks of %d objects in volume %s",
+		len(e.Objs), e.Objs[0].Volume.String())
+}
+
+// DeleteObjectData deletes the data of the objects in the bucket. The data
+// is grouped by the volume and the data in each volume is deleted by a
+// single request to its ds, which is sent by the send function. The
+// volumes are deleted concurrently, and the errors of the failed ones are
+// returned. The data kept for the unknown locks is returned as the
+// *LockUnknownError.
+func DeleteObjectData(cmapAPI cmap.SlaveAPI, send SendFunc, bucket string, objs []ObjectData) []error {
+	volumes := make(map[cmap.ID][]ObjectData)
+	for _, o := range objs {
+		volumes[o.Volume] = append(volumes[o.Volume], o)
+	}
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	for _, vobjs := range volumes {
+		wg.Add(1)
+		go func(vobjs []ObjectData) {
+			defer wg.Done()
+
+			if verrs := deleteVolumeData(cmapAPI, send, bucket, vobjs); len(verrs) > 0 {
+				mu.Lock()
+				errs = append(errs, verrs...)
+				mu.Unlock()
+			}
+		}(vobjs)
+	}
+	wg.Wait()
+
+	return errs
+}
+
+// deleteVolumeData deletes the data of the objects in the same volume.
+func deleteVolumeData(cmapAPI cmap.SlaveAPI, send SendFunc, bucket string, objs []ObjectData) []error {
+	vol := objs[0].Volume
+	ds, err := cmapAPI.SearchCall().Node().ID(objs[0].Ds).Do()
+	if err != nil {
+		return []error{errors.Wrapf(err, "find ds failed: %s", objs[0].Ds.String())}
+	}
+
+	oids := make([]string, len(objs))
+	for i, o := range objs {
+		oids[i] = o.Oid
+	}
+	body, err := json.Marshal(oids)
+	if err != nil {
+		return []error{errors.Wrap(err, "failed to encode object ids")}
+	}
+
+	headers := client.NewHeaders()
+	headers.SetVolumeID(vol.String())
+
+	resp, err := send(
+		client.DeleteObjectsFromPrimary, http.MethodPost,
+		"https://"+ds.Addr.String()+"/"+bucket,
+		bytes.NewReader(body), headers, int64(len(body)),
+	)
+	if err != nil {
+		return []error{errors.Wrap(err, "failed to send ds request")}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return []error{fmt.Errorf("ds returns http status code: %d", resp.StatusCode)}
+	}
+
+	result := client.DeleteObjectsResult{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return []error{errors.Wrap(err, "failed to decode ds response")}
+	}
+
+	byOid := make(map[string]ObjectData, len(objs))
+	for _, o := range objs {
+		byOid[o.Oid] = o
+	}
+	var (
+		errs    []error
+		failed  []client.DeleteObjectError
+		unknown []ObjectData
+	)
+	for _, e := range result.Errors {
+		if e.LockUnknown {
+			unknown = append(unknown, byOid[e.ObjectID])
+		} else {
+			failed = append(failed, e)
+		}
+	}
+	if len(failed) > 0 {
+		e := failed[0]
+		errs = append(errs, fmt.Errorf("failed to delete %d of %d objects in volume %s, %s: %s",
+			len(failed), len(objs), vol.String(), e.ObjectID, e.Message))
+	}
+	if len(unknown) > 0 {
+		errs = append(errs, &LockUnknownError{Objs: unknown})
+	}
+
+	return errs
+}
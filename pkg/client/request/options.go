@@ -1,14 +1,19 @@
 package request
 
+import "time"
+
 // EventFactoryOption holds the request factory options.
 type EventFactoryOption func(*eventFactoryOptions)
 
 type eventFactoryOptions struct {
-	useS3 bool
+	useS3     bool
+	region    string
+	clockSkew time.Duration
 }
 
 var defaultEventFactoryOptions = eventFactoryOptions{
-	useS3: true,
+	useS3:     true,
+	clockSkew: 15 * time.Minute,
 }
 
 // WithS3EventFactory means allow the factory to create s3 type of requests.
@@ -18,6 +23,22 @@ func WithS3EventFactory(enabled bool) EventFactoryOption {
 	}
 }
 
+// WithRegion restricts the requests to be signed for the given region.
+// Any region is allowed if it is empty.
+func WithRegion(region string) EventFactoryOption {
+	return func(o *eventFactoryOptions) {
+		o.region = region
+	}
+}
+
+// WithClockSkew sets the maximum difference between the time of the signed
+// request and the server clock.
+func WithClockSkew(skew time.Duration) EventFactoryOption {
+	return func(o *eventFactoryOptions) {
+		o.clockSkew = skew
+	}
+}
+
 // Option allows to set the client request options.
 type Option func(*options)
 
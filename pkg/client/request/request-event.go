@@ -24,21 +24,35 @@ type RequestEventFactory struct {
 
 // CreateRequestEvent creates a validated request event.
 func (f *RequestEventFactory) CreateRequestEvent(w http.ResponseWriter, r *http.Request) (client.RequestEvent, error) {
-	switch classifyProtocol(r.Header) {
+	switch classifyProtocol(r) {
 	case client.S3:
-		return s3.NewS3RequestEvent(w, r)
+		return s3.NewS3RequestEvent(w, r, f.o.region, f.o.clockSkew)
 	default:
 		return nil, client.ErrInvalidProtocol
 	}
 }
 
-func classifyProtocol(h http.Header) client.Protocol {
+func classifyProtocol(r *http.Request) client.Protocol {
+	h := r.Header
+	// Signature v2 requests may carry only the Date header.
+	if ok := h.Get("Authorization"); ok != "" {
+		return client.S3
+	}
 	if ok := h.Get("X-Amz-Date"); ok != "" {
 		return client.S3
 	}
 	if ok := h.Get("Amz-Sdk-Invocation-Id"); ok != "" {
 		return client.S3
 	}
+	// The presigned url carries the sign in the query instead of headers.
+	if ok := r.URL.Query().Get("X-Amz-Algorithm"); ok != "" {
+		return client.S3
+	}
+	// The anonymous request does not carry any sign, which is allowed to
+	// access only the public buckets and objects.
+	if ok := r.URL.Query().Get("X-Amz-Signature"); ok == "" {
+		return client.S3
+	}
 
 	return client.Unknown
 }
@@ -40,3 +40,15 @@ func NewRequest(reqType client.RequestType, method, url string, body io.Reader,
 
 	return nil, fmt.Errorf("no matching client type")
 }
+
+// SendFunc creates the request with the given arguments and sends it.
+type SendFunc func(reqType client.RequestType, method, url string, body io.Reader, headers client.Headers, contentLength int64) (*http.Response, error)
+
+// Send creates the request with the default options and sends it.
+func Send(reqType client.RequestType, method, url string, body io.Reader, headers client.Headers, contentLength int64) (*http.Response, error) {
+	req, err := NewRequest(reqType, method, url, body, headers, contentLength)
+	if err != nil {
+		return nil, err
+	}
+	return req.Send()
+}
@@ -1,10 +1,13 @@
 package s3
 
 import (
+	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/chanyoung/nil/pkg/client"
 	s3lib "github.com/chanyoung/nil/pkg/s3"
@@ -17,20 +20,48 @@ type S3RequestEvent struct {
 	httpWriter  http.ResponseWriter
 	httpRequest *http.Request
 
+	// region is the region which the request must be signed for, and
+	// clockSkew is the allowed difference of the request time.
+	region    string
+	clockSkew time.Duration
+
 	signVer    int
 	authArgsV2 *s3lib.SignV2
 	authArgsV4 *s3lib.SignV4
+
+	// presignV4 is set if the request is authenticated by the presigned
+	// url instead of the authorization header.
+	presignV4 *s3lib.PresignV4
 }
 
-// NewS3RequestEvent creates a new s3 request event.
-func NewS3RequestEvent(w http.ResponseWriter, r *http.Request) (client.RequestEvent, error) {
+// NewS3RequestEvent creates a new s3 request event. The request must be
+// signed for the given region within the clock skew.
+func NewS3RequestEvent(w http.ResponseWriter, r *http.Request, region string, clockSkew time.Duration) (client.RequestEvent, error) {
 	e := &S3RequestEvent{
 		protocol:    client.S3,
 		httpWriter:  w,
 		httpRequest: r,
+		region:      region,
+		clockSkew:   clockSkew,
 	}
 
 	authStr := r.Header.Get("Authorization")
+	if authStr == "" && s3lib.IsPresignedV4(r.URL.Query()) {
+		presign, s3err := s3lib.ParsePresignV4(r.URL.Query())
+		if s3err != s3lib.ErrNone {
+			return nil, client.ErrInvalidProtocol
+		}
+		e.signVer = s3lib.V4
+		e.authArgsV4 = &presign.SignV4
+		e.presignV4 = presign
+
+		return e, nil
+	}
+	// The anonymous request has no signature version.
+	if authStr == "" && r.URL.Query().Get("X-Amz-Signature") == "" {
+		return e, nil
+	}
+
 	v := s3lib.SignatureVersion(authStr)
 	switch v {
 	case s3lib.V2:
@@ -83,6 +114,11 @@ func (r *S3RequestEvent) AccessKey() string {
 	}
 }
 
+// Anonymous returns true if the request is not signed.
+func (r *S3RequestEvent) Anonymous() bool {
+	return r.signVer == 0
+}
+
 // Region is a getter of region.
 func (r *S3RequestEvent) Region() string {
 	switch r.signVer {
@@ -95,16 +131,66 @@ func (r *S3RequestEvent) Region() string {
 	}
 }
 
-// Bucket is a getter of bucket.
+// Bucket is a getter of bucket. The virtual-hosted-style request is already
+// rewritten into the path-style, so the bucket is the first segment of the
+// path.
 func (r *S3RequestEvent) Bucket() string {
-	return strings.Trim(r.httpRequest.RequestURI, "/")
+	return strings.SplitN(strings.TrimPrefix(r.httpRequest.URL.Path, "/"), "/", 2)[0]
+}
+
+// Validate checks the request can be authenticated at this time. The time
+// of the signed request must be close to the server clock, so that the
+// captured request cannot be replayed later.
+func (r *S3RequestEvent) Validate() s3lib.ErrorCode {
+	now := time.Now()
+
+	if p := r.presignV4; p != nil {
+		if !p.Credential.Matches(p.Date, r.region) {
+			return s3lib.ErrAuthorizationQueryParametersError
+		}
+		if p.Date.After(now.Add(r.clockSkew)) {
+			return s3lib.ErrRequestTimeTooSkewed
+		}
+		if p.Expired(now) {
+			return s3lib.ErrExpiredPresignRequest
+		}
+		return s3lib.ErrNone
+	}
+
+	switch r.signVer {
+	case s3lib.V2:
+		date := r.httpRequest.Header.Get("X-Amz-Date")
+		if date == "" {
+			date = r.httpRequest.Header.Get("Date")
+		}
+		t, err := http.ParseTime(date)
+		if err != nil {
+			return s3lib.ErrAccessDenied
+		}
+		if s3lib.Skewed(t, now, r.clockSkew) {
+			return s3lib.ErrRequestTimeTooSkewed
+		}
+	case s3lib.V4:
+		t, err := time.Parse(s3lib.AmzDateFormat, r.httpRequest.Header.Get("X-Amz-Date"))
+		if err != nil {
+			return s3lib.ErrAccessDenied
+		}
+		if !r.authArgsV4.Credential.Matches(t, r.region) {
+			return s3lib.ErrAuthorizationHeaderMalformed
+		}
+		if s3lib.Skewed(t, now, r.clockSkew) {
+			return s3lib.ErrRequestTimeTooSkewed
+		}
+	}
+
+	return s3lib.ErrNone
 }
 
 // Auth checks the given secret key is same with the encoded secret key in the http request.
 func (r *S3RequestEvent) Auth(secretKey string) bool {
 	switch r.signVer {
 	case s3lib.V2:
-		return true
+		return r.authV2(secretKey)
 	case s3lib.V4:
 		return r.authV4(secretKey)
 	default:
@@ -112,16 +198,42 @@ func (r *S3RequestEvent) Auth(secretKey string) bool {
 	}
 }
 
+func (r *S3RequestEvent) authV2(secretKey string) bool {
+	// The canonicalized resource starts with the bucket and the path of the
+	// request without decoding. The path of the virtual-hosted-style
+	// request is already rewritten to begin with the bucket.
+	resource := r.httpRequest.URL.EscapedPath()
+
+	stringToSign := s3lib.GenStringToSignV2(r.httpRequest, resource)
+	derivedSignature := s3lib.GenSignatureV2(secretKey, stringToSign)
+
+	return hmac.Equal([]byte(r.authArgsV2.Signature), []byte(derivedSignature))
+}
+
 func (r *S3RequestEvent) authV4(secretKey string) bool {
+	query := r.httpRequest.URL.Query()
+	date := r.httpRequest.Header.Get("X-Amz-Date")
+	payload := r.httpRequest.Header.Get("X-Amz-Content-Sha256")
+
+	// The presigned url carries the sign in the query, which is excluded
+	// from the canonical query string except for the signature itself.
+	if r.presignV4 != nil {
+		query.Del("X-Amz-Signature")
+		date = query.Get("X-Amz-Date")
+		payload = s3lib.UnsignedPayload
+	}
+
 	// Task 1: Create a Canonical Request for Signature Version 4.
 	// https://docs.aws.amazon.com/ko_kr/general/latest/gr/sigv4-create-canonical-request.html
+	// The canonical uri is the path as it is sent by the client, which does
+	// not include the bucket of the virtual-hosted-style request.
 	canonicalRequest := s3lib.GenCanonicalRequest(
 		r.httpRequest.Method,
-		r.httpRequest.RequestURI,
-		r.httpRequest.URL.Query().Encode(),
+		strings.SplitN(r.httpRequest.RequestURI, "?", 2)[0],
+		s3lib.GenCanonicalQueryString(query),
 		s3lib.GenCanonicalHeaders(r.httpRequest, r.authArgsV4.SignedHeaders),
 		s3lib.GenSignedHeadersString(r.authArgsV4.SignedHeaders),
-		r.httpRequest.Header.Get("X-Amz-Content-Sha256"),
+		payload,
 	)
 
 	// Task 2: Create a String to Sign for Signature Version 4.
@@ -129,7 +241,7 @@ func (r *S3RequestEvent) authV4(secretKey string) bool {
 	sha256CanonicalRequest := sha256.Sum256([]byte(canonicalRequest))
 	stringToSign := s3lib.GenStringToSign(
 		"AWS4-HMAC-SHA256",
-		r.httpRequest.Header.Get("X-Amz-Date"),
+		date,
 		r.authArgsV4.Credential.Scope(),
 		hex.EncodeToString(sha256CanonicalRequest[:]),
 	)
@@ -139,10 +251,26 @@ func (r *S3RequestEvent) authV4(secretKey string) bool {
 	signatureKey := s3lib.GenSignatureKey(secretKey, r.authArgsV4.Credential.Date, r.authArgsV4.Credential.Region, r.authArgsV4.Credential.Service)
 
 	derivedSignature := s3lib.GenSignature(signatureKey, stringToSign)
-	if r.authArgsV4.Signature != derivedSignature {
+	if !hmac.Equal([]byte(r.authArgsV4.Signature), []byte(derivedSignature)) {
 		return false
 	}
 
+	// The chunks of the streaming payload are signed in a chain starting
+	// from the signature of the headers. They are verified while the body
+	// is read, which is replaced by the decoded data.
+	if payload == s3lib.StreamingPayload {
+		size, err := strconv.ParseInt(r.httpRequest.Header.Get("X-Amz-Decoded-Content-Length"), 10, 64)
+		if err != nil || size < 0 {
+			return false
+		}
+
+		r.httpRequest.Body = s3lib.NewChunkedReader(
+			r.httpRequest.Body, signatureKey,
+			date, r.authArgsV4.Credential.Scope(), derivedSignature,
+		)
+		r.httpRequest.ContentLength = size
+	}
+
 	return true
 }
 
@@ -175,6 +303,11 @@ func (r *S3RequestEvent) SendInvalidURI() {
 	s3lib.SendError(r.httpWriter, s3lib.ErrInvalidURI, r.httpRequest.RequestURI, "")
 }
 
+// SendError sends the s3 error of the given code to the client.
+func (r *S3RequestEvent) SendError(code s3lib.ErrorCode) {
+	s3lib.SendError(r.httpWriter, code, r.httpRequest.RequestURI, "")
+}
+
 // CopyAuthHeader copy headers which is used to authenticate.
 func (r *S3RequestEvent) CopyAuthHeader() map[string]string {
 	header := make(map[string]string)
@@ -201,6 +334,12 @@ func (r *S3RequestEvent) Type() client.RequestType {
 		return client.WriteToPrimary
 	case client.WriteToFollower.String():
 		return client.WriteToFollower
+	case client.ReadFromPrimary.String():
+		return client.ReadFromPrimary
+	case client.DeleteFromPrimary.String():
+		return client.DeleteFromPrimary
+	case client.DeleteObjectsFromPrimary.String():
+		return client.DeleteObjectsFromPrimary
 	default:
 		return client.UnknownType
 	}
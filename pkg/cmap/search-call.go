@@ -128,6 +128,61 @@ func (c *SearchCallNode) Do() (Node, error) {
 	return Node{}, ErrNotFound
 }
 
+// Matrix returns a SearchCallMatrix object for searching encoding matrix.
+// The objects which are encoded together are grouped by the matrix, so
+// the id of the matrix is the id of the encoding group of them.
+func (c *SearchCall) Matrix() *SearchCallMatrix {
+	return &SearchCallMatrix{
+		cmap: c.cmap,
+		id:   -1,
+	}
+}
+
+// SearchCallMatrix is a handle of search call encoding matrix operation.
+type SearchCallMatrix struct {
+	cmap   *CMap
+	id     int
+	random bool
+}
+
+// ID set the matrix id search condition.
+func (c *SearchCallMatrix) ID(id int) *SearchCallMatrix {
+	c.id = id
+	return c
+}
+
+// Random will return the result in random.
+func (c *SearchCallMatrix) Random() *SearchCallMatrix {
+	c.random = true
+	return c
+}
+
+// Do returns the search result.
+func (c *SearchCallMatrix) Do() (int, error) {
+	var randIdx []int
+	if c.random {
+		randIdx = random.Perm(len(c.cmap.MatrixIDs))
+	}
+
+	for i := 0; i < len(c.cmap.MatrixIDs); i++ {
+		var id int
+		if c.random {
+			id = c.cmap.MatrixIDs[randIdx[i]]
+		} else {
+			id = c.cmap.MatrixIDs[i]
+		}
+
+		// If search condition for ID is set, but the ID is not matched.
+		if c.id != -1 && id != c.id {
+			continue
+		}
+
+		return id, nil
+	}
+
+	return -1, ErrNotFound
+}
+
 func init() {
 	// Initializes random seed.
 	random = rand.New(rand.NewSource(time.Now().Unix()))
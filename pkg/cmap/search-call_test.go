@@ -78,3 +78,43 @@ func TestSearchCallNode(t *testing.T) {
 		}
 	}
 }
+
+func TestSearchCallMatrix(t *testing.T) {
+	testMap := CMap{
+		Version:   1,
+		MatrixIDs: []int{3, 7, 11},
+	}
+
+	ct := newManager()
+	if err := ct.Update(&testMap); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll("./cmap")
+
+	c := ct.SearchCall()
+	for _, id := range testMap.MatrixIDs {
+		if find, err := c.Matrix().ID(id).Do(); err != nil {
+			t.Error(err)
+		} else if find != id {
+			t.Errorf("expected %d, got %d", id, find)
+		}
+	}
+
+	if _, err := c.Matrix().ID(5).Do(); err != ErrNotFound {
+		t.Errorf("expected %v, got %v", ErrNotFound, err)
+	}
+
+	if find, err := c.Matrix().Random().Do(); err != nil {
+		t.Error(err)
+	} else if find != 3 && find != 7 && find != 11 {
+		t.Errorf("expected one of %v, got %d", testMap.MatrixIDs, find)
+	}
+
+	empty := newManager()
+	if err := empty.Update(&CMap{Version: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := empty.SearchCall().Matrix().Random().Do(); err != ErrNotFound {
+		t.Errorf("expected %v, got %v", ErrNotFound, err)
+	}
+}
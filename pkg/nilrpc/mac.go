@@ -1,6 +1,10 @@
 package nilrpc
 
-import "github.com/chanyoung/nil/pkg/s3"
+import (
+	"time"
+
+	"github.com/chanyoung/nil/pkg/s3"
+)
 
 // MACAddUserRequest requests to create a new user with the given name.
 type MACAddUserRequest struct {
@@ -30,9 +34,201 @@ type MACMakeBucketRequest struct {
 	BucketName string
 	AccessKey  string
 	Region     string
+	// ACL is the canned acl of the bucket, which is private if empty.
+	ACL string
+	// ObjectLock is the object lock configuration in xml of the bucket
+	// which is created with the object lock enabled, and empty otherwise.
+	ObjectLock string
 }
 
 // MACMakeBucketResponse responses the result of addBucket.
 type MACMakeBucketResponse struct {
 	S3ErrCode s3.ErrorCode
 }
+
+// MACRemoveBucketRequest requests to remove the bucket. Region is the
+// region of the bucket, which is filled by the mds that receives the
+// request first.
+type MACRemoveBucketRequest struct {
+	BucketName string
+	Region     string
+}
+
+// MACRemoveBucketResponse responses the result of removing the bucket.
+type MACRemoveBucketResponse struct {
+	S3ErrCode s3.ErrorCode
+}
+
+// MACGetBucketRequest requests the information of the bucket.
+type MACGetBucketRequest struct {
+	BucketName string
+}
+
+// MACGetBucketResponse responses the owner and the region of the bucket.
+type MACGetBucketResponse struct {
+	S3ErrCode  s3.ErrorCode
+	BucketName string
+	// Owner is the access key of the bucket owner.
+	Owner  string
+	Region string
+	// Versioning is the versioning state of the bucket, which is empty
+	// if the bucket has never been versioned.
+	Versioning string
+	// Lifecycle is the lifecycle configuration of the bucket in xml, which
+	// is empty if the bucket has no lifecycle rules.
+	Lifecycle string
+	// ACL is the canned acl of the bucket.
+	ACL string
+	// Policy is the bucket policy in json, which is empty if the bucket
+	// has no policy.
+	Policy string
+	// CORS is the cors configuration of the bucket in xml, which is empty
+	// if the bucket has no cors rules.
+	CORS string
+	// ObjectLock is the object lock configuration of the bucket in xml,
+	// which is empty if the object lock is not enabled.
+	ObjectLock string
+
+	// policy is the parsed Policy, which is cached by ParsedPolicy.
+	policy     *s3.Policy
+	policyCode s3.ErrorCode
+}
+
+// ParsedPolicy returns the parsed bucket policy, or nil if the bucket has
+// no policy. The policy is parsed once and cached in the response, which
+// is not safe for concurrent use.
+func (r *MACGetBucketResponse) ParsedPolicy() (*s3.Policy, s3.ErrorCode) {
+	if r.Policy == "" {
+		return nil, s3.ErrNone
+	}
+	if r.policy == nil && r.policyCode == s3.ErrNone {
+		r.policy, r.policyCode = s3.ParsePolicy([]byte(r.Policy), r.BucketName)
+	}
+	return r.policy, r.policyCode
+}
+
+// MACSetBucketVersioningRequest requests to change the versioning state
+// of the bucket. Region is the region of the bucket, which is filled by
+// the mds that receives the request first.
+type MACSetBucketVersioningRequest struct {
+	BucketName string
+	Region     string
+	Versioning string
+}
+
+// MACSetBucketVersioningResponse responses the result of changing the
+// versioning state.
+type MACSetBucketVersioningResponse struct {
+	S3ErrCode s3.ErrorCode
+}
+
+// MACSetBucketLifecycleRequest requests to replace the lifecycle
+// configuration of the bucket. The empty configuration removes the
+// lifecycle rules.
+type MACSetBucketLifecycleRequest struct {
+	BucketName string
+	Region     string
+	Lifecycle  string
+}
+
+// MACSetBucketLifecycleResponse responses the result of replacing the
+// lifecycle configuration.
+type MACSetBucketLifecycleResponse struct {
+	S3ErrCode s3.ErrorCode
+}
+
+// MACSetBucketACLRequest requests to replace the canned acl of the bucket.
+type MACSetBucketACLRequest struct {
+	BucketName string
+	Region     string
+	ACL        string
+}
+
+// MACSetBucketACLResponse responses the result of replacing the acl.
+type MACSetBucketACLResponse struct {
+	S3ErrCode s3.ErrorCode
+}
+
+// MACSetBucketPolicyRequest requests to replace the bucket policy of the
+// bucket. The empty policy removes the policy.
+type MACSetBucketPolicyRequest struct {
+	BucketName string
+	Region     string
+	Policy     string
+}
+
+// MACSetBucketPolicyResponse responses the result of replacing the policy.
+type MACSetBucketPolicyResponse struct {
+	S3ErrCode s3.ErrorCode
+}
+
+// MACSetBucketCORSRequest requests to replace the cors configuration of
+// the bucket. The empty configuration removes the cors rules.
+type MACSetBucketCORSRequest struct {
+	BucketName string
+	Region     string
+	CORS       string
+}
+
+// MACSetBucketCORSResponse responses the result of replacing the cors
+// configuration.
+type MACSetBucketCORSResponse struct {
+	S3ErrCode s3.ErrorCode
+}
+
+// MACGenerateDataKeyRequest requests a new data key of the object which is
+// encrypted by the server.
+type MACGenerateDataKeyRequest struct {
+}
+
+// MACGenerateDataKeyResponse responses the data key and the key sealed by
+// the master key, which is recorded with the object.
+type MACGenerateDataKeyResponse struct {
+	S3ErrCode s3.ErrorCode
+	Key       []byte
+	SealedKey string
+}
+
+// MACDecryptDataKeyRequest requests to unseal the data key of the object
+// with the master key.
+type MACDecryptDataKeyRequest struct {
+	SealedKey string
+}
+
+// MACDecryptDataKeyResponse responses the unsealed data key.
+type MACDecryptDataKeyResponse struct {
+	S3ErrCode s3.ErrorCode
+	Key       []byte
+}
+
+// MACSetBucketObjectLockRequest requests to replace the object lock
+// configuration of the bucket.
+type MACSetBucketObjectLockRequest struct {
+	BucketName string
+	Region     string
+	ObjectLock string
+}
+
+// MACSetBucketObjectLockResponse responses the result of replacing the
+// object lock configuration.
+type MACSetBucketObjectLockResponse struct {
+	S3ErrCode s3.ErrorCode
+}
+
+// MACListBucketsRequest requests the list of buckets owned by the access key.
+type MACListBucketsRequest struct {
+	AccessKey string
+}
+
+// MACListBucketsResponse responses the list of buckets.
+type MACListBucketsResponse struct {
+	S3ErrCode s3.ErrorCode
+	OwnerName string
+	Buckets   []MACBucket
+}
+
+// MACBucket is the entry of the bucket list.
+type MACBucket struct {
+	Name         string
+	CreationDate time.Time
+}
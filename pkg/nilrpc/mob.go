@@ -1,23 +1,478 @@
 package nilrpc
 
-import "github.com/chanyoung/nil/pkg/cmap"
+import (
+	"time"
 
+	"github.com/chanyoung/nil/pkg/cmap"
+	"github.com/chanyoung/nil/pkg/s3"
+)
+
+// MOBObjectPutRequest requests to record the location of the written object.
 type MOBObjectPutRequest struct {
 	Name          string
 	Bucket        string
 	EncodingGroup cmap.ID
 	Volume        cmap.ID
+	DsID          cmap.ID
+	// ObjectID is the id of the object data stored in the ds.
+	ObjectID string
+	Size     int64
+	ETag     string
+
+	ContentType string
+	// Headers is the standard headers stored with the object, such as
+	// Cache-Control, keyed by the canonical header name.
+	Headers map[string]string
+	// Metadata is the user-defined metadata without x-amz-meta- prefix.
+	Metadata map[string]string
+	// ACL is the canned acl of the object.
+	ACL string
+	// Encryption is how the object data is encrypted by the gateway.
+	Encryption MOBEncryption
+	// Tags is the tags of the object.
+	Tags map[string]string
+	// Lock is the retention and the legal hold of the object.
+	Lock s3.ObjectLock
+
+	// CreateOnly fails the put with ErrPreconditionFailed if the object
+	// already exists.
+	CreateOnly bool
 }
-type MOBObjectPutResponse struct{}
 
-type MOBObjectGetRequest struct {
-	Name   string
-	Bucket string
+// MOBObjectPutResponse responses the result of recording the object.
+// Obsolete is the data of the overwritten object, which is not reachable
+// anymore and should be deleted in the ds.
+type MOBObjectPutResponse struct {
+	S3ErrCode s3.ErrorCode
+	VersionID string
+	Obsolete  []MOBObjectPart
 }
-type MOBObjectGetResponse struct {
+
+// MOBEncryption is how the object data is encrypted by the gateway. Type
+// is s3.SSES3 or s3.SSEC, or empty if the data is not encrypted. Key is the
+// data key sealed by the master key for SSE-S3, or the salt which derives
+// the data key from the customer key for SSE-C. KeyMD5 is the salted md5
+// of the customer key for SSE-C.
+type MOBEncryption struct {
+	Type   string
+	Key    string
+	KeyMD5 string
+}
+
+// MOBObjectPart is the location of a piece of the object data stored in
+// the ds. Offset is the position of the part in the object.
+type MOBObjectPart struct {
 	EncodingGroupID cmap.ID
 	VolumeID        cmap.ID
 	DsID            cmap.ID
+	ObjectID        string
+	Offset          int64
+	Size            int64
+}
+
+// MOBObjectGetRequest requests the location of the object. The latest
+// version is requested if the version id is empty.
+type MOBObjectGetRequest struct {
+	Name      string
+	Bucket    string
+	VersionID string
+}
+
+// MOBObjectGetResponse responses the location and the attributes of the object.
+// The object data is the concatenation of the parts in order. If the
+// requested version is a delete marker, DeleteMarker is set with the error.
+type MOBObjectGetResponse struct {
+	S3ErrCode    s3.ErrorCode
+	VersionID    string
+	DeleteMarker bool
+	Parts        []MOBObjectPart
+	Size         int64
+	ETag         string
+	LastModified time.Time
+	ContentType  string
+	Headers      map[string]string
+	Metadata     map[string]string
+	ACL          string
+	Encryption   MOBEncryption
+	Tags         map[string]string
+	Lock         s3.ObjectLock
+}
+
+// MOBObjectHeadRequest requests the attributes of the object. The latest
+// version is requested if the version id is empty.
+type MOBObjectHeadRequest struct {
+	Name      string
+	Bucket    string
+	VersionID string
+}
+
+// MOBObjectHeadResponse responses the attributes of the object. If the
+// requested version is a delete marker, DeleteMarker is set with the error.
+type MOBObjectHeadResponse struct {
+	S3ErrCode    s3.ErrorCode
+	VersionID    string
+	DeleteMarker bool
+	Size         int64
+	ETag         string
+	LastModified time.Time
+	ContentType  string
+	Headers      map[string]string
+	Metadata     map[string]string
+	ACL          string
+	Encryption   MOBEncryption
+	Tags         map[string]string
+	Lock         s3.ObjectLock
+}
+
+// MOBSetACLRequest requests to replace the canned acl of the version of
+// the object. The latest version is changed if the version id is empty.
+type MOBSetACLRequest struct {
+	Name      string
+	Bucket    string
+	VersionID string
+	ACL       string
+}
+
+// MOBSetACLResponse responses the result of replacing the acl and the
+// version which is changed.
+type MOBSetACLResponse struct {
+	S3ErrCode s3.ErrorCode
+	VersionID string
+}
+
+// MOBSetTagsRequest requests to replace the tags of the version of the
+// object. The latest version is changed if the version id is empty, and
+// the empty tags remove the tags.
+type MOBSetTagsRequest struct {
+	Name      string
+	Bucket    string
+	VersionID string
+	Tags      map[string]string
+}
+
+// MOBSetTagsResponse responses the result of replacing the tags and the
+// version which is changed.
+type MOBSetTagsResponse struct {
+	S3ErrCode s3.ErrorCode
+	VersionID string
+}
+
+// MOBSetRetentionRequest requests to replace the retention of the version
+// of the object. The latest version is changed if the version id is empty,
+// and the empty mode removes the retention. BypassGovernance allows to
+// shorten or remove the retention in the governance mode.
+type MOBSetRetentionRequest struct {
+	Name             string
+	Bucket           string
+	VersionID        string
+	Mode             string
+	RetainUntil      time.Time
+	BypassGovernance bool
+}
+
+// MOBSetRetentionResponse responses the result of replacing the retention
+// and the version which is changed.
+type MOBSetRetentionResponse struct {
+	S3ErrCode s3.ErrorCode
+	VersionID string
+}
+
+// MOBSetLegalHoldRequest requests to turn the legal hold of the version of
+// the object on or off. The latest version is changed if the version id is
+// empty.
+type MOBSetLegalHoldRequest struct {
+	Name      string
+	Bucket    string
+	VersionID string
+	LegalHold bool
+}
+
+// MOBSetLegalHoldResponse responses the result of changing the legal hold
+// and the version which is changed.
+type MOBSetLegalHoldResponse struct {
+	S3ErrCode s3.ErrorCode
+	VersionID string
+}
+
+// MOBObjectDeleteRequest requests to delete the version of the object.
+// If the version id is empty, the latest version is deleted, which puts
+// a delete marker in the versioned bucket. BypassGovernance allows to
+// delete the version retained in the governance mode.
+type MOBObjectDeleteRequest struct {
+	Name             string
+	Bucket           string
+	VersionID        string
+	BypassGovernance bool
+}
+
+// MOBObjectDeleteResponse responses the deleted version or the created
+// delete marker, and the location of the deleted object data, which is
+// used to delete the data in the ds.
+type MOBObjectDeleteResponse struct {
+	S3ErrCode    s3.ErrorCode
+	VersionID    string
+	DeleteMarker bool
+	Parts        []MOBObjectPart
+}
+
+// MOBObjectVersion identifies the version of the object. The empty version
+// id means the latest version.
+type MOBObjectVersion struct {
+	Name      string
+	VersionID string
+}
+
+// MOBDeleteObjectsRequest requests to delete the objects at once.
+// BypassGovernance allows to delete the versions retained in the
+// governance mode.
+type MOBDeleteObjectsRequest struct {
+	Bucket           string
+	Objects          []MOBObjectVersion
+	BypassGovernance bool
+}
+
+// MOBDeleteObjectsResponse responses the deleted versions in the order of
+// the request, and the location of the deleted object data, which is used
+// to delete the data in the ds. The not existing objects are regarded as
+// deleted.
+type MOBDeleteObjectsResponse struct {
+	S3ErrCode s3.ErrorCode
+	Deleted   []MOBDeletedObject
+	Parts     []MOBObjectPart
+}
+
+// MOBDeletedObject is the deleted version or the created delete marker.
+// S3ErrCode is set if the version is not deleted since it is locked.
+type MOBDeletedObject struct {
+	S3ErrCode    s3.ErrorCode
+	VersionID    string
+	DeleteMarker bool
+}
+
+// MOBGetDataLocksRequest requests the object locks of the versions whose
+// data are stored as the object ids in the ds.
+type MOBGetDataLocksRequest struct {
+	Bucket    string
+	ObjectIDs []string
+}
+
+// MOBGetDataLocksResponse responses the object locks of the versions which
+// own the data, keyed by the object id. The object id which no version owns
+// is not in the map.
+type MOBGetDataLocksResponse struct {
+	S3ErrCode s3.ErrorCode
+	Locks     map[string]s3.ObjectLock
+}
+
+// MOBObjectListRequest requests the list of objects in the bucket.
+// Token is the opaque continuation token of the previous response, and
+// the listing starts after the StartAfter key if no token is given.
+type MOBObjectListRequest struct {
+	Bucket     string
+	Prefix     string
+	Delimiter  string
+	StartAfter string
+	Token      string
+	MaxKeys    int
+}
+
+// MOBObjectListResponse responses the list of objects and common prefixes.
+type MOBObjectListResponse struct {
+	S3ErrCode      s3.ErrorCode
+	Objects        []MOBObjectEntry
+	CommonPrefixes []string
+	IsTruncated    bool
+	NextToken      string
+}
+
+// MOBObjectEntry is the entry of the object list.
+type MOBObjectEntry struct {
+	Name         string
+	Size         int64
+	ETag         string
+	LastModified time.Time
+}
+
+// MOBListVersionsRequest requests the list of object versions in the
+// bucket. The listing starts after the version of the markers.
+type MOBListVersionsRequest struct {
+	Bucket          string
+	Prefix          string
+	Delimiter       string
+	KeyMarker       string
+	VersionIDMarker string
+	MaxKeys         int
+}
+
+// MOBListVersionsResponse responses the list of versions and common
+// prefixes.
+type MOBListVersionsResponse struct {
+	S3ErrCode           s3.ErrorCode
+	Versions            []MOBVersionEntry
+	CommonPrefixes      []string
+	IsTruncated         bool
+	NextKeyMarker       string
+	NextVersionIDMarker string
+}
+
+// MOBVersionEntry is the entry of the version list.
+type MOBVersionEntry struct {
+	Name         string
+	VersionID    string
+	IsLatest     bool
+	DeleteMarker bool
+	Size         int64
+	ETag         string
+	LastModified time.Time
+}
+
+// MOBCreateUploadRequest requests to initiate a multipart upload.
+type MOBCreateUploadRequest struct {
+	Name        string
+	Bucket      string
+	ContentType string
+	Headers     map[string]string
+	Metadata    map[string]string
+	ACL         string
+	Encryption  MOBEncryption
+	Tags        map[string]string
+	Lock        s3.ObjectLock
+}
+
+// MOBCreateUploadResponse responses the id of the initiated upload.
+type MOBCreateUploadResponse struct {
+	S3ErrCode s3.ErrorCode
+	UploadID  string
+}
+
+// MOBGetUploadRequest requests the attributes of the multipart upload.
+type MOBGetUploadRequest struct {
+	Name     string
+	Bucket   string
+	UploadID string
+}
+
+// MOBGetUploadResponse responses the encryption of the upload, which the
+// parts are encrypted with.
+type MOBGetUploadResponse struct {
+	S3ErrCode  s3.ErrorCode
+	Encryption MOBEncryption
+}
+
+// MOBPutPartRequest requests to record the location of the written part.
+type MOBPutPartRequest struct {
+	Name          string
+	Bucket        string
+	UploadID      string
+	PartNumber    int
+	EncodingGroup cmap.ID
+	Volume        cmap.ID
+	DsID          cmap.ID
+	ObjectID      string
+	Size          int64
+	ETag          string
+}
+
+// MOBPutPartResponse responses the result of recording the part.
+// Obsolete is the data of the part which has been uploaded before
+// with the same part number.
+type MOBPutPartResponse struct {
+	S3ErrCode s3.ErrorCode
+	Obsolete  []MOBObjectPart
+}
+
+// MOBCompleteUploadRequest requests to complete the multipart upload
+// by assembling the given parts.
+type MOBCompleteUploadRequest struct {
+	Name     string
+	Bucket   string
+	UploadID string
+	Parts    []MOBCompletePart
+}
+
+// MOBCompletePart is the part number and the etag given by the client.
+type MOBCompletePart struct {
+	PartNumber int
+	ETag       string
+}
+
+// MOBCompleteUploadResponse responses the etag of the completed object.
+// Obsolete is the data of the parts which are not used and the data of
+// the overwritten object.
+type MOBCompleteUploadResponse struct {
+	S3ErrCode  s3.ErrorCode
+	ETag       string
+	VersionID  string
+	Encryption MOBEncryption
+	Obsolete   []MOBObjectPart
+}
+
+// MOBAbortUploadRequest requests to abort the multipart upload.
+type MOBAbortUploadRequest struct {
+	Name     string
+	Bucket   string
+	UploadID string
+}
+
+// MOBAbortUploadResponse responses the data of the uploaded parts.
+type MOBAbortUploadResponse struct {
+	S3ErrCode s3.ErrorCode
+	Obsolete  []MOBObjectPart
+}
+
+// MOBListPartsRequest requests the list of parts which have been uploaded.
+// The listing starts after the part number Marker.
+type MOBListPartsRequest struct {
+	Name     string
+	Bucket   string
+	UploadID string
+	Marker   int
+	MaxParts int
+}
+
+// MOBListPartsResponse responses the list of uploaded parts.
+type MOBListPartsResponse struct {
+	S3ErrCode   s3.ErrorCode
+	Parts       []MOBPartEntry
+	IsTruncated bool
+	NextMarker  int
+}
+
+// MOBPartEntry is the entry of the part list.
+type MOBPartEntry struct {
+	PartNumber   int
+	Size         int64
+	ETag         string
+	LastModified time.Time
+}
+
+// MOBListUploadsRequest requests the list of multipart uploads in progress.
+// The listing starts after the upload UploadIDMarker of the KeyMarker,
+// or after all uploads of the KeyMarker if no UploadIDMarker is given.
+type MOBListUploadsRequest struct {
+	Bucket         string
+	Prefix         string
+	Delimiter      string
+	KeyMarker      string
+	UploadIDMarker string
+	MaxUploads     int
+}
+
+// MOBListUploadsResponse responses the list of uploads and common prefixes.
+type MOBListUploadsResponse struct {
+	S3ErrCode          s3.ErrorCode
+	Uploads            []MOBUploadEntry
+	CommonPrefixes     []string
+	IsTruncated        bool
+	NextKeyMarker      string
+	NextUploadIDMarker string
+}
+
+// MOBUploadEntry is the entry of the multipart upload list.
+type MOBUploadEntry struct {
+	Name      string
+	UploadID  string
+	Initiated time.Time
 }
 
 type MOBGetChunkRequest struct {
@@ -27,7 +27,18 @@ const (
 	// MDS user domain methods.
 	MdsAccountAddUser MethodName = iota
 	MdsAccountMakeBucket
+	MdsAccountRemoveBucket
 	MdsAccountGetCredential
+	MdsAccountGetBucket
+	MdsAccountListBuckets
+	MdsAccountSetBucketVersioning
+	MdsAccountSetBucketLifecycle
+	MdsAccountSetBucketACL
+	MdsAccountSetBucketPolicy
+	MdsAccountSetBucketCORS
+	MdsAccountSetBucketObjectLock
+	MdsAccountGenerateDataKey
+	MdsAccountDecryptDataKey
 
 	// MDS cluster domain methods.
 	MdsMembershipGetClusterMap
@@ -39,6 +50,23 @@ const (
 	// MDS object domain methods.
 	MdsObjectPut
 	MdsObjectGet
+	MdsObjectHead
+	MdsObjectSetACL
+	MdsObjectSetTags
+	MdsObjectSetRetention
+	MdsObjectSetLegalHold
+	MdsObjectDelete
+	MdsObjectDeleteObjects
+	MdsObjectGetDataLocks
+	MdsObjectList
+	MdsObjectListVersions
+	MdsObjectCreateUpload
+	MdsObjectGetUpload
+	MdsObjectPutPart
+	MdsObjectCompleteUpload
+	MdsObjectAbortUpload
+	MdsObjectListParts
+	MdsObjectListUploads
 	MdsObjectGetChunk
 	MdsObjectSetChunk
 
@@ -71,8 +99,30 @@ func (m MethodName) String() string {
 		return MdsAccountPrefix + "." + "AddUser"
 	case MdsAccountMakeBucket:
 		return MdsAccountPrefix + "." + "MakeBucket"
+	case MdsAccountRemoveBucket:
+		return MdsAccountPrefix + "." + "RemoveBucket"
 	case MdsAccountGetCredential:
 		return MdsAccountPrefix + "." + "GetCredential"
+	case MdsAccountGetBucket:
+		return MdsAccountPrefix + "." + "GetBucket"
+	case MdsAccountListBuckets:
+		return MdsAccountPrefix + "." + "ListBuckets"
+	case MdsAccountSetBucketVersioning:
+		return MdsAccountPrefix + "." + "SetBucketVersioning"
+	case MdsAccountSetBucketLifecycle:
+		return MdsAccountPrefix + "." + "SetBucketLifecycle"
+	case MdsAccountSetBucketACL:
+		return MdsAccountPrefix + "." + "SetBucketACL"
+	case MdsAccountSetBucketPolicy:
+		return MdsAccountPrefix + "." + "SetBucketPolicy"
+	case MdsAccountSetBucketCORS:
+		return MdsAccountPrefix + "." + "SetBucketCORS"
+	case MdsAccountSetBucketObjectLock:
+		return MdsAccountPrefix + "." + "SetBucketObjectLock"
+	case MdsAccountGenerateDataKey:
+		return MdsAccountPrefix + "." + "GenerateDataKey"
+	case MdsAccountDecryptDataKey:
+		return MdsAccountPrefix + "." + "DecryptDataKey"
 
 	case MdsMembershipGetClusterMap:
 		return MdsMembershipPrefix + "." + "GetClusterMap"
@@ -89,6 +139,40 @@ func (m MethodName) String() string {
 		return MdsObjectPrefix + "." + "Put"
 	case MdsObjectGet:
 		return MdsObjectPrefix + "." + "Get"
+	case MdsObjectHead:
+		return MdsObjectPrefix + "." + "Head"
+	case MdsObjectSetACL:
+		return MdsObjectPrefix + "." + "SetACL"
+	case MdsObjectSetTags:
+		return MdsObjectPrefix + "." + "SetTags"
+	case MdsObjectSetRetention:
+		return MdsObjectPrefix + "." + "SetRetention"
+	case MdsObjectSetLegalHold:
+		return MdsObjectPrefix + "." + "SetLegalHold"
+	case MdsObjectDelete:
+		return MdsObjectPrefix + "." + "Delete"
+	case MdsObjectDeleteObjects:
+		return MdsObjectPrefix + "." + "DeleteObjects"
+	case MdsObjectGetDataLocks:
+		return MdsObjectPrefix + "." + "GetDataLocks"
+	case MdsObjectList:
+		return MdsObjectPrefix + "." + "List"
+	case MdsObjectListVersions:
+		return MdsObjectPrefix + "." + "ListVersions"
+	case MdsObjectCreateUpload:
+		return MdsObjectPrefix + "." + "CreateUpload"
+	case MdsObjectGetUpload:
+		return MdsObjectPrefix + "." + "GetUpload"
+	case MdsObjectPutPart:
+		return MdsObjectPrefix + "." + "PutPart"
+	case MdsObjectCompleteUpload:
+		return MdsObjectPrefix + "." + "CompleteUpload"
+	case MdsObjectAbortUpload:
+		return MdsObjectPrefix + "." + "AbortUpload"
+	case MdsObjectListParts:
+		return MdsObjectPrefix + "." + "ListParts"
+	case MdsObjectListUploads:
+		return MdsObjectPrefix + "." + "ListUploads"
 	case MdsObjectGetChunk:
 		return MdsObjectPrefix + "." + "GetChunk"
 	case MdsObjectSetChunk:
@@ -0,0 +1,192 @@
+package s3
+
+import (
+	"encoding/xml"
+	"net/http"
+)
+
+// The canned acls which are supported. The owner always has the full
+// control of the bucket or the object.
+const (
+	// ACLPrivate grants no one else access.
+	ACLPrivate = "private"
+	// ACLPublicRead grants everyone, including the anonymous, read access.
+	ACLPublicRead = "public-read"
+	// ACLAuthenticatedRead grants every authenticated user read access.
+	ACLAuthenticatedRead = "authenticated-read"
+)
+
+const (
+	// PermissionFullControl grants every permission.
+	PermissionFullControl = "FULL_CONTROL"
+	// PermissionRead grants reading the object or listing the bucket.
+	PermissionRead = "READ"
+
+	// GroupAllUsers is the uri of the group of everyone.
+	GroupAllUsers = "http://acs.amazonaws.com/groups/global/AllUsers"
+	// GroupAuthenticatedUsers is the uri of the group of every user who
+	// signs the request.
+	GroupAuthenticatedUsers = "http://acs.amazonaws.com/groups/global/AuthenticatedUsers"
+
+	// xsiNamespace is the xml name space of the type of the grantee.
+	xsiNamespace = "http://www.w3.org/2001/XMLSchema-instance"
+)
+
+// unsupportedCannedACLs is the canned acls of s3 which are not supported.
+var unsupportedCannedACLs = map[string]bool{
+	"public-read-write":         true,
+	"aws-exec-read":             true,
+	"bucket-owner-read":         true,
+	"bucket-owner-full-control": true,
+	"log-delivery-write":        true,
+}
+
+// grantHeaders is the headers which grant the permissions explicitly.
+var grantHeaders = []string{
+	"X-Amz-Grant-Read",
+	"X-Amz-Grant-Write",
+	"X-Amz-Grant-Read-Acp",
+	"X-Amz-Grant-Write-Acp",
+	"X-Amz-Grant-Full-Control",
+}
+
+// ParseCannedACL returns the canned acl in the request header, or empty
+// if the request does not have it. Only the canned acl is supported, so
+// the explicit grants are not implemented.
+func ParseCannedACL(header http.Header) (string, ErrorCode) {
+	for _, h := range grantHeaders {
+		if header.Get(h) != "" {
+			return "", ErrNotImplemented
+		}
+	}
+
+	acl := header.Get("X-Amz-Acl")
+	switch {
+	case acl == "", acl == ACLPrivate, acl == ACLPublicRead, acl == ACLAuthenticatedRead:
+		return acl, ErrNone
+	case unsupportedCannedACLs[acl]:
+		return "", ErrNotImplemented
+	default:
+		return "", ErrInvalidArgument
+	}
+}
+
+// ACLGrantsRead returns true if the canned acl grants the requester, who
+// is not the owner, read access.
+func ACLGrantsRead(acl string, authenticated bool) bool {
+	switch acl {
+	case ACLPublicRead:
+		return true
+	case ACLAuthenticatedRead:
+		return authenticated
+	default:
+		return false
+	}
+}
+
+// AccessControlPolicy is the owner and the grants of the bucket or the
+// object. It is the request body of the put acl request and the response
+// of the get acl request.
+type AccessControlPolicy struct {
+	XMLName           xml.Name `xml:"AccessControlPolicy"`
+	Xmlns             string   `xml:"xmlns,attr,omitempty"`
+	Owner             Owner
+	AccessControlList []Grant `xml:"AccessControlList>Grant"`
+}
+
+// Grant is the permission given to the grantee.
+type Grant struct {
+	Grantee    Grantee
+	Permission string
+}
+
+// Grantee is either the user or the group of users. The type of the
+// grantee is told by which one of the fields is set.
+type Grantee struct {
+	ID           string `xml:",omitempty"`
+	DisplayName  string `xml:",omitempty"`
+	URI          string `xml:",omitempty"`
+	EmailAddress string `xml:",omitempty"`
+}
+
+// MarshalXML encodes the grantee with its type in the attribute.
+func (g Grantee) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	typ := "CanonicalUser"
+	if g.URI != "" {
+		typ = "Group"
+	} else if g.EmailAddress != "" {
+		typ = "AmazonCustomerByEmail"
+	}
+	start.Attr = append(start.Attr,
+		xml.Attr{Name: xml.Name{Local: "xmlns:xsi"}, Value: xsiNamespace},
+		xml.Attr{Name: xml.Name{Local: "xsi:type"}, Value: typ},
+	)
+
+	// The alias does not have the method, which prevents the recursion.
+	type grantee Grantee
+	return e.EncodeElement(grantee(g), start)
+}
+
+// NewAccessControlPolicy returns the grants of the canned acl.
+func NewAccessControlPolicy(acl string, owner Owner) AccessControlPolicy {
+	p := AccessControlPolicy{
+		Xmlns: Namespace,
+		Owner: owner,
+		AccessControlList: []Grant{{
+			Grantee:    Grantee{ID: owner.ID, DisplayName: owner.DisplayName},
+			Permission: PermissionFullControl,
+		}},
+	}
+
+	switch acl {
+	case ACLPublicRead:
+		p.AccessControlList = append(p.AccessControlList, Grant{
+			Grantee:    Grantee{URI: GroupAllUsers},
+			Permission: PermissionRead,
+		})
+	case ACLAuthenticatedRead:
+		p.AccessControlList = append(p.AccessControlList, Grant{
+			Grantee:    Grantee{URI: GroupAuthenticatedUsers},
+			Permission: PermissionRead,
+		})
+	}
+
+	return p
+}
+
+// CannedACL returns the canned acl which has the same grants with the
+// policy. The owner must keep the full control, and only the read access
+// of the groups can be granted to the others.
+func (p *AccessControlPolicy) CannedACL(owner string) (string, ErrorCode) {
+	if p.Owner.ID != "" && p.Owner.ID != owner {
+		return "", ErrAccessDenied
+	}
+
+	acl, ownerGranted := ACLPrivate, false
+	for _, g := range p.AccessControlList {
+		switch g.Permission {
+		case PermissionFullControl, PermissionRead, "WRITE", "READ_ACP", "WRITE_ACP":
+		default:
+			return "", ErrMalformedACLError
+		}
+
+		switch {
+		case g.Grantee.ID == owner && g.Permission == PermissionFullControl:
+			ownerGranted = true
+		case g.Grantee.URI == GroupAllUsers && g.Permission == PermissionRead:
+			acl = ACLPublicRead
+		case g.Grantee.URI == GroupAuthenticatedUsers && g.Permission == PermissionRead:
+			// The read access of everyone includes the authenticated.
+			if acl != ACLPublicRead {
+				acl = ACLAuthenticatedRead
+			}
+		default:
+			return "", ErrNotImplemented
+		}
+	}
+	if !ownerGranted {
+		return "", ErrNotImplemented
+	}
+
+	return acl, ErrNone
+}
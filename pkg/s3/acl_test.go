@@ -0,0 +1,78 @@
+package s3
+
+import (
+	"encoding/xml"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestParseCannedACL(t *testing.T) {
+	testCases := []struct {
+		header http.Header
+		acl    string
+		code   ErrorCode
+	}{
+		{http.Header{}, "", ErrNone},
+		{http.Header{"X-Amz-Acl": {"private"}}, ACLPrivate, ErrNone},
+		{http.Header{"X-Amz-Acl": {"public-read"}}, ACLPublicRead, ErrNone},
+		{http.Header{"X-Amz-Acl": {"authenticated-read"}}, ACLAuthenticatedRead, ErrNone},
+		{http.Header{"X-Amz-Acl": {"public-read-write"}}, "", ErrNotImplemented},
+		{http.Header{"X-Amz-Acl": {"everyone"}}, "", ErrInvalidArgument},
+		{http.Header{"X-Amz-Grant-Read": {"id=abc"}}, "", ErrNotImplemented},
+	}
+
+	for i, c := range testCases {
+		acl, code := ParseCannedACL(c.header)
+		if acl != c.acl || code != c.code {
+			t.Errorf("case %d: expected (%q, %d), got (%q, %d)", i, c.acl, c.code, acl, code)
+		}
+	}
+}
+
+func TestAccessControlPolicyRoundTrip(t *testing.T) {
+	owner := Owner{ID: "owner", DisplayName: "owner"}
+	for _, acl := range []string{ACLPrivate, ACLPublicRead, ACLAuthenticatedRead} {
+		body, err := xml.Marshal(NewAccessControlPolicy(acl, owner))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(body), `xsi:type="CanonicalUser"`) {
+			t.Errorf("%s: grantee type is missing: %s", acl, body)
+		}
+
+		p := AccessControlPolicy{}
+		if err := xml.Unmarshal(body, &p); err != nil {
+			t.Fatal(err)
+		}
+		if got, code := p.CannedACL(owner.ID); got != acl || code != ErrNone {
+			t.Errorf("%s: got (%q, %d)", acl, got, code)
+		}
+	}
+}
+
+func TestCannedACL(t *testing.T) {
+	grant := func(g Grantee, perm string) Grant { return Grant{Grantee: g, Permission: perm} }
+	full := grant(Grantee{ID: "owner"}, PermissionFullControl)
+
+	testCases := []struct {
+		policy AccessControlPolicy
+		acl    string
+		code   ErrorCode
+	}{
+		{AccessControlPolicy{AccessControlList: []Grant{full}}, ACLPrivate, ErrNone},
+		{AccessControlPolicy{AccessControlList: []Grant{full, grant(Grantee{URI: GroupAuthenticatedUsers}, PermissionRead), grant(Grantee{URI: GroupAllUsers}, PermissionRead)}}, ACLPublicRead, ErrNone},
+		{AccessControlPolicy{Owner: Owner{ID: "other"}, AccessControlList: []Grant{full}}, "", ErrAccessDenied},
+		{AccessControlPolicy{AccessControlList: []Grant{grant(Grantee{URI: GroupAllUsers}, PermissionRead)}}, "", ErrNotImplemented},
+		{AccessControlPolicy{AccessControlList: []Grant{full, grant(Grantee{ID: "other"}, PermissionRead)}}, "", ErrNotImplemented},
+		{AccessControlPolicy{AccessControlList: []Grant{full, grant(Grantee{URI: GroupAllUsers}, "WRITE")}}, "", ErrNotImplemented},
+		{AccessControlPolicy{AccessControlList: []Grant{full, grant(Grantee{URI: GroupAllUsers}, "ALL")}}, "", ErrMalformedACLError},
+	}
+
+	for i, c := range testCases {
+		acl, code := c.policy.CannedACL("owner")
+		if acl != c.acl || code != c.code {
+			t.Errorf("case %d: expected (%q, %d), got (%q, %d)", i, c.acl, c.code, acl, code)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package s3
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	// StreamingPayload is the payload hash of the aws-chunked upload whose
+	// chunks are signed one by one.
+	// https://docs.aws.amazon.com/AmazonS3/latest/API/sigv4-streaming.html
+	StreamingPayload = "STREAMING-AWS4-HMAC-SHA256-PAYLOAD"
+
+	// chunkAlgorithm is the algorithm in the string to sign of a chunk.
+	chunkAlgorithm = "AWS4-HMAC-SHA256-PAYLOAD"
+
+	// maxChunkSize is the maximum size of a single chunk. Each chunk is
+	// buffered until its signature is verified.
+	maxChunkSize = 16 * 1024 * 1024
+)
+
+var (
+	// ErrChunkSignatureMismatch is returned if the signature of a chunk
+	// does not match.
+	ErrChunkSignatureMismatch = errors.New("s3: chunk signature does not match")
+
+	// ErrMalformedChunk is returned if the chunk is not well formed.
+	ErrMalformedChunk = errors.New("s3: malformed chunk")
+)
+
+// emptySHA256 is the hex encoded sha256 of the empty string.
+var emptySHA256 = hex.EncodeToString(sha256.New().Sum(nil))
+
+// ChunkedReader decodes the aws-chunked body. Each chunk is verified with
+// the signature chained to the previous one, and only the verified data
+// is given to the reader.
+type ChunkedReader struct {
+	body io.ReadCloser
+	r    *bufio.Reader
+
+	signingKey []byte
+	date       string
+	scope      string
+	prevSig    string
+
+	// buf is the verified data which is not read yet.
+	buf   []byte
+	chunk []byte
+	err   error
+}
+
+// NewChunkedReader returns a reader which decodes the aws-chunked body.
+// The seed signature is the signature of the request headers, to which
+// the signature of the first chunk is chained.
+func NewChunkedReader(body io.ReadCloser, signingKey []byte, date, scope, seedSignature string) *ChunkedReader {
+	return &ChunkedReader{
+		body:       body,
+		r:          bufio.NewReader(body),
+		signingKey: signingKey,
+		date:       date,
+		scope:      scope,
+		prevSig:    seedSignature,
+	}
+}
+
+// Read reads the verified data of the chunks.
+func (c *ChunkedReader) Read(p []byte) (int, error) {
+	for len(c.buf) == 0 {
+		if c.err != nil {
+			return 0, c.err
+		}
+		c.err = c.readChunk()
+	}
+
+	n := copy(p, c.buf)
+	c.buf = c.buf[n:]
+	return n, nil
+}
+
+// Err returns the error which stopped decoding the body, or nil if the body
+// is decoded to the end or not yet.
+func (c *ChunkedReader) Err() error {
+	if c.err == io.EOF {
+		return nil
+	}
+	return c.err
+}
+
+// Finish reads the rest of the body after the decoded data is read. The
+// body must end with the signed final chunk, and the data left unread is
+// not allowed. It returns the error of Err.
+func (c *ChunkedReader) Finish() error {
+	for c.err == nil {
+		if len(c.buf) > 0 {
+			c.err = ErrMalformedChunk
+			break
+		}
+		c.err = c.readChunk()
+	}
+	return c.Err()
+}
+
+// Close closes the underlying body.
+func (c *ChunkedReader) Close() error {
+	return c.body.Close()
+}
+
+// readChunk reads and verifies the next chunk. It returns io.EOF after the
+// final chunk, whose size is zero and which must end the body.
+func (c *ChunkedReader) readChunk() error {
+	// chunk = hex(size) + ";chunk-signature=" + signature + "\r\n" + data + "\r\n"
+	line, err := c.r.ReadSlice('\n')
+	if err != nil {
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		}
+		return ErrMalformedChunk
+	}
+	if !bytes.HasSuffix(line, []byte("\r\n")) {
+		return ErrMalformedChunk
+	}
+
+	fields := strings.SplitN(string(line[:len(line)-2]), ";", 2)
+	if len(fields) != 2 || !strings.HasPrefix(fields[1], "chunk-signature=") {
+		return ErrMalformedChunk
+	}
+	size, err := strconv.ParseInt(fields[0], 16, 64)
+	if err != nil || size < 0 || size > maxChunkSize {
+		return ErrMalformedChunk
+	}
+	signature := strings.TrimPrefix(fields[1], "chunk-signature=")
+
+	if int64(cap(c.chunk)) < size+2 {
+		c.chunk = make([]byte, size+2)
+	}
+	chunk := c.chunk[:size+2]
+	if _, err := io.ReadFull(c.r, chunk); err != nil {
+		return io.ErrUnexpectedEOF
+	}
+	if !bytes.HasSuffix(chunk, []byte("\r\n")) {
+		return ErrMalformedChunk
+	}
+	chunk = chunk[:size]
+
+	if !hmac.Equal([]byte(signature), []byte(c.signChunk(chunk))) {
+		return ErrChunkSignatureMismatch
+	}
+	c.prevSig = signature
+
+	if size == 0 {
+		if _, err := c.r.ReadByte(); err != io.EOF {
+			return ErrMalformedChunk
+		}
+		return io.EOF
+	}
+	c.buf = chunk
+
+	return nil
+}
+
+// signChunk returns the signature of the chunk data.
+func (c *ChunkedReader) signChunk(data []byte) string {
+	hashed := sha256.Sum256(data)
+	stringToSign := chunkAlgorithm + "\n" +
+		c.date + "\n" +
+		c.scope + "\n" +
+		c.prevSig + "\n" +
+		emptySHA256 + "\n" +
+		hex.EncodeToString(hashed[:])
+
+	return GenSignature(c.signingKey, stringToSign)
+}
@@ -0,0 +1,94 @@
+package s3
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// The example of the chunked upload from the aws documentation.
+// https://docs.aws.amazon.com/AmazonS3/latest/API/sigv4-streaming.html
+const (
+	exampleChunkDate  = "20130524T000000Z"
+	exampleChunkScope = "20130524/us-east-1/s3/aws4_request"
+	exampleChunkSeed  = "4f232c4386841ef735655705268965c44a0e4690baa4adea153f7db9fa80a0a9"
+)
+
+func exampleChunkedBody(sigs ...string) string {
+	return "10000;chunk-signature=" + sigs[0] + "\r\n" + strings.Repeat("a", 65536) + "\r\n" +
+		"400;chunk-signature=" + sigs[1] + "\r\n" + strings.Repeat("a", 1024) + "\r\n" +
+		"0;chunk-signature=" + sigs[2] + "\r\n\r\n"
+}
+
+func TestChunkedReader(t *testing.T) {
+	valid := []string{
+		"ad80c730a21e5b8d04586a2213dd63b9a0e99e0e2307b0ade35a65485a288648",
+		"0055627c9e194cb4542bae2aa5492e3c1575bbb81b612b7d234b86a503ef5497",
+		"b6c6ea8a5354eaf15b3cb7646744f4275b71ea724fed81ceb9323e279d449df9",
+	}
+	wrong := "0000000000000000000000000000000000000000000000000000000000000000"
+
+	testCases := []struct {
+		body string
+		err  error
+	}{
+		{exampleChunkedBody(valid...), nil},
+		{exampleChunkedBody(wrong, valid[1], valid[2]), ErrChunkSignatureMismatch},
+		{exampleChunkedBody(valid[0], valid[1], wrong), ErrChunkSignatureMismatch},
+		{exampleChunkedBody(valid...)[:66000], io.ErrUnexpectedEOF},
+		{"zz;chunk-signature=" + valid[0] + "\r\n", ErrMalformedChunk},
+		{"0\r\n\r\n", ErrMalformedChunk},
+		{strings.TrimSuffix(exampleChunkedBody(valid...), "0;chunk-signature="+valid[2]+"\r\n\r\n"), io.ErrUnexpectedEOF},
+		{exampleChunkedBody(valid...) + "trailing", ErrMalformedChunk},
+	}
+
+	key := GenSignatureKey(exampleSecretKey, "20130524", "us-east-1", "s3")
+	for i, c := range testCases {
+		r := NewChunkedReader(ioutil.NopCloser(strings.NewReader(c.body)), key, exampleChunkDate, exampleChunkScope, exampleChunkSeed)
+		data, err := ioutil.ReadAll(r)
+		if err != c.err || r.Err() != c.err {
+			t.Errorf("case %d: expected error %v, got %v", i, c.err, r.Err())
+		}
+		if c.err == nil && !bytes.Equal(data, bytes.Repeat([]byte("a"), 65536+1024)) {
+			t.Errorf("case %d: unexpected data of length %d", i, len(data))
+		}
+	}
+}
+
+func TestChunkedReaderFinish(t *testing.T) {
+	valid := []string{
+		"ad80c730a21e5b8d04586a2213dd63b9a0e99e0e2307b0ade35a65485a288648",
+		"0055627c9e194cb4542bae2aa5492e3c1575bbb81b612b7d234b86a503ef5497",
+		"b6c6ea8a5354eaf15b3cb7646744f4275b71ea724fed81ceb9323e279d449df9",
+	}
+	wrong := "0000000000000000000000000000000000000000000000000000000000000000"
+	body := exampleChunkedBody(valid...)
+
+	// The reader stops at the decoded content length, so the final chunk
+	// is checked only by Finish.
+	testCases := []struct {
+		body string
+		size int
+		err  error
+	}{
+		{body, 65536 + 1024, nil},
+		{exampleChunkedBody(valid[0], valid[1], wrong), 65536 + 1024, ErrChunkSignatureMismatch},
+		{body[:strings.LastIndex(body, "0;")], 65536 + 1024, io.ErrUnexpectedEOF},
+		{body + "trailing", 65536 + 1024, ErrMalformedChunk},
+		{body, 65536 + 1000, ErrMalformedChunk},
+		{body, 65536, ErrMalformedChunk},
+	}
+
+	key := GenSignatureKey(exampleSecretKey, "20130524", "us-east-1", "s3")
+	for i, c := range testCases {
+		r := NewChunkedReader(ioutil.NopCloser(strings.NewReader(c.body)), key, exampleChunkDate, exampleChunkScope, exampleChunkSeed)
+		if _, err := io.ReadFull(r, make([]byte, c.size)); err != nil {
+			t.Fatalf("case %d: %v", i, err)
+		}
+		if err := r.Finish(); err != c.err || r.Err() != c.err {
+			t.Errorf("case %d: expected error %v, got %v", i, c.err, r.Err())
+		}
+	}
+}
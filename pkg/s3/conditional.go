@@ -0,0 +1,60 @@
+package s3
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// CheckPreconditions evaluates the conditional headers of the request
+// against the etag and the last modified time of the object. The prefix
+// is prepended to the header names, for example x-amz-copy-source- for the
+// conditions of the copy source.
+//
+// It returns ErrPreconditionFailed if If-Match or If-Unmodified-Since does
+// not hold, and ErrNotModified if If-None-Match or If-Modified-Since does
+// not hold. As RFC 7232, the date conditions are ignored if the etag
+// conditions of the same kind are given.
+// https://tools.ietf.org/html/rfc7232#section-6
+func CheckPreconditions(h http.Header, prefix, etag string, lastModified time.Time) ErrorCode {
+	// The time in the header has the precision of a second.
+	lastModified = lastModified.Truncate(time.Second)
+
+	if v := h.Get(prefix + "If-Match"); v != "" {
+		if !matchETag(v, etag) {
+			return ErrPreconditionFailed
+		}
+	} else if t, err := http.ParseTime(h.Get(prefix + "If-Unmodified-Since")); err == nil {
+		if lastModified.After(t) {
+			return ErrPreconditionFailed
+		}
+	}
+
+	if v := h.Get(prefix + "If-None-Match"); v != "" {
+		if matchETag(v, etag) {
+			return ErrNotModified
+		}
+	} else if t, err := http.ParseTime(h.Get(prefix + "If-Modified-Since")); err == nil {
+		if !lastModified.After(t) {
+			return ErrNotModified
+		}
+	}
+
+	return ErrNone
+}
+
+// matchETag returns true if the etag is one of the etags in the header
+// value. The wildcard matches any etag.
+func matchETag(value, etag string) bool {
+	for _, v := range strings.Split(value, ",") {
+		v = strings.TrimSpace(v)
+		if v == "*" {
+			return true
+		}
+		v = strings.TrimPrefix(v, "W/")
+		if strings.Trim(v, "\"") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package s3
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCheckPreconditions(t *testing.T) {
+	const etag = "9b2cf535f27731c974343645a3985328"
+	lastModified := time.Date(2019, 1, 2, 3, 4, 5, 600, time.UTC)
+	before := lastModified.Add(-time.Hour).Format(http.TimeFormat)
+	same := lastModified.Format(http.TimeFormat)
+	after := lastModified.Add(time.Hour).Format(http.TimeFormat)
+
+	testCases := []struct {
+		header map[string]string
+		err    ErrorCode
+	}{
+		{map[string]string{}, ErrNone},
+		{map[string]string{"If-Match": "\"" + etag + "\""}, ErrNone},
+		{map[string]string{"If-Match": "\"other\", \"" + etag + "\""}, ErrNone},
+		{map[string]string{"If-Match": "*"}, ErrNone},
+		{map[string]string{"If-Match": "\"other\""}, ErrPreconditionFailed},
+		{map[string]string{"If-None-Match": "\"" + etag + "\""}, ErrNotModified},
+		{map[string]string{"If-None-Match": "W/\"" + etag + "\""}, ErrNotModified},
+		{map[string]string{"If-None-Match": "\"other\""}, ErrNone},
+		{map[string]string{"If-Unmodified-Since": same}, ErrNone},
+		{map[string]string{"If-Unmodified-Since": before}, ErrPreconditionFailed},
+		{map[string]string{"If-Unmodified-Since": "invalid"}, ErrNone},
+		{map[string]string{"If-Modified-Since": before}, ErrNone},
+		{map[string]string{"If-Modified-Since": same}, ErrNotModified},
+		{map[string]string{"If-Modified-Since": after}, ErrNotModified},
+		{map[string]string{"If-Match": "\"" + etag + "\"", "If-Unmodified-Since": before}, ErrNone},
+		{map[string]string{"If-None-Match": "\"other\"", "If-Modified-Since": after}, ErrNone},
+		{map[string]string{"If-Match": "\"other\"", "If-None-Match": "\"" + etag + "\""}, ErrPreconditionFailed},
+	}
+
+	for i, c := range testCases {
+		h := http.Header{}
+		for k, v := range c.header {
+			h.Set(k, v)
+		}
+		if err := CheckPreconditions(h, "", etag, lastModified); err != c.err {
+			t.Errorf("case %d: expected %d, got %d", i, c.err, err)
+		}
+	}
+
+	// The conditions of the copy source are given with the prefix.
+	h := http.Header{}
+	h.Set("X-Amz-Copy-Source-If-Match", "\"other\"")
+	if err := CheckPreconditions(h, "X-Amz-Copy-Source-", etag, lastModified); err != ErrPreconditionFailed {
+		t.Errorf("expected precondition failed with the prefix, got %d", err)
+	}
+}
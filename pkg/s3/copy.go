@@ -0,0 +1,76 @@
+package s3
+
+import (
+	"encoding/xml"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// CopyObjectResult is the response of the copy object request.
+type CopyObjectResult struct {
+	XMLName      xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ CopyObjectResult"`
+	LastModified string
+	ETag         string
+}
+
+// CopyPartResult is the response of the upload part copy request.
+type CopyPartResult struct {
+	XMLName      xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ CopyPartResult"`
+	LastModified string
+	ETag         string
+}
+
+// ParseCopySource parses the x-amz-copy-source header, which is the url
+// encoded bucket and key of the source object optionally followed by the
+// version id.
+func ParseCopySource(source string) (bucket, key, versionID string, err ErrorCode) {
+	if i := strings.Index(source, "?"); i >= 0 {
+		q, e := url.ParseQuery(source[i+1:])
+		if e != nil {
+			return "", "", "", ErrInvalidArgument
+		}
+		source, versionID = source[:i], q.Get("versionId")
+	}
+
+	path, e := url.PathUnescape(source)
+	if e != nil {
+		return "", "", "", ErrInvalidArgument
+	}
+
+	fields := strings.SplitN(strings.TrimPrefix(path, "/"), "/", 2)
+	if len(fields) != 2 || fields[0] == "" || fields[1] == "" {
+		return "", "", "", ErrInvalidArgument
+	}
+
+	return fields[0], fields[1], versionID, ErrNone
+}
+
+// ParseCopySourceRange parses the x-amz-copy-source-range header, which is
+// a single byte range of the source object in the form of bytes=first-last.
+// The whole object is copied if the header is empty.
+func ParseCopySourceRange(header string, size int64) (offset, length int64, err ErrorCode) {
+	if header == "" {
+		return 0, size, ErrNone
+	}
+
+	if !strings.HasPrefix(header, "bytes=") {
+		return 0, 0, ErrInvalidArgument
+	}
+	fields := strings.SplitN(strings.TrimPrefix(header, "bytes="), "-", 2)
+	if len(fields) != 2 {
+		return 0, 0, ErrInvalidArgument
+	}
+	first, e1 := strconv.ParseInt(fields[0], 10, 64)
+	last, e2 := strconv.ParseInt(fields[1], 10, 64)
+	if e1 != nil || e2 != nil || first < 0 || last < first {
+		return 0, 0, ErrInvalidArgument
+	}
+
+	// Unlike the range of the get request, the range must be in the object.
+	if last >= size {
+		return 0, 0, ErrInvalidRange
+	}
+
+	return first, last - first + 1, ErrNone
+}
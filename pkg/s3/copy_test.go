@@ -0,0 +1,56 @@
+package s3
+
+import "testing"
+
+func TestParseCopySource(t *testing.T) {
+	testCases := []struct {
+		source    string
+		bucket    string
+		key       string
+		versionID string
+		err       ErrorCode
+	}{
+		{"bucket/key", "bucket", "key", "", ErrNone},
+		{"/bucket/dir/key", "bucket", "dir/key", "", ErrNone},
+		{"bucket/a%20b%3Fc", "bucket", "a b?c", "", ErrNone},
+		{"bucket/key?versionId=v1", "bucket", "key", "v1", ErrNone},
+		{"bucket", "", "", "", ErrInvalidArgument},
+		{"bucket/", "", "", "", ErrInvalidArgument},
+		{"/key", "", "", "", ErrInvalidArgument},
+		{"bucket/%zz", "", "", "", ErrInvalidArgument},
+	}
+
+	for _, c := range testCases {
+		bucket, key, versionID, err := ParseCopySource(c.source)
+		if bucket != c.bucket || key != c.key || versionID != c.versionID || err != c.err {
+			t.Errorf("%q: expected (%q, %q, %q, %d), got (%q, %q, %q, %d)",
+				c.source, c.bucket, c.key, c.versionID, c.err, bucket, key, versionID, err)
+		}
+	}
+}
+
+func TestParseCopySourceRange(t *testing.T) {
+	testCases := []struct {
+		header string
+		size   int64
+		offset int64
+		length int64
+		err    ErrorCode
+	}{
+		{"", 100, 0, 100, ErrNone},
+		{"bytes=0-9", 100, 0, 10, ErrNone},
+		{"bytes=90-99", 100, 90, 10, ErrNone},
+		{"bytes=90-100", 100, 0, 0, ErrInvalidRange},
+		{"bytes=9-0", 100, 0, 0, ErrInvalidArgument},
+		{"bytes=-10", 100, 0, 0, ErrInvalidArgument},
+		{"bytes=0-", 100, 0, 0, ErrInvalidArgument},
+		{"items=0-9", 100, 0, 0, ErrInvalidArgument},
+	}
+
+	for _, c := range testCases {
+		offset, length, err := ParseCopySourceRange(c.header, c.size)
+		if offset != c.offset || length != c.length || err != c.err {
+			t.Errorf("%q: expected (%d, %d, %d), got (%d, %d, %d)", c.header, c.offset, c.length, c.err, offset, length, err)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package s3
+
+import (
+	"encoding/xml"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	// MaxCORSRules is the maximum number of rules in a cors configuration.
+	MaxCORSRules = 100
+
+	// maxCORSRuleID is the maximum length of the rule id.
+	maxCORSRuleID = 255
+)
+
+// corsMethods is the methods which the cors rule can allow.
+var corsMethods = map[string]bool{
+	http.MethodGet:    true,
+	http.MethodPut:    true,
+	http.MethodHead:   true,
+	http.MethodPost:   true,
+	http.MethodDelete: true,
+}
+
+// CORSConfiguration is the cors rules of the bucket. It is the request body
+// of the put bucket cors request and the response of the get bucket cors
+// request.
+type CORSConfiguration struct {
+	XMLName xml.Name   `xml:"CORSConfiguration"`
+	Xmlns   string     `xml:"xmlns,attr,omitempty"`
+	Rules   []CORSRule `xml:"CORSRule"`
+}
+
+// CORSRule allows the cross origin requests of the methods from the
+// origins. The origins and the headers can have a '*' wildcard.
+type CORSRule struct {
+	ID             string   `xml:",omitempty"`
+	AllowedMethods []string `xml:"AllowedMethod"`
+	AllowedOrigins []string `xml:"AllowedOrigin"`
+	AllowedHeaders []string `xml:"AllowedHeader"`
+	ExposeHeaders  []string `xml:"ExposeHeader"`
+	MaxAgeSeconds  int      `xml:",omitempty"`
+}
+
+// Validate checks the cors configuration is well formed and supported.
+func (c *CORSConfiguration) Validate() ErrorCode {
+	if len(c.Rules) == 0 || len(c.Rules) > MaxCORSRules {
+		return ErrMalformedXML
+	}
+
+	for i := range c.Rules {
+		if code := c.Rules[i].validate(); code != ErrNone {
+			return code
+		}
+	}
+	return ErrNone
+}
+
+// validate checks the rule is well formed and supported.
+func (r *CORSRule) validate() ErrorCode {
+	if len(r.ID) > maxCORSRuleID || r.MaxAgeSeconds < 0 {
+		return ErrInvalidArgument
+	}
+	if len(r.AllowedMethods) == 0 || len(r.AllowedOrigins) == 0 {
+		return ErrMalformedXML
+	}
+
+	for _, m := range r.AllowedMethods {
+		if !corsMethods[m] {
+			return ErrInvalidRequest
+		}
+	}
+	// Each origin and header can have at most one wildcard.
+	for _, o := range r.AllowedOrigins {
+		if strings.Count(o, "*") > 1 {
+			return ErrInvalidRequest
+		}
+	}
+	for _, h := range r.AllowedHeaders {
+		if strings.Count(h, "*") > 1 {
+			return ErrInvalidRequest
+		}
+	}
+
+	return ErrNone
+}
+
+// Match returns the first rule which allows the request of the method with
+// the headers from the origin, or nil if no rule allows it.
+func (c *CORSConfiguration) Match(origin, method string, headers []string) *CORSRule {
+	for i := range c.Rules {
+		if c.Rules[i].allows(origin, method, headers) {
+			return &c.Rules[i]
+		}
+	}
+	return nil
+}
+
+// allows returns true if the rule allows the request.
+func (r *CORSRule) allows(origin, method string, headers []string) bool {
+	if !r.allowsMethod(method) || !matchAny(r.AllowedOrigins, origin, nil) {
+		return false
+	}
+	// The header names are case insensitive.
+	for _, h := range headers {
+		if !matchAny(r.AllowedHeaders, h, strings.ToLower) {
+			return false
+		}
+	}
+	return true
+}
+
+// allowsMethod returns true if the rule allows the method.
+func (r *CORSRule) allowsMethod(method string) bool {
+	for _, m := range r.AllowedMethods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// SetHeaders sets the cors response headers of the request from the origin
+// which the rule allows. The preflight response has the headers of what
+// the actual request can do, which are the requested headers.
+func (r *CORSRule) SetHeaders(h http.Header, origin string, preflight bool, headers []string) {
+	h.Add("Vary", "Origin")
+	if len(r.AllowedOrigins) == 1 && r.AllowedOrigins[0] == "*" {
+		h.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		h.Set("Access-Control-Allow-Origin", origin)
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+	if len(r.ExposeHeaders) > 0 {
+		h.Set("Access-Control-Expose-Headers", strings.Join(r.ExposeHeaders, ", "))
+	}
+	if !preflight {
+		return
+	}
+
+	h.Set("Access-Control-Allow-Methods", strings.Join(r.AllowedMethods, ", "))
+	if len(headers) > 0 {
+		h.Set("Access-Control-Allow-Headers", strings.Join(headers, ", "))
+	}
+	if r.MaxAgeSeconds > 0 {
+		h.Set("Access-Control-Max-Age", strconv.Itoa(r.MaxAgeSeconds))
+	}
+}
+
+// ParseCORSHeaders returns the header names in the Access-Control-Request-
+// Headers of the preflight request.
+func ParseCORSHeaders(value string) []string {
+	headers := make([]string, 0)
+	for _, h := range strings.Split(value, ",") {
+		if h = strings.TrimSpace(h); h != "" {
+			headers = append(headers, h)
+		}
+	}
+	return headers
+}
@@ -0,0 +1,103 @@
+package s3
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestCORSValidate(t *testing.T) {
+	rule := func(methods, origins, headers []string) CORSRule {
+		return CORSRule{AllowedMethods: methods, AllowedOrigins: origins, AllowedHeaders: headers}
+	}
+	get, all := []string{"GET"}, []string{"*"}
+
+	testCases := []struct {
+		conf CORSConfiguration
+		code ErrorCode
+	}{
+		{CORSConfiguration{Rules: []CORSRule{rule(get, all, nil)}}, ErrNone},
+		{CORSConfiguration{Rules: []CORSRule{rule([]string{"PUT", "POST"}, []string{"https://*.example.com"}, []string{"x-amz-*"})}}, ErrNone},
+		{CORSConfiguration{}, ErrMalformedXML},
+		{CORSConfiguration{Rules: []CORSRule{rule(nil, all, nil)}}, ErrMalformedXML},
+		{CORSConfiguration{Rules: []CORSRule{rule(get, nil, nil)}}, ErrMalformedXML},
+		{CORSConfiguration{Rules: []CORSRule{rule([]string{"PATCH"}, all, nil)}}, ErrInvalidRequest},
+		{CORSConfiguration{Rules: []CORSRule{rule(get, []string{"https://*.*.com"}, nil)}}, ErrInvalidRequest},
+		{CORSConfiguration{Rules: []CORSRule{rule(get, all, []string{"**"})}}, ErrInvalidRequest},
+		{CORSConfiguration{Rules: []CORSRule{{AllowedMethods: get, AllowedOrigins: all, MaxAgeSeconds: -1}}}, ErrInvalidArgument},
+	}
+
+	for i, c := range testCases {
+		if code := c.conf.Validate(); code != c.code {
+			t.Errorf("case %d: expected %d, got %d", i, c.code, code)
+		}
+	}
+}
+
+func TestCORSMatch(t *testing.T) {
+	conf := CORSConfiguration{Rules: []CORSRule{
+		{ID: "upload", AllowedMethods: []string{"PUT", "POST"}, AllowedOrigins: []string{"https://*.example.com"}, AllowedHeaders: []string{"Content-*", "x-amz-*"}},
+		{ID: "read", AllowedMethods: []string{"GET", "HEAD"}, AllowedOrigins: []string{"*"}},
+	}}
+
+	testCases := []struct {
+		origin, method string
+		headers        []string
+		id             string
+	}{
+		{"https://app.example.com", "PUT", []string{"content-type", "X-Amz-Meta-A"}, "upload"},
+		{"https://app.example.com", "PUT", []string{"authorization"}, ""},
+		{"http://app.example.com", "PUT", nil, ""},
+		{"http://other.com", "GET", nil, "read"},
+		{"http://other.com", "GET", []string{"range"}, ""},
+		{"http://other.com", "DELETE", nil, ""},
+	}
+
+	for i, c := range testCases {
+		id := ""
+		if r := conf.Match(c.origin, c.method, c.headers); r != nil {
+			id = r.ID
+		}
+		if id != c.id {
+			t.Errorf("case %d: expected rule %q, got %q", i, c.id, id)
+		}
+	}
+}
+
+func TestCORSSetHeaders(t *testing.T) {
+	r := CORSRule{
+		AllowedMethods: []string{"GET", "PUT"},
+		AllowedOrigins: []string{"https://app.example.com"},
+		AllowedHeaders: []string{"*"},
+		ExposeHeaders:  []string{"ETag"},
+		MaxAgeSeconds:  600,
+	}
+
+	h := http.Header{}
+	r.SetHeaders(h, "https://app.example.com", true, ParseCORSHeaders("content-type, x-amz-date"))
+	expected := http.Header{
+		"Vary":                             {"Origin"},
+		"Access-Control-Allow-Origin":      {"https://app.example.com"},
+		"Access-Control-Allow-Credentials": {"true"},
+		"Access-Control-Expose-Headers":    {"ETag"},
+		"Access-Control-Allow-Methods":     {"GET, PUT"},
+		"Access-Control-Allow-Headers":     {"content-type, x-amz-date"},
+		"Access-Control-Max-Age":           {"600"},
+	}
+	if !reflect.DeepEqual(h, expected) {
+		t.Errorf("expected %v, got %v", expected, h)
+	}
+
+	// The actual request does not have the headers of the preflight.
+	h = http.Header{}
+	r.AllowedOrigins = []string{"*"}
+	r.SetHeaders(h, "https://app.example.com", false, nil)
+	expected = http.Header{
+		"Vary":                          {"Origin"},
+		"Access-Control-Allow-Origin":   {"*"},
+		"Access-Control-Expose-Headers": {"ETag"},
+	}
+	if !reflect.DeepEqual(h, expected) {
+		t.Errorf("expected %v, got %v", expected, h)
+	}
+}
@@ -0,0 +1,57 @@
+package s3
+
+import "encoding/xml"
+
+// MaxDeleteObjects is the maximum number of objects which can be deleted
+// in a single delete objects request.
+const MaxDeleteObjects = 1000
+
+// ObjectIdentifier is the object to be deleted.
+type ObjectIdentifier struct {
+	Key       string
+	VersionId string `xml:",omitempty"`
+}
+
+// Delete is the request body of the delete objects request. In the quiet
+// mode, only the failed objects are reported in the response.
+type Delete struct {
+	XMLName xml.Name           `xml:"Delete"`
+	Quiet   bool               `xml:"Quiet"`
+	Objects []ObjectIdentifier `xml:"Object"`
+}
+
+// DeletedObject is the entry of the successfully deleted object. If the
+// delete marker is created or deleted, its version id is given.
+type DeletedObject struct {
+	Key                   string
+	VersionId             string `xml:",omitempty"`
+	DeleteMarker          bool   `xml:",omitempty"`
+	DeleteMarkerVersionId string `xml:",omitempty"`
+}
+
+// DeleteError is the entry of the object which is failed to be deleted.
+type DeleteError struct {
+	Key       string
+	VersionId string `xml:",omitempty"`
+	Code      string
+	Message   string
+}
+
+// NewDeleteError returns the entry of the failed object with the
+// information of the error code.
+func NewDeleteError(obj ObjectIdentifier, code ErrorCode) DeleteError {
+	e := GetErrorInfo(code)
+	return DeleteError{
+		Key:       obj.Key,
+		VersionId: obj.VersionId,
+		Code:      e.Code,
+		Message:   e.Description,
+	}
+}
+
+// DeleteResult is the response of the delete objects request.
+type DeleteResult struct {
+	XMLName xml.Name        `xml:"http://s3.amazonaws.com/doc/2006-03-01/ DeleteResult"`
+	Deleted []DeletedObject `xml:"Deleted"`
+	Errors  []DeleteError   `xml:"Error"`
+}
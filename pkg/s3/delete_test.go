@@ -0,0 +1,35 @@
+package s3
+
+import (
+	"encoding/xml"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeDelete(t *testing.T) {
+	body := `<?xml version="1.0" encoding="UTF-8"?>
+<Delete xmlns="http://s3.amazonaws.com/doc/2006-03-01/">
+	<Quiet>true</Quiet>
+	<Object><Key>a</Key></Object>
+	<Object><Key>b/c</Key><VersionId>v1</VersionId></Object>
+</Delete>`
+
+	del := Delete{}
+	if err := xml.Unmarshal([]byte(body), &del); err != nil {
+		t.Fatal(err)
+	}
+	if !del.Quiet {
+		t.Errorf("expected quiet mode")
+	}
+	expected := []ObjectIdentifier{{Key: "a"}, {Key: "b/c", VersionId: "v1"}}
+	if !reflect.DeepEqual(del.Objects, expected) {
+		t.Errorf("expected %v, got %v", expected, del.Objects)
+	}
+}
+
+func TestNewDeleteError(t *testing.T) {
+	e := NewDeleteError(ObjectIdentifier{Key: "k"}, ErrKeyTooLongError)
+	if e.Key != "k" || e.Code != "KeyTooLongError" || e.Message == "" {
+		t.Errorf("unexpected delete error: %+v", e)
+	}
+}
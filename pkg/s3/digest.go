@@ -0,0 +1,79 @@
+package s3
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"hash"
+	"io"
+	"net/http"
+)
+
+// DigestReader hashes the body while it is read, so that the body can be
+// verified against the Content-MD5 and the X-Amz-Content-Sha256 headers
+// after it is written.
+type DigestReader struct {
+	r io.Reader
+
+	md5        hash.Hash
+	contentMD5 []byte
+
+	sha256        hash.Hash
+	contentSHA256 []byte
+}
+
+// NewDigestReader returns a reader which hashes the body for the digests
+// given in the header. The body is read as it is if no digest is given.
+func NewDigestReader(body io.Reader, h http.Header) (*DigestReader, ErrorCode) {
+	d := &DigestReader{}
+	writers := make([]io.Writer, 0, 2)
+
+	if v, ok := h["Content-Md5"]; ok {
+		sum, err := base64.StdEncoding.DecodeString(v[0])
+		if err != nil || len(sum) != md5.Size {
+			return nil, ErrInvalidDigest
+		}
+		d.md5, d.contentMD5 = md5.New(), sum
+		writers = append(writers, d.md5)
+	}
+
+	// The payload of the presigned url and the streaming upload are not
+	// hashed by the client.
+	switch v := h.Get("X-Amz-Content-Sha256"); v {
+	case "", UnsignedPayload, StreamingPayload:
+	default:
+		sum, err := hex.DecodeString(v)
+		if err != nil || len(sum) != sha256.Size {
+			return nil, ErrInvalidArgument
+		}
+		d.sha256, d.contentSHA256 = sha256.New(), sum
+		writers = append(writers, d.sha256)
+	}
+
+	if len(writers) == 0 {
+		d.r = body
+	} else {
+		d.r = io.TeeReader(body, io.MultiWriter(writers...))
+	}
+
+	return d, ErrNone
+}
+
+// Read reads the body and hashes it.
+func (d *DigestReader) Read(p []byte) (int, error) {
+	return d.r.Read(p)
+}
+
+// Verify checks the read body matches the given digests. It must be called
+// after the body is read to the end.
+func (d *DigestReader) Verify() ErrorCode {
+	if d.md5 != nil && !bytes.Equal(d.md5.Sum(nil), d.contentMD5) {
+		return ErrBadDigest
+	}
+	if d.sha256 != nil && !bytes.Equal(d.sha256.Sum(nil), d.contentSHA256) {
+		return ErrXAmzContentSHA256Mismatch
+	}
+	return ErrNone
+}
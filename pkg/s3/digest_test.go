@@ -0,0 +1,59 @@
+package s3
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestDigestReader(t *testing.T) {
+	const body = "hello"
+	// The digests of the body and of the empty string.
+	const (
+		helloMD5    = "XUFAKrxLKna5cZ2REBfFkg=="
+		helloSHA256 = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+		otherMD5    = "1B2M2Y8AsgTpgAmY7PhCfg=="
+		otherSHA256 = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	)
+
+	testCases := []struct {
+		header map[string]string
+		err    ErrorCode
+		verify ErrorCode
+	}{
+		{map[string]string{}, ErrNone, ErrNone},
+		{map[string]string{"Content-Md5": helloMD5}, ErrNone, ErrNone},
+		{map[string]string{"Content-Md5": otherMD5}, ErrNone, ErrBadDigest},
+		{map[string]string{"Content-Md5": "hello"}, ErrInvalidDigest, ErrNone},
+		{map[string]string{"X-Amz-Content-Sha256": helloSHA256}, ErrNone, ErrNone},
+		{map[string]string{"X-Amz-Content-Sha256": otherSHA256}, ErrNone, ErrXAmzContentSHA256Mismatch},
+		{map[string]string{"X-Amz-Content-Sha256": UnsignedPayload}, ErrNone, ErrNone},
+		{map[string]string{"X-Amz-Content-Sha256": "hello"}, ErrInvalidArgument, ErrNone},
+		{map[string]string{"Content-Md5": helloMD5, "X-Amz-Content-Sha256": otherSHA256}, ErrNone, ErrXAmzContentSHA256Mismatch},
+	}
+
+	for i, c := range testCases {
+		h := http.Header{}
+		for k, v := range c.header {
+			h.Set(k, v)
+		}
+
+		d, err := NewDigestReader(strings.NewReader(body), h)
+		if err != c.err {
+			t.Errorf("case %d: expected error %d, got %d", i, c.err, err)
+			continue
+		}
+		if err != ErrNone {
+			continue
+		}
+
+		data, _ := ioutil.ReadAll(d)
+		if string(data) != body {
+			t.Errorf("case %d: expected body %q, got %q", i, body, data)
+		}
+		if v := d.Verify(); v != c.verify {
+			t.Errorf("case %d: expected verify error %d, got %d", i, c.verify, v)
+		}
+	}
+}
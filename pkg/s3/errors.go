@@ -27,7 +27,10 @@ type ErrorCode int
 const (
 	ErrNone = iota
 	ErrAccessDenied
+	ErrAccessForbidden
 	ErrAccountProblem
+	ErrAuthorizationHeaderMalformed
+	ErrAuthorizationQueryParametersError
 	ErrBadDigest
 	ErrBucketAlreadyExists
 	ErrBucketAlreadyOwnedByYou
@@ -35,6 +38,7 @@ const (
 	ErrCredentialsNotSupported
 	ErrEntityTooSmall
 	ErrEntityTooLarge
+	ErrExpiredPresignRequest
 	ErrExpiredToken
 	ErrIllegalVersioningConfigurationException
 	ErrIncompleteBody
@@ -50,16 +54,21 @@ const (
 	ErrInvalidEncryptionAlgorithmError
 	ErrInvalidLocationConstraint
 	ErrInvalidObjectState
+	ErrInvalidPart
+	ErrInvalidPartOrder
 	ErrInvalidPayer
 	ErrInvalidRange
+	ErrInvalidRequest
 	ErrInvalidRequestSignVersion
 	ErrInvalidSecurity
 	ErrInvalidSignatureFormat
 	ErrInvalidStorageClass
+	ErrInvalidTag
 	ErrInvalidToken
 	ErrInvalidURI
 	ErrKeyTooLongError
 	ErrMalformedACLError
+	ErrMalformedPolicy
 	ErrMalformedPOSTRequest
 	ErrMalformedXML
 	ErrMaxMessageLengthExceeded
@@ -70,12 +79,17 @@ const (
 	ErrMissingRequestBodyError
 	ErrMissingSecurityHeader
 	ErrNoSuchBucket
+	ErrNoSuchCORSConfiguration
 	ErrNoSuchKey
+	ErrNoSuchLifecycleConfiguration
+	ErrNoSuchObjectLockConfiguration
 	ErrNoSuchUpload
 	ErrNoSuchVersion
 	ErrNotImplemented
+	ErrNotModified
 	ErrNotSignedUp
 	ErrNoSuchBucketPolicy
+	ErrObjectLockConfigurationNotFoundError
 	ErrOperationAborted
 	ErrPermanentRedirect
 	ErrPreconditionFailed
@@ -92,6 +106,7 @@ const (
 	ErrUnexpectedContent
 	ErrUnresolvableGrantByEmailAddress
 	ErrUserKeyMustBeSpecified
+	ErrXAmzContentSHA256Mismatch
 )
 
 var errorInfos = map[ErrorCode]ErrorInfo{
@@ -100,6 +115,26 @@ var errorInfos = map[ErrorCode]ErrorInfo{
 		Description: "Access denied.",
 		HTTPCode:    http.StatusForbidden,
 	},
+	ErrAccessForbidden: {
+		Code:        "AccessForbidden",
+		Description: "CORSResponse: This CORS request is not allowed.",
+		HTTPCode:    http.StatusForbidden,
+	},
+	ErrAuthorizationHeaderMalformed: {
+		Code:        "AuthorizationHeaderMalformed",
+		Description: "The authorization header is malformed; the date or the region of the credential scope is wrong.",
+		HTTPCode:    http.StatusBadRequest,
+	},
+	ErrAuthorizationQueryParametersError: {
+		Code:        "AuthorizationQueryParametersError",
+		Description: "Error parsing the X-Amz-Credential parameter or the X-Amz-Expires parameter is invalid.",
+		HTTPCode:    http.StatusBadRequest,
+	},
+	ErrBadDigest: {
+		Code:        "BadDigest",
+		Description: "The Content-MD5 you specified did not match what we received.",
+		HTTPCode:    http.StatusBadRequest,
+	},
 	ErrBucketAlreadyExists: {
 		Code:        "BucketAlreadyExists",
 		Description: "The requested bucket name is not available. The bucket namespace is shared by all users of the system. Please select a different name and try again.",
@@ -110,6 +145,31 @@ var errorInfos = map[ErrorCode]ErrorInfo{
 		Description: "The bucket you tried to delete is not empty.",
 		HTTPCode:    http.StatusConflict,
 	},
+	ErrEntityTooSmall: {
+		Code:        "EntityTooSmall",
+		Description: "Your proposed upload is smaller than the minimum allowed object size.",
+		HTTPCode:    http.StatusBadRequest,
+	},
+	ErrEntityTooLarge: {
+		Code:        "EntityTooLarge",
+		Description: "Your proposed upload exceeds the maximum allowed object size.",
+		HTTPCode:    http.StatusBadRequest,
+	},
+	ErrExpiredPresignRequest: {
+		Code:        "AccessDenied",
+		Description: "Request has expired.",
+		HTTPCode:    http.StatusForbidden,
+	},
+	ErrIllegalVersioningConfigurationException: {
+		Code:        "IllegalVersioningConfigurationException",
+		Description: "The versioning configuration specified in the request is invalid.",
+		HTTPCode:    http.StatusBadRequest,
+	},
+	ErrIncompleteBody: {
+		Code:        "IncompleteBody",
+		Description: "You did not provide the number of bytes specified by the Content-Length HTTP header.",
+		HTTPCode:    http.StatusBadRequest,
+	},
 	ErrInternalError: {
 		Code:        "InternalError",
 		Description: "We encountered an internal error. Please try again.",
@@ -120,6 +180,11 @@ var errorInfos = map[ErrorCode]ErrorInfo{
 		Description: "The AWS access key Id you provided does not exist in our records.",
 		HTTPCode:    http.StatusForbidden,
 	},
+	ErrInvalidArgument: {
+		Code:        "InvalidArgument",
+		Description: "Invalid Argument.",
+		HTTPCode:    http.StatusBadRequest,
+	},
 	ErrInvalidBucketName: {
 		Code:        "InvalidBucketName",
 		Description: "The specified bucket is not valid.",
@@ -135,11 +200,46 @@ var errorInfos = map[ErrorCode]ErrorInfo{
 		Description: "The provided signature format is incorrect.",
 		HTTPCode:    http.StatusBadRequest,
 	},
+	ErrInvalidDigest: {
+		Code:        "InvalidDigest",
+		Description: "The Content-MD5 you specified is not valid.",
+		HTTPCode:    http.StatusBadRequest,
+	},
+	ErrInvalidEncryptionAlgorithmError: {
+		Code:        "InvalidEncryptionAlgorithmError",
+		Description: "The encryption request you specified is not valid. The valid value is AES256.",
+		HTTPCode:    http.StatusBadRequest,
+	},
+	ErrInvalidTag: {
+		Code:        "InvalidTag",
+		Description: "The tag provided was not a valid tag.",
+		HTTPCode:    http.StatusBadRequest,
+	},
 	ErrInvalidLocationConstraint: {
 		Code:        "InvalidLocationConstraint",
 		Description: "The specified location constraint is not valid. For more information about regions, see How to Select a Region for Your Buckets.",
 		HTTPCode:    http.StatusBadRequest,
 	},
+	ErrInvalidPart: {
+		Code:        "InvalidPart",
+		Description: "One or more of the specified parts could not be found. The part may not have been uploaded, or the specified entity tag may not match the part's entity tag.",
+		HTTPCode:    http.StatusBadRequest,
+	},
+	ErrInvalidPartOrder: {
+		Code:        "InvalidPartOrder",
+		Description: "The list of parts was not in ascending order. The parts list must be specified in order by part number.",
+		HTTPCode:    http.StatusBadRequest,
+	},
+	ErrInvalidRange: {
+		Code:        "InvalidRange",
+		Description: "The requested range cannot be satisfied.",
+		HTTPCode:    http.StatusRequestedRangeNotSatisfiable,
+	},
+	ErrInvalidRequest: {
+		Code:        "InvalidRequest",
+		Description: "The request is not valid.",
+		HTTPCode:    http.StatusBadRequest,
+	},
 	ErrInvalidRequestSignVersion: {
 		Code:        "InvalidRequest",
 		Description: "The authorization mechanism you have provided is not supported. Please use AWS4-HMAC-SHA256.",
@@ -155,6 +255,36 @@ var errorInfos = map[ErrorCode]ErrorInfo{
 		Description: "Your key is too long.",
 		HTTPCode:    http.StatusBadRequest,
 	},
+	ErrMalformedACLError: {
+		Code:        "MalformedACLError",
+		Description: "The XML you provided was not well-formed or did not validate against our published schema.",
+		HTTPCode:    http.StatusBadRequest,
+	},
+	ErrMalformedPolicy: {
+		Code:        "MalformedPolicy",
+		Description: "The policy you provided is not valid JSON or has an invalid statement.",
+		HTTPCode:    http.StatusBadRequest,
+	},
+	ErrMalformedXML: {
+		Code:        "MalformedXML",
+		Description: "The XML you provided was not well-formed or did not validate against our published schema.",
+		HTTPCode:    http.StatusBadRequest,
+	},
+	ErrMetadataTooLarge: {
+		Code:        "MetadataTooLarge",
+		Description: "Your metadata headers exceed the maximum allowed metadata size.",
+		HTTPCode:    http.StatusBadRequest,
+	},
+	ErrMethodNotAllowed: {
+		Code:        "MethodNotAllowed",
+		Description: "The specified method is not allowed against this resource.",
+		HTTPCode:    http.StatusMethodNotAllowed,
+	},
+	ErrMissingContentLength: {
+		Code:        "MissingContentLength",
+		Description: "You must provide the Content-Length HTTP header.",
+		HTTPCode:    http.StatusLengthRequired,
+	},
 	ErrMissingSecurityHeader: {
 		Code:        "MissingSecurityHeader",
 		Description: "Your request is missing a required header.",
@@ -162,19 +292,84 @@ var errorInfos = map[ErrorCode]ErrorInfo{
 	},
 	ErrNoSuchBucket: {
 		Code:        "NoSuchBucket",
+		Description: "The specified bucket does not exist.",
+		HTTPCode:    http.StatusNotFound,
+	},
+	ErrNoSuchCORSConfiguration: {
+		Code:        "NoSuchCORSConfiguration",
+		Description: "The CORS configuration does not exist.",
+		HTTPCode:    http.StatusNotFound,
+	},
+	ErrNoSuchKey: {
+		Code:        "NoSuchKey",
 		Description: "The specified key does not exist.",
 		HTTPCode:    http.StatusNotFound,
 	},
+	ErrNoSuchLifecycleConfiguration: {
+		Code:        "NoSuchLifecycleConfiguration",
+		Description: "The lifecycle configuration does not exist.",
+		HTTPCode:    http.StatusNotFound,
+	},
+	ErrNoSuchObjectLockConfiguration: {
+		Code:        "NoSuchObjectLockConfiguration",
+		Description: "The specified object does not have a ObjectLock configuration.",
+		HTTPCode:    http.StatusNotFound,
+	},
+	ErrObjectLockConfigurationNotFoundError: {
+		Code:        "ObjectLockConfigurationNotFoundError",
+		Description: "Object Lock configuration does not exist for this bucket.",
+		HTTPCode:    http.StatusNotFound,
+	},
+	ErrNoSuchBucketPolicy: {
+		Code:        "NoSuchBucketPolicy",
+		Description: "The bucket policy does not exist.",
+		HTTPCode:    http.StatusNotFound,
+	},
+	ErrNoSuchUpload: {
+		Code:        "NoSuchUpload",
+		Description: "The specified multipart upload does not exist. The upload ID might be invalid, or the multipart upload might have been aborted or completed.",
+		HTTPCode:    http.StatusNotFound,
+	},
+	ErrNoSuchVersion: {
+		Code:        "NoSuchVersion",
+		Description: "The version ID specified in the request does not match an existing version.",
+		HTTPCode:    http.StatusNotFound,
+	},
+	ErrNotModified: {
+		Code:        "NotModified",
+		Description: "Not Modified.",
+		HTTPCode:    http.StatusNotModified,
+	},
+	ErrNotImplemented: {
+		Code:        "NotImplemented",
+		Description: "A header you provided implies functionality that is not implemented.",
+		HTTPCode:    http.StatusNotImplemented,
+	},
 	ErrNotSignedUp: {
 		Code:        "NotSignedUp",
 		Description: "Your account is not signed up for the Amazon S3 service. You must sign up before you can use Amazon S3. You can sign up at the following URL: https://aws.amazon.com/s3",
 		HTTPCode:    http.StatusForbidden,
 	},
+	ErrPreconditionFailed: {
+		Code:        "PreconditionFailed",
+		Description: "At least one of the preconditions you specified did not hold.",
+		HTTPCode:    http.StatusPreconditionFailed,
+	},
 	ErrRequestTimeout: {
 		Code:        "RequestTimeout",
 		Description: "Your socket connection to the server was not read from or written to within the timeout period.",
 		HTTPCode:    http.StatusBadRequest,
 	},
+	ErrRequestTimeTooSkewed: {
+		Code:        "RequestTimeTooSkewed",
+		Description: "The difference between the request time and the server's time is too large.",
+		HTTPCode:    http.StatusForbidden,
+	},
+	ErrSignatureDoesNotMatch: {
+		Code:        "SignatureDoesNotMatch",
+		Description: "The request signature we calculated does not match the signature you provided. Check your AWS secret access key and signing method.",
+		HTTPCode:    http.StatusForbidden,
+	},
 	ErrTooManyBuckets: {
 		Code:        "TooManyBuckets",
 		Description: "You have attempted to create more buckets than allowed.",
@@ -185,6 +380,11 @@ var errorInfos = map[ErrorCode]ErrorInfo{
 		Description: "The bucket POST must contain the specified field name. If it is specified, check the order of the fields.",
 		HTTPCode:    http.StatusBadRequest,
 	},
+	ErrXAmzContentSHA256Mismatch: {
+		Code:        "XAmzContentSHA256Mismatch",
+		Description: "The provided 'x-amz-content-sha256' header does not match what was computed.",
+		HTTPCode:    http.StatusBadRequest,
+	},
 }
 
 // SendError writes error response to the given http.responseWriter.
@@ -0,0 +1,28 @@
+package s3
+
+import (
+	"net"
+	"strings"
+)
+
+// BucketFromHost returns the bucket name of the virtual-hosted-style
+// request, whose host is the bucket name followed by one of the base
+// domains. It returns false if the request is path-style.
+// https://docs.aws.amazon.com/AmazonS3/latest/dev/VirtualHosting.html
+func BucketFromHost(host string, baseDomains []string) (string, bool) {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+
+	for _, d := range baseDomains {
+		if !strings.HasSuffix(host, "."+d) {
+			continue
+		}
+		if bucket := strings.TrimSuffix(host, "."+d); bucket != "" {
+			return bucket, true
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,31 @@
+package s3
+
+import "testing"
+
+func TestBucketFromHost(t *testing.T) {
+	domains := []string{"s3.example.com", "s3.kr.example.com"}
+
+	testCases := []struct {
+		host   string
+		bucket string
+		ok     bool
+	}{
+		{"s3.example.com", "", false},
+		{"s3.example.com:50000", "", false},
+		{"bucket.s3.example.com", "bucket", true},
+		{"bucket.s3.example.com:50000", "bucket", true},
+		{"Bucket.S3.Example.Com.", "bucket", true},
+		{"my.bucket.s3.example.com", "my.bucket", true},
+		{"bucket.s3.kr.example.com", "bucket", true},
+		{"bucket.example.com", "", false},
+		{"localhost:50000", "", false},
+		{"127.0.0.1:50000", "", false},
+	}
+
+	for _, c := range testCases {
+		bucket, ok := BucketFromHost(c.host, domains)
+		if bucket != c.bucket || ok != c.ok {
+			t.Errorf("%q: expected (%q, %v), got (%q, %v)", c.host, c.bucket, c.ok, bucket, ok)
+		}
+	}
+}
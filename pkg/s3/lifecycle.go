@@ -0,0 +1,273 @@
+package s3
+
+import (
+	"encoding/xml"
+	"strings"
+	"time"
+)
+
+const (
+	// MaxLifecycleRules is the maximum number of rules in a lifecycle
+	// configuration.
+	MaxLifecycleRules = 1000
+
+	// maxLifecycleRuleID is the maximum length of the rule id.
+	maxLifecycleRuleID = 255
+)
+
+// LifecycleConfiguration is the lifecycle rules of the bucket. It is the
+// request body of the put bucket lifecycle configuration request and the
+// response of the get bucket lifecycle configuration request.
+type LifecycleConfiguration struct {
+	XMLName xml.Name        `xml:"LifecycleConfiguration"`
+	Xmlns   string          `xml:"xmlns,attr,omitempty"`
+	Rules   []LifecycleRule `xml:"Rule"`
+}
+
+// LifecycleRule is the actions applied to the objects which match the
+// filter. The prefix out of the filter is the legacy form of the filter.
+type LifecycleRule struct {
+	ID                             string                          `xml:",omitempty"`
+	Status                         string                          `xml:"Status"`
+	Filter                         *LifecycleFilter                `xml:",omitempty"`
+	Prefix                         *string                         `xml:",omitempty"`
+	Expiration                     *LifecycleExpiration            `xml:",omitempty"`
+	NoncurrentVersionExpiration    *NoncurrentVersionExpiration    `xml:",omitempty"`
+	AbortIncompleteMultipartUpload *AbortIncompleteMultipartUpload `xml:",omitempty"`
+	Transitions                    []LifecycleTransition           `xml:"Transition"`
+	NoncurrentVersionTransitions   []LifecycleTransition           `xml:"NoncurrentVersionTransition"`
+}
+
+// LifecycleFilter selects the objects by one of the key prefix, the tag
+// or the conjunction of them.
+type LifecycleFilter struct {
+	Prefix *string       `xml:",omitempty"`
+	Tag    *Tag          `xml:",omitempty"`
+	And    *LifecycleAnd `xml:",omitempty"`
+}
+
+// LifecycleAnd is the conjunction of the key prefix and the tags.
+type LifecycleAnd struct {
+	Prefix string `xml:",omitempty"`
+	Tags   []Tag  `xml:"Tag"`
+}
+
+// Tag is the key and the value pair attached to the object.
+type Tag struct {
+	Key   string
+	Value string
+}
+
+// LifecycleExpiration expires the current version of the object after the
+// days from its creation or at the date. The delete marker which has no
+// noncurrent versions is removed if ExpiredObjectDeleteMarker is set.
+type LifecycleExpiration struct {
+	Days                      int    `xml:",omitempty"`
+	Date                      string `xml:",omitempty"`
+	ExpiredObjectDeleteMarker bool   `xml:",omitempty"`
+}
+
+// NoncurrentVersionExpiration removes the noncurrent version after the
+// days from it became noncurrent.
+type NoncurrentVersionExpiration struct {
+	NoncurrentDays int
+}
+
+// AbortIncompleteMultipartUpload aborts the multipart upload which is not
+// completed within the days from its initiation.
+type AbortIncompleteMultipartUpload struct {
+	DaysAfterInitiation int
+}
+
+// LifecycleTransition moves the object into the other storage class.
+type LifecycleTransition struct {
+	Days           int    `xml:",omitempty"`
+	NoncurrentDays int    `xml:",omitempty"`
+	Date           string `xml:",omitempty"`
+	StorageClass   string
+}
+
+// Validate checks the lifecycle configuration is well formed and supported.
+func (c *LifecycleConfiguration) Validate() ErrorCode {
+	if len(c.Rules) == 0 || len(c.Rules) > MaxLifecycleRules {
+		return ErrMalformedXML
+	}
+
+	ids := make(map[string]bool, len(c.Rules))
+	for i := range c.Rules {
+		r := &c.Rules[i]
+		if r.ID != "" {
+			if len(r.ID) > maxLifecycleRuleID || ids[r.ID] {
+				return ErrInvalidArgument
+			}
+			ids[r.ID] = true
+		}
+		if code := r.validate(); code != ErrNone {
+			return code
+		}
+	}
+
+	return ErrNone
+}
+
+// validate checks the rule is well formed and supported.
+func (r *LifecycleRule) validate() ErrorCode {
+	if r.Status != "Enabled" && r.Status != "Disabled" {
+		return ErrMalformedXML
+	}
+	if r.Filter != nil && r.Prefix != nil {
+		return ErrMalformedXML
+	}
+	if f := r.Filter; f != nil {
+		n := 0
+		if f.Prefix != nil {
+			n++
+		}
+		if f.Tag != nil {
+			n++
+		}
+		if f.And != nil {
+			n++
+		}
+		if n > 1 {
+			return ErrMalformedXML
+		}
+		if code := validateTags(r.Tags()); code != ErrNone {
+			return code
+		}
+	}
+
+	// Moving the data into the other storage class is not supported.
+	if len(r.Transitions) > 0 || len(r.NoncurrentVersionTransitions) > 0 {
+		return ErrNotImplemented
+	}
+
+	if r.Expiration == nil && r.NoncurrentVersionExpiration == nil && r.AbortIncompleteMultipartUpload == nil {
+		return ErrInvalidRequest
+	}
+
+	if e := r.Expiration; e != nil {
+		n := 0
+		if e.Days != 0 {
+			n++
+		}
+		if e.Date != "" {
+			n++
+		}
+		if e.ExpiredObjectDeleteMarker {
+			n++
+		}
+		if n != 1 || e.Days < 0 {
+			return ErrInvalidArgument
+		}
+		if e.Date != "" {
+			if _, ok := parseLifecycleDate(e.Date); !ok {
+				return ErrInvalidArgument
+			}
+		}
+		// The delete marker does not have tags.
+		if e.ExpiredObjectDeleteMarker && len(r.Tags()) > 0 {
+			return ErrInvalidArgument
+		}
+	}
+	if e := r.NoncurrentVersionExpiration; e != nil && e.NoncurrentDays <= 0 {
+		return ErrInvalidArgument
+	}
+	if a := r.AbortIncompleteMultipartUpload; a != nil {
+		if a.DaysAfterInitiation <= 0 {
+			return ErrInvalidArgument
+		}
+		// The multipart upload does not have tags.
+		if len(r.Tags()) > 0 {
+			return ErrInvalidRequest
+		}
+	}
+
+	return ErrNone
+}
+
+// Enabled returns true if the rule is applied.
+func (r *LifecycleRule) Enabled() bool {
+	return r.Status == "Enabled"
+}
+
+// KeyPrefix returns the key prefix of the objects which the rule applies to.
+func (r *LifecycleRule) KeyPrefix() string {
+	if r.Prefix != nil {
+		return *r.Prefix
+	}
+	if f := r.Filter; f != nil {
+		if f.Prefix != nil {
+			return *f.Prefix
+		}
+		if f.And != nil {
+			return f.And.Prefix
+		}
+	}
+	return ""
+}
+
+// Tags returns the tags which the object must have to be applied the rule.
+func (r *LifecycleRule) Tags() []Tag {
+	if f := r.Filter; f != nil {
+		if f.Tag != nil {
+			return []Tag{*f.Tag}
+		}
+		if f.And != nil {
+			return f.And.Tags
+		}
+	}
+	return nil
+}
+
+// Match returns true if the rule applies to the object of the key and the
+// tags.
+func (r *LifecycleRule) Match(key string, tags map[string]string) bool {
+	if !strings.HasPrefix(key, r.KeyPrefix()) {
+		return false
+	}
+	for _, t := range r.Tags() {
+		if v, ok := tags[t.Key]; !ok || v != t.Value {
+			return false
+		}
+	}
+	return true
+}
+
+// Expired returns true if the current version created at the time is
+// expired at now.
+func (e *LifecycleExpiration) Expired(created, now time.Time) bool {
+	if e.Date != "" {
+		date, ok := parseLifecycleDate(e.Date)
+		return ok && !now.Before(date)
+	}
+	if e.Days > 0 {
+		return !now.Before(ExpirationTime(created, e.Days))
+	}
+	return false
+}
+
+// ExpirationTime returns the time when the days are passed from the given
+// time. The result is rounded up to the next midnight UTC.
+func ExpirationTime(t time.Time, days int) time.Time {
+	t = t.UTC().AddDate(0, 0, days)
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	if midnight.Equal(t) {
+		return t
+	}
+	return midnight.AddDate(0, 0, 1)
+}
+
+// parseLifecycleDate parses the date of the lifecycle action, which must be
+// the midnight UTC in the ISO 8601 format.
+func parseLifecycleDate(date string) (time.Time, bool) {
+	t, err := time.Parse(time.RFC3339, date)
+	if err != nil {
+		return time.Time{}, false
+	}
+	t = t.UTC()
+	if t.Hour() != 0 || t.Minute() != 0 || t.Second() != 0 || t.Nanosecond() != 0 {
+		return time.Time{}, false
+	}
+	return t, true
+}
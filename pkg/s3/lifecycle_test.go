@@ -0,0 +1,80 @@
+package s3
+
+import (
+	"encoding/xml"
+	"testing"
+	"time"
+)
+
+func TestLifecycleValidate(t *testing.T) {
+	testCases := []struct {
+		body string
+		code ErrorCode
+	}{
+		{`<LifecycleConfiguration><Rule><ID>logs</ID><Filter><Prefix>logs/</Prefix></Filter><Status>Enabled</Status><Expiration><Days>30</Days></Expiration></Rule></LifecycleConfiguration>`, ErrNone},
+		{`<LifecycleConfiguration><Rule><Prefix></Prefix><Status>Disabled</Status><AbortIncompleteMultipartUpload><DaysAfterInitiation>7</DaysAfterInitiation></AbortIncompleteMultipartUpload></Rule></LifecycleConfiguration>`, ErrNone},
+		{`<LifecycleConfiguration><Rule><Filter><Tag><Key>k</Key><Value>v</Value></Tag></Filter><Status>Enabled</Status><NoncurrentVersionExpiration><NoncurrentDays>1</NoncurrentDays></NoncurrentVersionExpiration></Rule></LifecycleConfiguration>`, ErrNone},
+		{`<LifecycleConfiguration><Rule><Filter/><Status>Enabled</Status><Expiration><Date>2030-01-01T00:00:00.000Z</Date></Expiration></Rule></LifecycleConfiguration>`, ErrNone},
+		{`<LifecycleConfiguration></LifecycleConfiguration>`, ErrMalformedXML},
+		{`<LifecycleConfiguration><Rule><Status>On</Status><Expiration><Days>1</Days></Expiration></Rule></LifecycleConfiguration>`, ErrMalformedXML},
+		{`<LifecycleConfiguration><Rule><Filter><Prefix>a</Prefix><Tag><Key>k</Key><Value>v</Value></Tag></Filter><Status>Enabled</Status><Expiration><Days>1</Days></Expiration></Rule></LifecycleConfiguration>`, ErrMalformedXML},
+		{`<LifecycleConfiguration><Rule><ID>a</ID><Status>Enabled</Status><Expiration><Days>1</Days></Expiration></Rule><Rule><ID>a</ID><Status>Enabled</Status><Expiration><Days>2</Days></Expiration></Rule></LifecycleConfiguration>`, ErrInvalidArgument},
+		{`<LifecycleConfiguration><Rule><Status>Enabled</Status></Rule></LifecycleConfiguration>`, ErrInvalidRequest},
+		{`<LifecycleConfiguration><Rule><Status>Enabled</Status><Expiration><Days>1</Days><ExpiredObjectDeleteMarker>true</ExpiredObjectDeleteMarker></Expiration></Rule></LifecycleConfiguration>`, ErrInvalidArgument},
+		{`<LifecycleConfiguration><Rule><Status>Enabled</Status><Expiration><Date>2030-01-01T12:00:00Z</Date></Expiration></Rule></LifecycleConfiguration>`, ErrInvalidArgument},
+		{`<LifecycleConfiguration><Rule><Filter><Tag><Key>k</Key><Value>v</Value></Tag></Filter><Status>Enabled</Status><AbortIncompleteMultipartUpload><DaysAfterInitiation>1</DaysAfterInitiation></AbortIncompleteMultipartUpload></Rule></LifecycleConfiguration>`, ErrInvalidRequest},
+		{`<LifecycleConfiguration><Rule><Status>Enabled</Status><Transition><Days>30</Days><StorageClass>GLACIER</StorageClass></Transition></Rule></LifecycleConfiguration>`, ErrNotImplemented},
+		{`<LifecycleConfiguration><Rule><Filter><Tag><Key></Key><Value>v</Value></Tag></Filter><Status>Enabled</Status><Expiration><Days>1</Days></Expiration></Rule></LifecycleConfiguration>`, ErrInvalidTag},
+	}
+
+	for i, c := range testCases {
+		conf := LifecycleConfiguration{}
+		if err := xml.Unmarshal([]byte(c.body), &conf); err != nil {
+			t.Fatalf("case %d: %v", i, err)
+		}
+		if code := conf.Validate(); code != c.code {
+			t.Errorf("case %d: expected %d, got %d", i, c.code, code)
+		}
+	}
+}
+
+func TestLifecycleRuleMatch(t *testing.T) {
+	prefix := "logs/"
+	testCases := []struct {
+		rule  LifecycleRule
+		key   string
+		tags  map[string]string
+		match bool
+	}{
+		{LifecycleRule{}, "any", nil, true},
+		{LifecycleRule{Prefix: &prefix}, "logs/a", nil, true},
+		{LifecycleRule{Filter: &LifecycleFilter{Prefix: &prefix}}, "data/a", nil, false},
+		{LifecycleRule{Filter: &LifecycleFilter{Tag: &Tag{Key: "k", Value: "v"}}}, "a", map[string]string{"k": "v"}, true},
+		{LifecycleRule{Filter: &LifecycleFilter{Tag: &Tag{Key: "k", Value: "v"}}}, "a", nil, false},
+		{LifecycleRule{Filter: &LifecycleFilter{And: &LifecycleAnd{Prefix: "logs/", Tags: []Tag{{Key: "k", Value: "v"}}}}}, "logs/a", map[string]string{"k": "w"}, false},
+	}
+
+	for i, c := range testCases {
+		if match := c.rule.Match(c.key, c.tags); match != c.match {
+			t.Errorf("case %d: expected %v, got %v", i, c.match, match)
+		}
+	}
+}
+
+func TestExpirationTime(t *testing.T) {
+	testCases := []struct {
+		created  time.Time
+		days     int
+		expected time.Time
+	}{
+		{time.Date(2014, 1, 15, 10, 30, 0, 0, time.UTC), 3, time.Date(2014, 1, 19, 0, 0, 0, 0, time.UTC)},
+		{time.Date(2014, 1, 15, 0, 0, 0, 0, time.UTC), 3, time.Date(2014, 1, 18, 0, 0, 0, 0, time.UTC)},
+		{time.Date(2014, 1, 31, 23, 59, 59, 0, time.UTC), 30, time.Date(2014, 3, 3, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for i, c := range testCases {
+		if got := ExpirationTime(c.created, c.days); !got.Equal(c.expected) {
+			t.Errorf("case %d: expected %v, got %v", i, c.expected, got)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package s3
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+// Owner is the owner of the bucket or the object.
+type Owner struct {
+	ID          string
+	DisplayName string
+}
+
+// Bucket is the entry of the bucket list.
+type Bucket struct {
+	Name         string
+	CreationDate string
+}
+
+// ListAllMyBucketsResult is the response of the list buckets request.
+type ListAllMyBucketsResult struct {
+	XMLName xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ ListAllMyBucketsResult"`
+	Owner   Owner
+	Buckets []Bucket `xml:"Buckets>Bucket"`
+}
+
+// Object is the entry of the object list.
+type Object struct {
+	Key          string
+	LastModified string
+	ETag         string
+	Size         int64
+	StorageClass string
+	Owner        *Owner `xml:",omitempty"`
+}
+
+// CommonPrefix is the rolled up prefix of the object list.
+type CommonPrefix struct {
+	Prefix string
+}
+
+// ListBucketResult is the response of the list objects request.
+// The fields for the version 1 and the version 2 are omitted if empty.
+type ListBucketResult struct {
+	XMLName               xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ ListBucketResult"`
+	Name                  string
+	Prefix                string
+	Marker                *string `xml:",omitempty"`
+	NextMarker            string  `xml:",omitempty"`
+	StartAfter            string  `xml:",omitempty"`
+	ContinuationToken     string  `xml:",omitempty"`
+	NextContinuationToken string  `xml:",omitempty"`
+	KeyCount              *int    `xml:",omitempty"`
+	MaxKeys               int
+	Delimiter             string `xml:",omitempty"`
+	EncodingType          string `xml:",omitempty"`
+	IsTruncated           bool
+	Contents              []Object
+	CommonPrefixes        []CommonPrefix
+}
+
+// FormatTime formats the time in the form of the s3 xml response.
+func FormatTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000Z")
+}
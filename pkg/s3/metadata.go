@@ -0,0 +1,75 @@
+package s3
+
+import (
+	"net/http"
+	"strings"
+)
+
+const (
+	// MetadataPrefix is the header prefix of the user-defined metadata.
+	MetadataPrefix = "X-Amz-Meta-"
+	// MaxUserMetadataSize is the maximum size of the user-defined metadata,
+	// which is the sum of the lengths of the keys and the values.
+	MaxUserMetadataSize = 2 * 1024
+)
+
+// storedHeaders are the standard headers which are stored with the object
+// and sent back as they are on GET and HEAD.
+var storedHeaders = []string{
+	"Cache-Control",
+	"Content-Disposition",
+	"Content-Encoding",
+	"Content-Language",
+	"Expires",
+}
+
+// ObjectHeaders extracts the standard headers to be stored with the object.
+// The aws-chunked encoding is only for the transfer, so it is removed from
+// the Content-Encoding.
+func ObjectHeaders(h http.Header) map[string]string {
+	headers := make(map[string]string)
+	for _, k := range storedHeaders {
+		v := h.Get(k)
+		if k == "Content-Encoding" {
+			v = stripChunkedEncoding(v)
+		}
+		if v != "" {
+			headers[k] = v
+		}
+	}
+	return headers
+}
+
+// stripChunkedEncoding removes the aws-chunked from the list of encodings.
+func stripChunkedEncoding(encoding string) string {
+	encodings := make([]string, 0, 1)
+	for _, e := range strings.Split(encoding, ",") {
+		e = strings.TrimSpace(e)
+		if e == "" || strings.EqualFold(e, "aws-chunked") {
+			continue
+		}
+		encodings = append(encodings, e)
+	}
+	return strings.Join(encodings, ",")
+}
+
+// UserMetadata extracts the user-defined metadata from the headers. The
+// keys are returned in lower case without the prefix. It fails with
+// ErrMetadataTooLarge if the metadata exceeds MaxUserMetadataSize.
+func UserMetadata(h http.Header) (map[string]string, ErrorCode) {
+	meta := make(map[string]string)
+	size := 0
+	for k, v := range h {
+		if !strings.HasPrefix(k, MetadataPrefix) {
+			continue
+		}
+		key, value := strings.ToLower(k[len(MetadataPrefix):]), strings.Join(v, ",")
+		meta[key] = value
+		size += len(key) + len(value)
+	}
+
+	if size > MaxUserMetadataSize {
+		return nil, ErrMetadataTooLarge
+	}
+	return meta, ErrNone
+}
@@ -0,0 +1,62 @@
+package s3
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestObjectHeaders(t *testing.T) {
+	testCases := []struct {
+		header   http.Header
+		expected map[string]string
+	}{
+		{http.Header{}, map[string]string{}},
+		{
+			http.Header{
+				"Cache-Control":       {"max-age=60"},
+				"Content-Disposition": {"attachment"},
+				"Content-Type":        {"text/html"},
+				"X-Amz-Meta-Foo":      {"bar"},
+			},
+			map[string]string{
+				"Cache-Control":       "max-age=60",
+				"Content-Disposition": "attachment",
+			},
+		},
+		{http.Header{"Content-Encoding": {"gzip"}}, map[string]string{"Content-Encoding": "gzip"}},
+		{http.Header{"Content-Encoding": {"aws-chunked"}}, map[string]string{}},
+		{http.Header{"Content-Encoding": {"aws-chunked,gzip"}}, map[string]string{"Content-Encoding": "gzip"}},
+		{http.Header{"Content-Encoding": {"gzip, aws-chunked"}}, map[string]string{"Content-Encoding": "gzip"}},
+	}
+
+	for i, c := range testCases {
+		if got := ObjectHeaders(c.header); !reflect.DeepEqual(got, c.expected) {
+			t.Errorf("case %d: expected %v, got %v", i, c.expected, got)
+		}
+	}
+}
+
+func TestUserMetadata(t *testing.T) {
+	meta, code := UserMetadata(http.Header{
+		"X-Amz-Meta-Foo": {"bar"},
+		"X-Amz-Meta-Baz": {"a", "b"},
+		"Content-Type":   {"text/plain"},
+	})
+	if code != ErrNone {
+		t.Fatalf("expected no error, got %v", code)
+	}
+	if expected := map[string]string{"foo": "bar", "baz": "a,b"}; !reflect.DeepEqual(meta, expected) {
+		t.Errorf("expected %v, got %v", expected, meta)
+	}
+
+	// The size of the metadata is the sum of the keys and the values.
+	limit := strings.Repeat("v", MaxUserMetadataSize-len("key"))
+	if _, code := UserMetadata(http.Header{"X-Amz-Meta-Key": {limit}}); code != ErrNone {
+		t.Errorf("expected no error at the limit, got %v", code)
+	}
+	if _, code := UserMetadata(http.Header{"X-Amz-Meta-Key": {limit + "v"}}); code != ErrMetadataTooLarge {
+		t.Errorf("expected %v, got %v", ErrMetadataTooLarge, code)
+	}
+}
@@ -0,0 +1,87 @@
+package s3
+
+import "encoding/xml"
+
+// InitiateMultipartUploadResult is the response of the create multipart
+// upload request.
+type InitiateMultipartUploadResult struct {
+	XMLName  xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ InitiateMultipartUploadResult"`
+	Bucket   string
+	Key      string
+	UploadId string
+}
+
+// CompletePart is the part which makes up the completed object.
+type CompletePart struct {
+	PartNumber int
+	ETag       string
+}
+
+// CompleteMultipartUpload is the request body of the complete multipart
+// upload request.
+type CompleteMultipartUpload struct {
+	XMLName xml.Name       `xml:"CompleteMultipartUpload"`
+	Parts   []CompletePart `xml:"Part"`
+}
+
+// CompleteMultipartUploadResult is the response of the complete multipart
+// upload request.
+type CompleteMultipartUploadResult struct {
+	XMLName  xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ CompleteMultipartUploadResult"`
+	Location string
+	Bucket   string
+	Key      string
+	ETag     string
+}
+
+// Part is the entry of the part list.
+type Part struct {
+	PartNumber   int
+	LastModified string
+	ETag         string
+	Size         int64
+}
+
+// ListPartsResult is the response of the list parts request.
+type ListPartsResult struct {
+	XMLName              xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ ListPartsResult"`
+	Bucket               string
+	Key                  string
+	UploadId             string
+	Initiator            Owner
+	Owner                Owner
+	StorageClass         string
+	PartNumberMarker     int
+	NextPartNumberMarker int
+	MaxParts             int
+	IsTruncated          bool
+	Parts                []Part `xml:"Part"`
+}
+
+// Upload is the entry of the multipart upload list.
+type Upload struct {
+	Key          string
+	UploadId     string
+	Initiator    Owner
+	Owner        Owner
+	StorageClass string
+	Initiated    string
+}
+
+// ListMultipartUploadsResult is the response of the list multipart
+// uploads request.
+type ListMultipartUploadsResult struct {
+	XMLName            xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ ListMultipartUploadsResult"`
+	Bucket             string
+	KeyMarker          string
+	UploadIdMarker     string
+	NextKeyMarker      string
+	NextUploadIdMarker string
+	Prefix             string
+	Delimiter          string `xml:",omitempty"`
+	EncodingType       string `xml:",omitempty"`
+	MaxUploads         int
+	IsTruncated        bool
+	Uploads            []Upload `xml:"Upload"`
+	CommonPrefixes     []CommonPrefix
+}
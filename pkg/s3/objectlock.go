@@ -0,0 +1,279 @@
+package s3
+
+import (
+	"encoding/xml"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// RetentionGovernance is the retention mode which the user who is
+	// allowed to bypass the governance can change or remove.
+	RetentionGovernance = "GOVERNANCE"
+	// RetentionCompliance is the retention mode which no user can shorten
+	// or remove until the retention expires.
+	RetentionCompliance = "COMPLIANCE"
+
+	// LegalHoldOn and LegalHoldOff are the status of the legal hold.
+	LegalHoldOn  = "ON"
+	LegalHoldOff = "OFF"
+
+	// ObjectLockEnabled is the only valid state of the object lock of the
+	// bucket, which can not be disabled once enabled.
+	ObjectLockEnabled = "Enabled"
+
+	// BucketObjectLockHeader is the header of the create bucket request
+	// which enables the object lock of the bucket.
+	BucketObjectLockHeader = "X-Amz-Bucket-Object-Lock-Enabled"
+	// BypassGovernanceHeader is the header of the request which changes or
+	// removes the version retained in the governance mode.
+	BypassGovernanceHeader = "X-Amz-Bypass-Governance-Retention"
+
+	// MaxObjectLockSize is the maximum size of the request bodies of the
+	// object lock configuration, the retention and the legal hold.
+	MaxObjectLockSize = 64 * 1024
+
+	objectLockModeHeader        = "X-Amz-Object-Lock-Mode"
+	objectLockRetainUntilHeader = "X-Amz-Object-Lock-Retain-Until-Date"
+	objectLockLegalHoldHeader   = "X-Amz-Object-Lock-Legal-Hold"
+
+	// maxRetentionDays and maxRetentionYears are the maximum period of the
+	// default retention.
+	maxRetentionDays  = 36500
+	maxRetentionYears = 100
+)
+
+// ObjectLockConfiguration is the object lock of the bucket and the default
+// retention of the new objects in the bucket. It is the request body of
+// the put object lock configuration request and the response of the get
+// object lock configuration request.
+type ObjectLockConfiguration struct {
+	XMLName           xml.Name        `xml:"ObjectLockConfiguration"`
+	Xmlns             string          `xml:"xmlns,attr,omitempty"`
+	ObjectLockEnabled string          `xml:",omitempty"`
+	Rule              *ObjectLockRule `xml:",omitempty"`
+}
+
+// ObjectLockRule is the rule of the object lock of the bucket.
+type ObjectLockRule struct {
+	DefaultRetention DefaultRetention
+}
+
+// DefaultRetention is the retention applied to the new object which is not
+// given its own retention. Either the days or the years is given.
+type DefaultRetention struct {
+	Mode  string
+	Days  int `xml:",omitempty"`
+	Years int `xml:",omitempty"`
+}
+
+// Validate checks the configuration is well formed.
+func (c *ObjectLockConfiguration) Validate() ErrorCode {
+	if c.ObjectLockEnabled != ObjectLockEnabled {
+		return ErrMalformedXML
+	}
+
+	if c.Rule == nil {
+		return ErrNone
+	}
+	r := c.Rule.DefaultRetention
+	if !validRetentionMode(r.Mode) {
+		return ErrMalformedXML
+	}
+	if (r.Days == 0) == (r.Years == 0) {
+		return ErrMalformedXML
+	}
+	if r.Days < 0 || r.Days > maxRetentionDays || r.Years < 0 || r.Years > maxRetentionYears {
+		return ErrInvalidArgument
+	}
+	return ErrNone
+}
+
+// DefaultLock returns the retention of the object created at now by the
+// default retention. The mode is empty if there is no default retention.
+func (c *ObjectLockConfiguration) DefaultLock(now time.Time) ObjectLock {
+	if c.Rule == nil {
+		return ObjectLock{}
+	}
+
+	r := c.Rule.DefaultRetention
+	return ObjectLock{
+		Mode:        r.Mode,
+		RetainUntil: now.UTC().AddDate(r.Years, 0, r.Days),
+	}
+}
+
+// ObjectRetention is the retention of the version of the object. It is the
+// request body of the put object retention request and the response of the
+// get object retention request. The empty retention removes the retention.
+type ObjectRetention struct {
+	XMLName         xml.Name `xml:"Retention"`
+	Xmlns           string   `xml:"xmlns,attr,omitempty"`
+	Mode            string   `xml:",omitempty"`
+	RetainUntilDate string   `xml:",omitempty"`
+}
+
+// NewObjectRetention returns the retention of the lock.
+func NewObjectRetention(lock ObjectLock) ObjectRetention {
+	return ObjectRetention{
+		Xmlns:           Namespace,
+		Mode:            lock.Mode,
+		RetainUntilDate: FormatTime(lock.RetainUntil),
+	}
+}
+
+// Parse returns the mode and the time until which the version is retained,
+// which must be in the future from now. Both are empty if the retention is
+// removed.
+func (r *ObjectRetention) Parse(now time.Time) (string, time.Time, ErrorCode) {
+	if r.Mode == "" && r.RetainUntilDate == "" {
+		return "", time.Time{}, ErrNone
+	}
+	return parseRetention(r.Mode, r.RetainUntilDate, now, ErrMalformedXML)
+}
+
+// LegalHold is the legal hold of the version of the object. It is the
+// request body of the put object legal hold request and the response of
+// the get object legal hold request.
+type LegalHold struct {
+	XMLName xml.Name `xml:"LegalHold"`
+	Xmlns   string   `xml:"xmlns,attr,omitempty"`
+	Status  string
+}
+
+// NewLegalHold returns the legal hold of the status.
+func NewLegalHold(on bool) LegalHold {
+	h := LegalHold{Xmlns: Namespace, Status: LegalHoldOff}
+	if on {
+		h.Status = LegalHoldOn
+	}
+	return h
+}
+
+// On returns whether the legal hold is on. It fails if the status is
+// neither on nor off.
+func (h *LegalHold) On() (bool, ErrorCode) {
+	switch h.Status {
+	case LegalHoldOn:
+		return true, ErrNone
+	case LegalHoldOff:
+		return false, ErrNone
+	default:
+		return false, ErrMalformedXML
+	}
+}
+
+// ObjectLock is the retention and the legal hold of the version of the
+// object. The mode is empty if the version is not retained.
+type ObjectLock struct {
+	Mode        string
+	RetainUntil time.Time
+	LegalHold   bool
+}
+
+// Retained returns true if the retention of the version is not expired at
+// now.
+func (l ObjectLock) Retained(now time.Time) bool {
+	return l.Mode != "" && now.Before(l.RetainUntil)
+}
+
+// Protected returns true if the version can not be deleted or overwritten
+// at now. Only the governance mode can be bypassed, and never the legal
+// hold.
+func (l ObjectLock) Protected(bypassGovernance bool, now time.Time) bool {
+	if l.LegalHold {
+		return true
+	}
+	if !l.Retained(now) {
+		return false
+	}
+	return l.Mode == RetentionCompliance || !bypassGovernance
+}
+
+// AllowsRetention returns true if the retention of the version can be
+// replaced with the given one at now. The retention in effect can only be
+// extended or made stricter, unless it is in the governance mode and the
+// governance is bypassed.
+func (l ObjectLock) AllowsRetention(mode string, until time.Time, bypassGovernance bool, now time.Time) bool {
+	if !l.Retained(now) {
+		return true
+	}
+	if l.Mode == RetentionGovernance && bypassGovernance {
+		return true
+	}
+	if mode == "" || until.Before(l.RetainUntil) {
+		return false
+	}
+	return l.Mode == RetentionGovernance || mode == RetentionCompliance
+}
+
+// ParseObjectLockHeaders returns the lock of the object to be created,
+// which is given by the headers. It also returns whether any of the
+// headers is given.
+func ParseObjectLockHeaders(h http.Header, now time.Time) (ObjectLock, bool, ErrorCode) {
+	mode := h.Get(objectLockModeHeader)
+	until := h.Get(objectLockRetainUntilHeader)
+	hold := h.Get(objectLockLegalHoldHeader)
+	if mode == "" && until == "" && hold == "" {
+		return ObjectLock{}, false, ErrNone
+	}
+
+	lock := ObjectLock{}
+	switch hold {
+	case "", LegalHoldOff:
+	case LegalHoldOn:
+		lock.LegalHold = true
+	default:
+		return ObjectLock{}, true, ErrInvalidArgument
+	}
+
+	if mode != "" || until != "" {
+		var code ErrorCode
+		lock.Mode, lock.RetainUntil, code = parseRetention(mode, until, now, ErrInvalidArgument)
+		if code != ErrNone {
+			return ObjectLock{}, true, code
+		}
+	}
+	return lock, true, ErrNone
+}
+
+// SetObjectLockHeaders sets the response headers of the lock of the
+// version of the object.
+func SetObjectLockHeaders(h http.Header, lock ObjectLock) {
+	if lock.Mode != "" {
+		h.Set(objectLockModeHeader, lock.Mode)
+		h.Set(objectLockRetainUntilHeader, FormatTime(lock.RetainUntil))
+	}
+	if lock.LegalHold {
+		h.Set(objectLockLegalHoldHeader, LegalHoldOn)
+	}
+}
+
+// BypassGovernance returns true if the request asks to bypass the
+// governance mode retention.
+func BypassGovernance(h http.Header) bool {
+	return strings.EqualFold(h.Get(BypassGovernanceHeader), "true")
+}
+
+// parseRetention parses the mode and the date of the retention, which must
+// be given together. The date must be in the future from now. The code is
+// returned if either is malformed.
+func parseRetention(mode, date string, now time.Time, code ErrorCode) (string, time.Time, ErrorCode) {
+	if !validRetentionMode(mode) {
+		return "", time.Time{}, code
+	}
+	until, err := time.Parse(time.RFC3339, date)
+	if err != nil {
+		return "", time.Time{}, code
+	}
+	if !until.After(now) {
+		return "", time.Time{}, ErrInvalidArgument
+	}
+	return mode, until.UTC(), ErrNone
+}
+
+// validRetentionMode returns true if the mode is the valid retention mode.
+func validRetentionMode(mode string) bool {
+	return mode == RetentionGovernance || mode == RetentionCompliance
+}
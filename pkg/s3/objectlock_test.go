@@ -0,0 +1,131 @@
+package s3
+
+import (
+	"encoding/xml"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestObjectLockConfiguration(t *testing.T) {
+	now := time.Date(2020, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		body  string
+		code  ErrorCode
+		until time.Time
+	}{
+		{`<ObjectLockConfiguration><ObjectLockEnabled>Enabled</ObjectLockEnabled></ObjectLockConfiguration>`, ErrNone, time.Time{}},
+		{`<ObjectLockConfiguration><ObjectLockEnabled>Enabled</ObjectLockEnabled><Rule><DefaultRetention><Mode>GOVERNANCE</Mode><Days>30</Days></DefaultRetention></Rule></ObjectLockConfiguration>`, ErrNone, now.AddDate(0, 0, 30)},
+		{`<ObjectLockConfiguration><ObjectLockEnabled>Enabled</ObjectLockEnabled><Rule><DefaultRetention><Mode>COMPLIANCE</Mode><Years>7</Years></DefaultRetention></Rule></ObjectLockConfiguration>`, ErrNone, now.AddDate(7, 0, 0)},
+		// The object lock can not be disabled.
+		{`<ObjectLockConfiguration><ObjectLockEnabled>Disabled</ObjectLockEnabled></ObjectLockConfiguration>`, ErrMalformedXML, time.Time{}},
+		{`<ObjectLockConfiguration><ObjectLockEnabled>Enabled</ObjectLockEnabled><Rule><DefaultRetention><Mode>FOREVER</Mode><Days>1</Days></DefaultRetention></Rule></ObjectLockConfiguration>`, ErrMalformedXML, time.Time{}},
+		{`<ObjectLockConfiguration><ObjectLockEnabled>Enabled</ObjectLockEnabled><Rule><DefaultRetention><Mode>GOVERNANCE</Mode><Days>1</Days><Years>1</Years></DefaultRetention></Rule></ObjectLockConfiguration>`, ErrMalformedXML, time.Time{}},
+		{`<ObjectLockConfiguration><ObjectLockEnabled>Enabled</ObjectLockEnabled><Rule><DefaultRetention><Mode>GOVERNANCE</Mode></DefaultRetention></Rule></ObjectLockConfiguration>`, ErrMalformedXML, time.Time{}},
+		{`<ObjectLockConfiguration><ObjectLockEnabled>Enabled</ObjectLockEnabled><Rule><DefaultRetention><Mode>GOVERNANCE</Mode><Days>-1</Days></DefaultRetention></Rule></ObjectLockConfiguration>`, ErrInvalidArgument, time.Time{}},
+	}
+
+	for i, c := range testCases {
+		conf := ObjectLockConfiguration{}
+		if err := xml.Unmarshal([]byte(c.body), &conf); err != nil {
+			t.Fatalf("case %d: %v", i, err)
+		}
+		if code := conf.Validate(); code != c.code {
+			t.Errorf("case %d: expected code %d, got %d", i, c.code, code)
+			continue
+		}
+		if c.code != ErrNone {
+			continue
+		}
+		if lock := conf.DefaultLock(now); !lock.RetainUntil.Equal(c.until) {
+			t.Errorf("case %d: expected retention until %v, got %v", i, c.until, lock.RetainUntil)
+		}
+	}
+}
+
+func TestParseObjectLockHeaders(t *testing.T) {
+	now := time.Date(2020, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		header http.Header
+		lock   ObjectLock
+		given  bool
+		code   ErrorCode
+	}{
+		{http.Header{}, ObjectLock{}, false, ErrNone},
+		{http.Header{
+			"X-Amz-Object-Lock-Mode":              {"COMPLIANCE"},
+			"X-Amz-Object-Lock-Retain-Until-Date": {"2021-01-01T00:00:00.000Z"},
+		}, ObjectLock{Mode: RetentionCompliance, RetainUntil: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)}, true, ErrNone},
+		{http.Header{"X-Amz-Object-Lock-Legal-Hold": {"ON"}}, ObjectLock{LegalHold: true}, true, ErrNone},
+		// The mode without the date.
+		{http.Header{"X-Amz-Object-Lock-Mode": {"GOVERNANCE"}}, ObjectLock{}, true, ErrInvalidArgument},
+		// The date in the past.
+		{http.Header{
+			"X-Amz-Object-Lock-Mode":              {"GOVERNANCE"},
+			"X-Amz-Object-Lock-Retain-Until-Date": {"2019-01-01T00:00:00Z"},
+		}, ObjectLock{}, true, ErrInvalidArgument},
+		{http.Header{"X-Amz-Object-Lock-Legal-Hold": {"MAYBE"}}, ObjectLock{}, true, ErrInvalidArgument},
+	}
+
+	for i, c := range testCases {
+		lock, given, code := ParseObjectLockHeaders(c.header, now)
+		if code != c.code || given != c.given || lock != c.lock {
+			t.Errorf("case %d: expected %v %t %d, got %v %t %d", i, c.lock, c.given, c.code, lock, given, code)
+		}
+	}
+}
+
+func TestObjectLockProtected(t *testing.T) {
+	now := time.Date(2020, 3, 10, 12, 0, 0, 0, time.UTC)
+	later := now.Add(time.Hour)
+	earlier := now.Add(-time.Hour)
+
+	governance := ObjectLock{Mode: RetentionGovernance, RetainUntil: later}
+	compliance := ObjectLock{Mode: RetentionCompliance, RetainUntil: later}
+
+	testCases := []struct {
+		lock      ObjectLock
+		bypass    bool
+		protected bool
+	}{
+		{ObjectLock{}, false, false},
+		{governance, false, true},
+		{governance, true, false},
+		{compliance, true, true},
+		{ObjectLock{Mode: RetentionCompliance, RetainUntil: earlier}, false, false},
+		{ObjectLock{LegalHold: true}, true, true},
+	}
+	for i, c := range testCases {
+		if protected := c.lock.Protected(c.bypass, now); protected != c.protected {
+			t.Errorf("case %d: expected %t, got %t", i, c.protected, protected)
+		}
+	}
+
+	retentionCases := []struct {
+		lock    ObjectLock
+		mode    string
+		until   time.Time
+		bypass  bool
+		allowed bool
+	}{
+		// Extending is always allowed.
+		{governance, RetentionGovernance, later.Add(time.Hour), false, true},
+		{compliance, RetentionCompliance, later.Add(time.Hour), false, true},
+		{governance, RetentionCompliance, later, false, true},
+		// Shortening or removing the governance needs the bypass.
+		{governance, RetentionGovernance, now.Add(time.Minute), false, false},
+		{governance, "", time.Time{}, false, false},
+		{governance, "", time.Time{}, true, true},
+		// The compliance can not be weakened even with the bypass.
+		{compliance, RetentionCompliance, now.Add(time.Minute), true, false},
+		{compliance, RetentionGovernance, later.Add(time.Hour), true, false},
+		{compliance, "", time.Time{}, true, false},
+	}
+	for i, c := range retentionCases {
+		if allowed := c.lock.AllowsRetention(c.mode, c.until, c.bypass, now); allowed != c.allowed {
+			t.Errorf("retention case %d: expected %t, got %t", i, c.allowed, allowed)
+		}
+	}
+}
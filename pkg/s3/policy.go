@@ -0,0 +1,405 @@
+package s3
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"strings"
+)
+
+const (
+	// MaxPolicySize is the maximum size of the bucket policy document.
+	MaxPolicySize = 20 * 1024
+
+	// ResourcePrefix is the arn prefix of the buckets and the objects.
+	ResourcePrefix = "arn:aws:s3:::"
+)
+
+// The condition keys of the request which are supported.
+const (
+	// ConditionSourceIP is the ip address of the requester.
+	ConditionSourceIP = "aws:SourceIp"
+	// ConditionSecureTransport is "true" if the request is sent over tls.
+	ConditionSecureTransport = "aws:SecureTransport"
+	// ConditionPrefix is the prefix parameter of the list request.
+	ConditionPrefix = "s3:prefix"
+
+	// ConditionExistingObjectTag is followed by the tag key, and is the
+	// value of the tag of the object which the request accesses.
+	ConditionExistingObjectTag = "s3:ExistingObjectTag/"
+	// ConditionRequestObjectTag is followed by the tag key, and is the
+	// value of the tag which the request puts on the object.
+	ConditionRequestObjectTag = "s3:RequestObjectTag/"
+)
+
+// policyVersions is the versions of the policy language which are valid.
+var policyVersions = map[string]bool{
+	"2012-10-17": true,
+	"2008-10-17": true,
+}
+
+// conditionKeys is the condition keys which are supported.
+var conditionKeys = []string{
+	ConditionSourceIP,
+	ConditionSecureTransport,
+	ConditionPrefix,
+}
+
+// conditionKeyPrefixes is the prefixes of the condition keys which are
+// followed by the tag key.
+var conditionKeyPrefixes = []string{
+	ConditionExistingObjectTag,
+	ConditionRequestObjectTag,
+}
+
+// conditionOperator matches the value of the condition key of the request
+// with the values in the policy.
+type conditionOperator struct {
+	match func(pattern, value string) bool
+	// negated is true if the operator holds when no value matches,
+	// including when the request does not have the key.
+	negated bool
+	// validate checks the value in the policy, which is nil if any
+	// value is valid.
+	validate func(pattern string) bool
+}
+
+// conditionOperators is the condition operators which are supported.
+var conditionOperators = map[string]conditionOperator{
+	"StringEquals":    {match: stringEquals},
+	"StringNotEquals": {match: stringEquals, negated: true},
+	"StringLike":      {match: wildcardMatch},
+	"StringNotLike":   {match: wildcardMatch, negated: true},
+	"IpAddress":       {match: ipMatch, validate: validCIDR},
+	"NotIpAddress":    {match: ipMatch, negated: true, validate: validCIDR},
+	"Bool":            {match: strings.EqualFold, validate: validBool},
+}
+
+// PolicyEffect is the result of evaluating the bucket policy.
+type PolicyEffect int
+
+const (
+	// PolicyNoMatch means no statement applies to the request, so the
+	// access is decided by the owner and the acls.
+	PolicyNoMatch PolicyEffect = iota
+	// PolicyAllow means the request is allowed explicitly.
+	PolicyAllow
+	// PolicyDeny means the request is denied explicitly, which overrides
+	// any grant.
+	PolicyDeny
+)
+
+// Policy is the bucket policy document in json.
+type Policy struct {
+	Version   string
+	ID        string `json:"Id,omitempty"`
+	Statement []PolicyStatement
+}
+
+// PolicyStatement allows or denies the actions of the principal on the
+// resources when all the conditions hold.
+type PolicyStatement struct {
+	Sid       string `json:",omitempty"`
+	Effect    string
+	Principal PolicyPrincipal
+	Action    PolicyValues
+	Resource  PolicyValues
+	// Condition is the values of the condition keys by the operator.
+	Condition map[string]map[string]PolicyValues `json:",omitempty"`
+}
+
+// PolicyValues is the list of values, which is written as either a single
+// string or an array in json.
+type PolicyValues []string
+
+// UnmarshalJSON decodes either a single string or an array of strings.
+func (v *PolicyValues) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*v = PolicyValues{s}
+		return nil
+	}
+
+	var l []string
+	if err := json.Unmarshal(b, &l); err != nil {
+		return err
+	}
+	*v = l
+	return nil
+}
+
+// PolicyPrincipal is the users whom the statement applies to. The users
+// are identified by their access keys, and the wildcard is everyone
+// including the anonymous.
+type PolicyPrincipal struct {
+	AWS PolicyValues
+}
+
+// UnmarshalJSON decodes either the wildcard or the object of the users.
+func (p *PolicyPrincipal) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		p.AWS = PolicyValues{s}
+		return nil
+	}
+
+	type principal PolicyPrincipal
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.DisallowUnknownFields()
+	return d.Decode((*principal)(p))
+}
+
+// PolicyRequest is the request which the policy is evaluated against.
+type PolicyRequest struct {
+	// Principal is the access key of the requester, which is empty for
+	// the anonymous.
+	Principal string
+	Action    string
+	Resource  string
+	// Conditions is the values of the condition keys of the request. The
+	// key which the request does not have is absent.
+	Conditions map[string]string
+}
+
+// ParsePolicy decodes the policy document of the bucket and checks it is
+// valid and supported. The unknown elements are rejected, since ignoring
+// such as NotPrincipal would grant more than the owner intended.
+func ParsePolicy(doc []byte, bucket string) (*Policy, ErrorCode) {
+	p := &Policy{}
+	d := json.NewDecoder(bytes.NewReader(doc))
+	d.DisallowUnknownFields()
+	if err := d.Decode(p); err != nil {
+		return nil, ErrMalformedPolicy
+	}
+
+	if !policyVersions[p.Version] || len(p.Statement) == 0 {
+		return nil, ErrMalformedPolicy
+	}
+	for i := range p.Statement {
+		if !p.Statement[i].valid(bucket) {
+			return nil, ErrMalformedPolicy
+		}
+	}
+
+	return p, ErrNone
+}
+
+// valid returns true if the statement is well formed and its resources are
+// in the bucket.
+func (s *PolicyStatement) valid(bucket string) bool {
+	if s.Effect != "Allow" && s.Effect != "Deny" {
+		return false
+	}
+	if len(s.Principal.AWS) == 0 || len(s.Action) == 0 || len(s.Resource) == 0 {
+		return false
+	}
+
+	for _, a := range s.Action {
+		if a != "*" && !strings.HasPrefix(strings.ToLower(a), "s3:") {
+			return false
+		}
+	}
+	for _, r := range s.Resource {
+		if r != ResourcePrefix+bucket && !strings.HasPrefix(r, ResourcePrefix+bucket+"/") {
+			return false
+		}
+	}
+
+	for name, conds := range s.Condition {
+		op, ok := conditionOperators[name]
+		if !ok {
+			return false
+		}
+		for key, values := range conds {
+			if !supportedConditionKey(key) || len(values) == 0 {
+				return false
+			}
+			for _, v := range values {
+				if op.validate != nil && !op.validate(v) {
+					return false
+				}
+			}
+		}
+	}
+
+	return true
+}
+
+// Evaluate returns the effect of the policy on the request. The deny of
+// any statement overrides the allows of the others.
+func (p *Policy) Evaluate(r *PolicyRequest) PolicyEffect {
+	effect := PolicyNoMatch
+	for i := range p.Statement {
+		s := &p.Statement[i]
+		if !s.applies(r) {
+			continue
+		}
+		if s.Effect == "Deny" {
+			return PolicyDeny
+		}
+		effect = PolicyAllow
+	}
+	return effect
+}
+
+// applies returns true if the statement applies to the request.
+func (s *PolicyStatement) applies(r *PolicyRequest) bool {
+	principal := false
+	for _, p := range s.Principal.AWS {
+		if p == "*" || (r.Principal != "" && p == r.Principal) {
+			principal = true
+			break
+		}
+	}
+	if !principal {
+		return false
+	}
+
+	// The actions are case insensitive, but the resources are not.
+	if !matchAny(s.Action, r.Action, strings.ToLower) || !matchAny(s.Resource, r.Resource, nil) {
+		return false
+	}
+
+	for name, conds := range s.Condition {
+		op := conditionOperators[name]
+		for key, values := range conds {
+			if !op.holds(values, r.Conditions, key) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// matchAny returns true if the value matches any of the patterns after
+// both are normalized by the norm if it is not nil.
+func matchAny(patterns []string, value string, norm func(string) string) bool {
+	if norm != nil {
+		value = norm(value)
+	}
+	for _, p := range patterns {
+		if norm != nil {
+			p = norm(p)
+		}
+		if wildcardMatch(p, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// holds returns true if the condition of the key holds for the request.
+func (op conditionOperator) holds(values PolicyValues, conditions map[string]string, key string) bool {
+	value, ok := conditionValue(conditions, key)
+	if !ok {
+		return op.negated
+	}
+
+	for _, v := range values {
+		if op.match(v, value) {
+			return !op.negated
+		}
+	}
+	return op.negated
+}
+
+// conditionValue returns the value of the condition key of the request.
+func conditionValue(conditions map[string]string, key string) (string, bool) {
+	for k, v := range conditions {
+		if conditionKeyEqual(k, key) {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// conditionKeyEqual returns true if the condition keys are the same. The
+// condition keys are case insensitive, but the tag keys which follow the
+// prefixes are not.
+func conditionKeyEqual(a, b string) bool {
+	for _, p := range conditionKeyPrefixes {
+		if hasPrefixFold(a, p) && hasPrefixFold(b, p) {
+			return a[len(p):] == b[len(p):]
+		}
+	}
+	return strings.EqualFold(a, b)
+}
+
+// supportedConditionKey returns true if the condition key is supported.
+func supportedConditionKey(key string) bool {
+	for _, k := range conditionKeys {
+		if strings.EqualFold(k, key) {
+			return true
+		}
+	}
+	for _, p := range conditionKeyPrefixes {
+		if hasPrefixFold(key, p) && len(key) > len(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasPrefixFold returns true if the string begins with the prefix under
+// the case folding.
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
+
+// stringEquals returns true if the strings are the same.
+func stringEquals(pattern, value string) bool {
+	return pattern == value
+}
+
+// wildcardMatch returns true if the value matches the pattern, where '*'
+// matches any sequence of characters and '?' matches any one character.
+func wildcardMatch(pattern, value string) bool {
+	// The position to retry when the last '*' has to match more.
+	star, retry := -1, 0
+	p, v := 0, 0
+	for v < len(value) {
+		switch {
+		case p < len(pattern) && pattern[p] == '*':
+			star, retry = p, v
+			p++
+		case p < len(pattern) && (pattern[p] == '?' || pattern[p] == value[v]):
+			p++
+			v++
+		case star >= 0:
+			retry++
+			p, v = star+1, retry
+		default:
+			return false
+		}
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
+
+// ipMatch returns true if the ip address is in the range of the cidr, or
+// is the same with the address if the pattern is not a cidr.
+func ipMatch(pattern, value string) bool {
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return false
+	}
+	if _, n, err := net.ParseCIDR(pattern); err == nil {
+		return n.Contains(ip)
+	}
+	return ip.Equal(net.ParseIP(pattern))
+}
+
+// validCIDR returns true if the pattern is an ip address or a cidr.
+func validCIDR(pattern string) bool {
+	if _, _, err := net.ParseCIDR(pattern); err == nil {
+		return true
+	}
+	return net.ParseIP(pattern) != nil
+}
+
+// validBool returns true if the pattern is a boolean.
+func validBool(pattern string) bool {
+	return strings.EqualFold(pattern, "true") || strings.EqualFold(pattern, "false")
+}
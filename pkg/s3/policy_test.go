@@ -0,0 +1,162 @@
+package s3
+
+import "testing"
+
+func TestParsePolicy(t *testing.T) {
+	testCases := []struct {
+		doc  string
+		code ErrorCode
+	}{
+		{`{"Version": "2012-10-17", "Statement": [{"Effect": "Allow", "Principal": "*", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::bucket/*"}]}`, ErrNone},
+		{`{"Version": "2012-10-17", "Statement": [{"Sid": "a", "Effect": "Deny", "Principal": {"AWS": ["ak1", "ak2"]}, "Action": ["s3:*"], "Resource": ["arn:aws:s3:::bucket", "arn:aws:s3:::bucket/*"],
+			"Condition": {"NotIpAddress": {"aws:SourceIp": "10.0.0.0/8"}, "Bool": {"aws:SecureTransport": "false"}}}]}`, ErrNone},
+		{`{"Version": "2012-10-17", "Statement": [{"Effect": "Allow", "Principal": "*", "Action": "s3:ListBucket", "Resource": "arn:aws:s3:::bucket",
+			"Condition": {"StringLike": {"s3:prefix": ["home/*"]}}}]}`, ErrNone},
+		{`{"Version": "2012-10-17", "Statement": [{"Effect": "Allow", "Principal": "*", "Action": "s3:PutObject", "Resource": "arn:aws:s3:::bucket/*",
+			"Condition": {"StringEquals": {"s3:RequestObjectTag/class": "public"}}}]}`, ErrNone},
+		// Not json.
+		{`Version: 2012-10-17`, ErrMalformedPolicy},
+		// Invalid version.
+		{`{"Version": "2020-01-01", "Statement": [{"Effect": "Allow", "Principal": "*", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::bucket/*"}]}`, ErrMalformedPolicy},
+		// No statement.
+		{`{"Version": "2012-10-17", "Statement": []}`, ErrMalformedPolicy},
+		// Invalid effect.
+		{`{"Version": "2012-10-17", "Statement": [{"Effect": "Maybe", "Principal": "*", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::bucket/*"}]}`, ErrMalformedPolicy},
+		// Missing principal.
+		{`{"Version": "2012-10-17", "Statement": [{"Effect": "Allow", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::bucket/*"}]}`, ErrMalformedPolicy},
+		// Unsupported element.
+		{`{"Version": "2012-10-17", "Statement": [{"Effect": "Allow", "NotPrincipal": "*", "Principal": "*", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::bucket/*"}]}`, ErrMalformedPolicy},
+		// Unsupported principal.
+		{`{"Version": "2012-10-17", "Statement": [{"Effect": "Allow", "Principal": {"Service": "s3"}, "Action": "s3:GetObject", "Resource": "arn:aws:s3:::bucket/*"}]}`, ErrMalformedPolicy},
+		// Not an s3 action.
+		{`{"Version": "2012-10-17", "Statement": [{"Effect": "Allow", "Principal": "*", "Action": "iam:GetUser", "Resource": "arn:aws:s3:::bucket/*"}]}`, ErrMalformedPolicy},
+		// Resource of the other bucket.
+		{`{"Version": "2012-10-17", "Statement": [{"Effect": "Allow", "Principal": "*", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::bucket2/*"}]}`, ErrMalformedPolicy},
+		// Unsupported condition operator and key.
+		{`{"Version": "2012-10-17", "Statement": [{"Effect": "Allow", "Principal": "*", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::bucket/*",
+			"Condition": {"DateGreaterThan": {"aws:CurrentTime": "2020-01-01T00:00:00Z"}}}]}`, ErrMalformedPolicy},
+		{`{"Version": "2012-10-17", "Statement": [{"Effect": "Allow", "Principal": "*", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::bucket/*",
+			"Condition": {"StringEquals": {"aws:username": "a"}}}]}`, ErrMalformedPolicy},
+		// The tag condition without the tag key.
+		{`{"Version": "2012-10-17", "Statement": [{"Effect": "Allow", "Principal": "*", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::bucket/*",
+			"Condition": {"StringEquals": {"s3:ExistingObjectTag/": "a"}}}]}`, ErrMalformedPolicy},
+		// Invalid ip address.
+		{`{"Version": "2012-10-17", "Statement": [{"Effect": "Allow", "Principal": "*", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::bucket/*",
+			"Condition": {"IpAddress": {"aws:SourceIp": "10.0.0/8"}}}]}`, ErrMalformedPolicy},
+	}
+
+	for i, c := range testCases {
+		if _, code := ParsePolicy([]byte(c.doc), "bucket"); code != c.code {
+			t.Errorf("case %d: expected %d, got %d", i, c.code, code)
+		}
+	}
+}
+
+func TestPolicyEvaluate(t *testing.T) {
+	doc := `{
+		"Version": "2012-10-17",
+		"Statement": [
+			{"Effect": "Allow", "Principal": "*", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::bucket/public/*"},
+			{"Effect": "Allow", "Principal": {"AWS": "partner"}, "Action": ["s3:Get*", "s3:ListBucket"], "Resource": ["arn:aws:s3:::bucket", "arn:aws:s3:::bucket/shared/*"],
+				"Condition": {"IpAddress": {"aws:SourceIp": ["10.0.0.0/8", "192.168.1.1"]}}},
+			{"Effect": "Deny", "Principal": "*", "Action": "s3:*", "Resource": "arn:aws:s3:::bucket/*",
+				"Condition": {"Bool": {"aws:SecureTransport": "false"}}},
+			{"Effect": "Deny", "Principal": {"AWS": "partner"}, "Action": "s3:ListBucket", "Resource": "arn:aws:s3:::bucket",
+				"Condition": {"StringNotLike": {"s3:prefix": "shared/*"}}}
+		]
+	}`
+	p, code := ParsePolicy([]byte(doc), "bucket")
+	if code != ErrNone {
+		t.Fatalf("failed to parse policy: %d", code)
+	}
+
+	secure := func(ip string) map[string]string {
+		return map[string]string{ConditionSourceIP: ip, ConditionSecureTransport: "true"}
+	}
+	testCases := []struct {
+		req    PolicyRequest
+		effect PolicyEffect
+	}{
+		// The anonymous can read the public objects only.
+		{PolicyRequest{"", "s3:GetObject", ResourcePrefix + "bucket/public/a", secure("1.2.3.4")}, PolicyAllow},
+		{PolicyRequest{"", "s3:GetObject", ResourcePrefix + "bucket/private/a", secure("1.2.3.4")}, PolicyNoMatch},
+		{PolicyRequest{"", "s3:PutObject", ResourcePrefix + "bucket/public/a", secure("1.2.3.4")}, PolicyNoMatch},
+		// The action is case insensitive and matches the wildcard.
+		{PolicyRequest{"partner", "s3:getobjectacl", ResourcePrefix + "bucket/shared/a", secure("10.1.2.3")}, PolicyAllow},
+		// The source ip out of the range.
+		{PolicyRequest{"partner", "s3:GetObject", ResourcePrefix + "bucket/shared/a", secure("11.1.2.3")}, PolicyNoMatch},
+		{PolicyRequest{"partner", "s3:GetObject", ResourcePrefix + "bucket/shared/a", secure("192.168.1.1")}, PolicyAllow},
+		// The deny overrides the allow.
+		{PolicyRequest{"", "s3:GetObject", ResourcePrefix + "bucket/public/a", map[string]string{ConditionSecureTransport: "false"}}, PolicyDeny},
+		// The negated condition holds if the key is absent.
+		{PolicyRequest{"partner", "s3:ListBucket", ResourcePrefix + "bucket", secure("10.1.2.3")}, PolicyDeny},
+		{PolicyRequest{"partner", "s3:ListBucket", ResourcePrefix + "bucket", map[string]string{ConditionSourceIP: "10.1.2.3", ConditionPrefix: "shared/x"}}, PolicyAllow},
+		{PolicyRequest{"partner", "s3:ListBucket", ResourcePrefix + "bucket", map[string]string{ConditionSourceIP: "10.1.2.3", ConditionPrefix: "other/"}}, PolicyDeny},
+	}
+
+	for i, c := range testCases {
+		if effect := p.Evaluate(&c.req); effect != c.effect {
+			t.Errorf("case %d: expected %d, got %d", i, c.effect, effect)
+		}
+	}
+}
+
+func TestPolicyTagConditions(t *testing.T) {
+	doc := `{
+		"Version": "2012-10-17",
+		"Statement": [
+			{"Effect": "Allow", "Principal": "*", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::bucket/*",
+				"Condition": {"StringEquals": {"s3:existingobjecttag/Class": "public"}}},
+			{"Effect": "Deny", "Principal": "*", "Action": "s3:PutObject", "Resource": "arn:aws:s3:::bucket/*",
+				"Condition": {"StringNotEquals": {"s3:RequestObjectTag/Retention": ["1y", "5y"]}}}
+		]
+	}`
+	p, code := ParsePolicy([]byte(doc), "bucket")
+	if code != ErrNone {
+		t.Fatalf("failed to parse policy: %d", code)
+	}
+
+	testCases := []struct {
+		action     string
+		conditions map[string]string
+		effect     PolicyEffect
+	}{
+		// The prefix is case insensitive, but the tag key is not.
+		{"s3:GetObject", map[string]string{ConditionExistingObjectTag + "Class": "public"}, PolicyAllow},
+		{"s3:GetObject", map[string]string{ConditionExistingObjectTag + "class": "public"}, PolicyNoMatch},
+		{"s3:GetObject", map[string]string{ConditionExistingObjectTag + "Class": "private"}, PolicyNoMatch},
+		{"s3:GetObject", map[string]string{}, PolicyNoMatch},
+		// The object to be put must have the retention tag.
+		{"s3:PutObject", map[string]string{ConditionRequestObjectTag + "Retention": "5y"}, PolicyNoMatch},
+		{"s3:PutObject", map[string]string{ConditionRequestObjectTag + "Retention": "forever"}, PolicyDeny},
+		{"s3:PutObject", map[string]string{}, PolicyDeny},
+	}
+
+	for i, c := range testCases {
+		req := PolicyRequest{"ak", c.action, ResourcePrefix + "bucket/a", c.conditions}
+		if effect := p.Evaluate(&req); effect != c.effect {
+			t.Errorf("case %d: expected %d, got %d", i, c.effect, effect)
+		}
+	}
+}
+
+func TestWildcardMatch(t *testing.T) {
+	testCases := []struct {
+		pattern, value string
+		match          bool
+	}{
+		{"*", "", true},
+		{"a*c", "abbbc", true},
+		{"a*c", "abbbd", false},
+		{"a?c", "abc", true},
+		{"a?c", "ac", false},
+		{"*b*b", "abcbdb", true},
+		{"a*", "b", false},
+	}
+
+	for _, c := range testCases {
+		if match := wildcardMatch(c.pattern, c.value); match != c.match {
+			t.Errorf("%q %q: expected %v, got %v", c.pattern, c.value, c.match, match)
+		}
+	}
+}
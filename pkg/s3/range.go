@@ -0,0 +1,80 @@
+package s3
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Range is a satisfiable byte range of the object.
+type Range struct {
+	Start  int64
+	Length int64
+}
+
+// ContentRange returns the value of Content-Range header for the range.
+func (r Range) ContentRange(size int64) string {
+	return fmt.Sprintf("bytes %d-%d/%d", r.Start, r.Start+r.Length-1, size)
+}
+
+// ParseRange parses the http Range header value with the given object size.
+// It returns nil if the header is empty or syntactically invalid, which means
+// the range should be ignored and the whole object should be sent.
+// If none of the ranges are satisfiable, it returns ErrInvalidRange.
+// See https://tools.ietf.org/html/rfc7233#section-2.1
+func ParseRange(header string, size int64) ([]Range, ErrorCode) {
+	const prefix = "bytes="
+	if !strings.HasPrefix(header, prefix) {
+		return nil, ErrNone
+	}
+
+	var ranges []Range
+	for _, spec := range strings.Split(header[len(prefix):], ",") {
+		spec = strings.TrimSpace(spec)
+		dash := strings.Index(spec, "-")
+		if dash < 0 {
+			return nil, ErrNone
+		}
+		first, last := spec[:dash], spec[dash+1:]
+
+		// Suffix byte range: the last n bytes of the object.
+		if first == "" {
+			n, err := strconv.ParseInt(last, 10, 64)
+			if err != nil || n < 0 {
+				return nil, ErrNone
+			}
+			if n == 0 || size == 0 {
+				continue
+			}
+			if n > size {
+				n = size
+			}
+			ranges = append(ranges, Range{Start: size - n, Length: n})
+			continue
+		}
+
+		start, err := strconv.ParseInt(first, 10, 64)
+		if err != nil || start < 0 {
+			return nil, ErrNone
+		}
+		end := size - 1
+		if last != "" {
+			end, err = strconv.ParseInt(last, 10, 64)
+			if err != nil || end < start {
+				return nil, ErrNone
+			}
+			if end >= size {
+				end = size - 1
+			}
+		}
+		if start >= size {
+			continue
+		}
+		ranges = append(ranges, Range{Start: start, Length: end - start + 1})
+	}
+
+	if len(ranges) == 0 {
+		return nil, ErrInvalidRange
+	}
+	return ranges, ErrNone
+}
@@ -0,0 +1,47 @@
+package s3
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseRange(t *testing.T) {
+	testCases := []struct {
+		header string
+		size   int64
+		ranges []Range
+		err    ErrorCode
+	}{
+		{"", 100, nil, ErrNone},
+		{"bytes=0-9", 100, []Range{{0, 10}}, ErrNone},
+		{"bytes=90-", 100, []Range{{90, 10}}, ErrNone},
+		{"bytes=-10", 100, []Range{{90, 10}}, ErrNone},
+		{"bytes=-200", 100, []Range{{0, 100}}, ErrNone},
+		{"bytes=50-200", 100, []Range{{50, 50}}, ErrNone},
+		{"bytes=0-0, 10-19", 100, []Range{{0, 1}, {10, 10}}, ErrNone},
+		{"bytes=0-9,200-300", 100, []Range{{0, 10}}, ErrNone},
+		{"bytes=100-", 100, nil, ErrInvalidRange},
+		{"bytes=-0", 100, nil, ErrInvalidRange},
+		{"bytes=0-", 0, nil, ErrInvalidRange},
+		{"bytes=9-0", 100, nil, ErrNone},
+		{"bytes=a-b", 100, nil, ErrNone},
+		{"bytes=10", 100, nil, ErrNone},
+		{"items=0-9", 100, nil, ErrNone},
+	}
+
+	for _, c := range testCases {
+		ranges, err := ParseRange(c.header, c.size)
+		if err != c.err {
+			t.Errorf("%q: expected error %d, got %d", c.header, c.err, err)
+		}
+		if !reflect.DeepEqual(ranges, c.ranges) {
+			t.Errorf("%q: expected ranges %v, got %v", c.header, c.ranges, ranges)
+		}
+	}
+}
+
+func TestContentRange(t *testing.T) {
+	if got := (Range{Start: 10, Length: 10}).ContentRange(100); got != "bytes 10-19/100" {
+		t.Errorf("expected bytes 10-19/100, got %s", got)
+	}
+}
@@ -2,10 +2,14 @@ package s3
 
 import (
 	"crypto/hmac"
+	"crypto/sha1"
 	"crypto/sha256"
+	"encoding/base64"
 	"encoding/hex"
 	"net/http"
+	"net/url"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -18,6 +22,16 @@ const (
 	// Identifies signature version 4.
 	v4Identifier = "AWS4-HMAC-SHA256"
 	V4           = 4
+
+	// AmzDateFormat is the format of the X-Amz-Date.
+	AmzDateFormat = "20060102T150405Z"
+
+	// UnsignedPayload is the payload hash of the presigned url, which does
+	// not sign the payload.
+	UnsignedPayload = "UNSIGNED-PAYLOAD"
+
+	// maxPresignExpires is the longest expiry of the presigned url.
+	maxPresignExpires = 7 * 24 * time.Hour
 )
 
 // ValidateSignVersion checks the request provides valid sign version.
@@ -83,6 +97,25 @@ func (c *CredV4) Scope() string {
 	}, "/")
 }
 
+// Matches returns true if the credential scope is for the date of the
+// request and the given region. Any region is allowed if it is empty.
+func (c *CredV4) Matches(date time.Time, region string) bool {
+	if c.Date != date.UTC().Format("20060102") {
+		return false
+	}
+	return region == "" || c.Region == region
+}
+
+// Skewed returns true if the request time is out of the skew window around
+// the server clock.
+func Skewed(requestTime, now time.Time, skew time.Duration) bool {
+	d := now.Sub(requestTime)
+	if d < 0 {
+		d = -d
+	}
+	return d > skew
+}
+
 func parseCredV4(credString string) (c CredV4, err ErrorCode) {
 	// credString = accessKey + "/" + date + "/" + region + "/" service + "/" + "aws4_request"
 	args := strings.Split(credString, "/")
@@ -149,11 +182,73 @@ func ParseSignV4(authString string) (*SignV4, ErrorCode) {
 	}, ErrNone
 }
 
+// PresignV4 contains the fields of the url which is presigned with v4.
+// https://docs.aws.amazon.com/AmazonS3/latest/API/sigv4-query-string-auth.html
+type PresignV4 struct {
+	SignV4
+	Date    time.Time
+	Expires time.Duration
+}
+
+// Expired returns true if the presigned url is expired at the given time.
+func (p *PresignV4) Expired(now time.Time) bool {
+	return now.After(p.Date.Add(p.Expires))
+}
+
+// IsPresignedV4 returns true if the query carries the v4 presign.
+func IsPresignedV4(q url.Values) bool {
+	return q.Get("X-Amz-Algorithm") != ""
+}
+
+// ParsePresignV4 parses the v4 presign in the query of the url.
+func ParsePresignV4(q url.Values) (*PresignV4, ErrorCode) {
+	if q.Get("X-Amz-Algorithm") != v4Identifier {
+		return nil, ErrInvalidRequestSignVersion
+	}
+
+	cred, err := parseCredV4(q.Get("X-Amz-Credential"))
+	if err != ErrNone {
+		return nil, ErrAuthorizationQueryParametersError
+	}
+
+	date, e := time.Parse(AmzDateFormat, q.Get("X-Amz-Date"))
+	if e != nil {
+		return nil, ErrAuthorizationQueryParametersError
+	}
+
+	expires, e := strconv.Atoi(q.Get("X-Amz-Expires"))
+	if e != nil || expires < 0 || time.Duration(expires)*time.Second > maxPresignExpires {
+		return nil, ErrAuthorizationQueryParametersError
+	}
+
+	signedHeaders, signature := q.Get("X-Amz-SignedHeaders"), q.Get("X-Amz-Signature")
+	if signedHeaders == "" || signature == "" {
+		return nil, ErrAuthorizationQueryParametersError
+	}
+
+	return &PresignV4{
+		SignV4: SignV4{
+			Credential:    cred,
+			SignedHeaders: parseSignedHeaderV4(signedHeaders),
+			Signature:     signature,
+		},
+		Date:    date,
+		Expires: time.Duration(expires) * time.Second,
+	}, ErrNone
+}
+
+// ParseSignV2 parses the v2 authorization string.
 func ParseSignV2(authString string) (*SignV2, ErrorCode) {
+	// authString = "AWS" + " " + AWSAccessKeyId + ":" + Signature
+	if !strings.HasPrefix(authString, v2Identifier+" ") {
+		return nil, ErrMissingSecurityHeader
+	}
 	authString = strings.TrimPrefix(authString, v2Identifier)
-	authString = strings.TrimSuffix(authString, "=")
-	fields := strings.Split(strings.TrimSpace(authString), ":")
-	if len(fields) < 2 {
+
+	// The signature is base64 encoded and may end with padding, so split
+	// only at the first colon and keep the rest as it is.
+	fields := strings.SplitN(strings.TrimSpace(authString), ":", 2)
+	if len(fields) < 2 || fields[0] == "" || fields[1] == "" {
 		return nil, ErrMissingSecurityHeader
 	}
 
@@ -165,6 +260,118 @@ func ParseSignV2(authString string) (*SignV2, ErrorCode) {
 	}, ErrNone
 }
 
+// subresourcesV2 are the query parameters which are included in the
+// canonicalized resource of signature v2.
+var subresourcesV2 = map[string]bool{
+	"acl":                          true,
+	"cors":                         true,
+	"delete":                       true,
+	"lifecycle":                    true,
+	"location":                     true,
+	"logging":                      true,
+	"notification":                 true,
+	"partNumber":                   true,
+	"policy":                       true,
+	"requestPayment":               true,
+	"tagging":                      true,
+	"torrent":                      true,
+	"uploadId":                     true,
+	"uploads":                      true,
+	"versionId":                    true,
+	"versioning":                   true,
+	"versions":                     true,
+	"website":                      true,
+	"response-cache-control":       true,
+	"response-content-disposition": true,
+	"response-content-encoding":    true,
+	"response-content-language":    true,
+	"response-content-type":        true,
+	"response-expires":             true,
+}
+
+// GenStringToSignV2 generates string to sign of signature v2. The resource
+// is the path of the request including the bucket name.
+// https://docs.aws.amazon.com/AmazonS3/latest/dev/RESTAuthentication.html
+func GenStringToSignV2(r *http.Request, resource string) string {
+	// The date is given by the x-amz-date header which is included in the
+	// canonicalized amz headers if any.
+	date := r.Header.Get("Date")
+	if r.Header.Get("X-Amz-Date") != "" {
+		date = ""
+	}
+
+	return r.Method + "\n" +
+		r.Header.Get("Content-MD5") + "\n" +
+		r.Header.Get("Content-Type") + "\n" +
+		date + "\n" +
+		GenCanonicalizedAmzHeadersV2(r.Header) +
+		GenCanonicalizedResourceV2(resource, r.URL.Query())
+}
+
+// GenCanonicalizedAmzHeadersV2 generates canonicalized amz headers of
+// signature v2. Each x-amz- header is lowercased and sorted by its name,
+// and multiple values of the same header are joined with a comma.
+func GenCanonicalizedAmzHeadersV2(h http.Header) string {
+	names := make([]string, 0)
+	values := make(map[string]string)
+	for k, v := range h {
+		name := strings.ToLower(k)
+		if !strings.HasPrefix(name, "x-amz-") {
+			continue
+		}
+
+		trimmed := make([]string, len(v))
+		for i := range v {
+			trimmed[i] = strings.Join(strings.Fields(v[i]), " ")
+		}
+
+		if _, ok := values[name]; ok {
+			values[name] += "," + strings.Join(trimmed, ",")
+			continue
+		}
+		names = append(names, name)
+		values[name] = strings.Join(trimmed, ",")
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders string
+	for _, name := range names {
+		canonicalHeaders += name + ":" + values[name] + "\n"
+	}
+
+	return canonicalHeaders
+}
+
+// GenCanonicalizedResourceV2 generates canonicalized resource of signature
+// v2. The subresources in the query are appended in order of their names.
+func GenCanonicalizedResourceV2(resource string, query url.Values) string {
+	keys := make([]string, 0)
+	for k := range query {
+		if subresourcesV2[k] {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		return resource
+	}
+	sort.Strings(keys)
+
+	for i, k := range keys {
+		if v := query.Get(k); v != "" {
+			keys[i] = k + "=" + v
+		}
+	}
+
+	return resource + "?" + strings.Join(keys, "&")
+}
+
+// GenSignatureV2 generates signature of signature v2.
+func GenSignatureV2(secretKey, stringToSign string) string {
+	mac := hmac.New(sha1.New, []byte(secretKey))
+	mac.Write([]byte(stringToSign))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
 // GenCanonicalHeaders generates canonical headers from the http request.
 func GenCanonicalHeaders(r *http.Request, signedHeaders []string) string {
 	sort.Strings(signedHeaders)
@@ -202,6 +409,33 @@ func GenSignedHeadersString(signedHeaders []string) string {
 	return strings.Join(signedHeaders, ";")
 }
 
+// GenCanonicalQueryString generates canonical query string. The names and
+// values are uri encoded and sorted by the name and then the value.
+func GenCanonicalQueryString(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	params := make([]string, 0, len(q))
+	for _, k := range keys {
+		values := append([]string(nil), q[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			params = append(params, uriEncode(k)+"="+uriEncode(v))
+		}
+	}
+
+	return strings.Join(params, "&")
+}
+
+// uriEncode encodes the string as the signature v4 requires, which differs
+// from the query escape of url package in that space is encoded as %20.
+func uriEncode(s string) string {
+	return strings.Replace(url.QueryEscape(s), "+", "%20", -1)
+}
+
 // GenCanonicalRequest generates canonical request.
 // https://docs.aws.amazon.com/ko_kr/general/latest/gr/sigv4-create-canonical-request.html
 func GenCanonicalRequest(httpRequestMethod, uri, queryString, headers, signedHeaders, payload string) string {
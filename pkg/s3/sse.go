@@ -0,0 +1,288 @@
+package s3
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net/http"
+)
+
+const (
+	// SSEAlgorithm is the only supported algorithm of the server side
+	// encryption.
+	SSEAlgorithm = "AES256"
+
+	// SSES3 is the encryption with the data key managed by the server.
+	SSES3 = "SSE-S3"
+	// SSEC is the encryption with the key given by the client.
+	SSEC = "SSE-C"
+
+	// SSEKeySize is the size of the encryption keys in bytes.
+	SSEKeySize = 32
+
+	// SSEObjectPrefix is the header prefix of the sse-c key of the object
+	// of the request. The key of the copy source has the copy source
+	// prefix instead.
+	SSEObjectPrefix = "X-Amz-"
+
+	// sseSegmentSize is the size of the plaintext which is encrypted as a
+	// segment. The last segment can be shorter.
+	sseSegmentSize = 64 * 1024
+	// sseOverhead is the size of the authentication tag of each segment.
+	sseOverhead = 16
+)
+
+const (
+	sseHeader            = "X-Amz-Server-Side-Encryption"
+	sseCustomerAlgorithm = "Server-Side-Encryption-Customer-Algorithm"
+	sseCustomerKey       = "Server-Side-Encryption-Customer-Key"
+	sseCustomerKeyMD5    = "Server-Side-Encryption-Customer-Key-Md5"
+)
+
+// errSSEAuthentication is used when the encrypted segment is not the one
+// which is written with the key.
+var errSSEAuthentication = errors.New("s3: sse message authentication failed")
+
+// SSERequest is the server side encryption given by the request headers.
+type SSERequest struct {
+	// Type is SSES3 or SSEC, or empty if no encryption is given.
+	Type string
+	// CustomerKey is the key given by the client for SSEC.
+	CustomerKey []byte
+	// CustomerKeyMD5 is the base64 encoded md5 of the customer key, which
+	// is sent back to the client.
+	CustomerKeyMD5 string
+}
+
+// ParseSSE returns the server side encryption requested by the headers of
+// the request which writes the object.
+func ParseSSE(h http.Header) (SSERequest, ErrorCode) {
+	sse, code := ParseSSECustomerKey(h, SSEObjectPrefix)
+	if code != ErrNone {
+		return SSERequest{}, code
+	}
+
+	switch v := h.Get(sseHeader); {
+	case v == "":
+		return sse, ErrNone
+	case sse.Type != "":
+		// The encryption is either by the server or by the client.
+		return SSERequest{}, ErrInvalidArgument
+	case v == "aws:kms":
+		return SSERequest{}, ErrNotImplemented
+	case v != SSEAlgorithm:
+		return SSERequest{}, ErrInvalidArgument
+	}
+
+	return SSERequest{Type: SSES3}, ErrNone
+}
+
+// ParseSSECustomerKey returns the customer key given by the sse-c headers
+// with the prefix. The type is empty if the headers are not given.
+func ParseSSECustomerKey(h http.Header, prefix string) (SSERequest, ErrorCode) {
+	algorithm := h.Get(prefix + sseCustomerAlgorithm)
+	key := h.Get(prefix + sseCustomerKey)
+	keyMD5 := h.Get(prefix + sseCustomerKeyMD5)
+	if algorithm == "" && key == "" && keyMD5 == "" {
+		return SSERequest{}, ErrNone
+	}
+
+	if algorithm != SSEAlgorithm {
+		return SSERequest{}, ErrInvalidEncryptionAlgorithmError
+	}
+	k, err := base64.StdEncoding.DecodeString(key)
+	if err != nil || len(k) != SSEKeySize {
+		return SSERequest{}, ErrInvalidArgument
+	}
+	sum := md5.Sum(k)
+	if base64.StdEncoding.EncodeToString(sum[:]) != keyMD5 {
+		return SSERequest{}, ErrInvalidArgument
+	}
+
+	return SSERequest{Type: SSEC, CustomerKey: k, CustomerKeyMD5: keyMD5}, ErrNone
+}
+
+// SetSSEHeaders sets the response headers of the encryption of the object.
+// The md5 of the customer key is given by the client of the request, and
+// the sse-c headers are not set if it is empty.
+func SetSSEHeaders(h http.Header, typ, customerKeyMD5 string) {
+	switch typ {
+	case SSES3:
+		h.Set(sseHeader, SSEAlgorithm)
+	case SSEC:
+		if customerKeyMD5 != "" {
+			h.Set(SSEObjectPrefix+sseCustomerAlgorithm, SSEAlgorithm)
+			h.Set(SSEObjectPrefix+sseCustomerKeyMD5, customerKeyMD5)
+		}
+	}
+}
+
+// SSECustomerKeyDigest returns the salted md5 of the customer key, which
+// is stored to verify the key of the later requests without storing the
+// key itself.
+func SSECustomerKeyDigest(key, salt []byte) string {
+	h := md5.New()
+	h.Write(salt)
+	h.Write(key)
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// SSECustomerDataKey derives the data key of the object from the customer
+// key and the salt.
+func SSECustomerDataKey(key, salt []byte) []byte {
+	return sseMAC(key, salt)
+}
+
+// SSEPartKey derives the key of the stored data with the id from the data
+// key of the object. Each data of the object has its own key, so the same
+// nonces are not used twice with a key even if the part is uploaded again.
+func SSEPartKey(dataKey []byte, oid string) []byte {
+	return sseMAC(dataKey, []byte(oid))
+}
+
+// sseMAC returns the hmac-sha256 of the data with the key.
+func sseMAC(key, data []byte) []byte {
+	m := hmac.New(sha256.New, key)
+	m.Write(data)
+	return m.Sum(nil)
+}
+
+// EncryptedSize returns the size of the encrypted data of the plaintext
+// of the size.
+func EncryptedSize(size int64) int64 {
+	segments := (size + sseSegmentSize - 1) / sseSegmentSize
+	return size + segments*sseOverhead
+}
+
+// EncryptedRange returns the range of the encrypted data of the plaintext
+// of the size, which has the segments of the given range of the plaintext.
+func EncryptedRange(offset, length, size int64) (int64, int64) {
+	first := offset / sseSegmentSize
+	last := (offset + length - 1) / sseSegmentSize
+
+	start := first * (sseSegmentSize + sseOverhead)
+	end := (last + 1) * (sseSegmentSize + sseOverhead)
+	if max := EncryptedSize(size); end > max {
+		end = max
+	}
+	return start, end - start
+}
+
+// NewEncryptReader returns a reader of the encrypted data of the plaintext
+// read from r. The plaintext is sealed with aes-gcm in segments, and the
+// index of each segment is its nonce.
+func NewEncryptReader(r io.Reader, key []byte) (io.Reader, error) {
+	aead, err := sseAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &segmentReader{
+		r:    r,
+		aead: aead,
+		in:   make([]byte, sseSegmentSize),
+	}, nil
+}
+
+// NewDecryptReader returns a reader of the plaintext from the offset. The
+// encrypted data read from r starts at the segment of the offset, which is
+// the range given by EncryptedRange.
+func NewDecryptReader(r io.Reader, key []byte, offset int64) (io.Reader, error) {
+	aead, err := sseAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &segmentReader{
+		r:       r,
+		aead:    aead,
+		in:      make([]byte, sseSegmentSize+sseOverhead),
+		decrypt: true,
+		seq:     uint64(offset / sseSegmentSize),
+		skip:    int(offset % sseSegmentSize),
+	}, nil
+}
+
+// sseAEAD returns the aes-gcm cipher of the key.
+func sseAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// segmentReader encrypts or decrypts the data read from r in segments.
+type segmentReader struct {
+	r       io.Reader
+	aead    cipher.AEAD
+	decrypt bool
+
+	// seq is the index of the next segment.
+	seq uint64
+	// skip is the size of the plaintext in the first segment which is
+	// not read.
+	skip int
+
+	in  []byte
+	out []byte
+	buf []byte
+	err error
+}
+
+func (s *segmentReader) Read(p []byte) (int, error) {
+	for len(s.buf) == 0 {
+		if s.err != nil {
+			return 0, s.err
+		}
+		s.fill()
+	}
+
+	n := copy(p, s.buf)
+	s.buf = s.buf[n:]
+	return n, nil
+}
+
+// fill reads the next segment and seals or opens it.
+func (s *segmentReader) fill() {
+	n, err := io.ReadFull(s.r, s.in)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		// Only the last segment can be shorter.
+		s.err = io.EOF
+	} else if err != nil {
+		s.err = err
+		return
+	}
+	if n == 0 {
+		return
+	}
+
+	nonce := make([]byte, s.aead.NonceSize())
+	binary.BigEndian.PutUint64(nonce[len(nonce)-8:], s.seq)
+	s.seq++
+
+	if !s.decrypt {
+		s.out = s.aead.Seal(s.out[:0], nonce, s.in[:n], nil)
+		s.buf = s.out
+		return
+	}
+
+	s.out, err = s.aead.Open(s.out[:0], nonce, s.in[:n], nil)
+	if err != nil {
+		s.err = errSSEAuthentication
+		return
+	}
+	s.buf = s.out
+	if s.skip > 0 {
+		if s.skip > len(s.buf) {
+			s.skip = len(s.buf)
+		}
+		s.buf, s.skip = s.buf[s.skip:], 0
+	}
+}
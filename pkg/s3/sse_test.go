@@ -0,0 +1,107 @@
+package s3
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"testing"
+)
+
+func TestParseSSE(t *testing.T) {
+	key := bytes.Repeat([]byte{'k'}, SSEKeySize)
+	sum := md5.Sum(key)
+	encodedKey := base64.StdEncoding.EncodeToString(key)
+	keyMD5 := base64.StdEncoding.EncodeToString(sum[:])
+
+	customer := func(algorithm, key, keyMD5 string) http.Header {
+		return http.Header{
+			"X-Amz-Server-Side-Encryption-Customer-Algorithm": {algorithm},
+			"X-Amz-Server-Side-Encryption-Customer-Key":       {key},
+			"X-Amz-Server-Side-Encryption-Customer-Key-Md5":   {keyMD5},
+		}
+	}
+	both := customer(SSEAlgorithm, encodedKey, keyMD5)
+	both.Set("X-Amz-Server-Side-Encryption", SSEAlgorithm)
+
+	testCases := []struct {
+		header http.Header
+		typ    string
+		code   ErrorCode
+	}{
+		{http.Header{}, "", ErrNone},
+		{http.Header{"X-Amz-Server-Side-Encryption": {"AES256"}}, SSES3, ErrNone},
+		{http.Header{"X-Amz-Server-Side-Encryption": {"aws:kms"}}, "", ErrNotImplemented},
+		{http.Header{"X-Amz-Server-Side-Encryption": {"DES"}}, "", ErrInvalidArgument},
+		{customer(SSEAlgorithm, encodedKey, keyMD5), SSEC, ErrNone},
+		{customer("DES", encodedKey, keyMD5), "", ErrInvalidEncryptionAlgorithmError},
+		{customer(SSEAlgorithm, base64.StdEncoding.EncodeToString(key[1:]), keyMD5), "", ErrInvalidArgument},
+		{customer(SSEAlgorithm, encodedKey, ""), "", ErrInvalidArgument},
+		{both, "", ErrInvalidArgument},
+	}
+
+	for i, c := range testCases {
+		sse, code := ParseSSE(c.header)
+		if code != c.code || sse.Type != c.typ {
+			t.Errorf("case %d: expected %q %d, got %q %d", i, c.typ, c.code, sse.Type, code)
+		}
+	}
+}
+
+func TestSSEReader(t *testing.T) {
+	key := SSEPartKey(bytes.Repeat([]byte{'k'}, SSEKeySize), "oid")
+	size := int64(3*sseSegmentSize + 100)
+	plain := make([]byte, size)
+	rand.Read(plain)
+
+	r, err := NewEncryptReader(bytes.NewReader(plain), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if int64(len(enc)) != EncryptedSize(size) {
+		t.Fatalf("expected encrypted size %d, got %d", EncryptedSize(size), len(enc))
+	}
+
+	testCases := []struct {
+		offset, length int64
+	}{
+		{0, size},
+		{0, 1},
+		{sseSegmentSize - 1, 2},
+		{sseSegmentSize, sseSegmentSize},
+		{2*sseSegmentSize + 10, sseSegmentSize + 90},
+		{size - 1, 1},
+	}
+
+	for i, c := range testCases {
+		start, length := EncryptedRange(c.offset, c.length, size)
+		r, err := NewDecryptReader(bytes.NewReader(enc[start:start+length]), key, c.offset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Errorf("case %d: %v", i, err)
+			continue
+		}
+		if !bytes.HasPrefix(got, plain[c.offset:c.offset+c.length]) {
+			t.Errorf("case %d: plaintext mismatch", i)
+		}
+	}
+
+	// The modified data is not decrypted.
+	enc[sseSegmentSize+sseOverhead] ^= 1
+	r, err = NewDecryptReader(bytes.NewReader(enc), key, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ioutil.ReadAll(r); err != errSSEAuthentication {
+		t.Errorf("expected authentication failure, got %v", err)
+	}
+}
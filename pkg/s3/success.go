@@ -6,3 +6,19 @@ import "net/http"
 func SendSuccess(w http.ResponseWriter) {
 	writeResponse(w, nil, http.StatusOK)
 }
+
+// SendNoContent writes no content response to the given http.responseWriter.
+func SendNoContent(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SendNotModified writes not modified response to the given
+// http.responseWriter. The response must not have a body.
+func SendNotModified(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusNotModified)
+}
+
+// SendResponse writes ok response with the given xml message.
+func SendResponse(w http.ResponseWriter, response interface{}) {
+	writeResponse(w, response, http.StatusOK)
+}
@@ -0,0 +1,121 @@
+package s3
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// TaggingHeader is the header of the tags of the object to be created,
+	// which is encoded as the url query.
+	TaggingHeader = "X-Amz-Tagging"
+	// TaggingCountHeader is the response header of the number of the tags
+	// of the object.
+	TaggingCountHeader = "X-Amz-Tagging-Count"
+
+	// MaxObjectTags is the maximum number of the tags of an object.
+	MaxObjectTags = 10
+	// MaxTaggingSize is the maximum size of the request body of the put
+	// object tagging.
+	MaxTaggingSize = 64 * 1024
+
+	// maxTagKey and maxTagValue are the maximum lengths of the key and the
+	// value of a tag in characters.
+	maxTagKey   = 128
+	maxTagValue = 256
+
+	// reservedTagPrefix is the key prefix of the tags which the users can
+	// not create.
+	reservedTagPrefix = "aws:"
+)
+
+// Tagging is the tags of the object. It is the request body of the put
+// object tagging request and the response of the get object tagging
+// request.
+type Tagging struct {
+	XMLName xml.Name `xml:"Tagging"`
+	Xmlns   string   `xml:"xmlns,attr,omitempty"`
+	TagSet  TagSet
+}
+
+// TagSet is the list of the tags, which is present even if it is empty.
+type TagSet struct {
+	Tags []Tag `xml:"Tag"`
+}
+
+// NewTagging returns the tagging of the tags, which are sorted by the key.
+func NewTagging(tags map[string]string) Tagging {
+	t := Tagging{Xmlns: Namespace}
+	for k, v := range tags {
+		t.TagSet.Tags = append(t.TagSet.Tags, Tag{Key: k, Value: v})
+	}
+	sort.Slice(t.TagSet.Tags, func(i, j int) bool {
+		return t.TagSet.Tags[i].Key < t.TagSet.Tags[j].Key
+	})
+	return t
+}
+
+// Map checks the tags are valid and returns them keyed by the tag key.
+func (t *Tagging) Map() (map[string]string, ErrorCode) {
+	if code := validateTags(t.TagSet.Tags); code != ErrNone {
+		return nil, code
+	}
+
+	tags := make(map[string]string, len(t.TagSet.Tags))
+	for _, tag := range t.TagSet.Tags {
+		tags[tag.Key] = tag.Value
+	}
+	return tags, ErrNone
+}
+
+// ParseTaggingHeader returns the tags of the object to be created, which
+// are given by the tagging header. The tags are empty if the header is not
+// given.
+func ParseTaggingHeader(h http.Header) (map[string]string, ErrorCode) {
+	v := h.Get(TaggingHeader)
+	if v == "" {
+		return map[string]string{}, ErrNone
+	}
+
+	q, err := url.ParseQuery(v)
+	if err != nil {
+		return nil, ErrInvalidArgument
+	}
+	t := Tagging{}
+	for k, values := range q {
+		for _, v := range values {
+			t.TagSet.Tags = append(t.TagSet.Tags, Tag{Key: k, Value: v})
+		}
+	}
+	return t.Map()
+}
+
+// validateTags checks the number of the tags, the lengths of the keys and
+// the values, and that no key is given twice.
+func validateTags(tags []Tag) ErrorCode {
+	if len(tags) > MaxObjectTags {
+		return ErrInvalidTag
+	}
+
+	keys := make(map[string]bool, len(tags))
+	for _, t := range tags {
+		if t.Key == "" || utf8.RuneCountInString(t.Key) > maxTagKey {
+			return ErrInvalidTag
+		}
+		if utf8.RuneCountInString(t.Value) > maxTagValue {
+			return ErrInvalidTag
+		}
+		if strings.HasPrefix(strings.ToLower(t.Key), reservedTagPrefix) {
+			return ErrInvalidTag
+		}
+		if keys[t.Key] {
+			return ErrInvalidTag
+		}
+		keys[t.Key] = true
+	}
+	return ErrNone
+}
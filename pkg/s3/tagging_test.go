@@ -0,0 +1,76 @@
+package s3
+
+import (
+	"encoding/xml"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTaggingHeader(t *testing.T) {
+	testCases := []struct {
+		value string
+		tags  map[string]string
+		code  ErrorCode
+	}{
+		{"", map[string]string{}, ErrNone},
+		{"class=public", map[string]string{"class": "public"}, ErrNone},
+		{"class=public&retention=1y&empty=", map[string]string{"class": "public", "retention": "1y", "empty": ""}, ErrNone},
+		{"a%20b=c%2Fd", map[string]string{"a b": "c/d"}, ErrNone},
+		// The same key twice.
+		{"class=public&class=private", nil, ErrInvalidTag},
+		// The reserved prefix.
+		{"AWS:class=public", nil, ErrInvalidTag},
+		// The empty key.
+		{"=public", nil, ErrInvalidTag},
+		// Too long key and value.
+		{strings.Repeat("k", maxTagKey+1) + "=v", nil, ErrInvalidTag},
+		{"k=" + strings.Repeat("v", maxTagValue+1), nil, ErrInvalidTag},
+		// Too many tags.
+		{"a=1&b=2&c=3&d=4&e=5&f=6&g=7&h=8&i=9&j=10&k=11", nil, ErrInvalidTag},
+		{"a=%zz", nil, ErrInvalidArgument},
+	}
+
+	for i, c := range testCases {
+		tags, code := ParseTaggingHeader(http.Header{TaggingHeader: {c.value}})
+		if code != c.code {
+			t.Errorf("case %d: expected code %d, got %d", i, c.code, code)
+			continue
+		}
+		if code == ErrNone && !reflect.DeepEqual(tags, c.tags) {
+			t.Errorf("case %d: expected %v, got %v", i, c.tags, tags)
+		}
+	}
+}
+
+func TestTagging(t *testing.T) {
+	tags := map[string]string{"b": "2", "a": "1"}
+
+	b, err := xml.Marshal(NewTagging(tags))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `<Tagging xmlns="` + Namespace + `"><TagSet><Tag><Key>a</Key><Value>1</Value></Tag><Tag><Key>b</Key><Value>2</Value></Tag></TagSet></Tagging>`
+	if string(b) != expected {
+		t.Errorf("expected %s, got %s", expected, b)
+	}
+
+	// The empty tag set is still written.
+	b, err = xml.Marshal(NewTagging(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), "<TagSet></TagSet>") {
+		t.Errorf("expected the empty tag set, got %s", b)
+	}
+
+	parsed := Tagging{}
+	if err := xml.Unmarshal([]byte(expected), &parsed); err != nil {
+		t.Fatal(err)
+	}
+	got, code := parsed.Map()
+	if code != ErrNone || !reflect.DeepEqual(got, tags) {
+		t.Errorf("expected %v, got %v %d", tags, got, code)
+	}
+}
@@ -0,0 +1,94 @@
+package s3
+
+import "encoding/xml"
+
+const (
+	// Namespace is the xml name space of the s3 request and response body.
+	Namespace = "http://s3.amazonaws.com/doc/2006-03-01/"
+
+	// NullVersion is the version id of the object which is put while the
+	// versioning of the bucket is not enabled.
+	NullVersion = "null"
+)
+
+// VersioningConfiguration is the versioning state of the bucket. It is the
+// request body of the put bucket versioning request and the response of
+// the get bucket versioning request. The status is omitted if the
+// versioning has never been enabled on the bucket.
+type VersioningConfiguration struct {
+	XMLName   xml.Name `xml:"VersioningConfiguration"`
+	Xmlns     string   `xml:"xmlns,attr,omitempty"`
+	Status    string   `xml:",omitempty"`
+	MfaDelete string   `xml:",omitempty"`
+}
+
+// ObjectVersion is the entry of the version list, which is either the
+// version of the object or the delete marker.
+type ObjectVersion struct {
+	Key          string
+	VersionId    string
+	IsLatest     bool
+	LastModified string
+	ETag         string `xml:",omitempty"`
+	Size         *int64 `xml:",omitempty"`
+	StorageClass string `xml:",omitempty"`
+	Owner        Owner
+
+	deleteMarker bool
+}
+
+// MarshalXML encodes the entry in the element named after its kind.
+func (v ObjectVersion) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start.Name.Local = "Version"
+	if v.deleteMarker {
+		start.Name.Local = "DeleteMarker"
+	}
+
+	// The alias does not have the method, which prevents the recursion.
+	type entry ObjectVersion
+	return e.EncodeElement(entry(v), start)
+}
+
+// NewVersion returns the entry of the version of the object.
+func NewVersion(key, versionID string, isLatest bool, lastModified, etag string, size int64, owner Owner) ObjectVersion {
+	return ObjectVersion{
+		Key:          key,
+		VersionId:    versionID,
+		IsLatest:     isLatest,
+		LastModified: lastModified,
+		ETag:         etag,
+		Size:         &size,
+		StorageClass: "STANDARD",
+		Owner:        owner,
+	}
+}
+
+// NewDeleteMarker returns the entry of the delete marker.
+func NewDeleteMarker(key, versionID string, isLatest bool, lastModified string, owner Owner) ObjectVersion {
+	return ObjectVersion{
+		deleteMarker: true,
+		Key:          key,
+		VersionId:    versionID,
+		IsLatest:     isLatest,
+		LastModified: lastModified,
+		Owner:        owner,
+	}
+}
+
+// ListVersionsResult is the response of the list object versions request.
+// The versions and the delete markers are listed together in order.
+type ListVersionsResult struct {
+	XMLName             xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ ListVersionsResult"`
+	Name                string
+	Prefix              string
+	KeyMarker           string
+	VersionIdMarker     string
+	NextKeyMarker       string `xml:",omitempty"`
+	NextVersionIdMarker string `xml:",omitempty"`
+	MaxKeys             int
+	Delimiter           string `xml:",omitempty"`
+	EncodingType        string `xml:",omitempty"`
+	IsTruncated         bool
+	Versions            []ObjectVersion
+	CommonPrefixes      []CommonPrefix
+}
@@ -0,0 +1,69 @@
+package s3
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestDecodeVersioningConfiguration(t *testing.T) {
+	testCases := []struct {
+		body   string
+		status string
+	}{
+		{`<VersioningConfiguration xmlns="http://s3.amazonaws.com/doc/2006-03-01/"><Status>Enabled</Status></VersioningConfiguration>`, "Enabled"},
+		{`<VersioningConfiguration><Status>Suspended</Status></VersioningConfiguration>`, "Suspended"},
+	}
+
+	for _, c := range testCases {
+		v := VersioningConfiguration{}
+		if err := xml.Unmarshal([]byte(c.body), &v); err != nil {
+			t.Fatal(err)
+		}
+		if v.Status != c.status {
+			t.Errorf("expected status %q, got %q", c.status, v.Status)
+		}
+	}
+}
+
+func TestEncodeVersioningConfiguration(t *testing.T) {
+	b, err := xml.Marshal(VersioningConfiguration{Xmlns: Namespace})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `<VersioningConfiguration xmlns="` + Namespace + `"></VersioningConfiguration>`
+	if string(b) != expected {
+		t.Errorf("expected %s, got %s", expected, b)
+	}
+}
+
+func TestEncodeListVersionsResult(t *testing.T) {
+	owner := Owner{ID: "o", DisplayName: "o"}
+	b, err := xml.Marshal(ListVersionsResult{
+		Name: "bucket",
+		Versions: []ObjectVersion{
+			NewDeleteMarker("a", "v2", true, "t2", owner),
+			NewVersion("a", "v1", false, "t1", "\"e\"", 0, owner),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := string(b)
+	marker := strings.Index(s, "<DeleteMarker><Key>a</Key><VersionId>v2</VersionId><IsLatest>true</IsLatest>")
+	version := strings.Index(s, "<Version><Key>a</Key><VersionId>v1</VersionId><IsLatest>false</IsLatest>")
+	if marker < 0 || version < marker {
+		t.Errorf("unexpected order of the versions: %s", s)
+	}
+	if !strings.Contains(s, "<Size>0</Size>") {
+		t.Errorf("expected the size of the empty version: %s", s)
+	}
+	if strings.Count(s, "xmlns") != 1 {
+		t.Errorf("expected the name space only on the root: %s", s)
+	}
+	if strings.Count(s, "<Size>") != 1 {
+		t.Errorf("expected no size of the delete marker: %s", s)
+	}
+}
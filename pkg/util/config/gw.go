@@ -16,6 +16,18 @@ type Gw struct {
 	// WorkDir is a working directory of the gw.
 	WorkDir string
 
+	// BaseDomains is the comma separated domains of the gw. The request to
+	// the host which is the bucket name followed by one of the domains is
+	// addressed to the bucket in virtual-hosted-style.
+	BaseDomains string
+
+	// LocalClusterRegion is the region name of the local cluster. Requests
+	// signed for the other region are rejected.
+	LocalClusterRegion string
+	// ClockSkew is the maximum difference between the time of the signed
+	// request and the clock of the gw.
+	ClockSkew string
+
 	// LogLocation is the file path of mds logging.
 	// Default output path is stderr.
 	LogLocation string
@@ -16,6 +16,9 @@ type Mds struct {
 	// Rebalance is a period of check balance.
 	Rebalance string
 
+	// Lifecycle is a period of evaluating the lifecycle rules of the buckets.
+	Lifecycle string
+
 	// MySQLUser is the user ID of MySQL database.
 	MySQLUser string
 	// MySQLPassword is the password of MySQL user.
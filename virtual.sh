@@ -214,6 +214,7 @@ function rungw() {
       -p $port \
       --work-dir $workdir \
       --first-mds $HOST:$MDSBASEPORT \
+      --raft-local-cluster-region $region \
       --secure-certs-dir $CERTSDIR \
       -l log &
     echo $region gw $! >> $PID